@@ -0,0 +1,71 @@
+#!/usr/bin/env python3
+#######################################################################
+## Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+## SPDX-License-Identifier: Apache-2.0
+#######################################################################
+"""
+Function server of the python3 template of the process FaaS driver.
+
+It serves the function HANDLER ("module.function", e.g. hello.handle) on 127.0.0.1:PORT, following the function
+contract: GET /healthz returns 200 and POST / calls the function with the context and payload of the JSON message it
+receives, replying with the message holding the output and the logs of the function.
+"""
+
+import contextlib
+import importlib
+import io
+import json
+import os
+import sys
+import traceback
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+sys.path.insert(0, os.getcwd())
+module_name, function_name = os.environ["HANDLER"].rsplit(".", 1)
+
+
+class InputError(Exception):
+    pass
+
+
+class Handler(BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.reply(200 if self.path == "/healthz" else 404, {})
+
+    def do_POST(self):
+        try:
+            message = json.loads(self.rfile.read(int(self.headers.get("Content-Length", 0))) or "{}")
+        except ValueError as e:
+            self.reply(400, {"error": str(e)})
+            return
+        context = message.get("context") or {}
+        stdout, stderr = io.StringIO(), io.StringIO()
+        payload, error = None, None
+        with contextlib.redirect_stdout(stdout), contextlib.redirect_stderr(stderr):
+            try:
+                function = getattr(importlib.import_module(module_name), function_name)
+                payload = function(context, message.get("payload"))
+            except Exception as e:
+                error = {
+                    "type": "InputError" if isinstance(e, (InputError, ValueError)) else "FunctionError",
+                    "message": str(e),
+                    "stacktrace": traceback.format_exc().splitlines(),
+                }
+        context["logs"] = {"stdout": stdout.getvalue().splitlines(), "stderr": stderr.getvalue().splitlines()}
+        if error:
+            context["error"] = error
+        self.reply(200, {"context": context, "payload": payload})
+
+    def reply(self, code, body):
+        data = json.dumps(body).encode()
+        self.send_response(code)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(data)))
+        self.end_headers()
+        self.wfile.write(data)
+
+    def log_message(self, format, *args):
+        pass
+
+
+HTTPServer(("127.0.0.1", int(os.environ["PORT"])), Handler).serve_forever()
@@ -15,17 +15,17 @@ import (
 
 	"github.com/vmware/dispatch/pkg/api-manager/gateway"
 	"github.com/vmware/dispatch/pkg/controller"
+	"github.com/vmware/dispatch/pkg/coordination"
 	"github.com/vmware/dispatch/pkg/entity-store"
 	"github.com/vmware/dispatch/pkg/errors"
 	"github.com/vmware/dispatch/pkg/trace"
-	"github.com/vmware/dispatch/pkg/zookeeper"
 )
 
 // ControllerConfig defines configuration for controller
 type ControllerConfig struct {
-	ResyncPeriod      time.Duration
-	ZookeeperLocation string
-	Driver            zookeeper.Driver
+	ResyncPeriod time.Duration
+	Coordination coordination.Config
+	Driver       coordination.Driver
 }
 
 type apiEntityHandler struct {
@@ -140,10 +140,11 @@ func (h *apiEntityHandler) Error(ctx context.Context, obj entitystore.Entity) er
 // NewController creates a new controller
 func NewController(config *ControllerConfig, store entitystore.EntityStore, gw gateway.Gateway) controller.Controller {
 	c := controller.NewController(controller.Options{
-		ServiceName:       "APIs",
-		ResyncPeriod:      config.ResyncPeriod,
-		ZookeeperLocation: config.ZookeeperLocation,
-		Driver:            config.Driver,
+		ServiceName:  "APIs",
+		ResyncPeriod: config.ResyncPeriod,
+		Coordination: config.Coordination,
+		Driver:       config.Driver,
+		Store:        store,
 	})
 
 	c.AddEntityHandler(&apiEntityHandler{store: store, gw: gw})
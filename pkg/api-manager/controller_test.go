@@ -16,24 +16,24 @@ import (
 	"github.com/vmware/dispatch/pkg/api-manager/gateway"
 	"github.com/vmware/dispatch/pkg/api-manager/gateway/mocks"
 	"github.com/vmware/dispatch/pkg/controller"
+	coordmock "github.com/vmware/dispatch/pkg/coordination/mocks"
 	entitystore "github.com/vmware/dispatch/pkg/entity-store"
 	helpers "github.com/vmware/dispatch/pkg/testing/api"
-	zkmock "github.com/vmware/dispatch/pkg/zookeeper/mocks"
 )
 
 const (
-	testOrgID             = "dispatch"
-	testResyncPeriod      = 500 * time.Millisecond
-	testSleepDuration     = 2 * testResyncPeriod
-	testZookeeperLocation = "zookeeper.zookeeper.svc.cluster.local"
+	testOrgID         = "dispatch"
+	testResyncPeriod  = 500 * time.Millisecond
+	testSleepDuration = 2 * testResyncPeriod
+	testMember        = "member-0000000000"
 )
 
-func getTestDriver() *zkmock.Driver {
-	driver := &zkmock.Driver{}
-	driver.On("CreateNode", mock.Anything, mock.Anything).Return(nil)
-	driver.On("GetConnection").Return(nil)
+func getTestDriver() *coordmock.Driver {
+	driver := &coordmock.Driver{}
 	driver.On("LockEntity", mock.Anything).Return("lock", true)
 	driver.On("ReleaseEntity", "lock").Return(nil)
+	driver.On("Join", mock.Anything).Return(testMember, nil)
+	driver.On("Members", mock.Anything).Return([]string{testMember}, nil, nil)
 	driver.On("Close").Return(nil)
 	return driver
 }
@@ -171,7 +171,7 @@ func (h *Handlers) deleteAPI(params endpoint.DeleteAPIParams, principal interfac
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return endpoint.NewUpdateAPIBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -213,7 +213,7 @@ func (h *Handlers) getAPI(params endpoint.GetAPIParams, principal interface{}) m
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return endpoint.NewUpdateAPIBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -247,13 +247,22 @@ func (h *Handlers) getAPIs(params endpoint.GetApisParams, principal interface{})
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return endpoint.NewGetAPIBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
 				Message: swag.String(err.Error()),
 			})
 	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return endpoint.NewGetApisDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
 
 	err = h.Store.List(ctx, params.XDispatchOrg, opts, &apis)
 	if err != nil {
@@ -268,7 +277,7 @@ func (h *Handlers) getAPIs(params endpoint.GetApisParams, principal interface{})
 	for _, api := range apis {
 		apiModels = append(apiModels, apiEntityToModel(api))
 	}
-	return endpoint.NewGetApisOK().WithPayload(apiModels)
+	return endpoint.NewGetApisOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(apis))).WithPayload(apiModels)
 }
 
 func (h *Handlers) updateAPI(params endpoint.UpdateAPIParams, principal interface{}) middleware.Responder {
@@ -285,7 +294,7 @@ func (h *Handlers) updateAPI(params endpoint.UpdateAPIParams, principal interfac
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return endpoint.NewUpdateAPIBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -30,6 +30,9 @@ const (
 
 	// ErrorTypeSystemError captures enum value "SystemError"
 	ErrorTypeSystemError ErrorType = "SystemError"
+
+	// ErrorTypeTimeoutError captures enum value "TimeoutError"
+	ErrorTypeTimeoutError ErrorType = "TimeoutError"
 )
 
 // for schema
@@ -37,7 +40,7 @@ var errorTypeEnum []interface{}
 
 func init() {
 	var res []ErrorType
-	if err := json.Unmarshal([]byte(`["InputError","FunctionError","SystemError"]`), &res); err != nil {
+	if err := json.Unmarshal([]byte(`["InputError","FunctionError","SystemError","TimeoutError"]`), &res); err != nil {
 		panic(err)
 	}
 	for _, v := range res {
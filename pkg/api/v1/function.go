@@ -30,6 +30,10 @@ type Function struct {
 	// only used in seed.yaml
 	SourcePath string `json:"sourcePath,omitempty"`
 
+	// aliases of the function, pointing at weighted sets of its versions
+	// Read Only: true
+	Aliases []*FunctionAlias `json:"aliases"`
+
 	// created time
 	CreatedTime int64 `json:"createdTime,omitempty"`
 
@@ -46,6 +50,13 @@ type Function struct {
 	// functionImageURL
 	FunctionImageURL string `json:"functionImageURL,omitempty"`
 
+	// number of the latest published version of the function, 0 if no version was published
+	// Read Only: true
+	LatestVersion int64 `json:"latestVersion,omitempty"`
+
+	// limits
+	Limits *FunctionResources `json:"limits,omitempty"`
+
 	// kind
 	// Read Only: true
 	// Pattern: ^[\w\d\-]+$
@@ -63,9 +74,22 @@ type Function struct {
 	// Pattern: ^[\w\d][\w\d\-]*$
 	Name *string `json:"name"`
 
+	// number of instances of the function kept running when it is idle
+	// Minimum: 0
+	PreWarm int64 `json:"preWarm,omitempty"`
+
 	// reason
 	Reason []string `json:"reason"`
 
+	// retry policy of the asynchronous runs of the function
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// retention of the finished runs of the function
+	RunRetention *RunRetention `json:"runRetention,omitempty"`
+
+	// sandbox
+	Sandbox *FunctionSandbox `json:"sandbox,omitempty"`
+
 	// schema
 	Schema *Schema `json:"schema,omitempty"`
 
@@ -89,6 +113,11 @@ type Function struct {
 func (m *Function) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateAliases(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateFaasID(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -109,11 +138,36 @@ func (m *Function) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateLimits(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateName(formats); err != nil {
 		// prop
 		res = append(res, err)
 	}
 
+	if err := m.validatePreWarm(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRetryPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRunRetention(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateSandbox(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateSchema(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -145,6 +199,34 @@ func (m *Function) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Function) validateAliases(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Aliases) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Aliases); i++ {
+
+		if swag.IsZero(m.Aliases[i]) { // not required
+			continue
+		}
+
+		if m.Aliases[i] != nil {
+
+			if err := m.Aliases[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("aliases" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Function) validateFaasID(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.FaasID) { // not required
@@ -206,6 +288,99 @@ func (m *Function) validateName(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Function) validateLimits(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Limits) { // not required
+		return nil
+	}
+
+	if m.Limits != nil {
+
+		if err := m.Limits.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("limits")
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
+func (m *Function) validatePreWarm(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.PreWarm) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("preWarm", "body", int64(m.PreWarm), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Function) validateRetryPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RetryPolicy) { // not required
+		return nil
+	}
+
+	if m.RetryPolicy != nil {
+
+		if err := m.RetryPolicy.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("retryPolicy")
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
+func (m *Function) validateRunRetention(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RunRetention) { // not required
+		return nil
+	}
+
+	if m.RunRetention != nil {
+
+		if err := m.RunRetention.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("runRetention")
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
+func (m *Function) validateSandbox(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Sandbox) { // not required
+		return nil
+	}
+
+	if m.Sandbox != nil {
+
+		if err := m.Sandbox.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("sandbox")
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Function) validateSchema(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.Schema) { // not required
@@ -0,0 +1,155 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	"strconv"
+
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// NO TESTS
+
+// FunctionAlias function alias
+// swagger:model FunctionAlias
+type FunctionAlias struct {
+
+	// name
+	// Read Only: true
+	// Pattern: ^[\w\d][\w\d\-]*$
+	Name string `json:"name,omitempty"`
+
+	// versions the alias pointed at before its last update, restored by a rollback
+	// Read Only: true
+	Previous []*VersionWeight `json:"previous"`
+
+	// versions the runs of the alias are split between
+	// Required: true
+	// Min Items: 1
+	Routes []*VersionWeight `json:"routes"`
+}
+
+// Validate validates this function alias
+func (m *FunctionAlias) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateName(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validatePrevious(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRoutes(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *FunctionAlias) validateName(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Name) { // not required
+		return nil
+	}
+
+	if err := FieldPatternName.Validate("name", m.Name); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *FunctionAlias) validatePrevious(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Previous) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Previous); i++ {
+
+		if swag.IsZero(m.Previous[i]) { // not required
+			continue
+		}
+
+		if m.Previous[i] != nil {
+
+			if err := m.Previous[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("previous" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+
+		}
+
+	}
+
+	return nil
+}
+
+func (m *FunctionAlias) validateRoutes(formats strfmt.Registry) error {
+
+	if err := validate.Required("routes", "body", m.Routes); err != nil {
+		return err
+	}
+
+	iRoutesSize := int64(len(m.Routes))
+
+	if err := validate.MinItems("routes", "body", iRoutesSize, 1); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(m.Routes); i++ {
+
+		if swag.IsZero(m.Routes[i]) { // not required
+			continue
+		}
+
+		if m.Routes[i] != nil {
+
+			if err := m.Routes[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("routes" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+
+		}
+
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *FunctionAlias) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *FunctionAlias) UnmarshalBinary(b []byte) error {
+	var res FunctionAlias
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
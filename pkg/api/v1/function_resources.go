@@ -0,0 +1,77 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// NO TESTS
+
+// FunctionResources function resources
+// swagger:model FunctionResources
+type FunctionResources struct {
+
+	// CPU, e.g. 500m
+	CPU string `json:"cpu,omitempty"`
+
+	// memory, e.g. 128Mi
+	Memory string `json:"memory,omitempty"`
+
+	// maximum number of processes
+	// Minimum: 0
+	Pids int64 `json:"pids,omitempty"`
+}
+
+// Validate validates this function resources
+func (m *FunctionResources) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validatePids(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *FunctionResources) validatePids(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Pids) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("pids", "body", int64(m.Pids), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *FunctionResources) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *FunctionResources) UnmarshalBinary(b []byte) error {
+	var res FunctionResources
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -0,0 +1,60 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+)
+
+// NO TESTS
+
+// FunctionSandbox function sandbox
+// swagger:model FunctionSandbox
+type FunctionSandbox struct {
+
+	// Linux capabilities to drop, ALL drops all of them
+	DropCapabilities []string `json:"dropCapabilities"`
+
+	// deny network access to the function
+	NoNetwork bool `json:"noNetwork,omitempty"`
+
+	// mount the root filesystem read-only
+	ReadOnlyRootFS bool `json:"readOnlyRootFS,omitempty"`
+
+	// JSON seccomp profile, or unconfined
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+}
+
+// Validate validates this function sandbox
+func (m *FunctionSandbox) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *FunctionSandbox) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *FunctionSandbox) UnmarshalBinary(b []byte) error {
+	var res FunctionSandbox
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -0,0 +1,121 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	"encoding/json"
+
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// NO TESTS
+
+// LogLine log line
+// swagger:model LogLine
+type LogLine struct {
+
+	// line
+	// Required: true
+	Line *string `json:"line"`
+
+	// stream the line was written to
+	// Required: true
+	// Enum: [stdout stderr]
+	Stream *string `json:"stream"`
+}
+
+// Validate validates this log line
+func (m *LogLine) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateLine(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStream(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *LogLine) validateLine(formats strfmt.Registry) error {
+
+	if err := validate.Required("line", "body", m.Line); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var logLineStreamEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["stdout","stderr"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		logLineStreamEnum = append(logLineStreamEnum, v)
+	}
+}
+
+const (
+
+	// LogLineStreamStdout captures enum value "stdout"
+	LogLineStreamStdout string = "stdout"
+
+	// LogLineStreamStderr captures enum value "stderr"
+	LogLineStreamStderr string = "stderr"
+)
+
+func (m *LogLine) validateStreamEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, logLineStreamEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *LogLine) validateStream(formats strfmt.Registry) error {
+
+	if err := validate.Required("stream", "body", m.Stream); err != nil {
+		return err
+	}
+
+	// value enum
+	if err := m.validateStreamEnum("stream", "body", *m.Stream); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *LogLine) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *LogLine) UnmarshalBinary(b []byte) error {
+	var res LogLine
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
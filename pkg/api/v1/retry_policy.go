@@ -0,0 +1,150 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	"strconv"
+
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// NO TESTS
+
+// RetryPolicy retry policy
+// swagger:model RetryPolicy
+type RetryPolicy struct {
+
+	// delay before the second attempt in milliseconds, doubled after each attempt
+	// Minimum: 100
+	Backoff int64 `json:"backoff,omitempty"`
+
+	// maximum number of attempts, including the first one
+	// Maximum: 10
+	// Minimum: 1
+	MaxAttempts int64 `json:"maxAttempts,omitempty"`
+
+	// maximum delay between attempts in milliseconds
+	// Minimum: 0
+	MaxBackoff int64 `json:"maxBackoff,omitempty"`
+
+	// error types which are retried, all but InputError if empty
+	RetryOn []ErrorType `json:"retryOn"`
+}
+
+// Validate validates this retry policy
+func (m *RetryPolicy) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateBackoff(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMaxAttempts(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMaxBackoff(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRetryOn(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *RetryPolicy) validateBackoff(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Backoff) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("backoff", "body", int64(m.Backoff), 100, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RetryPolicy) validateMaxAttempts(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaxAttempts) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("maxAttempts", "body", int64(m.MaxAttempts), 1, false); err != nil {
+		return err
+	}
+
+	if err := validate.MaximumInt("maxAttempts", "body", int64(m.MaxAttempts), 10, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RetryPolicy) validateMaxBackoff(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaxBackoff) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("maxBackoff", "body", int64(m.MaxBackoff), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RetryPolicy) validateRetryOn(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RetryOn) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.RetryOn); i++ {
+
+		if err := m.RetryOn[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("retryOn" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *RetryPolicy) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *RetryPolicy) UnmarshalBinary(b []byte) error {
+	var res RetryPolicy
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -21,9 +21,21 @@ import (
 // swagger:model Run
 type Run struct {
 
+	// alias of the function to run, the version run is picked by weight among the versions of the alias
+	// Pattern: ^[\w\d][\w\d\-]*$
+	Alias string `json:"alias,omitempty"`
+
+	// attempts
+	// Read Only: true
+	Attempts []*RunAttempt `json:"attempts"`
+
 	// blocking
 	Blocking bool `json:"blocking,omitempty"`
 
+	// cold start latency in milliseconds, if instances of the function were started for the run
+	// Read Only: true
+	ColdStart int64 `json:"coldStart,omitempty"`
+
 	// error
 	Error *InvocationError `json:"error,omitempty"`
 
@@ -70,6 +82,9 @@ type Run struct {
 	// reason
 	Reason []string `json:"reason"`
 
+	// retry policy, used when the run is asynchronous. It overrides the retry policy of the function
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
 	// secrets
 	Secrets []string `json:"secrets"`
 
@@ -81,12 +96,26 @@ type Run struct {
 
 	// tags
 	Tags []*Tag `json:"tags"`
+
+	// version of the function to run, or the version picked through the alias
+	// Minimum: 0
+	Version int64 `json:"version,omitempty"`
 }
 
 // Validate validates this run
 func (m *Run) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateAlias(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateAttempts(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateError(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -117,6 +146,11 @@ func (m *Run) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateRetryPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateSecrets(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -137,12 +171,58 @@ func (m *Run) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateVersion(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
+func (m *Run) validateAlias(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Alias) { // not required
+		return nil
+	}
+
+	if err := FieldPatternName.Validate("alias", m.Alias); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Run) validateAttempts(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Attempts) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Attempts); i++ {
+
+		if swag.IsZero(m.Attempts[i]) { // not required
+			continue
+		}
+
+		if m.Attempts[i] != nil {
+
+			if err := m.Attempts[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("attempts" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Run) validateError(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.Error) { // not required
@@ -238,6 +318,26 @@ func (m *Run) validateReason(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Run) validateRetryPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RetryPolicy) { // not required
+		return nil
+	}
+
+	if m.RetryPolicy != nil {
+
+		if err := m.RetryPolicy.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("retryPolicy")
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Run) validateSecrets(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.Secrets) { // not required
@@ -300,6 +400,19 @@ func (m *Run) validateTags(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Run) validateVersion(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Version) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("version", "body", int64(m.Version), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *Run) MarshalBinary() ([]byte, error) {
 	if m == nil {
@@ -0,0 +1,88 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+)
+
+// NO TESTS
+
+// RunAttempt run attempt
+// swagger:model RunAttempt
+type RunAttempt struct {
+
+	// attempt number, starting at 1
+	// Read Only: true
+	Attempt int64 `json:"attempt,omitempty"`
+
+	// error
+	Error *InvocationError `json:"error,omitempty"`
+
+	// executed time
+	// Read Only: true
+	ExecutedTime int64 `json:"executedTime,omitempty"`
+
+	// finished time
+	// Read Only: true
+	FinishedTime int64 `json:"finishedTime,omitempty"`
+}
+
+// Validate validates this run attempt
+func (m *RunAttempt) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateError(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *RunAttempt) validateError(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Error) { // not required
+		return nil
+	}
+
+	if m.Error != nil {
+
+		if err := m.Error.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("error")
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *RunAttempt) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *RunAttempt) UnmarshalBinary(b []byte) error {
+	var res RunAttempt
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
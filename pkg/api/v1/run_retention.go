@@ -0,0 +1,93 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// NO TESTS
+
+// RunRetention run retention
+// swagger:model RunRetention
+type RunRetention struct {
+
+	// age in seconds after which finished runs are deleted, not limited if 0
+	// Minimum: 0
+	MaxAge int64 `json:"maxAge,omitempty"`
+
+	// maximum number of finished runs kept, not limited if 0
+	// Minimum: 0
+	MaxCount int64 `json:"maxCount,omitempty"`
+}
+
+// Validate validates this run retention
+func (m *RunRetention) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateMaxAge(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMaxCount(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *RunRetention) validateMaxAge(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaxAge) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("maxAge", "body", int64(m.MaxAge), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RunRetention) validateMaxCount(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaxCount) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("maxCount", "body", int64(m.MaxCount), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *RunRetention) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *RunRetention) UnmarshalBinary(b []byte) error {
+	var res RunRetention
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -42,6 +42,9 @@ const (
 
 	//StatusDELETED captures enum value "DELETED"
 	StatusDELETED Status = "DELETED"
+
+	// StatusCANCELLED captures enum value "CANCELLED"
+	StatusCANCELLED Status = "CANCELLED"
 )
 
 // NO TESTS
@@ -51,7 +54,7 @@ var statusEnum []interface{}
 
 func init() {
 	var res []Status
-	if err := json.Unmarshal([]byte(`["INITIALIZED","CREATING","READY","UPDATING","ERROR","DELETING"]`), &res); err != nil {
+	if err := json.Unmarshal([]byte(`["INITIALIZED","CREATING","READY","UPDATING","ERROR","DELETING","CANCELLED"]`), &res); err != nil {
 		panic(err)
 	}
 	for _, v := range res {
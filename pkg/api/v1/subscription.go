@@ -25,12 +25,15 @@ type Subscription struct {
 	// Read Only: true
 	CreatedTime int64 `json:"createdTime,omitempty"`
 
-	// event type
+	// event type, * matches any characters
 	// Required: true
 	// Max Length: 128
-	// Pattern: ^[\w\d\-\.]+$
+	// Pattern: ^[\w\d\-\.\*]+$
 	EventType *string `json:"eventType"`
 
+	// filter
+	Filter *SubscriptionFilter `json:"filter,omitempty"`
+
 	// function
 	// Required: true
 	// Pattern: ^[\w\d\-]+$
@@ -74,6 +77,11 @@ func (m *Subscription) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateFilter(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateFunction(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -124,12 +132,32 @@ func (m *Subscription) validateEventType(formats strfmt.Registry) error {
 		return err
 	}
 
-	if err := validate.Pattern("eventType", "body", string(*m.EventType), `^[\w\d\-\.]+$`); err != nil {
+	if err := validate.Pattern("eventType", "body", string(*m.EventType), `^[\w\d\-\.\*]+$`); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (m *Subscription) validateFilter(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Filter) { // not required
+		return nil
+	}
+
+	if m.Filter != nil {
+
+		if err := m.Filter.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("filter")
+			}
+			return err
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Subscription) validateFunction(formats strfmt.Registry) error {
 
 	if err := validate.Required("function", "body", m.Function); err != nil {
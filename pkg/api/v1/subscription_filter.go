@@ -0,0 +1,54 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/swag"
+)
+
+// NO TESTS
+
+// SubscriptionFilter subscription filter
+// swagger:model SubscriptionFilter
+type SubscriptionFilter struct {
+
+	// JSONPath predicates over the event data, e.g. "$.vm.cpus >= 4", all must match
+	Data []string `json:"data"`
+
+	// event type version, * matches any characters
+	EventTypeVersion string `json:"eventTypeVersion,omitempty"`
+
+	// extension values by extension name, * matches any characters
+	Extensions map[string]string `json:"extensions,omitempty"`
+
+	// event source, * matches any characters
+	Source string `json:"source,omitempty"`
+}
+
+// Validate validates this subscription filter
+func (m *SubscriptionFilter) Validate(formats strfmt.Registry) error {
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *SubscriptionFilter) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *SubscriptionFilter) UnmarshalBinary(b []byte) error {
+	var res SubscriptionFilter
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -0,0 +1,94 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package v1
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// NO TESTS
+
+// VersionWeight version weight
+// swagger:model VersionWeight
+type VersionWeight struct {
+
+	// version of the function
+	// Required: true
+	// Minimum: 1
+	Version *int64 `json:"version"`
+
+	// share of the runs of the alias sent to the version, relative to the weights of the other versions of the alias
+	// Minimum: 0
+	Weight int64 `json:"weight,omitempty"`
+}
+
+// Validate validates this version weight
+func (m *VersionWeight) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateVersion(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateWeight(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *VersionWeight) validateVersion(formats strfmt.Registry) error {
+
+	if err := validate.Required("version", "body", m.Version); err != nil {
+		return err
+	}
+
+	if err := validate.MinimumInt("version", "body", int64(*m.Version), 1, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *VersionWeight) validateWeight(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Weight) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("weight", "body", int64(m.Weight), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *VersionWeight) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *VersionWeight) UnmarshalBinary(b []byte) error {
+	var res VersionWeight
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -184,7 +184,26 @@ func (h *Handlers) getApps(params application.GetAppsParams, principal interface
 	opts := entitystore.Options{
 		Filter: entitystore.FilterExists(),
 	}
-	err := h.store.List(ctx, params.XDispatchOrg, opts, &apps)
+	var err error
+	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
+	if err != nil {
+		log.Debugf("%v", err)
+		return application.NewGetAppsDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return application.NewGetAppsDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	err = h.store.List(ctx, params.XDispatchOrg, opts, &apps)
 	if err != nil {
 		log.Errorf("store error when listing applications: %+v", err)
 		return application.NewGetAppsDefault(http.StatusInternalServerError).WithPayload(
@@ -197,7 +216,7 @@ func (h *Handlers) getApps(params application.GetAppsParams, principal interface
 	for _, app := range apps {
 		appModels = append(appModels, applicationEntityToModel(app))
 	}
-	return application.NewGetAppsOK().WithPayload(appModels)
+	return application.NewGetAppsOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(apps))).WithPayload(appModels)
 }
 
 func (h *Handlers) updateApp(params application.UpdateAppParams, principal interface{}) middleware.Responder {
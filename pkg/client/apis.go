@@ -23,7 +23,7 @@ type APIsClient interface {
 	DeleteAPI(ctx context.Context, organizationID string, apiName string) (*v1.API, error)
 	UpdateAPI(ctx context.Context, organizationID string, api *v1.API) (*v1.API, error)
 	GetAPI(ctx context.Context, organizationID string, apiName string) (*v1.API, error)
-	ListAPIs(ctx context.Context, organizationID string) ([]v1.API, error)
+	ListAPIs(ctx context.Context, organizationID string, opts ListOpts) ([]v1.API, string, error)
 }
 
 // NewAPIsClient is used to create a new APIs client
@@ -188,21 +188,25 @@ func getAPISwaggerError(err error) error {
 }
 
 // ListAPIs returns a list of APIs
-func (c *DefaultAPIsClient) ListAPIs(ctx context.Context, organizationID string) ([]v1.API, error) {
+func (c *DefaultAPIsClient) ListAPIs(ctx context.Context, organizationID string, opts ListOpts) ([]v1.API, string, error) {
 	params := endpoint.GetApisParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Endpoint.GetApis(&params, c.auth)
 	if err != nil {
-		return nil, listAPIsSwaggerError(err)
+		return nil, "", listAPIsSwaggerError(err)
 	}
 
 	apis := []v1.API{}
 	for _, api := range response.Payload {
 		apis = append(apis, *api)
 	}
-	return apis, nil
+	return apis, response.XDispatchNextPage, nil
 }
 
 func listAPIsSwaggerError(err error) error {
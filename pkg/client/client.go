@@ -54,6 +54,40 @@ func DefaultHTTPClient(host, basePath string) *swaggerclient.Runtime {
 	return transport
 }
 
+// ListOpts are options for listing a page of items
+type ListOpts struct {
+	// Limit is the maximum number of items to list, 0 means no limit
+	Limit int64
+	// OrderBy is the field by which items are sorted, prefix it with "-" for a descending order
+	OrderBy string
+	// Page is the token of the page to list, as returned by the previous list call
+	Page string
+	// Tags filter the listed items, e.g. "env=prod" or "tier=gold|silver", see utils.ParseTags for the syntax.
+	// They are ignored when listing identity resources.
+	Tags []string
+}
+
+func (o ListOpts) limit() *int64 {
+	if o.Limit == 0 {
+		return nil
+	}
+	return &o.Limit
+}
+
+func (o ListOpts) orderBy() *string {
+	if o.OrderBy == "" {
+		return nil
+	}
+	return &o.OrderBy
+}
+
+func (o ListOpts) page() *string {
+	if o.Page == "" {
+		return nil
+	}
+	return &o.Page
+}
+
 // baseClient represents fields & methods common for all Dispatch services.
 type baseClient struct {
 	organizationID string
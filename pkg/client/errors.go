@@ -71,6 +71,18 @@ func NewErrorAlreadyExists(apiError *v1.Error) *ErrorAlreadyExists {
 	}
 }
 
+// ErrorConflict represents error when the resource is in a state which does not allow the request
+type ErrorConflict struct {
+	baseError
+}
+
+// NewErrorConflict creates new instance of ErrorConflict based on Error Model
+func NewErrorConflict(apiError *v1.Error) *ErrorConflict {
+	return &ErrorConflict{
+		baseError: baseErrFromModel(apiError),
+	}
+}
+
 // ErrorNotFound represents error of missing resource
 type ErrorNotFound struct {
 	baseError
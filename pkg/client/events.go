@@ -27,21 +27,21 @@ type EventsClient interface {
 	CreateSubscription(ctx context.Context, organizationID string, subscription *v1.Subscription) (*v1.Subscription, error)
 	DeleteSubscription(ctx context.Context, organizationID string, subscriptionName string) (*v1.Subscription, error)
 	GetSubscription(ctx context.Context, organizationID string, subscriptionName string) (*v1.Subscription, error)
-	ListSubscriptions(ctx context.Context, organizationID string) ([]v1.Subscription, error)
+	ListSubscriptions(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Subscription, string, error)
 	UpdateSubscription(ctx context.Context, organizationID string, subscription *v1.Subscription) (*v1.Subscription, error)
 
 	// Event Drivers
 	CreateEventDriver(ctx context.Context, organizationID string, eventDriver *v1.EventDriver) (*v1.EventDriver, error)
 	DeleteEventDriver(ctx context.Context, organizationID string, eventDriverName string) (*v1.EventDriver, error)
 	GetEventDriver(ctx context.Context, organizationID string, eventDriverName string) (*v1.EventDriver, error)
-	ListEventDrivers(ctx context.Context, organizationID string) ([]v1.EventDriver, error)
+	ListEventDrivers(ctx context.Context, organizationID string, opts ListOpts) ([]v1.EventDriver, string, error)
 	UpdateEventDriver(ctx context.Context, organizationID string, eventDriver *v1.EventDriver) (*v1.EventDriver, error)
 
 	// Event Driver Types
 	CreateEventDriverType(ctx context.Context, organizationID string, eventDriverType *v1.EventDriverType) (*v1.EventDriverType, error)
 	DeleteEventDriverType(ctx context.Context, organizationID string, eventDriverTypeName string) (*v1.EventDriverType, error)
 	GetEventDriverType(ctx context.Context, organizationID string, eventDriverTypeName string) (*v1.EventDriverType, error)
-	ListEventDriverTypes(ctx context.Context, organizationID string) ([]v1.EventDriverType, error)
+	ListEventDriverTypes(ctx context.Context, organizationID string, opts ListOpts) ([]v1.EventDriverType, string, error)
 	UpdateEventDriverType(ctx context.Context, organizationID string, eventDriverType *v1.EventDriverType) (*v1.EventDriverType, error)
 }
 
@@ -204,20 +204,24 @@ func getSubscriptionSwaggerError(err error) error {
 }
 
 // ListSubscriptions lists all subscriptions
-func (c *DefaultEventsClient) ListSubscriptions(ctx context.Context, organizationID string) ([]v1.Subscription, error) {
+func (c *DefaultEventsClient) ListSubscriptions(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Subscription, string, error) {
 	params := subscriptions.GetSubscriptionsParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Subscriptions.GetSubscriptions(&params, c.auth)
 	if err != nil {
-		return nil, listSubscriptionsSwaggerError(err)
+		return nil, "", listSubscriptionsSwaggerError(err)
 	}
 	subscriptions := []v1.Subscription{}
 	for _, f := range response.Payload {
 		subscriptions = append(subscriptions, *f)
 	}
-	return subscriptions, nil
+	return subscriptions, response.XDispatchNextPage, nil
 }
 
 func listSubscriptionsSwaggerError(err error) error {
@@ -379,20 +383,24 @@ func getDriverSwaggerError(err error) error {
 }
 
 // ListEventDrivers lists all drivers
-func (c *DefaultEventsClient) ListEventDrivers(ctx context.Context, organizationID string) ([]v1.EventDriver, error) {
+func (c *DefaultEventsClient) ListEventDrivers(ctx context.Context, organizationID string, opts ListOpts) ([]v1.EventDriver, string, error) {
 	params := drivers.GetDriversParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Drivers.GetDrivers(&params, c.auth)
 	if err != nil {
-		return nil, listDriversSwaggerError(err)
+		return nil, "", listDriversSwaggerError(err)
 	}
 	drivers := []v1.EventDriver{}
 	for _, f := range response.Payload {
 		drivers = append(drivers, *f)
 	}
-	return drivers, nil
+	return drivers, response.XDispatchNextPage, nil
 }
 
 func listDriversSwaggerError(err error) error {
@@ -554,20 +562,24 @@ func getDriverTypeSwaggerError(err error) error {
 }
 
 // ListEventDriverTypes lists all drivers
-func (c *DefaultEventsClient) ListEventDriverTypes(ctx context.Context, organizationID string) ([]v1.EventDriverType, error) {
+func (c *DefaultEventsClient) ListEventDriverTypes(ctx context.Context, organizationID string, opts ListOpts) ([]v1.EventDriverType, string, error) {
 	params := drivers.GetDriverTypesParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Drivers.GetDriverTypes(&params, c.auth)
 	if err != nil {
-		return nil, listDriverTypesSwaggerError(err)
+		return nil, "", listDriverTypesSwaggerError(err)
 	}
 	drivers := []v1.EventDriverType{}
 	for _, f := range response.Payload {
 		drivers = append(drivers, *f)
 	}
-	return drivers, nil
+	return drivers, response.XDispatchNextPage, nil
 }
 
 func listDriverTypesSwaggerError(err error) error {
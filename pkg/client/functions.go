@@ -8,6 +8,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/go-openapi/runtime"
@@ -25,18 +26,29 @@ type FunctionsClient interface {
 	// Function Runner
 	RunFunction(ctx context.Context, organizationID string, run *v1.Run) (*v1.Run, error)
 	GetFunctionRun(ctx context.Context, organizationID string, opts FunctionOpts) (*v1.Run, error)
-	ListRuns(ctx context.Context, organizationID string, opts FunctionOpts) ([]v1.Run, error)
+	GetRunLogs(ctx context.Context, organizationID string, opts FunctionOpts, follow bool, out io.Writer) error
+	ListRuns(ctx context.Context, organizationID string, opts FunctionOpts) ([]v1.Run, string, error)
+	CancelRun(ctx context.Context, organizationID string, opts FunctionOpts) (*v1.Run, error)
+	DeleteRuns(ctx context.Context, organizationID string, opts FunctionOpts, olderThan time.Time) (int64, error)
 
 	// Function store
 	CreateFunction(ctx context.Context, organizationID string, function *v1.Function) (*v1.Function, error)
 	DeleteFunction(ctx context.Context, organizationID string, functionName string) (*v1.Function, error)
 	GetFunction(ctx context.Context, organizationID string, functionName string) (*v1.Function, error)
-	ListFunctions(ctx context.Context, organizationID string) ([]v1.Function, error)
+	ListFunctions(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Function, string, error)
 	UpdateFunction(ctx context.Context, organizationID string, function *v1.Function) (*v1.Function, error)
+
+	// Function versions and aliases
+	PublishFunction(ctx context.Context, organizationID string, functionName string) (*v1.FunctionVersion, error)
+	SetAlias(ctx context.Context, organizationID string, functionName string, alias *v1.FunctionAlias) (*v1.Function, error)
+	DeleteAlias(ctx context.Context, organizationID string, functionName string, aliasName string) (*v1.Function, error)
+	RollbackAlias(ctx context.Context, organizationID string, functionName string, aliasName string) (*v1.Function, error)
 }
 
 // FunctionOpts are options for retrieving function runs
 type FunctionOpts struct {
+	ListOpts
+
 	FunctionName *string
 	RunName      *string
 	Since        time.Time
@@ -148,24 +160,135 @@ func getRunSwaggerError(err error) error {
 	}
 }
 
+// GetRunLogs writes the log lines of a function run to out, one JSON object per line. If follow is set, the lines
+// are written as the run logs them, until the run finishes.
+func (c *DefaultFunctionsClient) GetRunLogs(ctx context.Context, organizationID string, opts FunctionOpts, follow bool, out io.Writer) error {
+	params := runner.GetRunLogsParams{
+		Context:      ctx,
+		XDispatchOrg: c.getOrgID(organizationID),
+		FunctionName: opts.FunctionName,
+		RunName:      strfmt.UUID(*opts.RunName),
+		Follow:       &follow,
+	}
+	_, err := c.client.Runner.GetRunLogs(&params, c.auth, out)
+	return getRunLogsSwaggerError(err)
+}
+
+func getRunLogsSwaggerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch v := err.(type) {
+	case *runner.GetRunLogsUnauthorized:
+		return NewErrorUnauthorized(v.Payload)
+	case *runner.GetRunLogsForbidden:
+		return NewErrorForbidden(v.Payload)
+	case *runner.GetRunLogsNotFound:
+		return NewErrorNotFound(v.Payload)
+	case *runner.GetRunLogsDefault:
+		return NewErrorServerUnknownError(v.Payload)
+	default:
+		// shouldn't happen, but we need to be prepared:
+		return fmt.Errorf("unexpected error received from server: %s", err)
+	}
+}
+
+// CancelRun cancels a function run which has not finished yet
+func (c *DefaultFunctionsClient) CancelRun(ctx context.Context, organizationID string, opts FunctionOpts) (*v1.Run, error) {
+	params := runner.CancelRunParams{
+		Context:      ctx,
+		XDispatchOrg: c.getOrgID(organizationID),
+		FunctionName: opts.FunctionName,
+		RunName:      strfmt.UUID(*opts.RunName),
+	}
+	response, err := c.client.Runner.CancelRun(&params, c.auth)
+	if err != nil {
+		return nil, cancelRunSwaggerError(err)
+	}
+	return response.Payload, nil
+}
+
+func cancelRunSwaggerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch v := err.(type) {
+	case *runner.CancelRunBadRequest:
+		return NewErrorBadRequest(v.Payload)
+	case *runner.CancelRunUnauthorized:
+		return NewErrorUnauthorized(v.Payload)
+	case *runner.CancelRunForbidden:
+		return NewErrorForbidden(v.Payload)
+	case *runner.CancelRunNotFound:
+		return NewErrorNotFound(v.Payload)
+	case *runner.CancelRunConflict:
+		return NewErrorConflict(v.Payload)
+	case *runner.CancelRunDefault:
+		return NewErrorServerUnknownError(v.Payload)
+	default:
+		// shouldn't happen, but we need to be prepared:
+		return fmt.Errorf("unexpected error received from server: %s", err)
+	}
+}
+
+// DeleteRuns deletes the finished function runs filtered by opts which were created before olderThan, and returns the
+// number of deleted runs
+func (c *DefaultFunctionsClient) DeleteRuns(ctx context.Context, organizationID string, opts FunctionOpts, olderThan time.Time) (int64, error) {
+	params := runner.DeleteRunsParams{
+		Context:      ctx,
+		XDispatchOrg: c.getOrgID(organizationID),
+		FunctionName: opts.FunctionName,
+		OlderThan:    olderThan.Unix(),
+		Tags:         opts.Tags,
+	}
+	response, err := c.client.Runner.DeleteRuns(&params, c.auth)
+	if err != nil {
+		return 0, deleteRunsSwaggerError(err)
+	}
+	return response.Payload, nil
+}
+
+func deleteRunsSwaggerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch v := err.(type) {
+	case *runner.DeleteRunsBadRequest:
+		return NewErrorBadRequest(v.Payload)
+	case *runner.DeleteRunsUnauthorized:
+		return NewErrorUnauthorized(v.Payload)
+	case *runner.DeleteRunsForbidden:
+		return NewErrorForbidden(v.Payload)
+	case *runner.DeleteRunsDefault:
+		return NewErrorServerUnknownError(v.Payload)
+	default:
+		// shouldn't happen, but we need to be prepared:
+		return fmt.Errorf("unexpected error received from server: %s", err)
+	}
+}
+
 // ListRuns lists all the available results from previous function runs filtered by opts
-func (c *DefaultFunctionsClient) ListRuns(ctx context.Context, organizationID string, opts FunctionOpts) ([]v1.Run, error) {
+func (c *DefaultFunctionsClient) ListRuns(ctx context.Context, organizationID string, opts FunctionOpts) ([]v1.Run, string, error) {
 	s := opts.Since.Unix()
 	params := runner.GetRunsParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
 		FunctionName: opts.FunctionName,
 		Since:        &s,
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Runner.GetRuns(&params, c.auth)
 	if err != nil {
-		return nil, listRunsSwaggerError(err)
+		return nil, "", listRunsSwaggerError(err)
 	}
 	runs := []v1.Run{}
 	for _, run := range response.Payload {
 		runs = append(runs, *run)
 	}
-	return runs, nil
+	return runs, response.XDispatchNextPage, nil
 }
 
 func listRunsSwaggerError(err error) error {
@@ -293,20 +416,24 @@ func getFunctionSwaggerError(err error) error {
 }
 
 // ListFunctions lists all functions
-func (c *DefaultFunctionsClient) ListFunctions(ctx context.Context, organizationID string) ([]v1.Function, error) {
+func (c *DefaultFunctionsClient) ListFunctions(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Function, string, error) {
 	params := store.GetFunctionsParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Store.GetFunctions(&params, c.auth)
 	if err != nil {
-		return nil, listFunctionsSwaggerError(err)
+		return nil, "", listFunctionsSwaggerError(err)
 	}
 	functions := []v1.Function{}
 	for _, f := range response.Payload {
 		functions = append(functions, *f)
 	}
-	return functions, nil
+	return functions, response.XDispatchNextPage, nil
 }
 
 func listFunctionsSwaggerError(err error) error {
@@ -361,3 +488,155 @@ func updateFunctionSwaggerError(err error) error {
 		return fmt.Errorf("unexpected error received from server: %s", err)
 	}
 }
+
+// PublishFunction publishes the current state of a function as a new immutable version
+func (c *DefaultFunctionsClient) PublishFunction(ctx context.Context, organizationID string, functionName string) (*v1.FunctionVersion, error) {
+	params := store.PublishFunctionParams{
+		Context:      ctx,
+		XDispatchOrg: c.getOrgID(organizationID),
+		FunctionName: functionName,
+	}
+	response, err := c.client.Store.PublishFunction(&params, c.auth)
+	if err != nil {
+		return nil, publishFunctionSwaggerError(err)
+	}
+	return response.Payload, nil
+}
+
+func publishFunctionSwaggerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch v := err.(type) {
+	case *store.PublishFunctionBadRequest:
+		return NewErrorBadRequest(v.Payload)
+	case *store.PublishFunctionUnauthorized:
+		return NewErrorUnauthorized(v.Payload)
+	case *store.PublishFunctionForbidden:
+		return NewErrorForbidden(v.Payload)
+	case *store.PublishFunctionNotFound:
+		return NewErrorNotFound(v.Payload)
+	case *store.PublishFunctionConflict:
+		return NewErrorConflict(v.Payload)
+	case *store.PublishFunctionDefault:
+		return NewErrorServerUnknownError(v.Payload)
+	default:
+		// shouldn't happen, but we need to be prepared:
+		return fmt.Errorf("unexpected error received from server: %s", err)
+	}
+}
+
+// SetAlias creates or updates an alias of a function
+func (c *DefaultFunctionsClient) SetAlias(ctx context.Context, organizationID string, functionName string, alias *v1.FunctionAlias) (*v1.Function, error) {
+	params := store.SetAliasParams{
+		Context:      ctx,
+		XDispatchOrg: c.getOrgID(organizationID),
+		AliasName:    alias.Name,
+		Body:         alias,
+		FunctionName: functionName,
+	}
+	response, err := c.client.Store.SetAlias(&params, c.auth)
+	if err != nil {
+		return nil, setAliasSwaggerError(err)
+	}
+	return response.Payload, nil
+}
+
+func setAliasSwaggerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch v := err.(type) {
+	case *store.SetAliasBadRequest:
+		return NewErrorBadRequest(v.Payload)
+	case *store.SetAliasUnauthorized:
+		return NewErrorUnauthorized(v.Payload)
+	case *store.SetAliasForbidden:
+		return NewErrorForbidden(v.Payload)
+	case *store.SetAliasNotFound:
+		return NewErrorNotFound(v.Payload)
+	case *store.SetAliasConflict:
+		return NewErrorConflict(v.Payload)
+	case *store.SetAliasDefault:
+		return NewErrorServerUnknownError(v.Payload)
+	default:
+		// shouldn't happen, but we need to be prepared:
+		return fmt.Errorf("unexpected error received from server: %s", err)
+	}
+}
+
+// DeleteAlias deletes an alias of a function
+func (c *DefaultFunctionsClient) DeleteAlias(ctx context.Context, organizationID string, functionName string, aliasName string) (*v1.Function, error) {
+	params := store.DeleteAliasParams{
+		Context:      ctx,
+		XDispatchOrg: c.getOrgID(organizationID),
+		AliasName:    aliasName,
+		FunctionName: functionName,
+	}
+	response, err := c.client.Store.DeleteAlias(&params, c.auth)
+	if err != nil {
+		return nil, deleteAliasSwaggerError(err)
+	}
+	return response.Payload, nil
+}
+
+func deleteAliasSwaggerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch v := err.(type) {
+	case *store.DeleteAliasBadRequest:
+		return NewErrorBadRequest(v.Payload)
+	case *store.DeleteAliasUnauthorized:
+		return NewErrorUnauthorized(v.Payload)
+	case *store.DeleteAliasForbidden:
+		return NewErrorForbidden(v.Payload)
+	case *store.DeleteAliasNotFound:
+		return NewErrorNotFound(v.Payload)
+	case *store.DeleteAliasConflict:
+		return NewErrorConflict(v.Payload)
+	case *store.DeleteAliasDefault:
+		return NewErrorServerUnknownError(v.Payload)
+	default:
+		// shouldn't happen, but we need to be prepared:
+		return fmt.Errorf("unexpected error received from server: %s", err)
+	}
+}
+
+// RollbackAlias restores the versions an alias of a function pointed at before its last update
+func (c *DefaultFunctionsClient) RollbackAlias(ctx context.Context, organizationID string, functionName string, aliasName string) (*v1.Function, error) {
+	params := store.RollbackAliasParams{
+		Context:      ctx,
+		XDispatchOrg: c.getOrgID(organizationID),
+		AliasName:    aliasName,
+		FunctionName: functionName,
+	}
+	response, err := c.client.Store.RollbackAlias(&params, c.auth)
+	if err != nil {
+		return nil, rollbackAliasSwaggerError(err)
+	}
+	return response.Payload, nil
+}
+
+func rollbackAliasSwaggerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch v := err.(type) {
+	case *store.RollbackAliasBadRequest:
+		return NewErrorBadRequest(v.Payload)
+	case *store.RollbackAliasUnauthorized:
+		return NewErrorUnauthorized(v.Payload)
+	case *store.RollbackAliasForbidden:
+		return NewErrorForbidden(v.Payload)
+	case *store.RollbackAliasNotFound:
+		return NewErrorNotFound(v.Payload)
+	case *store.RollbackAliasConflict:
+		return NewErrorConflict(v.Payload)
+	case *store.RollbackAliasDefault:
+		return NewErrorServerUnknownError(v.Payload)
+	default:
+		// shouldn't happen, but we need to be prepared:
+		return fmt.Errorf("unexpected error received from server: %s", err)
+	}
+}
@@ -7,6 +7,7 @@ package client_test
 
 import (
 	"context"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -36,3 +37,27 @@ func TestCreateFunction(t *testing.T) {
 	assert.Equal(t, functionResponse, functionBody)
 
 }
+
+func TestListFunctionsPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/function", r.URL.Path)
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		assert.Equal(t, "-createdTime", r.URL.Query().Get("orderBy"))
+		assert.Equal(t, "page1", r.URL.Query().Get("page"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Dispatch-Next-Page", "page2")
+		w.Write([]byte(`[{"name":"f1"},{"name":"f2"}]`))
+	}))
+	defer server.Close()
+
+	fclient := client.NewFunctionsClient(server.URL, nil, testOrgID)
+
+	functions, next, err := fclient.ListFunctions(context.Background(), testOrgID, client.ListOpts{
+		Limit:   2,
+		OrderBy: "-createdTime",
+		Page:    "page1",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, functions, 2)
+	assert.Equal(t, "page2", next)
+}
@@ -27,21 +27,21 @@ type IdentityClient interface {
 	DeletePolicy(ctx context.Context, organizationID string, policyName string) (*v1.Policy, error)
 	UpdatePolicy(ctx context.Context, organizationID string, policy *v1.Policy) (*v1.Policy, error)
 	GetPolicy(ctx context.Context, organizationID string, policyName string) (*v1.Policy, error)
-	ListPolicies(ctx context.Context, organizationID string) ([]v1.Policy, error)
+	ListPolicies(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Policy, string, error)
 
 	// Organizations
 	CreateOrganization(ctx context.Context, organizationID string, org *v1.Organization) (*v1.Organization, error)
 	DeleteOrganization(ctx context.Context, organizationID string, orgName string) (*v1.Organization, error)
 	UpdateOrganization(ctx context.Context, organizationID string, org *v1.Organization) (*v1.Organization, error)
 	GetOrganization(ctx context.Context, organizationID string, orgName string) (*v1.Organization, error)
-	ListOrganizations(ctx context.Context, organizationID string) ([]v1.Organization, error)
+	ListOrganizations(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Organization, string, error)
 
 	// Service Accounts
 	CreateServiceAccount(ctx context.Context, organizationID string, svcAccount *v1.ServiceAccount) (*v1.ServiceAccount, error)
 	DeleteServiceAccount(ctx context.Context, organizationID string, svcAccountName string) (*v1.ServiceAccount, error)
 	UpdateServiceAccount(ctx context.Context, organizationID string, svcAccount *v1.ServiceAccount) (*v1.ServiceAccount, error)
 	GetServiceAccount(ctx context.Context, organizationID string, svcAccountName string) (*v1.ServiceAccount, error)
-	ListServiceAccounts(ctx context.Context, organizationID string) ([]v1.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, organizationID string, opts ListOpts) ([]v1.ServiceAccount, string, error)
 
 	// Other operations
 	GetVersion(ctx context.Context) (*v1.Version, error)
@@ -207,20 +207,23 @@ func getPolicySwaggerError(err error) error {
 }
 
 // ListPolicies lists all functions
-func (c *DefaultIdentityClient) ListPolicies(ctx context.Context, organizationID string) ([]v1.Policy, error) {
+func (c *DefaultIdentityClient) ListPolicies(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Policy, string, error) {
 	params := swaggerpolicy.GetPoliciesParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
 	}
 	response, err := c.client.Policy.GetPolicies(&params, c.auth)
 	if err != nil {
-		return nil, listPoliciesSwaggerError(err)
+		return nil, "", listPoliciesSwaggerError(err)
 	}
 	policies := []v1.Policy{}
 	for _, f := range response.Payload {
 		policies = append(policies, *f)
 	}
-	return policies, nil
+	return policies, response.XDispatchNextPage, nil
 }
 
 func listPoliciesSwaggerError(err error) error {
@@ -380,7 +383,7 @@ func getOrganizationSwaggerError(err error) error {
 }
 
 // ListOrganizations lists all functions
-func (c *DefaultIdentityClient) ListOrganizations(ctx context.Context, organizationID string) ([]v1.Organization, error) {
+func (c *DefaultIdentityClient) ListOrganizations(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Organization, string, error) {
 	orgID := c.getOrgID(organizationID)
 	params := swaggerorgs.GetOrganizationsParams{
 		Context:      ctx,
@@ -388,13 +391,13 @@ func (c *DefaultIdentityClient) ListOrganizations(ctx context.Context, organizat
 	}
 	response, err := c.client.Organization.GetOrganizations(&params, c.auth)
 	if err != nil {
-		return nil, listOrganizationsSwaggerError(err)
+		return nil, "", listOrganizationsSwaggerError(err)
 	}
 	policies := []v1.Organization{}
 	for _, f := range response.Payload {
 		policies = append(policies, *f)
 	}
-	return policies, nil
+	return policies, response.XDispatchNextPage, nil
 }
 
 func listOrganizationsSwaggerError(err error) error {
@@ -553,20 +556,23 @@ func getServiceAccountSwaggerError(err error) error {
 }
 
 // ListServiceAccounts lists all functions
-func (c *DefaultIdentityClient) ListServiceAccounts(ctx context.Context, organizationID string) ([]v1.ServiceAccount, error) {
+func (c *DefaultIdentityClient) ListServiceAccounts(ctx context.Context, organizationID string, opts ListOpts) ([]v1.ServiceAccount, string, error) {
 	params := swaggeraccounts.GetServiceAccountsParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
 	}
 	response, err := c.client.Serviceaccount.GetServiceAccounts(&params, c.auth)
 	if err != nil {
-		return nil, listServiceAccountsSwaggerError(err)
+		return nil, "", listServiceAccountsSwaggerError(err)
 	}
 	policies := []v1.ServiceAccount{}
 	for _, f := range response.Payload {
 		policies = append(policies, *f)
 	}
-	return policies, nil
+	return policies, response.XDispatchNextPage, nil
 }
 
 func listServiceAccountsSwaggerError(err error) error {
@@ -24,14 +24,14 @@ type ImagesClient interface {
 	DeleteImage(ctx context.Context, organizationID string, imageName string) (*v1.Image, error)
 	UpdateImage(ctx context.Context, organizationID string, image *v1.Image) (*v1.Image, error)
 	GetImage(ctx context.Context, organizationID string, imageName string) (*v1.Image, error)
-	ListImages(ctx context.Context, organizationID string) ([]v1.Image, error)
+	ListImages(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Image, string, error)
 
 	// BaseImages
 	CreateBaseImage(ctx context.Context, organizationID string, baseImage *v1.BaseImage) (*v1.BaseImage, error)
 	DeleteBaseImage(ctx context.Context, organizationID string, baseImageName string) (*v1.BaseImage, error)
 	UpdateBaseImage(ctx context.Context, organizationID string, baseImage *v1.BaseImage) (*v1.BaseImage, error)
 	GetBaseImage(ctx context.Context, organizationID string, baseImageName string) (*v1.BaseImage, error)
-	ListBaseImages(ctx context.Context, organizationID string) ([]v1.BaseImage, error)
+	ListBaseImages(ctx context.Context, organizationID string, opts ListOpts) ([]v1.BaseImage, string, error)
 }
 
 // NewImagesClient is used to create a new Images client
@@ -196,20 +196,24 @@ func getImageSwaggerError(err error) error {
 }
 
 // ListImages returns a list of images
-func (c *DefaultImagesClient) ListImages(ctx context.Context, organizationID string) ([]v1.Image, error) {
+func (c *DefaultImagesClient) ListImages(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Image, string, error) {
 	params := imageclient.GetImagesParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Image.GetImages(&params, c.auth)
 	if err != nil {
-		return nil, listImagesSwaggerError(err)
+		return nil, "", listImagesSwaggerError(err)
 	}
 	images := []v1.Image{}
 	for _, image := range response.Payload {
 		images = append(images, *image)
 	}
-	return images, nil
+	return images, response.XDispatchNextPage, nil
 }
 
 func listImagesSwaggerError(err error) error {
@@ -373,20 +377,24 @@ func getBaseImageSwaggerError(err error) error {
 }
 
 // ListBaseImages returns a list of base images
-func (c *DefaultImagesClient) ListBaseImages(ctx context.Context, organizationID string) ([]v1.BaseImage, error) {
+func (c *DefaultImagesClient) ListBaseImages(ctx context.Context, organizationID string, opts ListOpts) ([]v1.BaseImage, string, error) {
 	params := baseimageclient.GetBaseImagesParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.BaseImage.GetBaseImages(&params, c.auth)
 	if err != nil {
-		return nil, listBaseImagesSwaggerError(err)
+		return nil, "", listBaseImagesSwaggerError(err)
 	}
 	images := []v1.BaseImage{}
 	for _, image := range response.Payload {
 		images = append(images, *image)
 	}
-	return images, nil
+	return images, response.XDispatchNextPage, nil
 }
 
 func listBaseImagesSwaggerError(err error) error {
@@ -3,7 +3,9 @@
 package mocks
 
 import context "context"
+import io "io"
 import mock "github.com/stretchr/testify/mock"
+import time "time"
 import v1 "github.com/vmware/dispatch/pkg/api/v1"
 import client "github.com/vmware/dispatch/pkg/client"
 
@@ -12,6 +14,29 @@ type FunctionsClient struct {
 	mock.Mock
 }
 
+// CancelRun provides a mock function with given fields: ctx, organizationID, opts
+func (_m *FunctionsClient) CancelRun(ctx context.Context, organizationID string, opts client.FunctionOpts) (*v1.Run, error) {
+	ret := _m.Called(ctx, organizationID, opts)
+
+	var r0 *v1.Run
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.FunctionOpts) *v1.Run); ok {
+		r0 = rf(ctx, organizationID, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.Run)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.FunctionOpts) error); ok {
+		r1 = rf(ctx, organizationID, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateFunction provides a mock function with given fields: ctx, organizationID, function
 func (_m *FunctionsClient) CreateFunction(ctx context.Context, organizationID string, function *v1.Function) (*v1.Function, error) {
 	ret := _m.Called(ctx, organizationID, function)
@@ -35,6 +60,29 @@ func (_m *FunctionsClient) CreateFunction(ctx context.Context, organizationID st
 	return r0, r1
 }
 
+// DeleteAlias provides a mock function with given fields: ctx, organizationID, functionName, aliasName
+func (_m *FunctionsClient) DeleteAlias(ctx context.Context, organizationID string, functionName string, aliasName string) (*v1.Function, error) {
+	ret := _m.Called(ctx, organizationID, functionName, aliasName)
+
+	var r0 *v1.Function
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *v1.Function); ok {
+		r0 = rf(ctx, organizationID, functionName, aliasName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.Function)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, organizationID, functionName, aliasName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteFunction provides a mock function with given fields: ctx, organizationID, functionName
 func (_m *FunctionsClient) DeleteFunction(ctx context.Context, organizationID string, functionName string) (*v1.Function, error) {
 	ret := _m.Called(ctx, organizationID, functionName)
@@ -58,6 +106,27 @@ func (_m *FunctionsClient) DeleteFunction(ctx context.Context, organizationID st
 	return r0, r1
 }
 
+// DeleteRuns provides a mock function with given fields: ctx, organizationID, opts, olderThan
+func (_m *FunctionsClient) DeleteRuns(ctx context.Context, organizationID string, opts client.FunctionOpts, olderThan time.Time) (int64, error) {
+	ret := _m.Called(ctx, organizationID, opts, olderThan)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.FunctionOpts, time.Time) int64); ok {
+		r0 = rf(ctx, organizationID, opts, olderThan)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.FunctionOpts, time.Time) error); ok {
+		r1 = rf(ctx, organizationID, opts, olderThan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFunction provides a mock function with given fields: ctx, organizationID, functionName
 func (_m *FunctionsClient) GetFunction(ctx context.Context, organizationID string, functionName string) (*v1.Function, error) {
 	ret := _m.Called(ctx, organizationID, functionName)
@@ -104,31 +173,52 @@ func (_m *FunctionsClient) GetFunctionRun(ctx context.Context, organizationID st
 	return r0, r1
 }
 
-// ListFunctions provides a mock function with given fields: ctx, organizationID
-func (_m *FunctionsClient) ListFunctions(ctx context.Context, organizationID string) ([]v1.Function, error) {
-	ret := _m.Called(ctx, organizationID)
+// GetRunLogs provides a mock function with given fields: ctx, organizationID, opts, follow, out
+func (_m *FunctionsClient) GetRunLogs(ctx context.Context, organizationID string, opts client.FunctionOpts, follow bool, out io.Writer) error {
+	ret := _m.Called(ctx, organizationID, opts, follow, out)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.FunctionOpts, bool, io.Writer) error); ok {
+		r0 = rf(ctx, organizationID, opts, follow, out)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListFunctions provides a mock function with given fields: ctx, organizationID, opts
+func (_m *FunctionsClient) ListFunctions(ctx context.Context, organizationID string, opts client.ListOpts) ([]v1.Function, string, error) {
+	ret := _m.Called(ctx, organizationID, opts)
 
 	var r0 []v1.Function
-	if rf, ok := ret.Get(0).(func(context.Context, string) []v1.Function); ok {
-		r0 = rf(ctx, organizationID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.ListOpts) []v1.Function); ok {
+		r0 = rf(ctx, organizationID, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]v1.Function)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, organizationID)
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.ListOpts) string); ok {
+		r1 = rf(ctx, organizationID, opts)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, client.ListOpts) error); ok {
+		r2 = rf(ctx, organizationID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // ListRuns provides a mock function with given fields: ctx, organizationID, opts
-func (_m *FunctionsClient) ListRuns(ctx context.Context, organizationID string, opts client.FunctionOpts) ([]v1.Run, error) {
+func (_m *FunctionsClient) ListRuns(ctx context.Context, organizationID string, opts client.FunctionOpts) ([]v1.Run, string, error) {
 	ret := _m.Called(ctx, organizationID, opts)
 
 	var r0 []v1.Run
@@ -140,9 +230,62 @@ func (_m *FunctionsClient) ListRuns(ctx context.Context, organizationID string,
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, client.FunctionOpts) error); ok {
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.FunctionOpts) string); ok {
 		r1 = rf(ctx, organizationID, opts)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, client.FunctionOpts) error); ok {
+		r2 = rf(ctx, organizationID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// PublishFunction provides a mock function with given fields: ctx, organizationID, functionName
+func (_m *FunctionsClient) PublishFunction(ctx context.Context, organizationID string, functionName string) (*v1.FunctionVersion, error) {
+	ret := _m.Called(ctx, organizationID, functionName)
+
+	var r0 *v1.FunctionVersion
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *v1.FunctionVersion); ok {
+		r0 = rf(ctx, organizationID, functionName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.FunctionVersion)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, organizationID, functionName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RollbackAlias provides a mock function with given fields: ctx, organizationID, functionName, aliasName
+func (_m *FunctionsClient) RollbackAlias(ctx context.Context, organizationID string, functionName string, aliasName string) (*v1.Function, error) {
+	ret := _m.Called(ctx, organizationID, functionName, aliasName)
+
+	var r0 *v1.Function
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *v1.Function); ok {
+		r0 = rf(ctx, organizationID, functionName, aliasName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.Function)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, organizationID, functionName, aliasName)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -173,6 +316,29 @@ func (_m *FunctionsClient) RunFunction(ctx context.Context, organizationID strin
 	return r0, r1
 }
 
+// SetAlias provides a mock function with given fields: ctx, organizationID, functionName, alias
+func (_m *FunctionsClient) SetAlias(ctx context.Context, organizationID string, functionName string, alias *v1.FunctionAlias) (*v1.Function, error) {
+	ret := _m.Called(ctx, organizationID, functionName, alias)
+
+	var r0 *v1.Function
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *v1.FunctionAlias) *v1.Function); ok {
+		r0 = rf(ctx, organizationID, functionName, alias)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.Function)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *v1.FunctionAlias) error); ok {
+		r1 = rf(ctx, organizationID, functionName, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateFunction provides a mock function with given fields: ctx, organizationID, function
 func (_m *FunctionsClient) UpdateFunction(ctx context.Context, organizationID string, function *v1.Function) (*v1.Function, error) {
 	ret := _m.Called(ctx, organizationID, function)
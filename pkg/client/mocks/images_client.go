@@ -5,6 +5,7 @@ package mocks
 import context "context"
 import mock "github.com/stretchr/testify/mock"
 import v1 "github.com/vmware/dispatch/pkg/api/v1"
+import client "github.com/vmware/dispatch/pkg/client"
 
 // ImagesClient is an autogenerated mock type for the ImagesClient type
 type ImagesClient struct {
@@ -149,50 +150,64 @@ func (_m *ImagesClient) GetImage(ctx context.Context, organizationID string, ima
 	return r0, r1
 }
 
-// ListBaseImages provides a mock function with given fields: ctx, organizationID
-func (_m *ImagesClient) ListBaseImages(ctx context.Context, organizationID string) ([]v1.BaseImage, error) {
-	ret := _m.Called(ctx, organizationID)
+// ListBaseImages provides a mock function with given fields: ctx, organizationID, opts
+func (_m *ImagesClient) ListBaseImages(ctx context.Context, organizationID string, opts client.ListOpts) ([]v1.BaseImage, string, error) {
+	ret := _m.Called(ctx, organizationID, opts)
 
 	var r0 []v1.BaseImage
-	if rf, ok := ret.Get(0).(func(context.Context, string) []v1.BaseImage); ok {
-		r0 = rf(ctx, organizationID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.ListOpts) []v1.BaseImage); ok {
+		r0 = rf(ctx, organizationID, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]v1.BaseImage)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, organizationID)
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.ListOpts) string); ok {
+		r1 = rf(ctx, organizationID, opts)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, client.ListOpts) error); ok {
+		r2 = rf(ctx, organizationID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
-// ListImages provides a mock function with given fields: ctx, organizationID
-func (_m *ImagesClient) ListImages(ctx context.Context, organizationID string) ([]v1.Image, error) {
-	ret := _m.Called(ctx, organizationID)
+// ListImages provides a mock function with given fields: ctx, organizationID, opts
+func (_m *ImagesClient) ListImages(ctx context.Context, organizationID string, opts client.ListOpts) ([]v1.Image, string, error) {
+	ret := _m.Called(ctx, organizationID, opts)
 
 	var r0 []v1.Image
-	if rf, ok := ret.Get(0).(func(context.Context, string) []v1.Image); ok {
-		r0 = rf(ctx, organizationID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.ListOpts) []v1.Image); ok {
+		r0 = rf(ctx, organizationID, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]v1.Image)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, organizationID)
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.ListOpts) string); ok {
+		r1 = rf(ctx, organizationID, opts)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, client.ListOpts) error); ok {
+		r2 = rf(ctx, organizationID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // UpdateBaseImage provides a mock function with given fields: ctx, organizationID, baseImage
@@ -5,6 +5,7 @@ package mocks
 import context "context"
 import mock "github.com/stretchr/testify/mock"
 import v1 "github.com/vmware/dispatch/pkg/api/v1"
+import client "github.com/vmware/dispatch/pkg/client"
 
 // SecretsClient is an autogenerated mock type for the SecretsClient type
 type SecretsClient struct {
@@ -71,27 +72,34 @@ func (_m *SecretsClient) GetSecret(ctx context.Context, organizationID string, s
 	return r0, r1
 }
 
-// ListSecrets provides a mock function with given fields: ctx, organizationID
-func (_m *SecretsClient) ListSecrets(ctx context.Context, organizationID string) ([]v1.Secret, error) {
-	ret := _m.Called(ctx, organizationID)
+// ListSecrets provides a mock function with given fields: ctx, organizationID, opts
+func (_m *SecretsClient) ListSecrets(ctx context.Context, organizationID string, opts client.ListOpts) ([]v1.Secret, string, error) {
+	ret := _m.Called(ctx, organizationID, opts)
 
 	var r0 []v1.Secret
-	if rf, ok := ret.Get(0).(func(context.Context, string) []v1.Secret); ok {
-		r0 = rf(ctx, organizationID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.ListOpts) []v1.Secret); ok {
+		r0 = rf(ctx, organizationID, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]v1.Secret)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, organizationID)
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.ListOpts) string); ok {
+		r1 = rf(ctx, organizationID, opts)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, client.ListOpts) error); ok {
+		r2 = rf(ctx, organizationID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // UpdateSecret provides a mock function with given fields: ctx, organizationID, secret
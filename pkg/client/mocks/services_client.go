@@ -5,6 +5,7 @@ package mocks
 import context "context"
 import mock "github.com/stretchr/testify/mock"
 import v1 "github.com/vmware/dispatch/pkg/api/v1"
+import client "github.com/vmware/dispatch/pkg/client"
 
 // ServicesClient is an autogenerated mock type for the ServicesClient type
 type ServicesClient struct {
@@ -94,48 +95,62 @@ func (_m *ServicesClient) GetServiceInstance(ctx context.Context, organizationID
 	return r0, r1
 }
 
-// ListServiceClasses provides a mock function with given fields: ctx, organizationID
-func (_m *ServicesClient) ListServiceClasses(ctx context.Context, organizationID string) ([]v1.ServiceClass, error) {
-	ret := _m.Called(ctx, organizationID)
+// ListServiceClasses provides a mock function with given fields: ctx, organizationID, opts
+func (_m *ServicesClient) ListServiceClasses(ctx context.Context, organizationID string, opts client.ListOpts) ([]v1.ServiceClass, string, error) {
+	ret := _m.Called(ctx, organizationID, opts)
 
 	var r0 []v1.ServiceClass
-	if rf, ok := ret.Get(0).(func(context.Context, string) []v1.ServiceClass); ok {
-		r0 = rf(ctx, organizationID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.ListOpts) []v1.ServiceClass); ok {
+		r0 = rf(ctx, organizationID, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]v1.ServiceClass)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, organizationID)
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.ListOpts) string); ok {
+		r1 = rf(ctx, organizationID, opts)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, client.ListOpts) error); ok {
+		r2 = rf(ctx, organizationID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
-// ListServiceInstances provides a mock function with given fields: ctx, organizationID
-func (_m *ServicesClient) ListServiceInstances(ctx context.Context, organizationID string) ([]v1.ServiceInstance, error) {
-	ret := _m.Called(ctx, organizationID)
+// ListServiceInstances provides a mock function with given fields: ctx, organizationID, opts
+func (_m *ServicesClient) ListServiceInstances(ctx context.Context, organizationID string, opts client.ListOpts) ([]v1.ServiceInstance, string, error) {
+	ret := _m.Called(ctx, organizationID, opts)
 
 	var r0 []v1.ServiceInstance
-	if rf, ok := ret.Get(0).(func(context.Context, string) []v1.ServiceInstance); ok {
-		r0 = rf(ctx, organizationID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, client.ListOpts) []v1.ServiceInstance); ok {
+		r0 = rf(ctx, organizationID, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]v1.ServiceInstance)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, organizationID)
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, client.ListOpts) string); ok {
+		r1 = rf(ctx, organizationID, opts)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, client.ListOpts) error); ok {
+		r2 = rf(ctx, organizationID, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
@@ -23,7 +23,7 @@ type SecretsClient interface {
 	DeleteSecret(ctx context.Context, organizationID string, secretName string) error
 	UpdateSecret(ctx context.Context, organizationID string, secret *v1.Secret) (*v1.Secret, error)
 	GetSecret(ctx context.Context, organizationID string, secretName string) (*v1.Secret, error)
-	ListSecrets(ctx context.Context, organizationID string) ([]v1.Secret, error)
+	ListSecrets(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Secret, string, error)
 }
 
 // NewSecretsClient is used to create a new secrets client
@@ -188,20 +188,24 @@ func getSecretSwaggerError(err error) error {
 }
 
 // ListSecrets lists secrets
-func (c *DefaultSecretsClient) ListSecrets(ctx context.Context, organizationID string) ([]v1.Secret, error) {
+func (c *DefaultSecretsClient) ListSecrets(ctx context.Context, organizationID string, opts ListOpts) ([]v1.Secret, string, error) {
 	params := secretclient.GetSecretsParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.Secret.GetSecrets(&params, c.auth)
 	if err != nil {
-		return nil, listSecretsSwaggerError(err)
+		return nil, "", listSecretsSwaggerError(err)
 	}
 	secrets := []v1.Secret{}
 	for _, secret := range response.Payload {
 		secrets = append(secrets, *secret)
 	}
-	return secrets, nil
+	return secrets, response.XDispatchNextPage, nil
 }
 
 func listSecretsSwaggerError(err error) error {
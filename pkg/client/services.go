@@ -23,11 +23,11 @@ type ServicesClient interface {
 	CreateServiceInstance(ctx context.Context, organizationID string, serviceInstance *v1.ServiceInstance) (*v1.ServiceInstance, error)
 	DeleteServiceInstance(ctx context.Context, organizationID string, serviceInstanceName string) error
 	GetServiceInstance(ctx context.Context, organizationID string, serviceInstanceName string) (*v1.ServiceInstance, error)
-	ListServiceInstances(ctx context.Context, organizationID string) ([]v1.ServiceInstance, error)
+	ListServiceInstances(ctx context.Context, organizationID string, opts ListOpts) ([]v1.ServiceInstance, string, error)
 
 	// Service Classes
 	GetServiceClass(ctx context.Context, organizationID string, serviceClassName string) (*v1.ServiceClass, error)
-	ListServiceClasses(ctx context.Context, organizationID string) ([]v1.ServiceClass, error)
+	ListServiceClasses(ctx context.Context, organizationID string, opts ListOpts) ([]v1.ServiceClass, string, error)
 }
 
 // NewServicesClient is used to create a new serviceInstances client
@@ -156,20 +156,24 @@ func getServiceInstanceSwaggerError(err error) error {
 }
 
 // ListServiceInstances lists service instances
-func (c *DefaultServicesClient) ListServiceInstances(ctx context.Context, organizationID string) ([]v1.ServiceInstance, error) {
+func (c *DefaultServicesClient) ListServiceInstances(ctx context.Context, organizationID string, opts ListOpts) ([]v1.ServiceInstance, string, error) {
 	params := serviceinstanceclient.GetServiceInstancesParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.ServiceInstance.GetServiceInstances(&params, c.auth)
 	if err != nil {
-		return nil, listServiceInstancesSwaggerError(err)
+		return nil, "", listServiceInstancesSwaggerError(err)
 	}
 	var serviceInstances []v1.ServiceInstance
 	for _, serviceInstance := range response.Payload {
 		serviceInstances = append(serviceInstances, *serviceInstance)
 	}
-	return serviceInstances, nil
+	return serviceInstances, response.XDispatchNextPage, nil
 }
 
 func listServiceInstancesSwaggerError(err error) error {
@@ -225,20 +229,24 @@ func getServiceClassSwaggerError(err error) error {
 }
 
 // ListServiceClasses lists service classes
-func (c *DefaultServicesClient) ListServiceClasses(ctx context.Context, organizationID string) ([]v1.ServiceClass, error) {
+func (c *DefaultServicesClient) ListServiceClasses(ctx context.Context, organizationID string, opts ListOpts) ([]v1.ServiceClass, string, error) {
 	params := serviceclassclient.GetServiceClassesParams{
 		Context:      ctx,
 		XDispatchOrg: c.getOrgID(organizationID),
+		Limit:        opts.limit(),
+		OrderBy:      opts.orderBy(),
+		Page:         opts.page(),
+		Tags:         opts.Tags,
 	}
 	response, err := c.client.ServiceClass.GetServiceClasses(&params, c.auth)
 	if err != nil {
-		return nil, listServiceClassesSwaggerError(err)
+		return nil, "", listServiceClassesSwaggerError(err)
 	}
 	serviceClasses := []v1.ServiceClass{}
 	for _, serviceClass := range response.Payload {
 		serviceClasses = append(serviceClasses, *serviceClass)
 	}
-	return serviceClasses, nil
+	return serviceClasses, response.XDispatchNextPage, nil
 }
 
 func listServiceClassesSwaggerError(err error) error {
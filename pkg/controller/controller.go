@@ -7,8 +7,10 @@ package controller
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
@@ -16,9 +18,9 @@ import (
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/semaphore"
 
+	"github.com/vmware/dispatch/pkg/coordination"
 	"github.com/vmware/dispatch/pkg/entity-store"
 	"github.com/vmware/dispatch/pkg/trace"
-	"github.com/vmware/dispatch/pkg/zookeeper"
 )
 
 // EntityHandler define an interface for entity operations of a generic controller
@@ -33,16 +35,77 @@ type EntityHandler interface {
 	Sync(ctx context.Context, resyncPeriod time.Duration) ([]entitystore.Entity, error)
 }
 
-const defaultWorkers = 1
+// WorkersHandler is an entity handler which sets how many entities of its type are processed concurrently, instead
+// of Options.Workers
+type WorkersHandler interface {
+	EntityHandler
+	Workers() int
+}
+
+const (
+	defaultWorkers       = 1
+	defaultRequeueDelay  = time.Second
+	defaultMaxRetries    = 5
+	defaultRetryDelay    = time.Second
+	defaultMaxRetryDelay = 5 * time.Minute
+	defaultGroup         = "controller"
+)
+
+// errEntityBusy is returned by processItem when the entity could not be locked, most likely because another replica
+// holds the lock
+var errEntityBusy = errors.New("entity is locked by another replica")
+
+// requeueError is returned by entity handlers to have the entity processed again after a delay
+type requeueError struct {
+	delay time.Duration
+}
+
+func (e requeueError) Error() string {
+	return fmt.Sprintf("entity requeued for %s", e.delay)
+}
+
+// Requeue returns an error which, returned by an entity handler, has the entity processed again after delay. Unlike
+// other errors, it is not counted as a failure, so that handlers can wait without holding the entity lock.
+func Requeue(delay time.Duration) error {
+	return requeueError{delay: delay}
+}
+
+// RequeueDelay returns the delay of an error returned by Requeue, and false for other errors
+func RequeueDelay(err error) (time.Duration, bool) {
+	requeue, ok := errors.Cause(err).(requeueError)
+	return requeue.delay, ok
+}
 
 // Options defines controller configuration
 type Options struct {
 	ServiceName string
 
-	ResyncPeriod      time.Duration
-	Workers           int
-	Driver            zookeeper.Driver
-	ZookeeperLocation string
+	ResyncPeriod time.Duration
+	Workers      int
+	// Driver coordinates the replicas of the service, it is created from Coordination if not set
+	Driver       coordination.Driver
+	Coordination coordination.Config
+
+	// Store, if set, is watched for entity changes, so that changes made by other replicas are processed
+	// right away instead of on the next resync.
+	Store entitystore.EntityStore
+
+	// Replicas of a service join a group named after the service, and entities are partitioned across the
+	// live members of the group with consistent hashing.
+	// SingleLeader, if set, restricts processing to the oldest member of the group instead, for handlers which must
+	// not run concurrently on several replicas.
+	SingleLeader bool
+	// RequeueDelay is the delay before processing again an entity which was locked by another replica
+	RequeueDelay time.Duration
+
+	// MaxRetries is how many times the processing of an entity is retried after an error, before the entity is moved
+	// to the ERROR status. The delay between retries starts at RetryDelay and doubles up to MaxRetryDelay.
+	MaxRetries    int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+
+	// Metrics is the map the controller publishes its metrics to, under the service name. Defaults to Metrics.
+	Metrics *expvar.Map
 }
 
 // WatchEvent captures entity together with the associated context
@@ -85,7 +148,29 @@ type DefaultController struct {
 	done    chan bool
 	watcher chan WatchEvent
 	options Options
-	driver  zookeeper.Driver
+	driver  coordination.Driver
+
+	// processing holds IDs of entities currently being processed by this controller
+	processing   sync.Map
+	stopWatching context.CancelFunc
+	watchers     sync.WaitGroup
+
+	// stopped is closed when the controller shuts down
+	stopped chan struct{}
+
+	// queues are the work queues by entity type
+	queues  map[reflect.Type]*workQueue
+	metrics *controllerMetrics
+
+	// member is the ID of this replica in the controller group, members are the live ones, oldest first, and ring
+	// partitions the entities across them
+	membersMu sync.RWMutex
+	member    string
+	members   []string
+	ring      *hashRing
+	// resync triggers a resync when the membership changes, as entities may have moved to this replica, or when a
+	// watch lost changes
+	resync chan struct{}
 
 	entityHandlers map[reflect.Type]EntityHandler
 }
@@ -95,33 +180,51 @@ func NewController(options Options) Controller {
 	if options.Workers == 0 {
 		options.Workers = defaultWorkers
 	}
-	if options.ZookeeperLocation == "" {
-		options.ZookeeperLocation = "127.0.0.1"
+	if options.RequeueDelay == 0 {
+		options.RequeueDelay = defaultRequeueDelay
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = defaultMaxRetries
+	}
+	if options.RetryDelay == 0 {
+		options.RetryDelay = defaultRetryDelay
+	}
+	if options.MaxRetryDelay == 0 {
+		options.MaxRetryDelay = defaultMaxRetryDelay
 	}
 	if options.Driver == nil {
-		driver, err := zookeeper.NewDriver(options.ZookeeperLocation)
+		driver, err := coordination.New(options.Coordination)
 		if err != nil {
-			log.Fatalf("Unable to get zookeeper driver for controller")
+			log.Fatalf("Unable to get coordination driver for controller: %v", err)
 		}
 		options.Driver = driver
-		log.Infof("Connected to zookeeper at address %v", options.ZookeeperLocation)
 	}
-	return &DefaultController{
+	dc := &DefaultController{
 		done:    make(chan bool),
 		watcher: make(chan WatchEvent),
 		options: options,
 		driver:  options.Driver,
+		stopped: make(chan struct{}),
+		queues:  map[reflect.Type]*workQueue{},
+		resync:  make(chan struct{}, 1),
 
 		entityHandlers: map[reflect.Type]EntityHandler{},
 	}
+	dc.metrics = newControllerMetrics(options.Metrics, dc.group())
+	return dc
 }
 
 // Start starts the controller watch loop
 func (dc *DefaultController) Start() {
+	// Join the controller group first, so that the initial sync only processes the entities owned by this replica
+	changed := dc.updateMembers()
+	go dc.followMembers(changed)
+
 	// Run sync once at the beginning to synchronize resources at service startup.
 	// This should block until resources are synced to ensure proper handling of requests.
-	dc.sync()
+	dc.sync(true)
 
+	dc.startWatching()
 	go dc.run(dc.done)
 }
 
@@ -138,6 +241,9 @@ func (dc *DefaultController) Watcher() Watcher {
 // AddEntityHandler adds entity handlers
 func (dc *DefaultController) AddEntityHandler(h EntityHandler) {
 	dc.entityHandlers[h.Type()] = h
+	q := newWorkQueue(dc.options.RetryDelay, dc.options.MaxRetryDelay)
+	dc.queues[h.Type()] = q
+	dc.metrics.addQueue(h.Type().Elem().Name(), q)
 }
 
 func (dc *DefaultController) processItem(ctx context.Context, e entitystore.Entity) error {
@@ -146,19 +252,21 @@ func (dc *DefaultController) processItem(ctx context.Context, e entitystore.Enti
 
 	log.Debugf("Processing Item: %v (%v) with status %v", e.GetName(), e.GetID(), e.GetStatus())
 
-	if err := dc.driver.CreateNode(fmt.Sprintf("/entities/%v", e.GetID()), []byte{}); err != nil {
-		log.Fatalf("Unable to create znode for %v (%v): %v", e.GetName(), e.GetID(), err)
-	} else {
-		log.Infof("Created znode /entities/%v", e.GetID())
-	}
+	dc.processing.Store(e.GetID(), struct{}{})
+	defer dc.processing.Delete(e.GetID())
 
 	lock, canModify := dc.driver.LockEntity(e.GetID())
 	if !canModify {
-		return errors.Errorf("Failed to acquire lock for %v. Someone else is processing this entity", e.GetID())
+		return errEntityBusy
 	}
 	log.Infof("Acquired lock for %v", e.GetID())
 	defer dc.driver.ReleaseEntity(lock)
 
+	if dc.isStale(ctx, e) {
+		log.Debugf("Skipping %v (%v), it has been modified since it was queued", e.GetName(), e.GetID())
+		return nil
+	}
+
 	var err error
 	h, ok := dc.entityHandlers[reflect.TypeOf(e)]
 	if !ok {
@@ -230,36 +338,277 @@ func DefaultSync(ctx context.Context, store entitystore.EntityStore, entityType
 	return entities, nil
 }
 
-func (dc *DefaultController) sync() error {
+// isStale returns true if the entity has been modified in the store since it was queued, i.e. the change has already
+// been acted upon. Without a store to compare with, entities are never considered stale.
+func (dc *DefaultController) isStale(ctx context.Context, e entitystore.Entity) bool {
+	if dc.options.Store == nil {
+		return false
+	}
+	current := reflect.New(reflect.TypeOf(e).Elem()).Interface().(entitystore.Entity)
+	found, err := dc.options.Store.Find(ctx, e.GetOrganizationID(), e.GetName(), entitystore.Options{}, current)
+	if err != nil || !found {
+		return false
+	}
+	return current.GetRevision() != e.GetRevision()
+}
+
+// pendingStatus returns true if the entity is in a state which requires an action from the entity handler
+func pendingStatus(e entitystore.Entity) bool {
+	if e.GetDelete() {
+		return true
+	}
+	switch e.GetStatus() {
+	case entitystore.StatusINITIALIZED, entitystore.StatusCREATING, entitystore.StatusUPDATING, entitystore.StatusDELETING:
+		return true
+	}
+	return false
+}
+
+// startWatching subscribes to the entity store change feed for every handled entity type
+func (dc *DefaultController) startWatching() {
+	ctx, cancel := context.WithCancel(context.Background())
+	dc.stopWatching = cancel
+	if dc.options.Store == nil {
+		return
+	}
+	for _, h := range dc.entityHandlers {
+		entityType := h.Type().Elem()
+		events, err := dc.options.Store.Watch(ctx, entitystore.DataType(entityType.Name()), "", 0)
+		if err != nil {
+			log.Errorf("%s unable to watch %s entities, relying on resync only: %v", dc.options.ServiceName, entityType.Name(), err)
+			continue
+		}
+		dc.watchers.Add(1)
+		go func() {
+			defer dc.watchers.Done()
+			dc.watch(ctx, entityType, events)
+		}()
+	}
+}
+
+// watch queues entities of the given type which need processing. A failed watch resumes from the last change it
+// received, or from the current one after a resync if the changes since are no longer kept. It returns once ctx is
+// done, or if the watch cannot resume.
+func (dc *DefaultController) watch(ctx context.Context, entityType reflect.Type, events <-chan entitystore.Event) {
+	var revision uint64
+	for {
+		var err error
+		revision, err = dc.handleEvents(ctx, entityType, events, revision)
+		if err == nil {
+			return
+		}
+		log.Warnf("%s watch of %s entities failed, resuming it: %v", dc.options.ServiceName, entityType.Name(), err)
+		events, err = dc.options.Store.Watch(ctx, entitystore.DataType(entityType.Name()), "", revision)
+		if errors.Cause(err) == entitystore.ErrRevisionTooOld {
+			revision = 0
+			dc.requestSync()
+			events, err = dc.options.Store.Watch(ctx, entitystore.DataType(entityType.Name()), "", revision)
+		}
+		if err != nil {
+			log.Errorf("%s unable to watch %s entities, relying on resync only: %v", dc.options.ServiceName, entityType.Name(), err)
+			return
+		}
+	}
+}
+
+// requestSync triggers a resync, unless one is already pending
+func (dc *DefaultController) requestSync() {
+	select {
+	case dc.resync <- struct{}{}:
+	default:
+	}
+}
+
+// handleEvents queues the entities of the events which need processing, and returns the store revision of the last
+// event once the channel is closed, with the error of the watch if it failed
+func (dc *DefaultController) handleEvents(ctx context.Context, entityType reflect.Type, events <-chan entitystore.Event, revision uint64) (uint64, error) {
+	for event := range events {
+		if event.Type == entitystore.EventError {
+			return revision, event.Err
+		}
+		revision = event.StoreRevision
+		// entities are deleted by the handlers themselves, nothing left to do
+		if event.Type == entitystore.EventDelete {
+			continue
+		}
+		if _, ok := dc.processing.Load(event.ID); ok {
+			// the entity is being processed by this controller, which is the most likely author of the change
+			continue
+		}
+		e := reflect.New(entityType).Interface().(entitystore.Entity)
+		if err := dc.options.Store.Get(ctx, event.OrganizationID, event.Name, entitystore.Options{}, e); err != nil {
+			log.Debugf("watch: unable to get %s %s/%s: %v", entityType.Name(), event.OrganizationID, event.Name, err)
+			continue
+		}
+		// if the entity changed since the event was sent, a newer event follows
+		if e.GetRevision() != event.Revision || !pendingStatus(e) || !dc.owns(e) {
+			continue
+		}
+		log.Debugf("watch: %s event for entity %s (%v)", event.Type, e.GetName(), e.GetStatus())
+		dc.enqueue(WatchEvent{Entity: e, Ctx: context.Background()})
+	}
+	return revision, nil
+}
+
+// sync lists the entities to process. The initial sync processes them before returning, later ones queue them.
+func (dc *DefaultController) sync(initial bool) error {
 	span, ctx := trace.Trace(context.Background(), "controller sync")
 	defer span.Finish()
-	if err := dc.driver.CreateNode("/entities", []byte{}); err != nil {
-		log.Fatalf("Unable to create overarching znode %v", err)
+	if dc.options.SingleLeader && !dc.isLeader() {
+		log.Debugf("%s skipping sync, this replica is not the leader", dc.options.ServiceName)
+		return nil
 	}
-	sem := semaphore.NewWeighted(int64(dc.options.Workers))
-	for _, handler := range dc.entityHandlers {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for entityType, handler := range dc.entityHandlers {
 		entities, err := handler.Sync(ctx, dc.options.ResyncPeriod)
 		if err != nil {
 			return err
 		}
+		q := dc.queues[entityType]
+		sem := semaphore.NewWeighted(int64(dc.workers(handler)))
 		for _, e := range entities {
+			key := queueKey(e)
+			if !dc.owns(e) || q.busy(key) {
+				continue
+			}
+			event := WatchEvent{Entity: e, Ctx: context.Background()}
+			if !initial {
+				q.add(key, event)
+				continue
+			}
 			if err := sem.Acquire(ctx, 1); err != nil {
 				log.Printf("Failed to acquire semaphore: %v", err)
 				break
 			}
-			go func(e entitystore.Entity) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
 				defer sem.Release(1)
-				log.Debugf("sync: processing entity %s (%v)", e.GetName(), e.GetStatus())
-				if err := dc.processItem(ctx, e); err != nil {
-					span.LogKV("error", err)
-					log.Error(err)
-				}
-			}(e)
+				log.Debugf("sync: processing entity %s (%v)", event.Entity.GetName(), event.Entity.GetStatus())
+				dc.handle(q, key, event)
+			}()
 		}
 	}
 	return nil
 }
 
+// workers returns how many entities of the handler type are processed concurrently
+func (dc *DefaultController) workers(h EntityHandler) int {
+	if wh, ok := h.(WorkersHandler); ok && wh.Workers() > 0 {
+		return wh.Workers()
+	}
+	return dc.options.Workers
+}
+
+// queueKey returns the key of an entity in the work queue
+func queueKey(e entitystore.Entity) string {
+	if e.GetID() != "" {
+		return e.GetID()
+	}
+	return e.GetOrganizationID() + "/" + e.GetName()
+}
+
+// enqueue queues an entity event to the queue of the entity type
+func (dc *DefaultController) enqueue(event WatchEvent) {
+	e := event.Entity
+	q, ok := dc.queues[reflect.TypeOf(e)]
+	if !ok {
+		log.Errorf("trying to process an entity with no entity handler: %v", reflect.TypeOf(e))
+		return
+	}
+	q.add(queueKey(e), event)
+}
+
+// handle processes an entity. Entities locked by another replica are queued again after the requeue delay, entities
+// requeued by their handler after the requested delay, and
+// failed ones after an exponential backoff, until they fail MaxRetries times and are moved to the ERROR status.
+// Failed entities are reloaded from the store, so that entities in which the handler recorded the error, e.g. by
+// moving them to the ERROR status, are not retried.
+func (dc *DefaultController) handle(q *workQueue, key string, event WatchEvent) {
+	e := event.Entity
+	entityType := reflect.TypeOf(e).Elem().Name()
+
+	start := time.Now()
+	err := dc.processItem(event.Ctx, e)
+	dc.metrics.observe(entityType, time.Since(start))
+
+	delay, requeue := RequeueDelay(err)
+	switch {
+	case err == nil:
+		q.forget(key)
+	case err == errEntityBusy:
+		log.Debugf("%s (%v) is locked by another replica, requeuing it", e.GetName(), e.GetID())
+		q.addAfter(key, event, dc.options.RequeueDelay)
+	case requeue:
+		q.forget(key)
+		// the handler may have updated the entity, which would otherwise be skipped as stale
+		if !dc.reload(&event) {
+			log.Debugf("Not requeuing %s (%v), it does not need processing anymore", e.GetName(), e.GetID())
+			return
+		}
+		log.Debugf("Requeuing %s (%v) in %s", e.GetName(), e.GetID(), delay)
+		q.addAfter(key, event, delay)
+	case !dc.reload(&event):
+		log.Debugf("Not retrying %s (%v), it does not need processing anymore: %v", e.GetName(), e.GetID(), err)
+		q.forget(key)
+	case q.numRetries(key) < dc.options.MaxRetries:
+		log.Warnf("Error processing %s (%v), retrying it: %v", e.GetName(), e.GetID(), err)
+		dc.metrics.retry(entityType)
+		q.addRateLimited(key, event)
+	default:
+		log.Errorf("Error processing %s (%v), giving up after %d retries: %v", e.GetName(), e.GetID(), dc.options.MaxRetries, err)
+		dc.metrics.failure(entityType)
+		q.forget(key)
+		if dc.options.Store != nil {
+			dc.options.Store.UpdateWithError(event.Ctx, event.Entity, err)
+		}
+	}
+}
+
+// reload replaces the entity of an event with its current version in the store, if any. It returns false if the
+// entity does not need processing anymore.
+func (dc *DefaultController) reload(event *WatchEvent) bool {
+	if dc.options.Store == nil {
+		return true
+	}
+	e := event.Entity
+	current := reflect.New(reflect.TypeOf(e).Elem()).Interface().(entitystore.Entity)
+	found, err := dc.options.Store.Find(event.Ctx, e.GetOrganizationID(), e.GetName(), entitystore.Options{}, current)
+	if err != nil {
+		log.Debugf("Unable to reload %s (%v): %v", e.GetName(), e.GetID(), err)
+		return true
+	}
+	if !found || !pendingStatus(current) {
+		return false
+	}
+	event.Entity = current
+	return true
+}
+
+// runQueue processes the entities of a queue, up to workers at a time, until the queue is shut down
+func (dc *DefaultController) runQueue(q *workQueue, workers int) {
+	sem := semaphore.NewWeighted(int64(workers))
+	for {
+		key, event, ok := q.get()
+		if !ok {
+			return
+		}
+		if err := sem.Acquire(context.Background(), 1); err != nil {
+			log.Warnf("Failed to acquire semaphore: %v", err)
+			q.done(key)
+			return
+		}
+		go func() {
+			defer sem.Release(1)
+			defer q.done(key)
+			e := event.Entity
+			log.Infof("received event=%s entity=%s", e.GetStatus(), e.GetName())
+			dc.handle(q, key, event)
+		}()
+	}
+}
+
 // run runs the control loop
 func (dc *DefaultController) run(stopChan <-chan bool) {
 	resyncTicker := time.NewTicker(dc.options.ResyncPeriod)
@@ -269,38 +618,123 @@ func (dc *DefaultController) run(stopChan <-chan bool) {
 
 	defer dc.driver.Close()
 
-	if err := dc.driver.CreateNode("/entities", []byte{}); err != nil {
-		log.Fatalf("Unable to create overarching znode %v", err)
+	for entityType, q := range dc.queues {
+		go dc.runQueue(q, dc.workers(dc.entityHandlers[entityType]))
 	}
-	// Start a worker pool.  The pool scales up to dc.options.Workers.
 	go func() {
-		sem := semaphore.NewWeighted(int64(dc.options.Workers))
-		for watchEvent := range dc.watcher {
-			if err := sem.Acquire(context.Background(), 1); err != nil {
-				log.Warnf("Failed to acquire semaphore: %v", err)
-				break
+		for event := range dc.watcher {
+			e := event.Entity
+			// changes to entities owned by other replicas are picked up by their store watch
+			if dc.options.Store != nil && !dc.owns(e) {
+				log.Debugf("skipping %s (%v), it is owned by another replica", e.GetName(), e.GetID())
+				continue
 			}
-			go func(event WatchEvent) {
-				e := event.Entity
-				defer sem.Release(1)
-				log.Infof("received event=%s entity=%s", e.GetStatus(), e.GetName())
-				if err := dc.processItem(event.Ctx, e); err != nil {
-					log.Error(err)
-				}
-			}(watchEvent)
+			dc.enqueue(event)
 		}
 	}()
 
 	go func() {
-		for range resyncTicker.C {
-			func() {
-				log.Debugf("%s periodic syncing with the underlying driver", dc.options.ServiceName)
-				if err := dc.sync(); err != nil {
-					log.Error(err)
-				}
-			}()
+		for {
+			select {
+			case <-resyncTicker.C:
+			case <-dc.resync:
+			case <-dc.stopped:
+				return
+			}
+			log.Debugf("%s periodic syncing with the underlying driver", dc.options.ServiceName)
+			if err := dc.sync(false); err != nil {
+				log.Error(err)
+			}
 		}
 	}()
 
 	<-stopChan
+	close(dc.stopped)
+	dc.stopWatching()
+	dc.watchers.Wait()
+	for _, q := range dc.queues {
+		q.shutDown()
+	}
+}
+
+func (dc *DefaultController) group() string {
+	if dc.options.ServiceName == "" {
+		return defaultGroup
+	}
+	return dc.options.ServiceName
+}
+
+// updateMembers joins the controller group if this replica is not a live member, e.g. after its session with the
+// coordination backend expired, and refreshes the members. It returns a channel closed on the next membership change,
+// or once it is time to try again after an error.
+func (dc *DefaultController) updateMembers() <-chan struct{} {
+	dc.membersMu.RLock()
+	member := dc.member
+	dc.membersMu.RUnlock()
+
+	members, watch, err := dc.driver.Members(dc.group())
+	if err == nil && !containsString(members, member) {
+		if member, err = dc.driver.Join(dc.group()); err == nil {
+			members, watch, err = dc.driver.Members(dc.group())
+		}
+	}
+	if err != nil {
+		log.Errorf("%s unable to update the members of the controller group: %v", dc.options.ServiceName, err)
+		retry := make(chan struct{})
+		time.AfterFunc(dc.options.RequeueDelay, func() { close(retry) })
+		return retry
+	}
+
+	dc.membersMu.Lock()
+	defer dc.membersMu.Unlock()
+	dc.member = member
+	dc.members = members
+	dc.ring = newHashRing(members)
+	log.Debugf("%s controller group members: %v, this replica is %s", dc.options.ServiceName, members, member)
+	return watch
+}
+
+// followMembers updates the members on every membership change, until the controller shuts down
+func (dc *DefaultController) followMembers(changed <-chan struct{}) {
+	for {
+		select {
+		case <-changed:
+		case <-dc.stopped:
+			return
+		}
+		changed = dc.updateMembers()
+		dc.requestSync()
+	}
+}
+
+// isLeader returns true if this replica is the oldest live member of the controller group
+func (dc *DefaultController) isLeader() bool {
+	dc.membersMu.RLock()
+	defer dc.membersMu.RUnlock()
+	return dc.member != "" && len(dc.members) > 0 && dc.members[0] == dc.member
+}
+
+// owns returns true if the entity is processed by this replica. Until the replica joins the controller group, it
+// processes every entity, unless it runs in single leader mode.
+func (dc *DefaultController) owns(e entitystore.Entity) bool {
+	if dc.options.SingleLeader {
+		return dc.isLeader()
+	}
+	dc.membersMu.RLock()
+	defer dc.membersMu.RUnlock()
+	if dc.member == "" || dc.ring == nil {
+		return true
+	}
+	// entities of each type are partitioned independently
+	key := fmt.Sprintf("%s/%s", reflect.TypeOf(e).Elem().Name(), e.GetID())
+	return dc.ring.owner(key) == dc.member
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
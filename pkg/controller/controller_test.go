@@ -7,23 +7,27 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"expvar"
+	"fmt"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	coordmock "github.com/vmware/dispatch/pkg/coordination/mocks"
 	"github.com/vmware/dispatch/pkg/entity-store"
 	helpers "github.com/vmware/dispatch/pkg/testing/api"
-	zkmock "github.com/vmware/dispatch/pkg/zookeeper/mocks"
 )
 
 const (
-	testOrgID             = "testAPIManagerOrg"
-	testResyncPeriod      = 2 * time.Second
-	testSleepDuration     = 2 * testResyncPeriod
-	testZookeeperLocation = "zookeeper.zookeeper.svc.cluster.local"
+	testOrgID         = "testAPIManagerOrg"
+	testResyncPeriod  = 2 * time.Second
+	testSleepDuration = 2 * testResyncPeriod
+	testMember        = "member-0000000000"
 )
 
 type testEntity struct {
@@ -66,12 +70,12 @@ func (h *testEntityHandler) Error(ctx context.Context, obj entitystore.Entity) e
 	return nil
 }
 
-func getTestDriver() *zkmock.Driver {
-	driver := &zkmock.Driver{}
-	driver.On("CreateNode", mock.Anything, mock.Anything).Return(nil)
-	driver.On("GetConnection").Return(nil)
+func getTestDriver() *coordmock.Driver {
+	driver := &coordmock.Driver{}
 	driver.On("LockEntity", mock.Anything).Return("lock", true)
 	driver.On("ReleaseEntity", "lock").Return(nil)
+	driver.On("Join", mock.Anything).Return(testMember, nil)
+	driver.On("Members", mock.Anything).Return([]string{testMember}, nil, nil)
 	driver.On("Close").Return(nil)
 	return driver
 }
@@ -126,3 +130,376 @@ func TestController(t *testing.T) {
 		t.Logf("deleted %s", name)
 	}
 }
+
+func TestControllerWatch(t *testing.T) {
+	ctx := context.Background()
+	store := helpers.MakeEntityStore(t)
+
+	deleteCounter := make(chan string, 100)
+	addCounter := make(chan string, 100)
+
+	controller := NewController(Options{
+		// long enough for resync to never kick in during the test
+		ResyncPeriod: time.Hour,
+		Driver:       getTestDriver(),
+		Store:        store,
+	})
+	controller.AddEntityHandler(&testEntityHandler{t: t, store: store, addCounter: addCounter, deleteCounter: deleteCounter})
+
+	controller.Start()
+	defer controller.Shutdown()
+
+	// entities added directly to the store (e.g. by another replica) are picked up by the controller
+	ent := &testEntity{entitystore.BaseEntity{
+		OrganizationID: testOrgID,
+		Name:           "test-watch",
+		Status:         entitystore.StatusCREATING,
+	}}
+	_, err := store.Add(ctx, ent)
+	assert.NoError(t, err)
+
+	select {
+	case name := <-addCounter:
+		assert.Equal(t, "test-watch", name)
+	case <-time.After(testSleepDuration):
+		t.Fatal("entity added to the store was not processed")
+	}
+
+	// entities which do not require an action are ignored
+	ent.Status = entitystore.StatusREADY
+	_, err = store.Update(ctx, ent.Revision, ent)
+	assert.NoError(t, err)
+
+	select {
+	case name := <-addCounter:
+		t.Errorf("unexpected Add call for %s", name)
+	case name := <-deleteCounter:
+		t.Errorf("unexpected Delete call for %s", name)
+	case <-time.After(testResyncPeriod):
+	}
+}
+
+// failingWatchStore fails the first watch after an event of store revision 7, and the changes since are no longer kept
+type failingWatchStore struct {
+	entitystore.EntityStore
+	sinces chan uint64
+	calls  int32
+}
+
+func (s *failingWatchStore) Watch(ctx context.Context, dataType entitystore.DataType, organizationID string, sinceRevision uint64) (<-chan entitystore.Event, error) {
+	s.sinces <- sinceRevision
+	switch atomic.AddInt32(&s.calls, 1) {
+	case 1:
+		events := make(chan entitystore.Event, 2)
+		events <- entitystore.Event{Type: entitystore.EventUpdate, DataType: dataType, OrganizationID: testOrgID, Name: "missing", StoreRevision: 7}
+		events <- entitystore.Event{Type: entitystore.EventError, Err: entitystore.ErrWatcherTooSlow}
+		close(events)
+		return events, nil
+	case 2:
+		return nil, entitystore.ErrRevisionTooOld
+	}
+	return s.EntityStore.Watch(ctx, dataType, organizationID, sinceRevision)
+}
+
+func TestControllerWatchResume(t *testing.T) {
+	ctx := context.Background()
+	store := &failingWatchStore{EntityStore: helpers.MakeEntityStore(t), sinces: make(chan uint64, 3)}
+
+	addCounter := make(chan string, 100)
+	controller := NewController(Options{
+		ResyncPeriod: time.Hour,
+		Driver:       getTestDriver(),
+		Store:        store,
+	})
+	controller.AddEntityHandler(&testEntityHandler{t: t, store: store, addCounter: addCounter, deleteCounter: make(chan string, 100)})
+
+	controller.Start()
+	defer controller.Shutdown()
+
+	// the failed watch resumes from the last event, then from the current revision
+	assert.Equal(t, uint64(0), <-store.sinces)
+	assert.Equal(t, uint64(7), <-store.sinces)
+	assert.Equal(t, uint64(0), <-store.sinces)
+
+	_, err := store.Add(ctx, &testEntity{entitystore.BaseEntity{
+		OrganizationID: testOrgID,
+		Name:           "test-watch-resume",
+		Status:         entitystore.StatusCREATING,
+	}})
+	assert.NoError(t, err)
+	select {
+	case name := <-addCounter:
+		assert.Equal(t, "test-watch-resume", name)
+	case <-time.After(testSleepDuration):
+		t.Fatal("entity added to the store was not processed")
+	}
+}
+
+func getMemberDriver(member string, members ...string) *coordmock.Driver {
+	driver := &coordmock.Driver{}
+	driver.On("LockEntity", mock.Anything).Return("lock", true)
+	driver.On("ReleaseEntity", "lock").Return(nil)
+	driver.On("Join", "test").Return(member, nil)
+	driver.On("Members", "test").Return(members, nil, nil)
+	driver.On("Close").Return(nil)
+	return driver
+}
+
+func TestControllerRequeuesBusyEntity(t *testing.T) {
+	ctx := context.Background()
+	store := helpers.MakeEntityStore(t)
+
+	addCounter := make(chan string, 100)
+
+	driver := &coordmock.Driver{}
+	// the entity is locked by another replica the first time
+	driver.On("LockEntity", mock.Anything).Return("", false).Once()
+	driver.On("LockEntity", mock.Anything).Return("lock", true)
+	driver.On("ReleaseEntity", "lock").Return(nil)
+	driver.On("Join", mock.Anything).Return(testMember, nil)
+	driver.On("Members", mock.Anything).Return([]string{testMember}, nil, nil)
+	driver.On("Close").Return(nil)
+
+	controller := NewController(Options{
+		ResyncPeriod: time.Hour,
+		RequeueDelay: 100 * time.Millisecond,
+		Driver:       driver,
+	})
+	controller.AddEntityHandler(&testEntityHandler{t: t, store: store, addCounter: addCounter})
+
+	controller.Start()
+	defer controller.Shutdown()
+
+	ent := &testEntity{entitystore.BaseEntity{
+		OrganizationID: testOrgID,
+		Name:           "test-busy",
+		Status:         entitystore.StatusCREATING,
+	}}
+	_, err := store.Add(ctx, ent)
+	assert.NoError(t, err)
+	watcher := controller.Watcher()
+	watcher.OnAction(ctx, ent)
+
+	select {
+	case name := <-addCounter:
+		assert.Equal(t, "test-busy", name)
+	case <-time.After(testSleepDuration):
+		t.Fatal("busy entity was not requeued")
+	}
+	driver.AssertNumberOfCalls(t, "LockEntity", 2)
+}
+
+func TestControllerSharding(t *testing.T) {
+	ctx := context.Background()
+	store := helpers.MakeEntityStore(t)
+
+	addCounter := make(chan string, 100)
+
+	controller := NewController(Options{
+		ServiceName:  "test",
+		ResyncPeriod: time.Hour,
+		Driver:       getMemberDriver("member-a", "member-a", "member-b"),
+		Store:        store,
+	})
+	controller.AddEntityHandler(&testEntityHandler{t: t, store: store, addCounter: addCounter})
+
+	controller.Start()
+	defer controller.Shutdown()
+
+	ring := newHashRing([]string{"member-a", "member-b"})
+	owned := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		ent := &testEntity{entitystore.BaseEntity{
+			OrganizationID: testOrgID,
+			Name:           fmt.Sprintf("test-shard-%d", i),
+			Status:         entitystore.StatusCREATING,
+		}}
+		_, err := store.Add(ctx, ent)
+		assert.NoError(t, err)
+		if ring.owner("testEntity/"+ent.ID) == "member-a" {
+			owned[ent.Name] = true
+		}
+	}
+
+	processed := map[string]bool{}
+	timeout := time.After(testResyncPeriod)
+	for done := false; !done; {
+		select {
+		case name := <-addCounter:
+			processed[name] = true
+		case <-timeout:
+			done = true
+		}
+	}
+	assert.Equal(t, owned, processed)
+}
+
+func TestControllerSingleLeader(t *testing.T) {
+	ctx := context.Background()
+
+	for _, member := range []string{"member-a", "member-b"} {
+		store := helpers.MakeEntityStore(t)
+		addCounter := make(chan string, 100)
+
+		controller := NewController(Options{
+			ServiceName:  "test",
+			ResyncPeriod: time.Hour,
+			Driver:       getMemberDriver(member, "member-a", "member-b"),
+			Store:        store,
+			SingleLeader: true,
+		})
+		controller.AddEntityHandler(&testEntityHandler{t: t, store: store, addCounter: addCounter})
+		controller.Start()
+
+		ent := &testEntity{entitystore.BaseEntity{
+			OrganizationID: testOrgID,
+			Name:           "test-leader",
+			Status:         entitystore.StatusCREATING,
+		}}
+		_, err := store.Add(ctx, ent)
+		assert.NoError(t, err)
+		watcher := controller.Watcher()
+		watcher.OnAction(ctx, ent)
+
+		select {
+		case name := <-addCounter:
+			// only the oldest member is the leader
+			assert.Equal(t, "member-a", member, "unexpected Add call for %s", name)
+		case <-time.After(testResyncPeriod):
+			assert.Equal(t, "member-b", member, "entity not processed by the leader")
+		}
+		controller.Shutdown()
+	}
+}
+
+type failingEntityHandler struct {
+	testEntityHandler
+}
+
+func (h *failingEntityHandler) Add(ctx context.Context, obj entitystore.Entity) error {
+	h.addCounter <- obj.GetName()
+	return errors.New("add failed")
+}
+
+func (h *failingEntityHandler) Workers() int {
+	return 2
+}
+
+func TestControllerRetries(t *testing.T) {
+	ctx := context.Background()
+	store := helpers.MakeEntityStore(t)
+
+	addCounter := make(chan string, 100)
+	metrics := new(expvar.Map).Init()
+
+	controller := NewController(Options{
+		ServiceName:   "test-retries",
+		ResyncPeriod:  time.Hour,
+		Driver:        getTestDriver(),
+		Store:         store,
+		MaxRetries:    2,
+		RetryDelay:    10 * time.Millisecond,
+		MaxRetryDelay: 20 * time.Millisecond,
+		Metrics:       metrics,
+	})
+	controller.AddEntityHandler(&failingEntityHandler{testEntityHandler{t: t, store: store, addCounter: addCounter}})
+
+	controller.Start()
+	defer controller.Shutdown()
+
+	ent := &testEntity{entitystore.BaseEntity{
+		OrganizationID: testOrgID,
+		Name:           "test-retry",
+		Status:         entitystore.StatusCREATING,
+	}}
+	_, err := store.Add(ctx, ent)
+	assert.NoError(t, err)
+
+	// processed once, then retried twice
+	for i := 0; i < 3; i++ {
+		select {
+		case <-addCounter:
+		case <-time.After(testSleepDuration):
+			t.Fatalf("entity not retried, %d calls", i)
+		}
+	}
+	select {
+	case <-addCounter:
+		t.Error("entity retried more than MaxRetries")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	failed := &testEntity{}
+	assert.NoError(t, store.Get(ctx, testOrgID, "test-retry", entitystore.Options{}, failed))
+	assert.Equal(t, entitystore.StatusERROR, failed.Status)
+	assert.Equal(t, []string{"add failed"}, []string(failed.Reason))
+
+	vars := metrics.Get("test-retries").(*expvar.Map)
+	assert.Equal(t, "2", vars.Get("testEntity.retries").String())
+	assert.Equal(t, "1", vars.Get("testEntity.failures").String())
+	assert.Equal(t, "3", vars.Get("testEntity.processed").String())
+	assert.Equal(t, "0", vars.Get("testEntity.queueDepth").String())
+}
+
+type requeuingEntityHandler struct {
+	testEntityHandler
+	requeues int
+}
+
+func (h *requeuingEntityHandler) Add(ctx context.Context, obj entitystore.Entity) error {
+	h.addCounter <- obj.GetName()
+	if h.requeues == 0 {
+		return nil
+	}
+	h.requeues--
+	// the entity is updated before being requeued, as handlers recording their progress do
+	if _, err := h.store.Update(ctx, obj.GetRevision(), obj); err != nil {
+		return err
+	}
+	return Requeue(10 * time.Millisecond)
+}
+
+func TestControllerRequeue(t *testing.T) {
+	ctx := context.Background()
+	store := helpers.MakeEntityStore(t)
+
+	addCounter := make(chan string, 100)
+	metrics := new(expvar.Map).Init()
+
+	controller := NewController(Options{
+		ServiceName:  "test-requeue",
+		ResyncPeriod: time.Hour,
+		Driver:       getTestDriver(),
+		Store:        store,
+		MaxRetries:   1,
+		Metrics:      metrics,
+	})
+	controller.AddEntityHandler(&requeuingEntityHandler{testEntityHandler{t: t, store: store, addCounter: addCounter}, 3})
+
+	controller.Start()
+	defer controller.Shutdown()
+
+	ent := &testEntity{entitystore.BaseEntity{
+		OrganizationID: testOrgID,
+		Name:           "test-requeue",
+		Status:         entitystore.StatusCREATING,
+	}}
+	_, err := store.Add(ctx, ent)
+	assert.NoError(t, err)
+
+	// requeues are not counted as retries
+	for i := 0; i < 4; i++ {
+		select {
+		case <-addCounter:
+		case <-time.After(testSleepDuration):
+			t.Fatalf("entity not requeued, %d calls", i)
+		}
+	}
+
+	requeued := &testEntity{}
+	assert.NoError(t, store.Get(ctx, testOrgID, "test-requeue", entitystore.Options{}, requeued))
+	assert.Equal(t, entitystore.StatusCREATING, requeued.Status)
+	vars := metrics.Get("test-requeue").(*expvar.Map)
+	assert.Nil(t, vars.Get("testEntity.retries"))
+	assert.Nil(t, vars.Get("testEntity.failures"))
+}
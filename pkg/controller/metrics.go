@@ -0,0 +1,61 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package controller
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+var metricsMu sync.Mutex
+
+// Metrics holds the metrics of the controllers of the process, by service. It is not published with expvar, which
+// also exposes the command line and memory statistics of the process, the server serves it on its metrics listener.
+var Metrics = new(expvar.Map).Init()
+
+// controllerMetrics are the metrics of the work queues of a controller, published as the "<service>" map of a
+// registry. Keys are prefixed with the entity type:
+// - queueDepth is the number of queued entities
+// - processed and processingSeconds are the number of processed entities and the total time spent processing them
+// - retries is the number of retries after a processing error
+// - failures is the number of entities moved to the ERROR status after too many retries
+type controllerMetrics struct {
+	vars *expvar.Map
+}
+
+func newControllerMetrics(registry *expvar.Map, service string) *controllerMetrics {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if registry == nil {
+		registry = Metrics
+	}
+	// controllers of the same service share their metrics
+	if vars, ok := registry.Get(service).(*expvar.Map); ok {
+		return &controllerMetrics{vars: vars}
+	}
+	vars := new(expvar.Map).Init()
+	registry.Set(service, vars)
+	return &controllerMetrics{vars: vars}
+}
+
+func (m *controllerMetrics) addQueue(entityType string, q *workQueue) {
+	m.vars.Set(entityType+".queueDepth", expvar.Func(func() interface{} { return q.len() }))
+}
+
+func (m *controllerMetrics) observe(entityType string, latency time.Duration) {
+	m.vars.Add(entityType+".processed", 1)
+	m.vars.AddFloat(entityType+".processingSeconds", latency.Seconds())
+}
+
+func (m *controllerMetrics) retry(entityType string) {
+	m.vars.Add(entityType+".retries", 1)
+}
+
+func (m *controllerMetrics) failure(entityType string) {
+	m.vars.Add(entityType+".failures", 1)
+}
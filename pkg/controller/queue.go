@@ -0,0 +1,146 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// workQueue is a FIFO queue of entity events keyed by entity ID. An entity is queued at most once: adding an entity
+// which is already queued replaces its event, and adding an entity which is being processed queues it again once it
+// is done, so that an entity is never processed concurrently by the queue workers.
+type workQueue struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// keys are the queued keys, in order
+	keys []string
+	// events are the events of the queued keys and of the keys added while being processed
+	events     map[string]WatchEvent
+	processing map[string]bool
+	retries    map[string]int
+	shutdown   bool
+}
+
+func newWorkQueue(baseDelay, maxDelay time.Duration) *workQueue {
+	q := &workQueue{
+		baseDelay:  baseDelay,
+		maxDelay:   maxDelay,
+		events:     make(map[string]WatchEvent),
+		processing: make(map[string]bool),
+		retries:    make(map[string]int),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// add queues the event of an entity, replacing the queued one if any
+func (q *workQueue) add(key string, event WatchEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shutdown {
+		return
+	}
+	_, queued := q.events[key]
+	q.events[key] = event
+	if queued || q.processing[key] {
+		return
+	}
+	q.keys = append(q.keys, key)
+	q.cond.Signal()
+}
+
+// addAfter queues the event of an entity after a delay
+func (q *workQueue) addAfter(key string, event WatchEvent, delay time.Duration) {
+	time.AfterFunc(delay, func() { q.add(key, event) })
+}
+
+// addRateLimited queues the event of an entity after a delay which doubles on every retry of the entity
+func (q *workQueue) addRateLimited(key string, event WatchEvent) {
+	q.mu.Lock()
+	retries := q.retries[key]
+	q.retries[key] = retries + 1
+	q.mu.Unlock()
+
+	delay := q.baseDelay
+	for i := 0; i < retries && delay < q.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > q.maxDelay {
+		delay = q.maxDelay
+	}
+	q.addAfter(key, event, delay)
+}
+
+// numRetries returns how many times an entity has been retried since it was last forgotten
+func (q *workQueue) numRetries(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.retries[key]
+}
+
+// forget resets the retries of an entity
+func (q *workQueue) forget(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.retries, key)
+}
+
+// get waits for an entity to process, and marks it as being processed. It returns false once the queue is shut down.
+func (q *workQueue) get() (string, WatchEvent, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.keys) == 0 && !q.shutdown {
+		q.cond.Wait()
+	}
+	if q.shutdown {
+		return "", WatchEvent{}, false
+	}
+	key := q.keys[0]
+	q.keys = q.keys[1:]
+	event := q.events[key]
+	delete(q.events, key)
+	q.processing[key] = true
+	return key, event, true
+}
+
+// done marks an entity as processed, queuing it again if it was added in the meantime
+func (q *workQueue) done(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.processing, key)
+	if _, ok := q.events[key]; ok && !q.shutdown {
+		q.keys = append(q.keys, key)
+		q.cond.Signal()
+	}
+}
+
+// busy returns true if an entity is queued or being processed
+func (q *workQueue) busy(key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, queued := q.events[key]
+	return queued || q.processing[key]
+}
+
+// len returns the number of queued entities
+func (q *workQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.keys)
+}
+
+// shutDown drops the queued entities and wakes up the workers waiting in get
+func (q *workQueue) shutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutdown = true
+	q.keys = nil
+	q.cond.Broadcast()
+}
@@ -0,0 +1,99 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/dispatch/pkg/entity-store"
+)
+
+func testEvent(name string, status entitystore.Status) WatchEvent {
+	return WatchEvent{Entity: &testEntity{entitystore.BaseEntity{Name: name, Status: status}}}
+}
+
+func TestWorkQueueDeduplicates(t *testing.T) {
+	q := newWorkQueue(time.Millisecond, time.Millisecond)
+
+	q.add("a", testEvent("a", entitystore.StatusCREATING))
+	q.add("b", testEvent("b", entitystore.StatusCREATING))
+	q.add("a", testEvent("a", entitystore.StatusDELETING))
+	assert.Equal(t, 2, q.len())
+
+	// the latest event of a queued entity wins, and keeps its place in the queue
+	key, event, ok := q.get()
+	assert.True(t, ok)
+	assert.Equal(t, "a", key)
+	assert.Equal(t, entitystore.StatusDELETING, event.Entity.GetStatus())
+	assert.True(t, q.busy("a"))
+
+	// an entity added while being processed is queued once it is done
+	q.add("a", testEvent("a", entitystore.StatusUPDATING))
+	assert.Equal(t, 1, q.len())
+	q.done("a")
+	assert.Equal(t, 2, q.len())
+
+	key, _, _ = q.get()
+	assert.Equal(t, "b", key)
+	q.done("b")
+	assert.False(t, q.busy("b"))
+	key, event, _ = q.get()
+	assert.Equal(t, "a", key)
+	assert.Equal(t, entitystore.StatusUPDATING, event.Entity.GetStatus())
+	q.done("a")
+	assert.Equal(t, 0, q.len())
+}
+
+func TestWorkQueueRetries(t *testing.T) {
+	q := newWorkQueue(10*time.Millisecond, 40*time.Millisecond)
+
+	event := testEvent("a", entitystore.StatusCREATING)
+	var delays []time.Duration
+	for i := 0; i < 4; i++ {
+		start := time.Now()
+		q.addRateLimited("a", event)
+		_, _, ok := q.get()
+		assert.True(t, ok)
+		delays = append(delays, time.Since(start))
+		q.done("a")
+	}
+	assert.Equal(t, 4, q.numRetries("a"))
+	// the delay doubles on every retry, up to the max delay
+	for i, min := range []time.Duration{10, 20, 40, 40} {
+		assert.True(t, delays[i] >= min*time.Millisecond, "retry %d after %s", i, delays[i])
+	}
+	assert.True(t, delays[3] < 80*time.Millisecond, "retry 3 after %s", delays[3])
+
+	q.forget("a")
+	assert.Equal(t, 0, q.numRetries("a"))
+}
+
+func TestWorkQueueShutDown(t *testing.T) {
+	q := newWorkQueue(time.Millisecond, time.Millisecond)
+	q.add("a", testEvent("a", entitystore.StatusCREATING))
+
+	done := make(chan bool)
+	go func() {
+		_, _, ok := q.get()
+		assert.True(t, ok)
+		_, _, ok = q.get()
+		done <- ok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.shutDown()
+	select {
+	case ok := <-done:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("get did not return after shutdown")
+	}
+
+	q.add("b", testEvent("b", entitystore.StatusCREATING))
+	assert.Equal(t, 0, q.len())
+}
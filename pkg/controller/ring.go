@@ -0,0 +1,58 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package controller
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// ringReplicas is the number of points of each member on the ring, more points spread the keys more evenly
+const ringReplicas = 64
+
+// hashRing assigns keys to members with consistent hashing: when a member joins or leaves, only the keys it owns,
+// or comes to own, move.
+type hashRing struct {
+	points  []uint32
+	members map[uint32]string
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+func newHashRing(members []string) *hashRing {
+	r := &hashRing{members: make(map[uint32]string)}
+	for _, m := range members {
+		for i := 0; i < ringReplicas; i++ {
+			point := hashKey(m + "#" + strconv.Itoa(i))
+			if _, ok := r.members[point]; ok {
+				// on collisions, the first member keeps the point
+				continue
+			}
+			r.members[point] = m
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// owner returns the member owning the key, the empty string if the ring has no member
+func (r *hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.members[r.points[i]]
+}
@@ -0,0 +1,42 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing(t *testing.T) {
+	assert.Equal(t, "", newHashRing(nil).owner("key"))
+
+	members := []string{"member-a", "member-b", "member-c"}
+	ring := newHashRing(members)
+
+	owners := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		owners[key] = ring.owner(key)
+		counts[owners[key]]++
+	}
+	for _, m := range members {
+		// each member gets a reasonable share of the keys
+		assert.True(t, counts[m] > 500, "%s owns %d keys", m, counts[m])
+	}
+
+	// when a member leaves, only its keys move
+	ring = newHashRing([]string{"member-a", "member-c"})
+	for key, owner := range owners {
+		if owner != "member-b" {
+			assert.Equal(t, owner, ring.owner(key))
+		} else {
+			assert.NotEqual(t, "member-b", ring.owner(key))
+		}
+	}
+}
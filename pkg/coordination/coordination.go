@@ -0,0 +1,75 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package coordination
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/vmware/dispatch/pkg/zookeeper"
+)
+
+// Coordination backends
+const (
+	BackendZookeeper = "zookeeper"
+	BackendEtcd      = "etcd"
+	BackendLocal     = "local"
+)
+
+const (
+	defaultZookeeperLocation = "127.0.0.1"
+	defaultEtcdEndpoint      = "http://127.0.0.1:2379"
+)
+
+// Driver coordinates the replicas of a service: it locks the entities being processed, and keeps track of the live
+// replicas, the members of a group
+type Driver interface {
+	// LockEntity locks an entity without waiting for it, preventing other drivers from modifying it.
+	// Returns the lock and whether it was acquired.
+	LockEntity(name string) (string, bool)
+	// ReleaseEntity releases a lock returned by LockEntity
+	ReleaseEntity(lock string)
+	// Join registers the driver as a member of a group, until the driver is closed or loses its connection.
+	// Returns the ID of the member.
+	Join(group string) (string, error)
+	// Members returns the IDs of the live members of a group, oldest first, and a channel which is closed on the
+	// next membership change
+	Members(group string) ([]string, <-chan struct{}, error)
+	// Close releases the locks and leaves the groups of the driver
+	Close()
+}
+
+// Config selects and configures the coordination backend
+type Config struct {
+	// Backend is one of zookeeper, etcd or local. Defaults to zookeeper.
+	Backend string
+	// ZookeeperLocation is the address of the zookeeper server
+	ZookeeperLocation string
+	// EtcdEndpoints are the client URLs of the etcd cluster
+	EtcdEndpoints []string
+}
+
+// New creates a driver for the configured backend.
+// Drivers connect lazily, an unreachable server makes the driver calls fail, not New.
+func New(config Config) (Driver, error) {
+	switch config.Backend {
+	case "", BackendZookeeper:
+		location := config.ZookeeperLocation
+		if location == "" {
+			location = defaultZookeeperLocation
+		}
+		return zookeeper.NewDriver(location)
+	case BackendEtcd:
+		endpoints := config.EtcdEndpoints
+		if len(endpoints) == 0 {
+			endpoints = []string{defaultEtcdEndpoint}
+		}
+		return NewEtcdDriver(endpoints), nil
+	case BackendLocal:
+		return NewLocalDriver(), nil
+	default:
+		return nil, errors.Errorf("unknown coordination backend %s, expected zookeeper, etcd or local", config.Backend)
+	}
+}
@@ -0,0 +1,350 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package coordination
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// etcdLeaseTTL is the TTL of the lease of a driver, in seconds. The locks and memberships of a driver which stops
+	// renewing its lease expire after it.
+	etcdLeaseTTL = 10
+	etcdPrefix   = "/dispatch"
+	etcdTimeout  = 5 * time.Second
+)
+
+// EtcdDriver coordinates replicas through an etcd v3 cluster, using its JSON gateway.
+// Locks and memberships are keys attached to the lease of the driver, so that they are removed when the driver is
+// closed or stops renewing its lease.
+type EtcdDriver struct {
+	endpoints []string
+	client    *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// keepAliveInterval is how often the lease is renewed, a fraction of its TTL
+	keepAliveInterval time.Duration
+
+	mu    sync.Mutex
+	lease string
+}
+
+type etcdKV struct {
+	Key            []byte `json:"key"`
+	Value          []byte `json:"value,omitempty"`
+	CreateRevision string `json:"create_revision,omitempty"`
+}
+
+type etcdPut struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+	Lease string `json:"lease,omitempty"`
+}
+
+type etcdRange struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+type etcdCompare struct {
+	Key            []byte `json:"key"`
+	Target         string `json:"target"`
+	CreateRevision string `json:"create_revision"`
+}
+
+type etcdRequestOp struct {
+	RequestPut *etcdPut `json:"request_put,omitempty"`
+}
+
+type etcdTxn struct {
+	Compare []etcdCompare   `json:"compare"`
+	Success []etcdRequestOp `json:"success"`
+}
+
+type etcdWatchRequest struct {
+	CreateRequest struct {
+		etcdRange
+		StartRevision string `json:"start_revision"`
+	} `json:"create_request"`
+}
+
+type etcdError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// NewEtcdDriver creates a driver for the etcd cluster with the given client URLs
+func NewEtcdDriver(endpoints []string) *EtcdDriver {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EtcdDriver{
+		endpoints:         endpoints,
+		client:            &http.Client{},
+		ctx:               ctx,
+		cancel:            cancel,
+		keepAliveInterval: etcdLeaseTTL * time.Second / 3,
+	}
+}
+
+// do sends a request to the first reachable endpoint
+func (d *EtcdDriver) do(ctx context.Context, path string, request interface{}) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling etcd request %s", path)
+	}
+	lastErr := errors.New("no etcd endpoint")
+	for _, endpoint := range d.endpoints {
+		req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid etcd endpoint %s", endpoint)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		res, err := d.client.Do(req.WithContext(ctx))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if res.StatusCode != http.StatusOK {
+			defer res.Body.Close()
+			var e etcdError
+			b, _ := ioutil.ReadAll(res.Body)
+			if json.Unmarshal(b, &e) != nil || e.Message == "" {
+				e.Message = string(b)
+			}
+			return nil, errors.Errorf("etcd request %s failed with status %d: %s", path, res.StatusCode, e.Message)
+		}
+		return res, nil
+	}
+	return nil, errors.Wrapf(lastErr, "etcd is unreachable at %v", d.endpoints)
+}
+
+// post sends a request and decodes its response
+func (d *EtcdDriver) post(path string, request, response interface{}) error {
+	ctx, cancel := context.WithTimeout(d.ctx, etcdTimeout)
+	defer cancel()
+	res, err := d.do(ctx, path, request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+		return errors.Wrapf(err, "error decoding etcd response %s", path)
+	}
+	return nil
+}
+
+// leaseID returns the lease of the driver, granted on first use and again once it expires
+func (d *EtcdDriver) leaseID() (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lease != "" {
+		return d.lease, nil
+	}
+	var res struct {
+		ID string `json:"ID"`
+	}
+	if err := d.post("/v3/lease/grant", map[string]interface{}{"TTL": etcdLeaseTTL}, &res); err != nil {
+		return "", errors.Wrap(err, "unable to grant an etcd lease")
+	}
+	d.lease = res.ID
+	go d.keepAlive(res.ID)
+	return res.ID, nil
+}
+
+// keepAlive renews the lease until the driver is closed or the lease expires
+func (d *EtcdDriver) keepAlive(lease string) {
+	ticker := time.NewTicker(d.keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.ctx.Done():
+			return
+		}
+		var res struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		if err := d.post("/v3/lease/keepalive", map[string]string{"ID": lease}, &res); err != nil {
+			log.Warnf("Unable to renew etcd lease %s: %v", lease, err)
+			continue
+		}
+		if ttl, _ := strconv.Atoi(res.Result.TTL); ttl <= 0 {
+			log.Warnf("Etcd lease %s expired, its locks and memberships are lost", lease)
+			d.mu.Lock()
+			if d.lease == lease {
+				d.lease = ""
+			}
+			d.mu.Unlock()
+			return
+		}
+	}
+}
+
+// LockEntity locks an entity by creating its lock key, unless it already exists
+func (d *EtcdDriver) LockEntity(name string) (string, bool) {
+	lease, err := d.leaseID()
+	if err != nil {
+		log.Warnf("Unable to lock %v: %v", name, err)
+		return "", false
+	}
+	key := []byte(etcdPrefix + "/locks/" + name)
+	txn := etcdTxn{
+		Compare: []etcdCompare{{Key: key, Target: "CREATE", CreateRevision: "0"}},
+		Success: []etcdRequestOp{{RequestPut: &etcdPut{Key: key, Value: []byte(lease), Lease: lease}}},
+	}
+	var res struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := d.post("/v3/kv/txn", txn, &res); err != nil {
+		log.Warnf("Unable to lock %v: %v", name, err)
+		return "", false
+	}
+	return string(key), res.Succeeded
+}
+
+// ReleaseEntity releases the entity by deleting its lock key
+func (d *EtcdDriver) ReleaseEntity(lock string) {
+	var res struct{}
+	if err := d.post("/v3/kv/deleterange", etcdRange{Key: []byte(lock)}, &res); err != nil {
+		log.Errorf("Unable to delete lock %v, it expires with the lease of the driver: %v", lock, err)
+	}
+}
+
+func etcdMembersPrefix(group string) string {
+	return etcdPrefix + "/members/" + group + "/"
+}
+
+// prefixEnd returns the end of the range of keys starting with prefix
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+// Join adds a member key to the group
+func (d *EtcdDriver) Join(group string) (string, error) {
+	lease, err := d.leaseID()
+	if err != nil {
+		return "", err
+	}
+	member := uuid.NewV4().String()
+	var res struct{}
+	put := etcdPut{Key: []byte(etcdMembersPrefix(group) + member), Value: []byte(member), Lease: lease}
+	if err := d.post("/v3/kv/put", put, &res); err != nil {
+		return "", errors.Wrapf(err, "unable to join group %v", group)
+	}
+	log.Infof("Joined group %v as %v", group, member)
+	return member, nil
+}
+
+// Members lists the member keys of the group, ordered by creation, and watches them from the revision they were
+// listed at
+func (d *EtcdDriver) Members(group string) ([]string, <-chan struct{}, error) {
+	prefix := etcdMembersPrefix(group)
+	var res struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		KVs []etcdKV `json:"kvs"`
+	}
+	if err := d.post("/v3/kv/range", etcdRange{Key: []byte(prefix), RangeEnd: prefixEnd(prefix)}, &res); err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to get members of group %v", group)
+	}
+	sort.Slice(res.KVs, func(i, j int) bool {
+		ri, _ := strconv.ParseInt(res.KVs[i].CreateRevision, 10, 64)
+		rj, _ := strconv.ParseInt(res.KVs[j].CreateRevision, 10, 64)
+		return ri < rj
+	})
+	var members []string
+	for _, kv := range res.KVs {
+		members = append(members, strings.TrimPrefix(string(kv.Key), prefix))
+	}
+
+	revision, _ := strconv.ParseInt(res.Header.Revision, 10, 64)
+	changed := make(chan struct{})
+	go d.watch(prefix, revision+1, changed)
+	return members, changed, nil
+}
+
+// watch closes changed on the first change of the keys under prefix since revision
+func (d *EtcdDriver) watch(prefix string, revision int64, changed chan<- struct{}) {
+	defer close(changed)
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+
+	var req etcdWatchRequest
+	req.CreateRequest.Key = []byte(prefix)
+	req.CreateRequest.RangeEnd = prefixEnd(prefix)
+	req.CreateRequest.StartRevision = strconv.FormatInt(revision, 10)
+	res, err := d.do(ctx, "/v3/watch", req)
+	if err != nil {
+		log.Warnf("Unable to watch %v: %v", prefix, err)
+		// the members are listed again once changed is closed, give etcd some time to come back
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+		}
+		return
+	}
+	defer res.Body.Close()
+
+	dec := json.NewDecoder(res.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Canceled bool              `json:"canceled"`
+				Events   []json.RawMessage `json:"events"`
+			} `json:"result"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		if msg.Result.Canceled || len(msg.Result.Events) > 0 {
+			return
+		}
+	}
+}
+
+// Close revokes the lease of the driver, which deletes its locks and memberships
+func (d *EtcdDriver) Close() {
+	d.cancel()
+	d.mu.Lock()
+	lease := d.lease
+	d.lease = ""
+	d.mu.Unlock()
+	if lease == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	res, err := d.do(ctx, "/v3/lease/revoke", map[string]string{"ID": lease})
+	if err != nil {
+		log.Warnf("Unable to revoke etcd lease %s, it expires in %ds: %v", lease, etcdLeaseTTL, err)
+		return
+	}
+	res.Body.Close()
+}
@@ -0,0 +1,333 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package coordination
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEtcd is an in-memory etcd JSON gateway, implementing the requests of the etcd driver
+type fakeEtcd struct {
+	mu        sync.Mutex
+	revision  int64
+	nextLease int64
+	leases    map[string]bool
+	keys      map[string]fakeEtcdKey
+	// changed is closed and replaced on every change of the keys
+	changed chan struct{}
+}
+
+type fakeEtcdKey struct {
+	value          []byte
+	lease          string
+	createRevision int64
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{
+		leases:  make(map[string]bool),
+		keys:    make(map[string]fakeEtcdKey),
+		changed: make(chan struct{}),
+	}
+}
+
+// notify wakes up the watches, with the lock held
+func (f *fakeEtcd) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// put sets a key, with the lock held
+func (f *fakeEtcd) put(key string, value []byte, lease string) {
+	f.revision++
+	kv, ok := f.keys[key]
+	if !ok {
+		kv.createRevision = f.revision
+	}
+	kv.value, kv.lease = value, lease
+	f.keys[key] = kv
+	f.notify()
+}
+
+// expire ends a lease, deleting its keys as etcd does once its TTL elapsed
+func (f *fakeEtcd) expire(lease string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.leases, lease)
+	for key, kv := range f.keys {
+		if kv.lease == lease {
+			delete(f.keys, key)
+		}
+	}
+	f.revision++
+	f.notify()
+}
+
+func (f *fakeEtcd) lease() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for lease := range f.leases {
+		return lease
+	}
+	return ""
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path == "/v3/watch" {
+		var watch etcdWatchRequest
+		json.Unmarshal(body, &watch)
+		revision, _ := strconv.ParseInt(watch.CreateRequest.StartRevision, 10, 64)
+		f.serveWatch(w, r, revision)
+		return
+	}
+	var lease struct {
+		ID string `json:"ID"`
+	}
+	var put etcdPut
+	var txn etcdTxn
+	var keys etcdRange
+	for _, v := range []interface{}{&lease, &put, &txn, &keys} {
+		json.Unmarshal(body, v)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := map[string]interface{}{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.nextLease++
+		id := strconv.FormatInt(f.nextLease, 10)
+		f.leases[id] = true
+		res["ID"] = id
+	case "/v3/lease/keepalive":
+		// an expired lease is renewed without TTL
+		result := map[string]string{"ID": lease.ID}
+		if f.leases[lease.ID] {
+			result["TTL"] = strconv.Itoa(etcdLeaseTTL)
+		}
+		res["result"] = result
+	case "/v3/lease/revoke":
+		f.mu.Unlock()
+		f.expire(lease.ID)
+		f.mu.Lock()
+	case "/v3/kv/put":
+		if put.Lease != "" && !f.leases[put.Lease] {
+			http.Error(w, `{"error":"etcdserver: requested lease not found","message":"etcdserver: requested lease not found"}`, http.StatusNotFound)
+			return
+		}
+		f.put(string(put.Key), put.Value, put.Lease)
+	case "/v3/kv/txn":
+		succeeded := true
+		for _, c := range txn.Compare {
+			kv := f.keys[string(c.Key)]
+			if strconv.FormatInt(kv.createRevision, 10) != c.CreateRevision {
+				succeeded = false
+			}
+		}
+		if succeeded {
+			for _, op := range txn.Success {
+				f.put(string(op.RequestPut.Key), op.RequestPut.Value, op.RequestPut.Lease)
+			}
+		}
+		res["succeeded"] = succeeded
+	case "/v3/kv/deleterange":
+		if _, ok := f.keys[string(keys.Key)]; ok {
+			delete(f.keys, string(keys.Key))
+			f.revision++
+			f.notify()
+		}
+	case "/v3/kv/range":
+		var kvs []etcdKV
+		for key, kv := range f.keys {
+			if key >= string(keys.Key) && key < string(keys.RangeEnd) {
+				kvs = append(kvs, etcdKV{Key: []byte(key), Value: kv.value, CreateRevision: strconv.FormatInt(kv.createRevision, 10)})
+			}
+		}
+		res["kvs"] = kvs
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	res["header"] = map[string]string{"revision": strconv.FormatInt(f.revision, 10)}
+	json.NewEncoder(w).Encode(res)
+}
+
+// serveWatch streams a message with an event once any key changed since revision, the range is not tracked
+func (f *fakeEtcd) serveWatch(w http.ResponseWriter, r *http.Request, revision int64) {
+	w.Write([]byte(`{"result":{"created":true}}` + "\n"))
+	w.(http.Flusher).Flush()
+	for {
+		f.mu.Lock()
+		current, changed := f.revision, f.changed
+		f.mu.Unlock()
+		if current >= revision {
+			break
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	w.Write([]byte(`{"result":{"events":[{"kv":{}}]}}` + "\n"))
+	w.(http.Flusher).Flush()
+}
+
+func newTestEtcdDriver(server *httptest.Server) *EtcdDriver {
+	d := NewEtcdDriver([]string{server.URL})
+	d.keepAliveInterval = 10 * time.Millisecond
+	return d
+}
+
+func TestEtcdDriverLock(t *testing.T) {
+	fake := newFakeEtcd()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	first := newTestEtcdDriver(server)
+	defer first.Close()
+	second := newTestEtcdDriver(server)
+	defer second.Close()
+
+	lock, ok := first.LockEntity("entity")
+	assert.True(t, ok)
+	assert.Equal(t, etcdPrefix+"/locks/entity", lock)
+
+	// the lock is held by the first driver
+	_, ok = second.LockEntity("entity")
+	assert.False(t, ok)
+	_, ok = first.LockEntity("entity")
+	assert.False(t, ok)
+	_, ok = second.LockEntity("other")
+	assert.True(t, ok)
+
+	first.ReleaseEntity(lock)
+	_, ok = second.LockEntity("entity")
+	assert.True(t, ok)
+
+	// closing the driver revokes its lease, which deletes its locks
+	second.Close()
+	_, ok = first.LockEntity("entity")
+	assert.True(t, ok)
+	_, ok = first.LockEntity("other")
+	assert.True(t, ok)
+}
+
+func TestEtcdDriverLeaseExpiry(t *testing.T) {
+	fake := newFakeEtcd()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	first := newTestEtcdDriver(server)
+	defer first.Close()
+	second := newTestEtcdDriver(server)
+	defer second.Close()
+
+	_, ok := first.LockEntity("entity")
+	require.True(t, ok)
+	lease := fake.lease()
+	require.NotEmpty(t, lease)
+
+	// the lease keeps being renewed
+	time.Sleep(50 * time.Millisecond)
+	_, ok = second.LockEntity("entity")
+	assert.False(t, ok)
+
+	// once the lease expires, its lock is released and the driver forgets it
+	fake.expire(lease)
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		first.mu.Lock()
+		lease := first.lease
+		first.mu.Unlock()
+		if lease == "" {
+			break
+		}
+	}
+	first.mu.Lock()
+	assert.Empty(t, first.lease)
+	first.mu.Unlock()
+	_, ok = second.LockEntity("entity")
+	assert.True(t, ok)
+
+	// a new lease is granted on next use
+	_, ok = first.LockEntity("other")
+	assert.True(t, ok)
+	first.mu.Lock()
+	assert.NotEqual(t, lease, first.lease)
+	assert.NotEmpty(t, first.lease)
+	first.mu.Unlock()
+}
+
+func TestEtcdDriverMembers(t *testing.T) {
+	fake := newFakeEtcd()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	first := newTestEtcdDriver(server)
+	defer first.Close()
+	second := newTestEtcdDriver(server)
+	defer second.Close()
+
+	members, changed, err := first.Members("group")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	firstMember, err := first.Join("group")
+	require.NoError(t, err)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("members not changed after join")
+	}
+	secondMember, err := second.Join("group")
+	require.NoError(t, err)
+
+	// members are ordered by creation
+	members, changed, err = first.Members("group")
+	require.NoError(t, err)
+	assert.Equal(t, []string{firstMember, secondMember}, members)
+
+	// the member of an expired lease leaves the group
+	second.mu.Lock()
+	lease := second.lease
+	second.mu.Unlock()
+	fake.expire(lease)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("members not changed after lease expiry")
+	}
+	members, _, err = first.Members("group")
+	require.NoError(t, err)
+	assert.Equal(t, []string{firstMember}, members)
+}
+
+func TestEtcdDriverUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	d := NewEtcdDriver([]string{server.URL})
+	defer d.Close()
+
+	_, ok := d.LockEntity("entity")
+	assert.False(t, ok)
+	_, err := d.Join("group")
+	assert.Error(t, err)
+	_, _, err = d.Members("group")
+	assert.True(t, strings.Contains(err.Error(), "etcd is unreachable"))
+}
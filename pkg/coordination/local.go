@@ -0,0 +1,103 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package coordination
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LocalDriver coordinates the controllers of a single process, for single-node deployments.
+// It is the only member of the groups it joins.
+type LocalDriver struct {
+	mu  sync.Mutex
+	seq int
+	// locks are the locks by entity
+	locks  map[string]string
+	groups map[string]*localGroup
+}
+
+type localGroup struct {
+	members []string
+	changed chan struct{}
+}
+
+// NewLocalDriver creates an in-process driver
+func NewLocalDriver() *LocalDriver {
+	return &LocalDriver{
+		locks:  make(map[string]string),
+		groups: make(map[string]*localGroup),
+	}
+}
+
+// LockEntity locks an entity, unless it is already locked
+func (d *LocalDriver) LockEntity(name string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.locks[name]; ok {
+		return "", false
+	}
+	d.seq++
+	lock := fmt.Sprintf("%s/lock-%010d", name, d.seq)
+	d.locks[name] = lock
+	return lock, true
+}
+
+// ReleaseEntity releases a lock
+func (d *LocalDriver) ReleaseEntity(lock string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := strings.LastIndex(lock, "/lock-")
+	if i < 0 {
+		return
+	}
+	if name := lock[:i]; d.locks[name] == lock {
+		delete(d.locks, name)
+	}
+}
+
+func (d *LocalDriver) group(name string) *localGroup {
+	g, ok := d.groups[name]
+	if !ok {
+		g = &localGroup{changed: make(chan struct{})}
+		d.groups[name] = g
+	}
+	return g
+}
+
+// Join adds a member to a group
+func (d *LocalDriver) Join(group string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seq++
+	member := fmt.Sprintf("member-%010d", d.seq)
+	g := d.group(group)
+	g.members = append(g.members, member)
+	close(g.changed)
+	g.changed = make(chan struct{})
+	return member, nil
+}
+
+// Members returns the members of a group
+func (d *LocalDriver) Members(group string) ([]string, <-chan struct{}, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g := d.group(group)
+	return append([]string(nil), g.members...), g.changed, nil
+}
+
+// Close releases the locks and removes the members of all groups
+func (d *LocalDriver) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.locks = make(map[string]string)
+	for _, g := range d.groups {
+		g.members = nil
+		close(g.changed)
+		g.changed = make(chan struct{})
+	}
+}
@@ -0,0 +1,83 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package coordination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestLocalDriverLock(t *testing.T) {
+	d := NewLocalDriver()
+
+	lock, ok := d.LockEntity("entity")
+	assert.True(t, ok)
+	_, ok = d.LockEntity("entity")
+	assert.False(t, ok)
+	_, ok = d.LockEntity("other")
+	assert.True(t, ok)
+
+	d.ReleaseEntity(lock)
+	_, ok = d.LockEntity("entity")
+	assert.True(t, ok)
+
+	// releasing an old lock does not release the current one
+	d.ReleaseEntity(lock)
+	_, ok = d.LockEntity("entity")
+	assert.False(t, ok)
+
+	d.Close()
+	_, ok = d.LockEntity("entity")
+	assert.True(t, ok)
+}
+
+func TestLocalDriverMembers(t *testing.T) {
+	d := NewLocalDriver()
+
+	members, changed, err := d.Members("group")
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+
+	first, err := d.Join("group")
+	assert.NoError(t, err)
+	assert.True(t, closed(changed))
+	second, err := d.Join("group")
+	assert.NoError(t, err)
+
+	members, changed, err = d.Members("group")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{first, second}, members)
+	assert.False(t, closed(changed))
+
+	d.Close()
+	assert.True(t, closed(changed))
+	members, _, err = d.Members("group")
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestNew(t *testing.T) {
+	d, err := New(Config{Backend: BackendLocal})
+	assert.NoError(t, err)
+	assert.IsType(t, &LocalDriver{}, d)
+
+	d, err = New(Config{Backend: BackendEtcd})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{defaultEtcdEndpoint}, d.(*EtcdDriver).endpoints)
+
+	_, err = New(Config{Backend: "consul"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+)
+
+var (
+	cancelLong = i18n.T(`Cancel a resource which is in progress.`)
+
+	cancelExample = i18n.T(`
+# Cancel a function run
+dispatch cancel run f98d0a7f-0c1d-4020-a488-cabc501b08e0`)
+)
+
+// NewCmdCancel creates a command object for the generic "cancel" action, which
+// cancels resources in progress on a server.
+func NewCmdCancel(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cancel TYPE NAME",
+		Short:   i18n.T("Cancel a resource in progress"),
+		Long:    cancelLong,
+		Example: cancelExample,
+		Run:     runHelp,
+	}
+	cmd.AddCommand(NewCmdCancelRun(out, errOut))
+	return cmd
+}
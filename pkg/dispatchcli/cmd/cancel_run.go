@@ -0,0 +1,67 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+)
+
+var (
+	cancelRunLong = i18n.T(`Cancel a function run. A queued run never starts, a running one is interrupted.`)
+
+	cancelRunExample = i18n.T(`
+# Cancel a run
+dispatch cancel run f98d0a7f-0c1d-4020-a488-cabc501b08e0
+
+# Cancel a run of a specific function
+dispatch cancel run example-function f98d0a7f-0c1d-4020-a488-cabc501b08e0`)
+)
+
+// NewCmdCancelRun creates command responsible for cancelling function runs.
+func NewCmdCancelRun(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "run [FUNCTION_NAME] RUN_ID",
+		Short:   i18n.T("Cancel function run"),
+		Long:    cancelRunLong,
+		Example: cancelRunExample,
+		Args:    cobra.RangeArgs(1, 2),
+		Aliases: []string{"runs"},
+		Run: func(cmd *cobra.Command, args []string) {
+			c := functionManagerClient()
+			err := cancelRun(out, errOut, cmd, args, c)
+			CheckErr(err)
+		},
+	}
+	return cmd
+}
+
+func cancelRun(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.FunctionsClient) error {
+	opts := client.FunctionOpts{RunName: &args[len(args)-1]}
+	if len(args) == 2 {
+		opts.FunctionName = &args[0]
+	}
+	run, err := c.CancelRun(context.TODO(), "", opts)
+	if err != nil {
+		return err
+	}
+	return formatCancelRunOutput(out, run)
+}
+
+func formatCancelRunOutput(out io.Writer, run *v1.Run) error {
+	if w, err := formatOutput(out, false, run); w {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "Cancelled run: %s\n", run.Name)
+	return err
+}
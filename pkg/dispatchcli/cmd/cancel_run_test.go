@@ -0,0 +1,34 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/client/mocks"
+)
+
+func TestCancelRun(t *testing.T) {
+	var buf bytes.Buffer
+	runName := "f98d0a7f-0c1d-4020-a488-cabc501b08e0"
+	functionName := "hello"
+
+	c := &mocks.FunctionsClient{}
+	c.On("CancelRun", mock.Anything, "", client.FunctionOpts{FunctionName: &functionName, RunName: &runName}).Return(
+		&v1.Run{Name: strfmt.UUID(runName), Status: v1.StatusCANCELLED}, nil)
+
+	err := cancelRun(&buf, &buf, nil, []string{functionName, runName}, c)
+	assert.NoError(t, err)
+	assert.Equal(t, "Cancelled run: "+runName+"\n", buf.String())
+	c.AssertExpectations(t)
+}
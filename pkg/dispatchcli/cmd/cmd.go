@@ -193,6 +193,9 @@ func NewCLI(in io.Reader, out, errOut io.Writer) *cobra.Command {
 	cmds.AddCommand(NewCmdUpdate(out, errOut))
 	cmds.AddCommand(NewCmdExec(out, errOut))
 	cmds.AddCommand(NewCmdDelete(out, errOut))
+	cmds.AddCommand(NewCmdCancel(out, errOut))
+	cmds.AddCommand(NewCmdPromote(out, errOut))
+	cmds.AddCommand(NewCmdRollback(out, errOut))
 	cmds.AddCommand(NewCmdLogin(in, out, errOut))
 	cmds.AddCommand(NewCmdLogout(in, out, errOut))
 	cmds.AddCommand(NewCmdEmit(out, errOut))
@@ -332,6 +332,7 @@ func NewCmdCreate(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd.AddCommand(NewCmdCreateBaseImage(out, errOut))
 	cmd.AddCommand(NewCmdCreateImage(out, errOut))
 	cmd.AddCommand(NewCmdCreateFunction(out, errOut))
+	cmd.AddCommand(NewCmdCreateVersion(out, errOut))
 	cmd.AddCommand(NewCmdCreateSecret(out, errOut))
 	cmd.AddCommand(NewCmdCreateAPI(out, errOut))
 	cmd.AddCommand(NewCmdCreateSubscription(out, errOut))
@@ -11,6 +11,7 @@ import (
 	"io"
 	"io/ioutil"
 	"path"
+	"time"
 
 	"github.com/go-openapi/spec"
 	"github.com/pkg/errors"
@@ -34,6 +35,20 @@ var (
 	fnSecrets             []string
 	fnServices            []string
 	timeout               int64
+	fnMemory              = ""
+	fnCPU                 = ""
+	fnPids                int64
+	fnReadOnly            = false
+	fnDropCapabilities    []string
+	fnSeccompProfile      = ""
+	fnNoNetwork           = false
+	fnMaxAttempts         int64
+	fnRetryBackoff        int64
+	fnRetryMaxBackoff     int64
+	fnRetryOn             []string
+	fnRunMaxAge           time.Duration
+	fnRunMaxCount         int64
+	fnPreWarm             int64
 )
 
 // NewCmdCreateFunction creates command responsible for dispatch function creation.
@@ -58,6 +73,20 @@ func NewCmdCreateFunction(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd.Flags().StringArrayVar(&fnSecrets, "secret", []string{}, "Function secrets, can be specified multiple times or a comma-delimited string")
 	cmd.Flags().StringArrayVar(&fnServices, "service", []string{}, "Service instances this function uses, can be specified multiple times or a comma-delimited string")
 	cmd.Flags().Int64Var(&timeout, "timeout", 0, "A timeout to limit function execution time (in milliseconds). Default: 0 (no timeout)")
+	cmd.Flags().StringVar(&fnMemory, "memory", "", "Memory limit of the function (e.g. 128Mi)")
+	cmd.Flags().StringVar(&fnCPU, "cpu", "", "CPU limit of the function (e.g. 500m)")
+	cmd.Flags().Int64Var(&fnPids, "pids", 0, "Maximum number of processes of the function")
+	cmd.Flags().BoolVar(&fnReadOnly, "read-only", false, "Mount the root filesystem of the function read-only")
+	cmd.Flags().StringSliceVar(&fnDropCapabilities, "drop-capability", []string{}, "Linux capabilities to drop (ALL drops all of them), can be specified multiple times or a comma-delimited string")
+	cmd.Flags().StringVar(&fnSeccompProfile, "seccomp-profile", "", "Path to a JSON seccomp profile for the function, or unconfined")
+	cmd.Flags().BoolVar(&fnNoNetwork, "no-network", false, "Deny network access to the function")
+	cmd.Flags().Int64Var(&fnMaxAttempts, "max-attempts", 0, "Maximum number of attempts of asynchronous runs of the function, up to 10. Default: 0 (no retries)")
+	cmd.Flags().Int64Var(&fnRetryBackoff, "retry-backoff", 1000, "Delay before retrying a failed run (in milliseconds, at least 100), doubled after each attempt")
+	cmd.Flags().Int64Var(&fnRetryMaxBackoff, "retry-max-backoff", 0, "Maximum delay between attempts of a run (in milliseconds). Default: 0 (no maximum)")
+	cmd.Flags().StringSliceVar(&fnRetryOn, "retry-on", []string{}, "Error types which are retried (InputError, FunctionError, SystemError, TimeoutError). Default: all but InputError")
+	cmd.Flags().DurationVar(&fnRunMaxAge, "run-max-age", 0, "Age after which finished runs of the function are deleted (e.g. 168h). Default: 0 (server setting)")
+	cmd.Flags().Int64Var(&fnRunMaxCount, "run-max-count", 0, "Maximum number of finished runs of the function kept. Default: 0 (server setting)")
+	cmd.Flags().Int64Var(&fnPreWarm, "pre-warm", 0, "Number of instances of the function kept running when it is idle. Default: 0 (stopped once idle, if the server has an idle TTL)")
 	cmd.MarkFlagRequired("image")
 	return cmd
 }
@@ -136,6 +165,49 @@ func createFunction(out, errOut io.Writer, cmd *cobra.Command, args []string, c
 		Out: schemaOut,
 	}
 
+	if fnMemory != "" || fnCPU != "" || fnPids != 0 {
+		function.Limits = &v1.FunctionResources{
+			Memory: fnMemory,
+			CPU:    fnCPU,
+			Pids:   fnPids,
+		}
+	}
+	if fnReadOnly || len(fnDropCapabilities) > 0 || fnSeccompProfile != "" || fnNoNetwork {
+		function.Sandbox = &v1.FunctionSandbox{
+			ReadOnlyRootFS:   fnReadOnly,
+			DropCapabilities: fnDropCapabilities,
+			SeccompProfile:   fnSeccompProfile,
+			NoNetwork:        fnNoNetwork,
+		}
+		if fnSeccompProfile != "" && fnSeccompProfile != "unconfined" {
+			fullPath := path.Join(workDir, fnSeccompProfile)
+			profile, err := ioutil.ReadFile(fullPath)
+			if err != nil {
+				return errors.Wrapf(err, "error when reading content of %s", fullPath)
+			}
+			function.Sandbox.SeccompProfile = string(profile)
+		}
+	}
+
+	if fnMaxAttempts > 1 {
+		function.RetryPolicy = &v1.RetryPolicy{
+			MaxAttempts: fnMaxAttempts,
+			Backoff:     fnRetryBackoff,
+			MaxBackoff:  fnRetryMaxBackoff,
+		}
+		for _, t := range fnRetryOn {
+			function.RetryPolicy.RetryOn = append(function.RetryPolicy.RetryOn, v1.ErrorType(t))
+		}
+	}
+
+	if fnRunMaxAge != 0 || fnRunMaxCount != 0 {
+		function.RunRetention = &v1.RunRetention{
+			MaxAge:   int64(fnRunMaxAge / time.Second),
+			MaxCount: fnRunMaxCount,
+		}
+	}
+	function.PreWarm = fnPreWarm
+
 	err = CallCreateFunction(c)(function)
 	if err != nil {
 		return err
@@ -8,8 +8,10 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/go-openapi/swag"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/vmware/dispatch/pkg/client"
 	"golang.org/x/net/context"
@@ -21,17 +23,25 @@ import (
 var (
 	createSubscriptionLong = i18n.T(`Create dispatch event subscription.`)
 
-	// TODO: add examples
-	createSubscriptionExample   = i18n.T(``)
+	createSubscriptionExample = i18n.T(`
+# Run hello for the vm.created events of a vCenter, with at least 4 CPUs
+dispatch create subscription hello --event-type vm.created --filter-source 'vcenter*.corp.local' --filter-data '$.cpus >= 4'
+# Run hello for the events of all the vm.* types
+dispatch create subscription hello --event-type 'vm.*'`)
 	createSubscriptionSecrets   []string
 	createSubscriptionEventType string
 	createSubscriptionName      string
+
+	createSubscriptionFilterSource           string
+	createSubscriptionFilterEventTypeVersion string
+	createSubscriptionFilterExtensions       []string
+	createSubscriptionFilterData             []string
 )
 
 // NewCmdCreateSubscription creates command responsible for subscription creation.
 func NewCmdCreateSubscription(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "subscription FUNCTION_NAME [--name SUBSCRIPTION_NAME] [--event-type EVENT.TYPE] [--secret SECRET1,SECRET2...]",
+		Use:     "subscription FUNCTION_NAME [--name SUBSCRIPTION_NAME] [--event-type EVENT.TYPE] [--secret SECRET1,SECRET2...] [--filter-source SOURCE] [--filter-extension NAME=VALUE] [--filter-data PREDICATE]",
 		Short:   i18n.T("Create subscription"),
 		Long:    createSubscriptionLong,
 		Example: createSubscriptionExample,
@@ -46,7 +56,11 @@ func NewCmdCreateSubscription(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd.Flags().StringArrayVar(&createSubscriptionSecrets, "secret", []string{}, "Function secrets, can be specified multiple times or a comma-delimited string")
 
 	cmd.Flags().StringVar(&createSubscriptionName, "name", "", "Subscription name. If not specified, will be randomly generated.")
-	cmd.Flags().StringVar(&createSubscriptionEventType, "event-type", "", "Event Type to filter on.")
+	cmd.Flags().StringVar(&createSubscriptionEventType, "event-type", "", "Event Type to filter on, * matches any characters.")
+	cmd.Flags().StringVar(&createSubscriptionFilterSource, "filter-source", "", "Only handle the events of this source, * matches any characters")
+	cmd.Flags().StringVar(&createSubscriptionFilterEventTypeVersion, "filter-event-type-version", "", "Only handle the events of this event type version, * matches any characters")
+	cmd.Flags().StringArrayVar(&createSubscriptionFilterExtensions, "filter-extension", []string{}, "Only handle the events with this extension value (NAME=VALUE, * matches any characters), can be specified multiple times")
+	cmd.Flags().StringArrayVar(&createSubscriptionFilterData, "filter-data", []string{}, "Only handle the events whose data matches this JSONPath predicate (e.g. '$.cpus >= 4'), can be specified multiple times")
 
 	return cmd
 }
@@ -72,13 +86,18 @@ func createSubscription(out, errOut io.Writer, cmd *cobra.Command, args []string
 		Function:  &args[0],
 		Secrets:   createSubscriptionSecrets,
 	}
+	filter, err := subscriptionFilter()
+	if err != nil {
+		return err
+	}
+	subscription.Filter = filter
 	if cmdFlagApplication != "" {
 		subscription.Tags = append(subscription.Tags, &v1.Tag{
 			Key:   "Application",
 			Value: cmdFlagApplication,
 		})
 	}
-	err := CallCreateSubscription(c)(subscription)
+	err = CallCreateSubscription(c)(subscription)
 	if err != nil {
 		return err
 	}
@@ -88,3 +107,27 @@ func createSubscription(out, errOut io.Writer, cmd *cobra.Command, args []string
 	fmt.Printf("created subscription: %s\n", *subscription.Name)
 	return nil
 }
+
+// subscriptionFilter returns the filter set with the flags, or nil if none is set
+func subscriptionFilter() (*v1.SubscriptionFilter, error) {
+	if createSubscriptionFilterSource == "" && createSubscriptionFilterEventTypeVersion == "" &&
+		len(createSubscriptionFilterExtensions) == 0 && len(createSubscriptionFilterData) == 0 {
+		return nil, nil
+	}
+	filter := &v1.SubscriptionFilter{
+		Source:           createSubscriptionFilterSource,
+		EventTypeVersion: createSubscriptionFilterEventTypeVersion,
+		Data:             createSubscriptionFilterData,
+	}
+	for _, extension := range createSubscriptionFilterExtensions {
+		kv := strings.SplitN(extension, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid extension filter %s, must be NAME=VALUE", extension)
+		}
+		if filter.Extensions == nil {
+			filter.Extensions = make(map[string]string)
+		}
+		filter.Extensions[kv[0]] = kv[1]
+	}
+	return filter, nil
+}
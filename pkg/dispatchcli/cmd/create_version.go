@@ -0,0 +1,59 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+)
+
+var (
+	createVersionLong = i18n.T(`Publish the current state of a function as a new immutable version. Later updates of the function do not change its published versions.`)
+
+	createVersionExample = i18n.T(`
+# Publish a new version of a function
+dispatch create version hello-py`)
+)
+
+// NewCmdCreateVersion creates command responsible for publishing function versions.
+func NewCmdCreateVersion(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "version FUNCTION_NAME",
+		Short:   i18n.T("Publish a function version"),
+		Long:    createVersionLong,
+		Example: createVersionExample,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			c := functionManagerClient()
+			err := createVersion(out, errOut, cmd, args, c)
+			CheckErr(err)
+		},
+	}
+	return cmd
+}
+
+func createVersion(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.FunctionsClient) error {
+	version, err := c.PublishFunction(context.TODO(), "", args[0])
+	if err != nil {
+		return err
+	}
+	return formatCreateVersionOutput(out, version)
+}
+
+func formatCreateVersionOutput(out io.Writer, version *v1.FunctionVersion) error {
+	if w, err := formatOutput(out, false, version); w {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "Published version %d of function: %s\n", version.Version, version.FunctionName)
+	return err
+}
@@ -69,6 +69,8 @@ func NewCmdDelete(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd.AddCommand(NewCmdDeleteBaseImage(out, errOut))
 	cmd.AddCommand(NewCmdDeleteImage(out, errOut))
 	cmd.AddCommand(NewCmdDeleteFunction(out, errOut))
+	cmd.AddCommand(NewCmdDeleteRuns(out, errOut))
+	cmd.AddCommand(NewCmdDeleteAlias(out, errOut))
 	cmd.AddCommand(NewCmdDeleteSecret(out, errOut))
 	cmd.AddCommand(NewCmdDeleteAPI(out, errOut))
 	cmd.AddCommand(NewCmdDeleteSubscription(out, errOut))
@@ -0,0 +1,55 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+)
+
+var (
+	deleteAliasLong = i18n.T(`Delete an alias of a function. The versions of the function are kept.`)
+
+	deleteAliasExample = i18n.T(`
+# Delete the canary alias
+dispatch delete alias hello-py canary`)
+)
+
+// NewCmdDeleteAlias creates command responsible for deleting function aliases.
+func NewCmdDeleteAlias(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "alias FUNCTION_NAME ALIAS",
+		Short:   i18n.T("Delete function alias"),
+		Long:    deleteAliasLong,
+		Example: deleteAliasExample,
+		Args:    cobra.ExactArgs(2),
+		Aliases: []string{"aliases"},
+		Run: func(cmd *cobra.Command, args []string) {
+			c := functionManagerClient()
+			err := deleteAlias(out, errOut, cmd, args, c)
+			CheckErr(err)
+		},
+	}
+	return cmd
+}
+
+func deleteAlias(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.FunctionsClient) error {
+	function, err := c.DeleteAlias(context.TODO(), "", args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if w, err := formatOutput(out, false, function); w {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Deleted alias %s of function: %s\n", args[1], args[0])
+	return err
+}
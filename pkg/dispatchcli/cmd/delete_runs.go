@@ -0,0 +1,74 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+)
+
+var (
+	deleteRunsLong = i18n.T(`Delete finished function runs created before a given age. Runs which have not finished are kept.`)
+
+	deleteRunsExample = i18n.T(`
+# Delete the runs older than a week
+dispatch delete runs --older-than 168h
+
+# Delete the runs of a specific function older than a day
+dispatch delete runs example-function --older-than 24h`)
+
+	olderThan time.Duration
+)
+
+// NewCmdDeleteRuns creates command responsible for deleting function runs.
+func NewCmdDeleteRuns(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "runs [FUNCTION_NAME] --older-than DURATION",
+		Short:   i18n.T("Delete function runs"),
+		Long:    deleteRunsLong,
+		Example: deleteRunsExample,
+		Args:    cobra.MaximumNArgs(1),
+		Aliases: []string{"run"},
+		Run: func(cmd *cobra.Command, args []string) {
+			c := functionManagerClient()
+			err := deleteRuns(out, errOut, cmd, args, c)
+			CheckErr(err)
+		},
+	}
+	cmd.Flags().DurationVar(&olderThan, "older-than", 0, "REQUIRED: delete runs created longer ago than this duration (e.g. 24h)")
+	return cmd
+}
+
+func deleteRuns(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.FunctionsClient) error {
+	if olderThan <= 0 {
+		return errors.New("error deleting runs: --older-than must be set to a positive duration")
+	}
+	opts := client.FunctionOpts{}
+	if len(args) == 1 {
+		opts.FunctionName = &args[0]
+	}
+	deleted, err := c.DeleteRuns(context.TODO(), "", opts, time.Now().Add(-olderThan))
+	if err != nil {
+		return err
+	}
+	return formatDeleteRunsOutput(out, deleted)
+}
+
+func formatDeleteRunsOutput(out io.Writer, deleted int64) error {
+	if w, err := formatOutput(out, false, deleted); w {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "Deleted %d runs\n", deleted)
+	return err
+}
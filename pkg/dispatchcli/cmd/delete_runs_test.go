@@ -0,0 +1,39 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/client/mocks"
+)
+
+func TestDeleteRuns(t *testing.T) {
+	var buf bytes.Buffer
+	functionName := "hello"
+
+	olderThan = 24 * time.Hour
+	defer func() { olderThan = 0 }()
+
+	c := &mocks.FunctionsClient{}
+	c.On("DeleteRuns", mock.Anything, "", client.FunctionOpts{FunctionName: &functionName}, mock.MatchedBy(func(t time.Time) bool {
+		return time.Since(t) >= 24*time.Hour && time.Since(t) < 25*time.Hour
+	})).Return(int64(1), nil)
+
+	err := deleteRuns(&buf, &buf, nil, []string{functionName}, c)
+	assert.NoError(t, err)
+	assert.Equal(t, "Deleted 1 runs\n", buf.String())
+	c.AssertExpectations(t)
+
+	olderThan = 0
+	assert.Error(t, deleteRuns(&buf, &buf, nil, nil, c))
+}
@@ -28,12 +28,14 @@ var (
 	execAllOutput = false
 	execInput     = "{}"
 	execSecrets   = []string{}
+	execAlias     = ""
+	execVersion   = int64(0)
 )
 
 // NewCmdExec creates a command to execute a dispatch function.
 func NewCmdExec(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "exec [--wait] [--input JSON] [--secret SECRET_1,SECRET_2...] FUNCTION_NAME",
+		Use:     "exec [--wait] [--input JSON] [--secret SECRET_1,SECRET_2...] [--alias ALIAS | --version VERSION] FUNCTION_NAME",
 		Short:   i18n.T("Execute a dispatch function"),
 		Long:    execLong,
 		Example: execExample,
@@ -50,6 +52,8 @@ func NewCmdExec(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd.Flags().StringVar(&execInput, "input", "{}", "Function input JSON object")
 	cmd.Flags().StringArrayVar(&execSecrets, "secret", []string{}, "Function secrets, can be specified multiple times or a comma-delimited string")
 	cmd.Flags().BoolVar(&execAllOutput, "all", false, "Also print metadata along with json output, ONLY with --json")
+	cmd.Flags().StringVar(&execAlias, "alias", "", "Run one of the versions of a function alias, picked by weight")
+	cmd.Flags().Int64Var(&execVersion, "version", 0, "Run a published version of the function")
 	return cmd
 }
 
@@ -72,6 +76,8 @@ func runExec(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.
 		Input:        input,
 		Secrets:      execSecrets,
 		FunctionName: functionName,
+		Alias:        execAlias,
+		Version:      execVersion,
 	}
 
 	functionResult, err := c.RunFunction(context.TODO(), "", run)
@@ -49,15 +49,21 @@ func NewCmdGetAPI(out io.Writer, errOut io.Writer) *cobra.Command {
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
 	cmd.Flags().StringVarP(&functionName, "func", "f", "", "get all apis for specified function")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
 func getAPIs(out, errOut io.Writer, cmd *cobra.Command, c client.APIsClient) error {
-	get, err := c.ListAPIs(context.TODO(), "")
+	get, next, err := c.ListAPIs(context.TODO(), "", listOpts())
 	if err != nil {
 		return err
 	}
-	return formatAPIOutput(out, true, get)
+	if err := formatAPIOutput(out, true, get); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func getAPI(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.APIsClient) error {
@@ -44,6 +44,8 @@ func NewCmdGetApplication(out io.Writer, errOut io.Writer) *cobra.Command {
 			CheckErr(err)
 		},
 	}
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -67,12 +69,26 @@ func getApplications(out, errOut io.Writer, cmd *cobra.Command) error {
 	params := &application.GetAppsParams{
 		Context:      context.Background(),
 		XDispatchOrg: getOrgFromConfig(),
+		Tags:         cmdFlagTags,
+	}
+	if cmdFlagLimit != 0 {
+		params.Limit = &cmdFlagLimit
+	}
+	if cmdFlagOrderBy != "" {
+		params.OrderBy = &cmdFlagOrderBy
+	}
+	if cmdFlagPage != "" {
+		params.Page = &cmdFlagPage
 	}
 	resp, err := client.Application.GetApps(params, GetAuthInfoWriter())
 	if err != nil {
 		return err
 	}
-	return formatApplicationOutput(out, true, resp.Payload)
+	if err := formatApplicationOutput(out, true, resp.Payload); err != nil {
+		return err
+	}
+	printNextPage(errOut, resp.XDispatchNextPage)
+	return nil
 }
 
 func formatApplicationOutput(out io.Writer, list bool, applications []*v1.Application) error {
@@ -46,6 +46,8 @@ func NewCmdGetBaseImage(out io.Writer, errOut io.Writer) *cobra.Command {
 			CheckErr(err)
 		},
 	}
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -59,11 +61,15 @@ func getBaseImage(out, errOut io.Writer, cmd *cobra.Command, args []string, c cl
 }
 
 func getBaseImages(out, errOut io.Writer, cmd *cobra.Command, c client.ImagesClient) error {
-	resp, err := c.ListBaseImages(context.TODO(), dispatchConfig.Organization)
+	resp, next, err := c.ListBaseImages(context.TODO(), dispatchConfig.Organization, listOpts())
 	if err != nil {
 		return err
 	}
-	return formatBaseImageOutput(out, true, resp)
+	if err := formatBaseImageOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatBaseImageOutput(out io.Writer, list bool, images []v1.BaseImage) error {
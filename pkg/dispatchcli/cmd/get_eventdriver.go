@@ -47,16 +47,22 @@ func NewCmdGetEventDriver(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
 func getEventDrivers(out, errOut io.Writer, cmd *cobra.Command, c client.EventsClient) error {
 
-	get, err := c.ListEventDrivers(context.TODO(), "")
+	get, next, err := c.ListEventDrivers(context.TODO(), "", listOpts())
 	if err != nil {
 		return err
 	}
-	return formatEventDriverOutput(out, true, get)
+	if err := formatEventDriverOutput(out, true, get); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func getEventDriver(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.EventsClient) error {
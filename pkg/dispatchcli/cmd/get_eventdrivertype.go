@@ -46,18 +46,24 @@ func NewCmdGetEventDriverType(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
 func getEventDriverTypes(out, errOut io.Writer, cmd *cobra.Command, c client.EventsClient) error {
 
-	get, err := c.ListEventDriverTypes(context.TODO(), "")
+	get, next, err := c.ListEventDriverTypes(context.TODO(), "", listOpts())
 	if err != nil {
 		return err
 	}
 	filtered := get
 
-	return formatEventDriverTypeOutput(out, true, filtered)
+	if err := formatEventDriverTypeOutput(out, true, filtered); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func getEventDriverType(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.EventsClient) error {
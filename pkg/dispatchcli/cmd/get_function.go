@@ -46,6 +46,8 @@ func NewCmdGetFunction(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -61,11 +63,15 @@ func getFunction(out, errOut io.Writer, cmd *cobra.Command, args []string, c cli
 }
 
 func getFunctions(out, errOut io.Writer, cmd *cobra.Command, c client.FunctionsClient) error {
-	resp, err := c.ListFunctions(context.TODO(), dispatchConfig.Organization)
+	resp, next, err := c.ListFunctions(context.TODO(), dispatchConfig.Organization, listOpts())
 	if err != nil {
 		return err
 	}
-	return formatFunctionOutput(out, true, resp)
+	if err := formatFunctionOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatFunctionOutput(out io.Writer, list bool, functions []v1.Function) error {
@@ -46,6 +46,8 @@ func NewCmdGetImage(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -60,11 +62,15 @@ func getImage(out, errOut io.Writer, cmd *cobra.Command, args []string, c client
 }
 
 func getImages(out, errOut io.Writer, cmd *cobra.Command, c client.ImagesClient) error {
-	resp, err := c.ListImages(context.TODO(), dispatchConfig.Organization)
+	resp, next, err := c.ListImages(context.TODO(), dispatchConfig.Organization, listOpts())
 	if err != nil {
 		return err
 	}
-	return formatImageOutput(out, true, resp)
+	if err := formatImageOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatImageOutput(out io.Writer, list bool, images []v1.Image) error {
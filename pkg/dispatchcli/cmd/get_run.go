@@ -6,13 +6,18 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/go-openapi/swag"
 	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
 
@@ -40,6 +45,9 @@ dispatch get runs example-function f98d0a7f-0c1d-4020-a488-cabc501b08e0
 
 # Follow runs for a specific function
 dispatch get runs example-function --follow
+
+# Follow the logs of a specific run until it finishes
+dispatch get run example-function f98d0a7f-0c1d-4020-a488-cabc501b08e0 --follow
 `)
 
 	followRuns = false
@@ -66,24 +74,26 @@ func NewCmdGetRun(out io.Writer, errOut io.Writer) *cobra.Command {
 				err = getFunctionRun(out, errOut, cmd, opts, c)
 			} else if len(args) == 1 {
 				opts := client.FunctionOpts{
+					ListOpts:     listOpts(),
 					FunctionName: &args[0],
 				}
 				err = getRuns(out, errOut, cmd, opts, c)
 			} else {
-				opts := client.FunctionOpts{}
+				opts := client.FunctionOpts{ListOpts: listOpts()}
 				err = getRuns(out, errOut, cmd, opts, c)
 			}
 			CheckErr(err)
 		},
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
-	cmd.Flags().BoolVarP(&followRuns, "follow", "f", false, "follow function runs, default: false")
+	cmd.Flags().BoolVarP(&followRuns, "follow", "f", false, "follow function runs, or the logs of a function run, default: false")
 	cmd.Flags().BoolVar(&last, "last", false, "get last executed run, default: false")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
 func getFunctionRun(out, errOut io.Writer, cmd *cobra.Command, opts client.FunctionOpts, c client.FunctionsClient) error {
-	since := time.Now()
 	resp, err := c.GetFunctionRun(context.TODO(), "", opts)
 
 	if err != nil {
@@ -93,17 +103,50 @@ func getFunctionRun(out, errOut io.Writer, cmd *cobra.Command, opts client.Funct
 		return err
 	}
 	if followRuns {
-		opts.Since = since
-		if err = followFilteredRuns(out, c, opts); err != nil {
+		if err = c.GetRunLogs(context.TODO(), "", opts, true, &runLogsWriter{out: out, errOut: errOut}); err != nil {
 			return err
 		}
+		if resp, err = c.GetFunctionRun(context.TODO(), "", opts); err != nil {
+			return err
+		}
+		return formatRunOutput(out, false, false, []v1.Run{*resp})
 	}
 	return nil
 }
 
+// runLogsWriter writes the log lines of a run sent by the server as they are received, the lines written by the
+// function to stderr are written to errOut
+type runLogsWriter struct {
+	out    io.Writer
+	errOut io.Writer
+	buf    []byte
+}
+
+func (w *runLogsWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		var line v1.LogLine
+		if err := json.Unmarshal(w.buf[:i], &line); err != nil {
+			return 0, errors.Wrap(err, "error decoding log line")
+		}
+		w.buf = w.buf[i+1:]
+		dest := w.out
+		if swag.StringValue(line.Stream) == v1.LogLineStreamStderr {
+			dest = w.errOut
+		}
+		if _, err := fmt.Fprintln(dest, swag.StringValue(line.Line)); err != nil {
+			return 0, err
+		}
+	}
+}
+
 func getRuns(out, errOut io.Writer, cmd *cobra.Command, opts client.FunctionOpts, c client.FunctionsClient) error {
 	since := time.Now()
-	resp, err := c.ListRuns(context.TODO(), "", opts)
+	resp, next, err := c.ListRuns(context.TODO(), "", opts)
 
 	if err != nil {
 		return err
@@ -120,6 +163,7 @@ func getRuns(out, errOut io.Writer, cmd *cobra.Command, opts client.FunctionOpts
 	if err = formatRunOutput(out, true, true, resp); err != nil {
 		return err
 	}
+	printNextPage(errOut, next)
 	if followRuns {
 		opts.Since = since
 		if err = followFilteredRuns(out, c, opts); err != nil {
@@ -153,7 +197,7 @@ func followFilteredRuns(out io.Writer, c client.FunctionsClient, opts client.Fun
 			before = t
 
 			if opts.FunctionName == nil || opts.RunName == nil {
-				resp, err = c.ListRuns(context.TODO(), "", opts)
+				resp, _, err = c.ListRuns(context.TODO(), "", opts)
 			} else {
 				var run *v1.Run
 				run, err = c.GetFunctionRun(context.TODO(), "", opts)
@@ -0,0 +1,48 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/client/mocks"
+)
+
+func TestGetRunFollowLogs(t *testing.T) {
+	var out, errOut bytes.Buffer
+	runName := "f98d0a7f-0c1d-4020-a488-cabc501b08e0"
+	functionName := "hello"
+	opts := client.FunctionOpts{FunctionName: &functionName, RunName: &runName}
+
+	c := &mocks.FunctionsClient{}
+	c.On("GetFunctionRun", mock.Anything, "", opts).Return(
+		&v1.Run{Name: strfmt.UUID(runName), FunctionName: functionName, Status: v1.StatusCREATING}, nil).Once()
+	c.On("GetFunctionRun", mock.Anything, "", opts).Return(
+		&v1.Run{Name: strfmt.UUID(runName), FunctionName: functionName, Status: v1.StatusREADY}, nil).Once()
+	c.On("GetRunLogs", mock.Anything, "", opts, true, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		w := args.Get(4).(io.Writer)
+		// lines may be split across writes
+		w.Write([]byte(`{"line":"hello","stream":"stdout"}` + "\n" + `{"line":"oops",`))
+		w.Write([]byte(`"stream":"stderr"}` + "\n"))
+	})
+
+	followRuns = true
+	defer func() { followRuns = false }()
+	err := getFunctionRun(&out, &errOut, nil, opts, c)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "hello\n")
+	assert.Contains(t, out.String(), string(v1.StatusREADY))
+	assert.Equal(t, "oops\n", errOut.String())
+	c.AssertExpectations(t)
+}
@@ -48,6 +48,8 @@ func NewCmdGetSecret(out io.Writer, errOut io.Writer) *cobra.Command {
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
 	cmd.Flags().BoolVarP(&getSecretContent, "all", "", false, "also get secret content (in json format)")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -63,11 +65,15 @@ func getSecret(out, errOut io.Writer, cmd *cobra.Command, args []string, c clien
 }
 
 func getSecrets(out, errOut io.Writer, cmd *cobra.Command, c client.SecretsClient) error {
-	resp, err := c.ListSecrets(context.TODO(), dispatchConfig.Organization)
+	resp, next, err := c.ListSecrets(context.TODO(), dispatchConfig.Organization, listOpts())
 	if err != nil {
 		return err
 	}
-	return formatSecretOutput(out, true, resp)
+	if err := formatSecretOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatSecretOutput(out io.Writer, list bool, secrets []v1.Secret) error {
@@ -45,6 +45,8 @@ func NewCmdGetServiceClass(out io.Writer, errOut io.Writer) *cobra.Command {
 			CheckErr(err)
 		},
 	}
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -59,11 +61,15 @@ func getServiceClass(out, errOut io.Writer, cmd *cobra.Command, args []string, c
 }
 
 func getServiceClasses(out, errOut io.Writer, cmd *cobra.Command, c client.ServicesClient) error {
-	resp, err := c.ListServiceClasses(context.TODO(), dispatchConfig.Organization)
+	resp, next, err := c.ListServiceClasses(context.TODO(), dispatchConfig.Organization, listOpts())
 	if err != nil {
 		return err
 	}
-	return formatServiceClassOutput(out, true, resp)
+	if err := formatServiceClassOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatServiceClassOutput(out io.Writer, list bool, serviceClasses []v1.ServiceClass) error {
@@ -44,6 +44,8 @@ func NewCmdGetServiceInstance(out io.Writer, errOut io.Writer) *cobra.Command {
 			CheckErr(err)
 		},
 	}
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -58,11 +60,15 @@ func getServiceInstance(out, errOut io.Writer, cmd *cobra.Command, args []string
 }
 
 func getServiceInstances(out, errOut io.Writer, cmd *cobra.Command, c client.ServicesClient) error {
-	resp, err := c.ListServiceInstances(context.TODO(), dispatchConfig.Organization)
+	resp, next, err := c.ListServiceInstances(context.TODO(), dispatchConfig.Organization, listOpts())
 	if err != nil {
 		return err
 	}
-	return formatServiceInstanceOutput(out, true, resp)
+	if err := formatServiceInstanceOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatServiceInstanceOutput(out io.Writer, list bool, serviceInstances []v1.ServiceInstance) error {
@@ -46,6 +46,8 @@ func NewCmdGetSubscription(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&cmdFlagApplication, "application", "a", "", "filter by application")
+	addListFlags(cmd)
+	addTagFlags(cmd)
 	return cmd
 }
 
@@ -59,11 +61,15 @@ func getSubscription(out, errOut io.Writer, cmd *cobra.Command, args []string, c
 }
 
 func getSubscriptions(out, errOut io.Writer, cmd *cobra.Command, c client.EventsClient) error {
-	resp, err := c.ListSubscriptions(context.TODO(), "")
+	resp, next, err := c.ListSubscriptions(context.TODO(), "", listOpts())
 	if err != nil {
 		return err
 	}
-	return formatSubscriptionOutput(out, true, resp)
+	if err := formatSubscriptionOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatSubscriptionOutput(out io.Writer, list bool, subscriptions []v1.Subscription) error {
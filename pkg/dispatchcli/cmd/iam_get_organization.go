@@ -44,6 +44,7 @@ func NewCmdIamGetOrganization(out, errOut io.Writer) *cobra.Command {
 			CheckErr(err)
 		},
 	}
+	addListFlags(cmd)
 	return cmd
 }
 
@@ -57,11 +58,15 @@ func getOrganization(out, errOut io.Writer, cmd *cobra.Command, args []string, c
 }
 
 func getOrganizations(out, errOut io.Writer, cmd *cobra.Command, c client.IdentityClient) error {
-	resp, err := c.ListOrganizations(context.TODO(), "")
+	resp, next, err := c.ListOrganizations(context.TODO(), "", listOpts())
 	if err != nil {
 		return err
 	}
-	return formatOrganizationOutput(out, true, resp)
+	if err := formatOrganizationOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatOrganizationOutput(out io.Writer, list bool, organizations []v1.Organization) error {
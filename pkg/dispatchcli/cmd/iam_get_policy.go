@@ -50,6 +50,7 @@ func NewCmdIamGetPolicy(out, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd.Flags().BoolVarP(&printRuleContent, "wide", "w", false, "print rule context")
+	addListFlags(cmd)
 	return cmd
 }
 
@@ -63,11 +64,15 @@ func getPolicy(out, errOut io.Writer, cmd *cobra.Command, args []string, c clien
 }
 
 func getPolicies(out, errOut io.Writer, cmd *cobra.Command, c client.IdentityClient) error {
-	resp, err := c.ListPolicies(context.TODO(), "")
+	resp, next, err := c.ListPolicies(context.TODO(), "", listOpts())
 	if err != nil {
 		return err
 	}
-	return formatPolicyOutput(out, true, resp)
+	if err := formatPolicyOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatPolicyOutput(out io.Writer, list bool, policies []v1.Policy) error {
@@ -44,6 +44,7 @@ func NewCmdIamGetServiceAccount(out, errOut io.Writer) *cobra.Command {
 			CheckErr(err)
 		},
 	}
+	addListFlags(cmd)
 	return cmd
 }
 
@@ -57,11 +58,15 @@ func getServiceAccount(out, errOut io.Writer, cmd *cobra.Command, args []string,
 }
 
 func getServiceAccounts(out, errOut io.Writer, cmd *cobra.Command, c client.IdentityClient) error {
-	resp, err := c.ListServiceAccounts(context.TODO(), "")
+	resp, next, err := c.ListServiceAccounts(context.TODO(), "", listOpts())
 	if err != nil {
 		return err
 	}
-	return formatServiceAccountOutput(out, true, resp)
+	if err := formatServiceAccountOutput(out, true, resp); err != nil {
+		return err
+	}
+	printNextPage(errOut, next)
+	return nil
 }
 
 func formatServiceAccountOutput(out io.Writer, list bool, serviceAccounts []v1.ServiceAccount) error {
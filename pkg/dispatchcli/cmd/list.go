@@ -0,0 +1,52 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vmware/dispatch/pkg/client"
+)
+
+var (
+	cmdFlagLimit   int64
+	cmdFlagOrderBy string
+	cmdFlagPage    string
+	cmdFlagTags    []string
+)
+
+// addListFlags adds the flags used to sort and page through a list of resources
+func addListFlags(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&cmdFlagLimit, "limit", 0, "maximum number of items to list, all of them if 0")
+	cmd.Flags().StringVar(&cmdFlagOrderBy, "order-by", "", "sort items by field, e.g. name or createdTime, prefix with '-' for a descending order")
+	cmd.Flags().StringVar(&cmdFlagPage, "page", "", "token of the page to list, as printed when listing the previous page")
+}
+
+// addTagFlags adds the flag used to filter a list of resources by tags
+func addTagFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&cmdFlagTags, "tag", []string{}, "filter by tag, all of them must match: "+
+		"key=value, key!=value, key=v1|v2 (any of the values), key^=prefix, key*=text, key>n, key<=n, key (set) or !key (not set)")
+}
+
+func listOpts() client.ListOpts {
+	return client.ListOpts{
+		Limit:   cmdFlagLimit,
+		OrderBy: cmdFlagOrderBy,
+		Page:    cmdFlagPage,
+		Tags:    cmdFlagTags,
+	}
+}
+
+// printNextPage prints how to list the next page, if any. It goes to errOut, which keeps json and yaml outputs valid.
+func printNextPage(errOut io.Writer, nextPage string) {
+	if nextPage == "" {
+		return
+	}
+	fmt.Fprintf(errOut, "More items available, list them with: --page %s\n", nextPage)
+}
@@ -0,0 +1,103 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+)
+
+var (
+	promoteLong = i18n.T(`Point an alias of a function at one or more published versions. The runs of the alias are split
+between its versions according to their weights, versions without a weight share the runs equally. The alias is
+created if it does not exist, the versions it pointed at before can be restored with "dispatch rollback".`)
+
+	promoteExample = i18n.T(`
+# Send all the runs of the prod alias to version 2
+dispatch promote hello-py prod 2
+
+# Send 90% of the runs of the canary alias to version 2 and 10% to version 3
+dispatch promote hello-py canary 2=90 3=10`)
+)
+
+// NewCmdPromote creates command responsible for pointing function aliases at versions.
+func NewCmdPromote(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "promote FUNCTION_NAME ALIAS VERSION[=WEIGHT]...",
+		Short:   i18n.T("Point a function alias at versions"),
+		Long:    promoteLong,
+		Example: promoteExample,
+		Args:    cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			c := functionManagerClient()
+			err := promote(out, errOut, cmd, args, c)
+			CheckErr(err)
+		},
+	}
+	return cmd
+}
+
+// parseVersionWeight parses a VERSION[=WEIGHT] argument
+func parseVersionWeight(arg string) (*v1.VersionWeight, error) {
+	parts := strings.SplitN(arg, "=", 2)
+	version, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || version < 1 {
+		return nil, errors.Errorf("invalid version %s, expected a positive number", parts[0])
+	}
+	route := &v1.VersionWeight{Version: swag.Int64(version)}
+	if len(parts) == 2 {
+		route.Weight, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || route.Weight < 0 {
+			return nil, errors.Errorf("invalid weight %s of version %d, expected a non-negative number", parts[1], version)
+		}
+	}
+	return route, nil
+}
+
+func promote(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.FunctionsClient) error {
+	alias := &v1.FunctionAlias{Name: args[1]}
+	for _, arg := range args[2:] {
+		route, err := parseVersionWeight(arg)
+		if err != nil {
+			return err
+		}
+		alias.Routes = append(alias.Routes, route)
+	}
+	function, err := c.SetAlias(context.TODO(), "", args[0], alias)
+	if err != nil {
+		return err
+	}
+	return formatAliasOutput(out, function, alias.Name, "Promoted")
+}
+
+// formatAliasOutput prints the versions an alias of a function points at, after an action on the alias
+func formatAliasOutput(out io.Writer, function *v1.Function, aliasName, action string) error {
+	if w, err := formatOutput(out, false, function); w {
+		return err
+	}
+	var routes []string
+	for _, a := range function.Aliases {
+		if a.Name != aliasName {
+			continue
+		}
+		for _, r := range a.Routes {
+			routes = append(routes, fmt.Sprintf("%d=%d", *r.Version, r.Weight))
+		}
+	}
+	_, err := fmt.Fprintf(out, "%s alias %s of function %s: %s\n", action, aliasName, *function.Name, strings.Join(routes, " "))
+	return err
+}
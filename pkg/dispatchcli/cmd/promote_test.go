@@ -0,0 +1,59 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/client/mocks"
+)
+
+func TestPromote(t *testing.T) {
+	var buf bytes.Buffer
+	routes := []*v1.VersionWeight{
+		{Version: swag.Int64(2), Weight: 90},
+		{Version: swag.Int64(3), Weight: 10},
+	}
+	function := &v1.Function{
+		Name:    swag.String("hello"),
+		Aliases: []*v1.FunctionAlias{{Name: "canary", Routes: routes}},
+	}
+
+	c := &mocks.FunctionsClient{}
+	c.On("SetAlias", mock.Anything, "", "hello", &v1.FunctionAlias{Name: "canary", Routes: routes}).Return(function, nil)
+
+	err := promote(&buf, &buf, nil, []string{"hello", "canary", "2=90", "3=10"}, c)
+	assert.NoError(t, err)
+	assert.Equal(t, "Promoted alias canary of function hello: 2=90 3=10\n", buf.String())
+	c.AssertExpectations(t)
+
+	for _, arg := range []string{"0", "two", "2=-1"} {
+		err = promote(&buf, &buf, nil, []string{"hello", "canary", arg}, c)
+		assert.Error(t, err, arg)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	var buf bytes.Buffer
+	function := &v1.Function{
+		Name:    swag.String("hello"),
+		Aliases: []*v1.FunctionAlias{{Name: "prod", Routes: []*v1.VersionWeight{{Version: swag.Int64(1)}}}},
+	}
+
+	c := &mocks.FunctionsClient{}
+	c.On("RollbackAlias", mock.Anything, "", "hello", "prod").Return(function, nil)
+
+	err := rollback(&buf, &buf, nil, []string{"hello", "prod"}, c)
+	assert.NoError(t, err)
+	assert.Equal(t, "Rolled back alias prod of function hello: 1=0\n", buf.String())
+	c.AssertExpectations(t)
+}
@@ -0,0 +1,49 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/dispatch/pkg/client"
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+)
+
+var (
+	rollbackLong = i18n.T(`Point an alias of a function back at the versions it pointed at before it was last promoted.`)
+
+	rollbackExample = i18n.T(`
+# Roll back the prod alias
+dispatch rollback hello-py prod`)
+)
+
+// NewCmdRollback creates command responsible for rolling back function aliases.
+func NewCmdRollback(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rollback FUNCTION_NAME ALIAS",
+		Short:   i18n.T("Roll back a function alias"),
+		Long:    rollbackLong,
+		Example: rollbackExample,
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			c := functionManagerClient()
+			err := rollback(out, errOut, cmd, args, c)
+			CheckErr(err)
+		},
+	}
+	return cmd
+}
+
+func rollback(out, errOut io.Writer, cmd *cobra.Command, args []string, c client.FunctionsClient) error {
+	function, err := c.RollbackAlias(context.TODO(), "", args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return formatAliasOutput(out, function, args[1], "Rolled back")
+}
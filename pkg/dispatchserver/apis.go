@@ -75,8 +75,8 @@ func initAPIs(config *serverConfig, store entitystore.EntityStore, gw gateway.Ga
 	api := operations.NewAPIManagerAPI(swaggerSpec)
 
 	apiController := apimanager.NewController(&apimanager.ControllerConfig{
-		ResyncPeriod:      config.ResyncPeriod,
-		ZookeeperLocation: config.ZookeeperLocation,
+		ResyncPeriod: config.ResyncPeriod,
+		Coordination: coordinationConfig(config),
 	}, store, gw)
 	apiController.Start()
 
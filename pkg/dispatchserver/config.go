@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/spf13/pflag"
+
+	"github.com/vmware/dispatch/pkg/coordination"
 )
 
 // emptyRegistryAuth == echo -n '{"username":"","password":"","email":""}' | base64
@@ -40,10 +42,17 @@ type serverConfig struct {
 	TLSCertificate    string `mapstructure:"tls-certificate" json:"tls-certificate"`
 	TLSCertificateKey string `mapstrucutre:"tls-certificate-key" json:"tls-certificate-key"`
 
+	// MetricsAddress is the host:port of the metrics listener, disabled if empty
+	MetricsAddress string `mapstructure:"metrics-address" json:"metrics-address"`
+
 	Tracer            string `mapstructure:"tracer" json:"tracer"`
 	Debug             bool   `mapstructure:"debug" json:"debug"`
 	ZookeeperLocation string `mapstructure:"zookeeper-location" json:"zookeeper-location"`
 
+	// Coordination backend of the service controllers
+	Coordination  string   `mapstructure:"coordination" json:"coordination"`
+	EtcdEndpoints []string `mapstructure:"etcd-endpoints" json:"etcd-endpoints"`
+
 	// Local server config options
 	Local localConfig `mapstructure:"local" json:"local"`
 
@@ -66,6 +75,12 @@ type serverConfig struct {
 
 	// Service Manager config options
 	Services servicesConfig `mapstructure:"services" json:"services"`
+
+	// Entity store migration options
+	Migrate migrateConfig `mapstructure:"migrate" json:"migrate"`
+
+	// Entity store export options
+	Store storeConfig `mapstructure:"store" json:"store"`
 }
 
 var defaultConfig = &serverConfig{}
@@ -74,7 +89,7 @@ func configGlobalFlags(flags *pflag.FlagSet) {
 	flags.StringVar(&dispatchConfigPath, "config", "", "config file to use")
 
 	flags.String("db-file", "./dispatch.db", "Database address, or database file path")
-	flags.String("db-backend", "boltdb", "Database type to use")
+	flags.String("db-backend", "boltdb", "Database type to use: boltdb, postgres or sqlite")
 	flags.String("db-database", "dispatch", "Database bucket or schema")
 	flags.String("db-username", "dispatch", "Database username")
 	flags.String("db-password", "dispatch", "Database password")
@@ -96,8 +111,20 @@ func configGlobalFlags(flags *pflag.FlagSet) {
 	flags.String("tls-certificate", "", "Path to the certificate file")
 	flags.String("tls-certificate-key", "", "Path to the certificate private key")
 	flags.Bool("enable-tls", false, "Enable TLS (HTTPS) listener.")
+	flags.String("metrics-address", "", "host:port of the listener serving the controller metrics at /debug/vars, e.g. 127.0.0.1:9090. Disabled if empty")
 
 	flags.String("tracer", "", "OpenTracing-compatible Tracer URL")
 	flags.String("zookeeper-location", "", "URL pointing to the location of a zookeeper service")
+	flags.String("coordination", "", "Coordination backend of the service controllers: zookeeper, etcd or local. Defaults to local for the local server, zookeeper otherwise")
+	flags.StringSlice("etcd-endpoints", []string{"http://127.0.0.1:2379"}, "Client URLs of the etcd cluster, for the etcd coordination backend")
 	flags.Bool("debug", false, "Enable debugging logs")
 }
+
+// coordinationConfig returns the coordination backend configuration of the service controllers
+func coordinationConfig(config *serverConfig) coordination.Config {
+	return coordination.Config{
+		Backend:           config.Coordination,
+		ZookeeperLocation: config.ZookeeperLocation,
+		EtcdEndpoints:     config.EtcdEndpoints,
+	}
+}
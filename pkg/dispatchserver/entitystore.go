@@ -6,12 +6,29 @@
 package dispatchserver
 
 import (
+	"context"
+
 	log "github.com/sirupsen/logrus"
 
 	entitystore "github.com/vmware/dispatch/pkg/entity-store"
 )
 
+// entityStore opens the entity store, and warns if it needs to be migrated
 func entityStore(config *serverConfig) entitystore.EntityStore {
+	store := openEntityStore(config)
+	if m, ok := store.(entitystore.Migrator); ok {
+		version, err := m.MigrationVersion(context.Background())
+		if err != nil {
+			log.Warnf("Unable to get the version of the entity store: %s", err)
+		} else if version < entitystore.LatestVersion() {
+			log.Warnf("The entity store is at version %d, the latest one is %d: run dispatch-server migrate",
+				version, entitystore.LatestVersion())
+		}
+	}
+	return store
+}
+
+func openEntityStore(config *serverConfig) entitystore.EntityStore {
 	store, err := entitystore.NewFromBackend(
 		entitystore.BackendConfig{
 			Backend:  config.DatabaseBackend,
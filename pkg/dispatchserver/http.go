@@ -8,6 +8,8 @@ package dispatchserver
 import "github.com/vmware/dispatch/pkg/http"
 
 func httpServer(config *serverConfig) *http.Server {
+	serveMetrics(config)
+
 	server := http.NewServer(nil)
 	server.Host = config.Host
 	server.Port = config.Port
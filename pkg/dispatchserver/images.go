@@ -65,8 +65,8 @@ func initImages(config *serverConfig, store entitystore.EntityStore) (http.Handl
 	api := operations.NewImageManagerAPI(swaggerSpec)
 
 	c := &imagemanager.ControllerConfig{
-		ResyncPeriod:      config.ResyncPeriod,
-		ZookeeperLocation: config.ZookeeperLocation,
+		ResyncPeriod: config.ResyncPeriod,
+		Coordination: coordinationConfig(config),
 	}
 
 	registryAuth := config.RegistryAuth
@@ -6,24 +6,48 @@
 package dispatchserver
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"time"
 
+	dockerclient "github.com/docker/docker/client"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/vmware/dispatch/pkg/api-manager/gateway/local"
+	"github.com/vmware/dispatch/pkg/coordination"
 	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+	entitystore "github.com/vmware/dispatch/pkg/entity-store"
 	"github.com/vmware/dispatch/pkg/events/transport"
+	"github.com/vmware/dispatch/pkg/function-manager"
+	fnapi "github.com/vmware/dispatch/pkg/functions"
 	dockerfaas "github.com/vmware/dispatch/pkg/functions/docker"
+	"github.com/vmware/dispatch/pkg/functions/process"
 	"github.com/vmware/dispatch/pkg/http"
 	"github.com/vmware/dispatch/pkg/secret-store/service"
 )
 
 type localConfig struct {
-	DockerHost     string `mapstructure:"docker-host" json:"docker-host,omitempty"`
-	GatewayPort    int    `mapstructure:"gateway-port" json:"gateway-port,omitempty"`
-	GatewayTLSPort int    `mapstructure:"gateway-tls-port" json:"gateway-tls-port,omitempty"`
+	FaaS                 string        `mapstructure:"faas" json:"faas,omitempty"`
+	FunctionTemplates    string        `mapstructure:"function-templates" json:"function-templates,omitempty"`
+	FunctionsDir         string        `mapstructure:"functions-dir" json:"functions-dir,omitempty"`
+	DockerHost           string        `mapstructure:"docker-host" json:"docker-host,omitempty"`
+	GatewayPort          int           `mapstructure:"gateway-port" json:"gateway-port,omitempty"`
+	GatewayTLSPort       int           `mapstructure:"gateway-tls-port" json:"gateway-tls-port,omitempty"`
+	MinReplicas          int           `mapstructure:"min-replicas" json:"min-replicas,omitempty"`
+	MaxReplicas          int           `mapstructure:"max-replicas" json:"max-replicas,omitempty"`
+	ContainerConcurrency int           `mapstructure:"container-concurrency" json:"container-concurrency,omitempty"`
+	ScaleDownCooldown    time.Duration `mapstructure:"scale-down-cooldown" json:"scale-down-cooldown,omitempty"`
+	DeadLetterTopic      string        `mapstructure:"dead-letter-topic" json:"dead-letter-topic,omitempty"`
+	RunMaxAge            time.Duration `mapstructure:"run-max-age" json:"run-max-age,omitempty"`
+	RunMaxCount          int           `mapstructure:"run-max-count" json:"run-max-count,omitempty"`
+	IdleTTL              time.Duration `mapstructure:"idle-ttl" json:"idle-ttl,omitempty"`
+	EventBufferSize      int           `mapstructure:"event-buffer-size" json:"event-buffer-size,omitempty"`
+	EventOverflow        string        `mapstructure:"event-overflow" json:"event-overflow,omitempty"`
+	EventDrainTimeout    time.Duration `mapstructure:"event-drain-timeout" json:"event-drain-timeout,omitempty"`
 }
 
 // NewCmdLocal creates a subcommand to run Dispatch Local server
@@ -39,22 +63,44 @@ func NewCmdLocal(out io.Writer, config *serverConfig) *cobra.Command {
 	}
 	cmd.SetOutput(out)
 
+	cmd.Flags().String("faas", "docker", "FaaS backend to use (docker|process)")
+	cmd.Flags().String("function-templates", "", "Directory of the function templates used as images by the process FaaS")
+	cmd.Flags().String("functions-dir", filepath.Join(os.TempDir(), "dispatch-functions"), "Directory the process FaaS lays out functions in")
 	cmd.Flags().String("docker-host", "127.0.0.1", "Docker host/IP. It must be reachable from Dispatch Server.")
 	cmd.Flags().Int("gateway-port", 8081, "Port for local API Gateway")
 	cmd.Flags().Int("gateway-tls-port", 8444, "TLS port for local API Gateway (only when TLS Enabled in global flags)")
+	cmd.Flags().Int("min-replicas", 1, "Number of containers started for each function")
+	cmd.Flags().Int("max-replicas", 4, "Maximum number of containers of each function")
+	cmd.Flags().Int("container-concurrency", 10, "Maximum number of concurrent requests sent to a function container (0 means no limit)")
+	cmd.Flags().Duration("scale-down-cooldown", 5*time.Minute, "How long a function container above min-replicas stays idle before it is removed")
+	cmd.Flags().String("dead-letter-topic", "", "Topic failed asynchronous runs are published to (disabled if empty)")
+	cmd.Flags().Duration("run-max-age", 0, "Age after which finished function runs are deleted (0 means no limit)")
+	cmd.Flags().Int("run-max-count", 0, "Maximum number of finished runs kept per function (0 means no limit)")
+	cmd.Flags().Duration("idle-ttl", 0, "How long a function stays idle before its containers are stopped, except its pre-warmed ones (0 means never)")
+	cmd.Flags().Int("event-buffer-size", 1000, "Number of events buffered for each event subscription")
+	cmd.Flags().String("event-overflow", transport.OverflowReject, "What happens to the events of a subscription whose buffer is full (reject|drop-newest|drop-oldest)")
+	cmd.Flags().Duration("event-drain-timeout", 30*time.Second, "How long the server handles the buffered events when it stops, the events left are dropped")
 
 	return cmd
 }
 
 func runLocal(config *serverConfig) {
 	config.DisableRegistry = true
+	// the local server is the only replica of each service, it does not need zookeeper
+	if config.Coordination == "" {
+		config.Coordination = coordination.BackendLocal
+	}
 
-	store := entityStore(config)
-	docker := dockerClient(config)
+	// the local server is the only user of its store, which can be migrated right away
+	store := openEntityStore(config)
+	if m, ok := store.(entitystore.Migrator); ok {
+		if _, err := m.Migrate(context.Background(), -1, false); err != nil {
+			log.Fatalf("Error migrating the entity store: %+v", err)
+		}
+	}
 	functions := functionsClient(config)
 	secrets := secretsClient(config)
 	services := servicesClient(config)
-	images := imagesClient(config)
 
 	secretsService := &service.DBSecretsService{EntityStore: store}
 	secretsHandler := initSecrets(config, secretsService)
@@ -62,14 +108,54 @@ func runLocal(config *serverConfig) {
 	imagesHandler, imagesShutdown := initImages(config, store)
 	defer imagesShutdown()
 
-	faas := dockerfaas.New(docker)
+	var faas fnapi.FaaSDriver
+	var images functionmanager.ImageGetter
+	var imageBuilder fnapi.ImageBuilder
+	var docker dockerclient.CommonAPIClient
+	switch config.Local.FaaS {
+	case "docker":
+		docker = dockerClient(config)
+		dockerFaaS := dockerfaas.New(docker)
+		dockerFaaS.MinReplicas = config.Local.MinReplicas
+		dockerFaaS.MaxReplicas = config.Local.MaxReplicas
+		dockerFaaS.ContainerConcurrency = config.Local.ContainerConcurrency
+		dockerFaaS.ScaleDownCooldown = config.Local.ScaleDownCooldown
+		faas = dockerFaaS
+		images = imagesClient(config)
+	case "process":
+		// functions run as child processes, from the function templates of a local directory instead of images
+		if config.Local.FunctionTemplates == "" {
+			log.Fatalf("--function-templates is required by the process FaaS")
+		}
+		faas = process.New()
+		images = &process.TemplateImages{Dir: config.Local.FunctionTemplates}
+		imageBuilder = process.NewImageBuilder(config.Local.FunctionsDir)
+	default:
+		log.Fatalf("FaaS %s not supported", config.Local.FaaS)
+	}
+	// failed runs are published to the dead-letter topic through the in-memory transport of the event manager
+	eventTransport, err := transport.NewInMemory(
+		transport.OptInMemoryBufferSize(config.Local.EventBufferSize),
+		transport.OptInMemoryOverflow(config.Local.EventOverflow),
+		transport.OptInMemoryDrainTimeout(config.Local.EventDrainTimeout),
+	)
+	if err != nil {
+		log.Fatalf("Error creating in-memory event transport: %+v", err)
+	}
+	defer eventTransport.Close()
+	config.Functions.DeadLetterTopic = config.Local.DeadLetterTopic
+	config.Functions.RunMaxAge = config.Local.RunMaxAge
+	config.Functions.RunMaxCount = config.Local.RunMaxCount
+	config.Functions.IdleTTL = config.Local.IdleTTL
 	functionsDeps := functionsDependencies{
-		store:          store,
-		faas:           faas,
-		dockerclient:   docker,
-		imagesClient:   images,
-		secretsClient:  secrets,
-		servicesClient: services,
+		store:               store,
+		faas:                faas,
+		dockerclient:        docker,
+		imagesClient:        images,
+		secretsClient:       secrets,
+		servicesClient:      services,
+		imageBuilder:        imageBuilder,
+		deadLetterTransport: eventTransport,
 	}
 	functionsHandler, functionsShutdown := initFunctions(config, functionsDeps)
 	defer functionsShutdown()
@@ -93,7 +179,6 @@ func runLocal(config *serverConfig) {
 	apisHandler, apisShutdown := initAPIs(config, store, gw)
 	defer apisShutdown()
 
-	eventTransport := transport.NewInMemory()
 	eventsDeps := eventsDependencies{
 		store:     store,
 		transport: eventTransport,
@@ -43,6 +43,8 @@ func NewCLI(out io.Writer) *cobra.Command {
 	cmd.AddCommand(NewCmdAPIs(out, defaultConfig))
 	cmd.AddCommand(NewCmdIdentity(out, defaultConfig))
 	cmd.AddCommand(NewCmdServices(out, defaultConfig))
+	cmd.AddCommand(NewCmdMigrate(out, defaultConfig))
+	cmd.AddCommand(NewCmdStore(out, defaultConfig))
 
 	return cmd
 }
@@ -0,0 +1,39 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package dispatchserver
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/controller"
+)
+
+var metricsOnce sync.Once
+
+// serveMetrics serves the controller metrics as JSON at /debug/vars of config.MetricsAddress, once per process.
+// The metrics are kept off the API listeners, which can be public.
+func serveMetrics(config *serverConfig) {
+	if config.MetricsAddress == "" {
+		return
+	}
+	metricsOnce.Do(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprintf(w, "{\n\"controller\": %s\n}\n", controller.Metrics.String())
+		})
+		go func() {
+			log.Infof("Serving metrics at http://%s/debug/vars", config.MetricsAddress)
+			if err := http.ListenAndServe(config.MetricsAddress, mux); err != nil {
+				log.Errorf("Error serving metrics: %+v", err)
+			}
+		}()
+	})
+}
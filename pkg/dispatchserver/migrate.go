@@ -0,0 +1,79 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package dispatchserver
+
+// NO TESTS
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+	entitystore "github.com/vmware/dispatch/pkg/entity-store"
+)
+
+type migrateConfig struct {
+	To     int  `mapstructure:"to" json:"to,omitempty"`
+	DryRun bool `mapstructure:"dry-run" json:"dry-run,omitempty"`
+}
+
+var migrateLong = i18n.T(`Migrate the entity store to the latest version, or to the version given with --to.
+Migrations above the target version are rolled back. Dispatch services should be stopped while migrating.`)
+
+// NewCmdMigrate creates a subcommand to migrate the entity store
+func NewCmdMigrate(out io.Writer, config *serverConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "migrate",
+		Short:  i18n.T("Migrate the Dispatch entity store"),
+		Long:   migrateLong,
+		Args:   cobra.NoArgs,
+		PreRun: bindLocalFlags(&config.Migrate),
+		Run: func(cmd *cobra.Command, args []string) {
+			runMigrate(out, config)
+		},
+	}
+	cmd.SetOutput(out)
+
+	cmd.Flags().Int("to", -1, "Version to migrate to, the latest one if negative")
+	cmd.Flags().Bool("dry-run", false, "Print the migrations without applying them")
+	return cmd
+}
+
+func runMigrate(out io.Writer, config *serverConfig) {
+	ctx := context.Background()
+	store, ok := openEntityStore(config).(entitystore.Migrator)
+	if !ok {
+		log.Fatalf("The %s entity store does not support migrations", config.DatabaseBackend)
+	}
+
+	steps, err := store.Migrate(ctx, config.Migrate.To, config.Migrate.DryRun)
+	for _, step := range steps {
+		action := "Applied"
+		if step.Rollback {
+			action = "Rolled back"
+		}
+		if config.Migrate.DryRun {
+			action = "Would apply"
+			if step.Rollback {
+				action = "Would roll back"
+			}
+		}
+		fmt.Fprintf(out, "%s migration %d: %s\n", action, step.Version, step.Description)
+	}
+	if err != nil {
+		log.Fatalf("Error migrating the entity store: %+v", err)
+	}
+
+	version, err := store.MigrationVersion(ctx)
+	if err != nil {
+		log.Fatalf("Error getting the version of the entity store: %+v", err)
+	}
+	fmt.Fprintf(out, "Entity store at version %d\n", version)
+}
@@ -83,8 +83,8 @@ func initServices(config *serverConfig, store entitystore.EntityStore, secretsCl
 
 	controller := servicemanager.NewController(
 		&servicemanager.ControllerConfig{
-			ResyncPeriod:      config.ResyncPeriod,
-			ZookeeperLocation: config.ZookeeperLocation,
+			ResyncPeriod: config.ResyncPeriod,
+			Coordination: coordinationConfig(config),
 		},
 		store,
 		k8sClient,
@@ -0,0 +1,149 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package dispatchserver
+
+// NO TESTS
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/vmware/dispatch/pkg/api-manager"
+	"github.com/vmware/dispatch/pkg/application-manager"
+	"github.com/vmware/dispatch/pkg/dispatchcli/i18n"
+	entitystore "github.com/vmware/dispatch/pkg/entity-store"
+	driverentities "github.com/vmware/dispatch/pkg/event-manager/drivers/entities"
+	subscriptionentities "github.com/vmware/dispatch/pkg/event-manager/subscriptions/entities"
+	"github.com/vmware/dispatch/pkg/functions"
+	"github.com/vmware/dispatch/pkg/identity-manager"
+	"github.com/vmware/dispatch/pkg/image-manager"
+	"github.com/vmware/dispatch/pkg/secret-store"
+	serviceentities "github.com/vmware/dispatch/pkg/service-manager/entities"
+)
+
+type storeConfig struct {
+	Organization string   `mapstructure:"organization" json:"organization,omitempty"`
+	Types        []string `mapstructure:"type" json:"type,omitempty"`
+}
+
+// dataTypes are the data types of the entities stored by dispatch services
+var dataTypes = []entitystore.DataType{
+	entitystore.DataType(entitystore.GetDataType(&apimanager.API{})),
+	entitystore.DataType(entitystore.GetDataType(&applicationmanager.Application{})),
+	entitystore.DataType(entitystore.GetDataType(&driverentities.Driver{})),
+	entitystore.DataType(entitystore.GetDataType(&driverentities.DriverType{})),
+	entitystore.DataType(entitystore.GetDataType(&subscriptionentities.Subscription{})),
+	entitystore.DataType(entitystore.GetDataType(&functions.Function{})),
+	entitystore.DataType(entitystore.GetDataType(&functions.FunctionVersion{})),
+	entitystore.DataType(entitystore.GetDataType(&functions.FnRun{})),
+	entitystore.DataType(entitystore.GetDataType(&functions.Source{})),
+	entitystore.DataType(entitystore.GetDataType(&identitymanager.Policy{})),
+	entitystore.DataType(entitystore.GetDataType(&identitymanager.ServiceAccount{})),
+	entitystore.DataType(entitystore.GetDataType(&identitymanager.Organization{})),
+	entitystore.DataType(entitystore.GetDataType(&imagemanager.BaseImage{})),
+	entitystore.DataType(entitystore.GetDataType(&imagemanager.Image{})),
+	entitystore.DataType(entitystore.GetDataType(&secretstore.SecretEntity{})),
+	entitystore.DataType(entitystore.GetDataType(&serviceentities.Broker{})),
+	entitystore.DataType(entitystore.GetDataType(&serviceentities.ServicePlan{})),
+	entitystore.DataType(entitystore.GetDataType(&serviceentities.ServiceClass{})),
+	entitystore.DataType(entitystore.GetDataType(&serviceentities.ServiceBinding{})),
+	entitystore.DataType(entitystore.GetDataType(&serviceentities.ServiceInstance{})),
+}
+
+var (
+	exportLong = i18n.T(`Export the entities of the entity store to a JSON-lines archive, written to FILE or to the standard output.
+The archive can be imported into any entity store backend.`)
+
+	importLong = i18n.T(`Import the entities of an archive, read from FILE or from the standard input, into the entity store.
+Entities which already exist are replaced. Dispatch services should be stopped while importing.`)
+)
+
+// NewCmdStore creates a subcommand to export and import the entity store
+func NewCmdStore(out io.Writer, config *serverConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "store",
+		Short: i18n.T("Export or import the Dispatch entity store"),
+	}
+	cmd.SetOutput(out)
+
+	exportCmd := &cobra.Command{
+		Use:    "export [FILE]",
+		Short:  i18n.T("Export the entity store to an archive"),
+		Long:   exportLong,
+		Args:   cobra.MaximumNArgs(1),
+		PreRun: bindLocalFlags(&config.Store),
+		Run: func(cmd *cobra.Command, args []string) {
+			runExport(config, args)
+		},
+	}
+	exportCmd.SetOutput(out)
+	exportCmd.Flags().String("organization", "", "Export the entities of an organization only")
+	exportCmd.Flags().StringSlice("type", []string{}, "Export the entities of some data types only, e.g. Function,Image")
+
+	importCmd := &cobra.Command{
+		Use:   "import [FILE]",
+		Short: i18n.T("Import an archive into the entity store"),
+		Long:  importLong,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runImport(config, args)
+		},
+	}
+	importCmd.SetOutput(out)
+
+	cmd.AddCommand(exportCmd, importCmd)
+	return cmd
+}
+
+func runExport(config *serverConfig, args []string) {
+	opts := entitystore.ExportOptions{
+		OrganizationID: config.Store.Organization,
+		DataTypes:      dataTypes,
+	}
+	if len(config.Store.Types) > 0 {
+		opts.DataTypes = nil
+		for _, t := range config.Store.Types {
+			opts.DataTypes = append(opts.DataTypes, entitystore.DataType(t))
+		}
+	}
+
+	w := os.Stdout
+	if len(args) > 0 {
+		f, err := os.Create(args[0])
+		if err != nil {
+			log.Fatalf("Error creating the archive: %+v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	count, err := entitystore.WriteArchive(context.Background(), openEntityStore(config), w, opts)
+	if err != nil {
+		log.Fatalf("Error exporting the entity store: %+v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d entities\n", count)
+}
+
+func runImport(config *serverConfig, args []string) {
+	r := os.Stdin
+	if len(args) > 0 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			log.Fatalf("Error opening the archive: %+v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	count, err := entitystore.ReadArchive(context.Background(), openEntityStore(config), r)
+	if err != nil {
+		log.Fatalf("Error importing the entity store after %d entities: %+v", count, err)
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d entities\n", count)
+}
@@ -0,0 +1,182 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package entitystore
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	archiveFormat        = "dispatch-entity-store"
+	archiveFormatVersion = 1
+)
+
+// Record is an exported entity
+type Record struct {
+	DataType DataType `json:"type"`
+	// Revision is the revision of the entity in the exporting store
+	Revision uint64 `json:"revision"`
+	// Entity is the JSON document of the entity
+	Entity json.RawMessage `json:"entity"`
+}
+
+// ExportOptions selects the exported entities
+type ExportOptions struct {
+	// OrganizationID restricts the export to an organization, if set
+	OrganizationID string
+	// DataTypes restricts the export to some data types, if set
+	DataTypes []DataType
+}
+
+func (o ExportOptions) matches(dataType DataType, organizationID string) bool {
+	if o.OrganizationID != "" && o.OrganizationID != organizationID {
+		return false
+	}
+	if len(o.DataTypes) == 0 {
+		return true
+	}
+	for _, dt := range o.DataTypes {
+		if dt == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveHeader is the first line of an archive, the following ones are records
+type ArchiveHeader struct {
+	Format        string    `json:"format"`
+	FormatVersion int       `json:"formatVersion"`
+	CreatedTime   time.Time `json:"createdTime"`
+	// MigrationVersion is the version of the exporting store, see Migrator
+	MigrationVersion int `json:"migrationVersion"`
+}
+
+// key returns the key of the entity held by the record
+func (r Record) key() (string, BaseEntity, error) {
+	var base BaseEntity
+	if err := json.Unmarshal(r.Entity, &base); err != nil {
+		return "", base, errors.Wrapf(err, "error unmarshalling %s entity", r.DataType)
+	}
+	if r.DataType == "" || base.OrganizationID == "" || base.Name == "" {
+		return "", base, errors.Errorf("invalid record: type, organization and name are required")
+	}
+	return buildKey(r.DataType, base.OrganizationID, base.Name), base, nil
+}
+
+// WriteArchive exports the entities of the store to w, as JSON lines. It returns the number of exported entities.
+func WriteArchive(ctx context.Context, es EntityStore, w io.Writer, opts ExportOptions) (int, error) {
+	header := ArchiveHeader{
+		Format:        archiveFormat,
+		FormatVersion: archiveFormatVersion,
+		CreatedTime:   time.Now().UTC(),
+	}
+	if m, ok := es.(Migrator); ok {
+		version, err := m.MigrationVersion(ctx)
+		if err != nil {
+			return 0, err
+		}
+		header.MigrationVersion = version
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(header); err != nil {
+		return 0, errors.Wrap(err, "error writing the archive header")
+	}
+	count := 0
+	err := es.Export(ctx, opts, func(r Record) error {
+		if err := enc.Encode(r); err != nil {
+			return errors.Wrap(err, "error writing a record")
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// ReadArchive imports the entities of an archive written by WriteArchive into the store, replacing the entities
+// which already exist. Entities of an archive older than the store are upgraded by the migrations applied since.
+// It returns the number of imported entities.
+func ReadArchive(ctx context.Context, es EntityStore, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	var header ArchiveHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, errors.Wrap(err, "error reading the archive header")
+	}
+	if header.Format != archiveFormat {
+		return 0, errors.Errorf("not an entity store archive")
+	}
+	if header.FormatVersion != archiveFormatVersion {
+		return 0, errors.Errorf("unsupported archive format version %d", header.FormatVersion)
+	}
+	var upgrades []MigrationStep
+	if m, ok := es.(Migrator); ok {
+		version, err := m.MigrationVersion(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if version < header.MigrationVersion {
+			return 0, errors.Errorf("the archive is at version %d, the store at version %d: migrate the store first",
+				header.MigrationVersion, version)
+		}
+		for _, m := range Migrations() {
+			if m.DataType != "" && m.Version > header.MigrationVersion && m.Version <= version {
+				upgrades = append(upgrades, MigrationStep{Migration: m})
+			}
+		}
+	}
+
+	count := 0
+	for {
+		var record Record
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, errors.Wrapf(err, "error reading record %d", count+1)
+		}
+		if err := upgrade(&record, upgrades); err != nil {
+			return count, err
+		}
+		if err := es.Import(ctx, record); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// upgrade converts the entity of a record with the migrations of its data type
+func upgrade(record *Record, upgrades []MigrationStep) error {
+	var doc map[string]interface{}
+	for _, step := range upgrades {
+		if step.DataType != record.DataType {
+			continue
+		}
+		if doc == nil {
+			if err := json.Unmarshal(record.Entity, &doc); err != nil {
+				return errors.Wrapf(err, "error unmarshalling %s entity", record.DataType)
+			}
+		}
+		if _, err := step.convert(doc); err != nil {
+			return err
+		}
+	}
+	if doc == nil {
+		return nil
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return errors.Wrapf(err, "error marshalling %s entity", record.DataType)
+	}
+	record.Entity = b
+	return nil
+}
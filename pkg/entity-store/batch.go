@@ -0,0 +1,233 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package entitystore
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/docker/libkv/store"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// kvBatch is a libkv store which stages writes until they are committed to the underlying store as a batch.
+// Reads see the staged writes. Staged pairs have a zero LastIndex, the entities written get their revision on commit.
+type kvBatch struct {
+	kv store.Store
+
+	writes map[string]*kvWrite
+	// keys are the written keys, in the order of their first write
+	keys []string
+
+	// entities are the entities written by key, their revision is set on commit
+	entities map[string][]Entity
+	// events are published once the batch is committed
+	events []Event
+}
+
+type kvWrite struct {
+	// value is nil if the key is deleted
+	value []byte
+	// previous is the committed pair replaced by the write, nil if the key does not exist
+	previous *store.KVPair
+}
+
+func newKVBatch(kv store.Store) *kvBatch {
+	return &kvBatch{
+		kv:       kv,
+		writes:   make(map[string]*kvWrite),
+		entities: make(map[string][]Entity),
+	}
+}
+
+// stage records a write, keeping the committed pair it replaces
+func (b *kvBatch) stage(key string, value []byte) error {
+	if w, ok := b.writes[key]; ok {
+		w.value = value
+		return nil
+	}
+	previous, err := b.kv.Get(key)
+	if err == store.ErrKeyNotFound {
+		previous = nil
+	} else if err != nil {
+		return err
+	}
+	b.writes[key] = &kvWrite{value: value, previous: previous}
+	b.keys = append(b.keys, key)
+	return nil
+}
+
+// track sets the revision of the entity once the batch is committed
+func (b *kvBatch) track(key string, entity Entity) {
+	b.entities[key] = append(b.entities[key], entity)
+}
+
+// Get returns the staged value of the key, or its committed one
+func (b *kvBatch) Get(key string) (*store.KVPair, error) {
+	if w, ok := b.writes[key]; ok {
+		if w.value == nil {
+			return nil, store.ErrKeyNotFound
+		}
+		return &store.KVPair{Key: key, Value: w.value}, nil
+	}
+	return b.kv.Get(key)
+}
+
+// Exists tells whether the key has a staged or committed value
+func (b *kvBatch) Exists(key string) (bool, error) {
+	_, err := b.Get(key)
+	if err == store.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List lists the committed pairs of a directory, with the staged writes applied
+func (b *kvBatch) List(directory string) ([]*store.KVPair, error) {
+	pairs, err := b.kv.List(directory)
+	if err != nil && err != store.ErrKeyNotFound {
+		return nil, err
+	}
+	var kvs []*store.KVPair
+	for _, kv := range pairs {
+		if _, ok := b.writes[kv.Key]; !ok {
+			kvs = append(kvs, kv)
+		}
+	}
+	for key, w := range b.writes {
+		if w.value != nil && strings.HasPrefix(key, directory) {
+			kvs = append(kvs, &store.KVPair{Key: key, Value: w.value})
+		}
+	}
+	if len(kvs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+// Put stages a write
+func (b *kvBatch) Put(key string, value []byte, options *store.WriteOptions) error {
+	return b.stage(key, value)
+}
+
+// AtomicPut stages a write if the current value of the key is previous, or if the key does not exist and previous is
+// nil
+func (b *kvBatch) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	current, err := b.Get(key)
+	if err != nil && err != store.ErrKeyNotFound {
+		return false, nil, err
+	}
+	switch {
+	case previous == nil && current != nil:
+		return false, nil, store.ErrKeyExists
+	case previous != nil && current == nil:
+		return false, nil, store.ErrKeyNotFound
+	case previous != nil && previous.LastIndex != current.LastIndex:
+		return false, nil, store.ErrKeyModified
+	}
+	if err := b.stage(key, value); err != nil {
+		return false, nil, err
+	}
+	return true, &store.KVPair{Key: key, Value: value}, nil
+}
+
+// Delete stages the deletion of a key
+func (b *kvBatch) Delete(key string) error {
+	return b.stage(key, nil)
+}
+
+// AtomicDelete stages the deletion of a key if its current value is previous
+func (b *kvBatch) AtomicDelete(key string, previous *store.KVPair) (bool, error) {
+	current, err := b.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if previous == nil || previous.LastIndex != current.LastIndex {
+		return false, store.ErrKeyModified
+	}
+	return true, b.stage(key, nil)
+}
+
+// Watch is not supported in a batch
+func (b *kvBatch) Watch(key string, stopCh <-chan struct{}) (<-chan *store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+// WatchTree is not supported in a batch
+func (b *kvBatch) WatchTree(directory string, stopCh <-chan struct{}) (<-chan []*store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+// NewLock is not supported in a batch
+func (b *kvBatch) NewLock(key string, options *store.LockOptions) (store.Locker, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+// DeleteTree is not supported in a batch
+func (b *kvBatch) DeleteTree(directory string) error {
+	return store.ErrCallNotSupported
+}
+
+// Close does nothing, the underlying store is closed by its owner
+func (b *kvBatch) Close() {
+}
+
+// commit writes the staged values to the underlying store. Each write fails if the key was modified since it was
+// staged, in which case the writes already done are reverted.
+func (b *kvBatch) commit() error {
+	var done []string
+	indexes := make(map[string]uint64)
+	for _, key := range b.keys {
+		w := b.writes[key]
+		var err error
+		switch {
+		case w.value != nil:
+			var kv *store.KVPair
+			_, kv, err = b.kv.AtomicPut(key, w.value, w.previous, &store.WriteOptions{IsDir: false})
+			if err == nil {
+				indexes[key] = kv.LastIndex
+			}
+		case w.previous != nil:
+			_, err = b.kv.AtomicDelete(key, w.previous)
+		}
+		if err != nil {
+			b.revert(done)
+			return errors.Wrapf(err, "error committing %s", key)
+		}
+		done = append(done, key)
+	}
+
+	for key, entities := range b.entities {
+		for _, e := range entities {
+			e.setRevision(indexes[key])
+		}
+	}
+	for i, e := range b.events {
+		if e.Type != EventDelete {
+			b.events[i].Revision = indexes[buildKey(e.DataType, e.OrganizationID, e.Name)]
+		}
+	}
+	return nil
+}
+
+// revert restores the previous values of the keys written by a failed commit
+func (b *kvBatch) revert(keys []string) {
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+		previous := b.writes[key].previous
+		var err error
+		if previous == nil {
+			err = b.kv.Delete(key)
+		} else {
+			err = b.kv.Put(key, previous.Value, &store.WriteOptions{IsDir: false})
+		}
+		if err != nil {
+			log.Errorf("error reverting %s after a failed commit: %s", key, err)
+		}
+	}
+}
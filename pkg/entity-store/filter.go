@@ -5,6 +5,14 @@
 
 package entitystore
 
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 const (
 	// FilterVerbIn tests containment
 	FilterVerbIn Verb = "in"
@@ -18,6 +26,39 @@ const (
 	// FilterVerbAfter tests two time.Time
 	FilterVerbAfter Verb = "after"
 
+	// FilterVerbNotEqual tests inequality, a missing tag is not equal to any value
+	FilterVerbNotEqual Verb = "notEqual"
+
+	// FilterVerbPrefix tests that a string starts with the object
+	FilterVerbPrefix Verb = "prefix"
+
+	// FilterVerbContains tests that a string contains the object
+	FilterVerbContains Verb = "contains"
+
+	// FilterVerbExists tests that a tag is set, whatever its value, the object is ignored
+	FilterVerbExists Verb = "exists"
+
+	// FilterVerbGreaterThan compares two numbers
+	FilterVerbGreaterThan Verb = "greaterThan"
+
+	// FilterVerbGreaterOrEqual compares two numbers
+	FilterVerbGreaterOrEqual Verb = "greaterOrEqual"
+
+	// FilterVerbLessThan compares two numbers
+	FilterVerbLessThan Verb = "lessThan"
+
+	// FilterVerbLessOrEqual compares two numbers
+	FilterVerbLessOrEqual Verb = "lessOrEqual"
+
+	// FilterVerbAnd tests that all the statements of the object, a []FilterStat, are true
+	FilterVerbAnd Verb = "and"
+
+	// FilterVerbOr tests that at least one of the statements of the object, a []FilterStat, is true
+	FilterVerbOr Verb = "or"
+
+	// FilterVerbNot negates the statement of the object, a FilterStat
+	FilterVerbNot Verb = "not"
+
 	// FilterScopeField defines that the subject is a BaseEntity field
 	FilterScopeField Scope = "field"
 
@@ -40,7 +81,8 @@ type Filter interface {
 	FilterStats() []FilterStat
 }
 
-// FilterStat (Filter Statement) defines one filter criterion
+// FilterStat (Filter Statement) defines one filter criterion.
+// Group statements (and, or, not) have neither scope nor subject, their object holds the nested statements.
 type FilterStat struct {
 	Scope   Scope
 	Subject string
@@ -58,6 +100,69 @@ func FilterStatByApplication(name string) FilterStat {
 	}
 }
 
+// FilterStatAnd defines a group statement, which is true if all the statements are true
+func FilterStatAnd(stats ...FilterStat) FilterStat {
+	return FilterStat{
+		Verb:   FilterVerbAnd,
+		Object: stats,
+	}
+}
+
+// FilterStatOr defines a group statement, which is true if any of the statements is true
+func FilterStatOr(stats ...FilterStat) FilterStat {
+	return FilterStat{
+		Verb:   FilterVerbOr,
+		Object: stats,
+	}
+}
+
+// FilterStatNot defines a statement, which is true if stat is false
+func FilterStatNot(stat FilterStat) FilterStat {
+	return FilterStat{
+		Verb:   FilterVerbNot,
+		Object: stat,
+	}
+}
+
+// isGroup tells whether the verb applies to nested statements rather than to a subject
+func (v Verb) isGroup() bool {
+	return v == FilterVerbAnd || v == FilterVerbOr || v == FilterVerbNot
+}
+
+// subStats returns the statements nested in a group statement
+func (fs FilterStat) subStats() ([]FilterStat, error) {
+	switch stats := fs.Object.(type) {
+	case FilterStat:
+		if fs.Verb != FilterVerbNot {
+			break
+		}
+		return []FilterStat{stats}, nil
+	case []FilterStat:
+		if fs.Verb == FilterVerbNot {
+			break
+		}
+		return stats, nil
+	}
+	return nil, errors.Errorf("error filtering: invalid object of a '%s' verb: %v", fs.Verb, fs.Object)
+}
+
+// toNumber converts numbers, and strings holding a number such as tag values, to a float64
+func toNumber(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
 type filter struct {
 	statements []FilterStat
 }
@@ -9,6 +9,9 @@ import (
 	"context"
 	"encoding/json"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/docker/libkv/store"
@@ -18,13 +21,18 @@ import (
 )
 
 type libkvEntityStore struct {
-	kv store.Store
+	kv     store.Store
+	events *broadcaster
+
+	// batch stages the writes of the stores passed to Transaction functions, it is also their kv
+	batch *kvBatch
 }
 
 // newLibkv is the EntityStore constructor
 func newLibkv(kv store.Store) EntityStore {
 	return &libkvEntityStore{
-		kv: kv,
+		kv:     kv,
+		events: newHistoryBroadcaster(),
 	}
 }
 
@@ -66,6 +74,7 @@ func (es *libkvEntityStore) Add(ctx context.Context, entity Entity) (id string,
 		return "", &kvUniqueViolation{key}
 	}
 
+	setNewVersion(entity)
 	id = uuid.NewV4().String()
 	entity.setID(id)
 
@@ -83,6 +92,8 @@ func (es *libkvEntityStore) Add(ctx context.Context, entity Entity) (id string,
 		return "", err
 	}
 	entity.setRevision(resp.LastIndex)
+	es.track(key, entity)
+	es.publish(newEvent(EventAdd, entity))
 	return id, nil
 }
 
@@ -116,6 +127,8 @@ func (es *libkvEntityStore) Update(ctx context.Context, lastRevision uint64, ent
 		return 0, err
 	}
 	entity.setRevision(kv.LastIndex)
+	es.track(key, entity)
+	es.publish(newEvent(EventUpdate, entity))
 	return int64(kv.LastIndex), nil
 }
 
@@ -126,7 +139,17 @@ func (es *libkvEntityStore) Delete(ctx context.Context, organizationID string, n
 		return errors.Errorf("organizationID cannot be empty")
 	}
 	key := buildKey(getDataType(entity), organizationID, name)
-	return es.kv.Delete(key)
+	if err := es.kv.Delete(key); err != nil {
+		return err
+	}
+	es.publish(Event{
+		Type:           EventDelete,
+		DataType:       getDataType(entity),
+		OrganizationID: organizationID,
+		Name:           name,
+		ID:             entity.GetID(),
+	})
+	return nil
 }
 
 // SoftDelete marks a single entity for deletion
@@ -179,9 +202,14 @@ func (es *libkvEntityStore) Get(ctx context.Context, organizationID string, name
 
 func doFilterStat(fs FilterStat, entity Entity) (bool, error) {
 
+	if fs.Verb.isGroup() {
+		return doFilterGroup(fs, entity)
+	}
+
 	rv := reflect.ValueOf(entity).Elem()
 
 	var subjectValue interface{}
+	tagSet := false
 	switch fs.Scope {
 	case FilterScopeField, FilterScopeExtra:
 		field := rv.FieldByName(fs.Subject)
@@ -198,12 +226,51 @@ func doFilterStat(fs FilterStat, entity Entity) (bool, error) {
 		if !ok {
 			return false, errors.Errorf("unexpected error: should be the an instance of type Tags")
 		}
-		subjectValue = tags[fs.Subject]
+		subjectValue, tagSet = tags[fs.Subject]
 	}
 
 	switch fs.Verb {
 	case FilterVerbEqual:
 		return reflect.DeepEqual(subjectValue, fs.Object), nil
+	case FilterVerbNotEqual:
+		return !reflect.DeepEqual(subjectValue, fs.Object), nil
+	case FilterVerbExists:
+		if fs.Scope != FilterScopeTag {
+			return false, errors.Errorf("error filtering: '%s' verb only applies to tags", fs.Verb)
+		}
+		return tagSet, nil
+	case FilterVerbPrefix, FilterVerbContains:
+		object, ok := fs.Object.(string)
+		if !ok {
+			return false, errors.Errorf("error filtering: object of a '%s' verb must be a string", fs.Verb)
+		}
+		subject := reflect.ValueOf(subjectValue)
+		if subject.Kind() != reflect.String {
+			return false, errors.Errorf("error filtering: subject of a '%s' verb must be a string", fs.Verb)
+		}
+		if fs.Verb == FilterVerbPrefix {
+			return strings.HasPrefix(subject.String(), object), nil
+		}
+		return strings.Contains(subject.String(), object), nil
+	case FilterVerbGreaterThan, FilterVerbGreaterOrEqual, FilterVerbLessThan, FilterVerbLessOrEqual:
+		object, ok := toNumber(fs.Object)
+		if !ok {
+			return false, errors.Errorf("error filtering: object of a '%s' verb must be a number", fs.Verb)
+		}
+		// subjects which are not numbers, such as a missing tag, never match
+		subject, ok := toNumber(subjectValue)
+		if !ok {
+			return false, nil
+		}
+		switch fs.Verb {
+		case FilterVerbGreaterThan:
+			return subject > object, nil
+		case FilterVerbGreaterOrEqual:
+			return subject >= object, nil
+		case FilterVerbLessThan:
+			return subject < object, nil
+		}
+		return subject <= object, nil
 	case FilterVerbIn:
 		objects := reflect.ValueOf(fs.Object)
 		if objects.Kind() != reflect.Slice {
@@ -234,6 +301,33 @@ func doFilterStat(fs FilterStat, entity Entity) (bool, error) {
 	}
 }
 
+func doFilterGroup(fs FilterStat, entity Entity) (bool, error) {
+	stats, err := fs.subStats()
+	if err != nil {
+		return false, err
+	}
+	for _, stat := range stats {
+		ok, err := doFilterStat(stat, entity)
+		if err != nil {
+			return false, err
+		}
+		switch fs.Verb {
+		case FilterVerbNot:
+			return !ok, nil
+		case FilterVerbOr:
+			if ok {
+				return true, nil
+			}
+		case FilterVerbAnd:
+			if !ok {
+				return false, nil
+			}
+		}
+	}
+	// an empty "or" is false, an empty "and" is true
+	return fs.Verb == FilterVerbAnd, nil
+}
+
 func doFilter(filter Filter, entity Entity) (bool, error) {
 	for _, fs := range filter.FilterStats() {
 		ok, err := doFilterStat(fs, entity)
@@ -277,6 +371,9 @@ func (es *libkvEntityStore) list(ctx context.Context, organizationID string, opt
 	}
 
 	key := buildKeyWithoutOrg(DataType(elemType.Elem().Name()))
+	if organizationID != "" {
+		key = buildKey(DataType(elemType.Elem().Name()), organizationID)
+	}
 
 	kvs, err := es.kv.List(key)
 	if err != nil {
@@ -297,7 +394,7 @@ func (es *libkvEntityStore) list(ctx context.Context, organizationID string, opt
 		if opts.Filter != nil {
 			ok, errFilter := doFilter(opts.Filter, entity)
 			if errFilter != nil {
-				log.Print(err)
+				return errors.Wrap(errFilter, "error filtering entity")
 			}
 			if !ok {
 				continue
@@ -307,7 +404,224 @@ func (es *libkvEntityStore) list(ctx context.Context, organizationID string, opt
 
 		slice = reflect.Append(slice, obj)
 	}
+
+	slice, err = page(slice, opts)
+	if err != nil {
+		return err
+	}
 	rv.Elem().Set(slice)
 
 	return nil
 }
+
+// page sorts the listed entities and returns the subset selected by the offset and limit options.
+// Entities are listed by key, the sort being stable keeps it as the last ordering criterion.
+func page(slice reflect.Value, opts Options) (reflect.Value, error) {
+	field, descending, err := opts.orderBy()
+	if err != nil {
+		return slice, err
+	}
+	offset, err := opts.offset()
+	if err != nil {
+		return slice, err
+	}
+	if opts.Limit < 0 {
+		return slice, errors.Errorf("invalid limit %d", opts.Limit)
+	}
+
+	if field != "" {
+		sort.SliceStable(slice.Interface(), func(i, j int) bool {
+			a := slice.Index(i).Elem().FieldByName(field)
+			b := slice.Index(j).Elem().FieldByName(field)
+			if descending {
+				return less(b, a)
+			}
+			return less(a, b)
+		})
+	}
+
+	if offset > slice.Len() {
+		offset = slice.Len()
+	}
+	end := slice.Len()
+	if opts.Limit > 0 && offset+opts.Limit < end {
+		end = offset + opts.Limit
+	}
+	return slice.Slice(offset, end), nil
+}
+
+// track sets the revision of an entity written in a transaction once it is committed
+func (es *libkvEntityStore) track(key string, entity Entity) {
+	if es.batch != nil {
+		es.batch.track(key, entity)
+	}
+}
+
+// publish publishes an event, or delays it until the transaction is committed
+func (es *libkvEntityStore) publish(e Event) {
+	if es.batch != nil {
+		es.batch.events = append(es.batch.events, e)
+		return
+	}
+	es.events.publish(e)
+}
+
+// Transaction calls fn with a store which stages its writes, and commits them as a batch if fn returns nil.
+// libkv has no transactions: the batch fails if one of the written entities was modified in the meantime and the
+// writes already done are then reverted, but a crash in the middle of a commit leaves it partially applied.
+// Transactions started from fn are part of the enclosing one.
+func (es *libkvEntityStore) Transaction(ctx context.Context, fn func(tx EntityStore) error) error {
+	if es.batch != nil {
+		return fn(es)
+	}
+	batch := newKVBatch(es.kv)
+	if err := fn(&libkvEntityStore{kv: batch, events: es.events, batch: batch}); err != nil {
+		return err
+	}
+	if err := batch.commit(); err != nil {
+		return errors.Wrap(err, "error committing the transaction")
+	}
+	for _, e := range batch.events {
+		es.events.publish(e)
+	}
+	return nil
+}
+
+// Watch streams changes of entities of a single data type. BoltDB is embedded, so only changes made through this
+// process are seen, and the store revisions start over when the process restarts. The last changes are kept in
+// memory for the watchers resuming from a revision.
+func (es *libkvEntityStore) Watch(ctx context.Context, dataType DataType, organizationID string, sinceRevision uint64) (<-chan Event, error) {
+	return es.events.watch(ctx, dataType, organizationID, sinceRevision, es.events.changes)
+}
+
+// migrationKey is the prefix of the keys recording the applied migrations
+const migrationKey = "migration/"
+
+type kvMigration struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	AppliedTime time.Time `json:"appliedTime"`
+}
+
+// MigrationVersion returns the version of the last migration applied to the store
+func (es *libkvEntityStore) MigrationVersion(ctx context.Context) (int, error) {
+	return migrationVersion(ctx, es)
+}
+
+// Migrate migrates the store to the target version. Key-value stores have no transactions, an interrupted migration
+// leaves converted entities behind, which are skipped when it is applied again.
+func (es *libkvEntityStore) Migrate(ctx context.Context, target int, dryRun bool) ([]MigrationStep, error) {
+	return migrate(ctx, es, target, dryRun)
+}
+
+func (es *libkvEntityStore) appliedMigrations() (map[int]bool, error) {
+	applied := make(map[int]bool)
+	kvs, err := es.kv.List(migrationKey)
+	if err == store.ErrKeyNotFound {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing migrations")
+	}
+	for _, kv := range kvs {
+		var m kvMigration
+		if err := json.Unmarshal(kv.Value, &m); err != nil {
+			return nil, errors.Wrapf(err, "error unmarshalling migration %s", kv.Key)
+		}
+		applied[m.Version] = true
+	}
+	return applied, nil
+}
+
+func (es *libkvEntityStore) applyMigration(step MigrationStep) error {
+	if step.DataType != "" {
+		kvs, err := es.kv.List(buildKeyWithoutOrg(step.DataType))
+		if err != nil && err != store.ErrKeyNotFound {
+			return errors.Wrap(err, "error listing entities")
+		}
+		for _, kv := range kvs {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(kv.Value, &doc); err != nil {
+				return errors.Wrapf(err, "error unmarshalling entity %s", kv.Key)
+			}
+			converted, err := step.convert(doc)
+			if err != nil {
+				return err
+			}
+			if !converted {
+				continue
+			}
+			data, err := json.Marshal(doc)
+			if err != nil {
+				return errors.Wrapf(err, "error marshalling entity %s", kv.Key)
+			}
+			if _, _, err := es.kv.AtomicPut(kv.Key, data, kv, &store.WriteOptions{IsDir: false}); err != nil {
+				return errors.Wrapf(err, "error updating entity %s", kv.Key)
+			}
+		}
+	}
+
+	key := migrationKey + strconv.Itoa(step.Version)
+	if step.Rollback {
+		if err := es.kv.Delete(key); err != nil {
+			return errors.Wrap(err, "error deleting the migration record")
+		}
+		return nil
+	}
+	data, err := json.Marshal(kvMigration{
+		Version:     step.Version,
+		Description: step.Description,
+		AppliedTime: time.Now(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling the migration record")
+	}
+	if err := es.kv.Put(key, data, nil); err != nil {
+		return errors.Wrap(err, "error recording the migration")
+	}
+	return nil
+}
+
+// Export calls fn with each stored entity selected by opts, ordered by data type, organization and name.
+// The keys of a libkv store cannot be listed all at once, so the data types must be given.
+func (es *libkvEntityStore) Export(ctx context.Context, opts ExportOptions, fn func(Record) error) error {
+	if len(opts.DataTypes) == 0 {
+		return errors.New("the data types to export are required")
+	}
+	dataTypes := append([]DataType(nil), opts.DataTypes...)
+	sort.Slice(dataTypes, func(i, j int) bool { return dataTypes[i] < dataTypes[j] })
+
+	for _, dt := range dataTypes {
+		prefix := buildKeyWithoutOrg(dt)
+		if opts.OrganizationID != "" {
+			prefix = buildKey(dt, opts.OrganizationID)
+		}
+		kvs, err := es.kv.List(prefix)
+		if err == store.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "error listing %s entities", dt)
+		}
+		sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+		for _, kv := range kvs {
+			if err := fn(Record{DataType: dt, Revision: kv.LastIndex, Entity: kv.Value}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Import stores an exported entity as is, replacing the entity with the same key if any. libkv stores assign
+// revisions themselves, the revision of the record is not kept.
+func (es *libkvEntityStore) Import(ctx context.Context, record Record) error {
+	key, _, err := record.key()
+	if err != nil {
+		return err
+	}
+	if err := es.kv.Put(key, record.Entity, &store.WriteOptions{IsDir: false}); err != nil {
+		return errors.Wrapf(err, "error importing entity %s", key)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package entitystore
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/trace"
+)
+
+// Migration is a step of the upgrade of the entity store. Migrations are applied in increasing order of version and
+// recorded in the store, so that each of them is applied once.
+type Migration struct {
+	// Version orders the migrations, it must be unique across all the registered migrations
+	Version int
+	// Description tells what the migration does
+	Description string
+
+	// DataType is the type of the entities converted by Up and Down, if any.
+	// Converted entities get the version of the migration, entities already at this version are not converted.
+	DataType DataType
+	// Up converts the JSON document of an entity to this version
+	Up func(doc map[string]interface{}) error
+	// Down converts the JSON document of an entity back to the previous version
+	Down func(doc map[string]interface{}) error
+
+	// schemaUp and schemaDown change the schema of SQL stores
+	schemaUp   string
+	schemaDown string
+}
+
+// MigrationStep is a migration applied, or rolled back, by Migrate
+type MigrationStep struct {
+	Migration
+	// Rollback is true if the migration is rolled back
+	Rollback bool
+}
+
+// Migrator is implemented by the entity stores which support migrations
+type Migrator interface {
+	// MigrationVersion returns the version of the last migration applied to the store, 0 if none
+	MigrationVersion(ctx context.Context) (int, error)
+	// Migrate applies the registered migrations up to the target version, and rolls back the applied ones above it.
+	// A negative target is the latest version. The steps are returned in the order they are, or would be if dryRun
+	// is set, carried out.
+	Migrate(ctx context.Context, target int, dryRun bool) ([]MigrationStep, error)
+}
+
+// migrationBackend records the migrations applied to a store, and carries them out
+type migrationBackend interface {
+	appliedMigrations() (map[int]bool, error)
+	applyMigration(step MigrationStep) error
+}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   []Migration
+)
+
+func init() {
+	RegisterMigration(Migration{
+		Version:     1,
+		Description: "index entities by type and organization",
+		schemaUp:    "CREATE INDEX IF NOT EXISTS entity_type_organization_id ON entity (type, organization_id)",
+		schemaDown:  "DROP INDEX IF EXISTS entity_type_organization_id",
+	})
+}
+
+// RegisterMigration registers a migration. It is meant to be called from the init function of the package declaring
+// the entity type, and panics if another migration has the same version.
+func RegisterMigration(m Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	if m.Version <= 0 {
+		panic(errors.Errorf("invalid migration version %d", m.Version))
+	}
+	if m.DataType != "" && (m.Up == nil || m.Down == nil) {
+		panic(errors.Errorf("migration %d converts %s entities, it needs both Up and Down", m.Version, m.DataType))
+	}
+	i := sort.Search(len(migrations), func(i int) bool { return migrations[i].Version >= m.Version })
+	if i < len(migrations) && migrations[i].Version == m.Version {
+		panic(errors.Errorf("migration %d is already registered: %s", m.Version, migrations[i].Description))
+	}
+	migrations = append(migrations, Migration{})
+	copy(migrations[i+1:], migrations[i:])
+	migrations[i] = m
+}
+
+// Migrations returns the registered migrations, in increasing order of version
+func Migrations() []Migration {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	return append([]Migration(nil), migrations...)
+}
+
+// LatestVersion returns the version of the last registered migration
+func LatestVersion() int {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// entityVersion returns the version of the last migration below version which converts entities of dataType, 0 if
+// there is none
+func entityVersion(dataType DataType, version int) uint64 {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	v := 0
+	for _, m := range migrations {
+		if m.Version >= version {
+			break
+		}
+		if m.DataType == dataType {
+			v = m.Version
+		}
+	}
+	return uint64(v)
+}
+
+// setNewVersion sets the version of an entity being added, which is up to date with the registered migrations
+func setNewVersion(entity Entity) {
+	if entity.GetVersion() == 0 {
+		entity.setVersion(entityVersion(getDataType(entity), LatestVersion()+1))
+	}
+}
+
+// migrationPlan returns the steps leading from the applied migrations to the target version
+func migrationPlan(applied map[int]bool, target int) ([]MigrationStep, error) {
+	registered := Migrations()
+	latest := LatestVersion()
+	if target < 0 {
+		target = latest
+	}
+	if target > latest {
+		return nil, errors.Errorf("unknown version %d, the latest one is %d", target, latest)
+	}
+	for version := range applied {
+		if version > latest {
+			return nil, errors.Errorf("the store has migration %d, which is unknown to this version of dispatch", version)
+		}
+	}
+
+	var steps []MigrationStep
+	// roll back first, in decreasing order
+	for i := len(registered) - 1; i >= 0; i-- {
+		if m := registered[i]; m.Version > target && applied[m.Version] {
+			steps = append(steps, MigrationStep{Migration: m, Rollback: true})
+		}
+	}
+	for _, m := range registered {
+		if m.Version <= target && !applied[m.Version] {
+			steps = append(steps, MigrationStep{Migration: m})
+		}
+	}
+	return steps, nil
+}
+
+func migrationVersion(ctx context.Context, b migrationBackend) (int, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	applied, err := b.appliedMigrations()
+	if err != nil {
+		return 0, errors.Wrap(err, "error listing applied migrations")
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+func migrate(ctx context.Context, b migrationBackend, target int, dryRun bool) ([]MigrationStep, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	applied, err := b.appliedMigrations()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing applied migrations")
+	}
+	steps, err := migrationPlan(applied, target)
+	if err != nil || dryRun {
+		return steps, err
+	}
+	for i, step := range steps {
+		if err := b.applyMigration(step); err != nil {
+			if step.Rollback {
+				return steps[:i], errors.Wrapf(err, "error rolling back migration %d", step.Version)
+			}
+			return steps[:i], errors.Wrapf(err, "error applying migration %d", step.Version)
+		}
+		log.Debugf("migration %d (%s) done, rollback=%t", step.Version, step.Description, step.Rollback)
+	}
+	return steps, nil
+}
+
+// convert applies the step to the JSON document of an entity.
+// It returns false if the entity did not need to be converted.
+func (s MigrationStep) convert(doc map[string]interface{}) (bool, error) {
+	var version int
+	if v, ok := doc["version"].(float64); ok {
+		version = int(v)
+	}
+
+	var err error
+	switch {
+	case !s.Rollback && version < s.Version:
+		err = s.Up(doc)
+		version = s.Version
+	case s.Rollback && version >= s.Version:
+		err = s.Down(doc)
+		version = int(entityVersion(s.DataType, s.Version))
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "error converting entity %v with migration %d", doc["name"], s.Version)
+	}
+	doc["version"] = version
+	return true, nil
+}
@@ -5,7 +5,126 @@
 
 package entitystore
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 // Options defines a set of query options for list and get
 type Options struct {
 	Filter Filter
+
+	// OrderBy is the name of the BaseEntity field (case insensitive) by which listed entities are sorted, e.g.
+	// "createdTime". A "-" prefix sorts in descending order. Entities which compare equal are sorted by
+	// organization and name, so the order (and therefore pagination) is stable.
+	OrderBy string
+
+	// Limit is the maximum number of entities returned by List, 0 means no limit
+	Limit int
+
+	// Offset is the number of entities skipped by List
+	Offset int
+
+	// Cursor is the token returned by NextCursor to continue a previous List, it overrides Offset
+	Cursor string
+}
+
+// cursor is the content of the opaque Options.Cursor token
+type cursor struct {
+	Offset int `json:"offset"`
+}
+
+// NextCursor returns the cursor pointing to the page following the one listed with opts, given the number of
+// entities which were returned. An empty string is returned if there are no more pages to list.
+func NextCursor(opts Options, count int) string {
+	if opts.Limit <= 0 || count < opts.Limit {
+		return ""
+	}
+	offset, err := opts.offset()
+	if err != nil {
+		return ""
+	}
+	b, _ := json.Marshal(cursor{Offset: offset + count})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// offset returns the number of entities to skip, taking the cursor into account
+func (o Options) offset() (int, error) {
+	if o.Cursor == "" {
+		if o.Offset < 0 {
+			return 0, errors.Errorf("invalid offset %d", o.Offset)
+		}
+		return o.Offset, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return 0, errors.Errorf("invalid cursor %s", o.Cursor)
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Offset < 0 {
+		return 0, errors.Errorf("invalid cursor %s", o.Cursor)
+	}
+	return c.Offset, nil
+}
+
+// orderBy validates OrderBy and returns the name of the BaseEntity field to sort by, if any
+func (o Options) orderBy() (field string, descending bool, err error) {
+	name := o.OrderBy
+	if strings.HasPrefix(name, "-") {
+		name = name[1:]
+		descending = true
+	}
+	if name == "" {
+		return "", false, nil
+	}
+	f, ok := reflect.TypeOf(BaseEntity{}).FieldByNameFunc(func(n string) bool {
+		return strings.EqualFold(n, name)
+	})
+	if !ok || !isSortable(f.Type) {
+		return "", false, errors.Errorf("cannot order by %s", o.OrderBy)
+	}
+	return f.Name, descending, nil
+}
+
+// Validate checks the ordering and paging options
+func (o Options) Validate() error {
+	if o.Limit < 0 {
+		return errors.Errorf("invalid limit %d", o.Limit)
+	}
+	if _, err := o.offset(); err != nil {
+		return err
+	}
+	_, _, err := o.orderBy()
+	return err
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func isSortable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Uint64, reflect.Bool:
+		return true
+	}
+	return t == timeType
+}
+
+// less compares two values of a sortable BaseEntity field
+func less(a, b reflect.Value) bool {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Before(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return false
 }
@@ -0,0 +1,1133 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package entitystore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq" // also, import due to init() registration of the driver
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/trace"
+)
+
+// notifyChannel is the postgres NOTIFY channel on which entity events are published
+const notifyChannel = "entity_events"
+
+// dialect hides the differences between the SQL databases which can back the entity store
+type dialect interface {
+	// jsonType is the type of the JSON columns
+	jsonType() string
+	// jsonText extracts the text value of key, an SQL expression, from a JSON column
+	jsonText(column, key string) string
+	// castTime converts an SQL expression to a value which compares as a point in time
+	castTime(expr string) string
+	// castNumber converts an SQL expression to a number, or to NULL if it does not hold one
+	castNumber(expr string) string
+	// limit returns the LIMIT and OFFSET clauses, a zero limit meaning no limit
+	limit(limit, offset int) string
+	// isUniqueViolation tells whether the error is caused by adding an entity which already exists
+	isUniqueViolation(err error) bool
+	// notifies tells whether entity events can be published to other processes through the database
+	notifies() bool
+	// serialKey is the type of an integer primary key assigned in increasing order
+	serialKey() string
+}
+
+// sqlExecutor is implemented by both sqlx.DB and sqlx.Tx, so that entities can be read and written in a transaction
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	NamedExec(query string, arg interface{}) (sql.Result, error)
+	Queryx(query string, args ...interface{}) (*sqlx.Rows, error)
+	Rebind(query string) string
+}
+
+// sqlEntityStore stores entities in a single SQL table, whose JSON columns can be queried
+type sqlEntityStore struct {
+	db      *sqlx.DB
+	dialect dialect
+	dsn     string
+	events  *broadcaster
+
+	// exec runs the entity queries, it is the transaction of the stores passed to Transaction functions
+	exec sqlExecutor
+	// txParent is the store which started the transaction, if any
+	txParent *sqlEntityStore
+	// txEvents are the events published once the transaction is committed
+	txEvents []Event
+
+	listenOnce sync.Once
+	listenErr  error
+}
+
+type dbEntity struct {
+	Key            string         `db:"key"`
+	ID             string         `db:"id"`
+	Name           string         `db:"name"`
+	Type           string         `db:"type"`
+	OrganizationID string         `db:"organization_id"`
+	CreatedTime    time.Time      `db:"created_time"`
+	ModifiedTime   time.Time      `db:"modified_time"`
+	Revision       uint64         `db:"revision"`
+	Version        uint64         `db:"version"`
+	Status         string         `db:"status"`
+	Delete         bool           `db:"delete"`
+	Reason         Reason         `db:"reason"`
+	Spec           Spec           `db:"spec"`
+	Tags           Tags           `db:"tags"`
+	Value          types.JSONText `db:"value"`
+}
+
+// the value and scan methods listed in the following is used to
+// serialize and unserialize the type Reason,  Spec and Tags into and from JSON columns.
+// The values are strings, which keeps them readable in databases storing JSON as text, e.g. sqlite.
+func value(v interface{}) (driver.Value, error) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling type=%s", reflect.TypeOf(v))
+	}
+	return string(j), nil
+}
+
+func scan(v interface{}, src interface{}) error {
+	var source []byte
+	switch s := src.(type) {
+	case []byte:
+		source = s
+	case string:
+		source = []byte(s)
+	default:
+		return errors.Errorf("error scanning type=%s: type assertion .([]byte) failed", reflect.TypeOf(v))
+	}
+	err := json.Unmarshal(source, v)
+	if err != nil {
+		return errors.Wrapf(err, "error scanning type=%s unmarshal failed", reflect.TypeOf(v))
+	}
+	return nil
+}
+
+// Value implements interface driver.Valuer
+func (v Reason) Value() (driver.Value, error) {
+	return value(v)
+}
+
+// Scan implements interface sql.Scanner
+func (v *Reason) Scan(src interface{}) error {
+	return scan(v, src)
+}
+
+// Value implements interface driver.Valuer
+func (v Spec) Value() (driver.Value, error) {
+	return value(v)
+}
+
+// Scan implements interface sql.Scanner
+func (v *Spec) Scan(src interface{}) error {
+	return scan(v, src)
+}
+
+// Value implements interface driver.Valuer
+func (v Tags) Value() (driver.Value, error) {
+	return value(v)
+}
+
+// Scan implements interface sql.Scanner
+func (v *Tags) Scan(src interface{}) error {
+	return scan(v, src)
+}
+
+func (p *sqlEntityStore) createTable() error {
+
+	sql := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS entity (
+		key 			TEXT PRIMARY KEY,
+		id 				TEXT,
+		name 			TEXT,
+		type			TEXT,
+		organization_id TEXT,
+		created_time 	TIMESTAMP,
+		modified_time 	TIMESTAMP,
+		revision 		BIGINT,
+		version 		BIGINT,
+		status 			TEXT,
+		"delete" 		TEXT,
+		spec 			%[1]s,
+		reason 			%[1]s,
+		tags			%[1]s,
+		value 			%[1]s
+	)`, p.dialect.jsonType())
+	_, err := p.db.Exec(sql)
+	if err != nil {
+		return errors.Wrap(err, "fail to create the entity table")
+	}
+
+	// the changes are recorded for the watchers resuming from a revision
+	sql = fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS entity_change (
+		revision 		%s,
+		type 			TEXT,
+		data_type 		TEXT,
+		organization_id TEXT,
+		name 			TEXT,
+		id 				TEXT,
+		entity_revision BIGINT
+	)`, p.dialect.serialKey())
+	_, err = p.db.Exec(sql)
+	if err != nil {
+		return errors.Wrap(err, "fail to create the entity change table")
+	}
+
+	sql = `
+		CREATE TABLE IF NOT EXISTS migration (
+		version 		BIGINT PRIMARY KEY,
+		description 	TEXT,
+		applied_time 	TIMESTAMP
+	)`
+	_, err = p.db.Exec(sql)
+	if err != nil {
+		return errors.Wrap(err, "fail to create the migration table")
+	}
+	return nil
+}
+
+func (p *sqlEntityStore) dropTable() error {
+	sql := `
+	DROP TABLE IF EXISTS entity`
+	_, err := p.db.Exec(sql)
+	if err != nil {
+		log.Debug(err)
+		return errors.Wrap(err, "fail to drop the entity table")
+	}
+	_, err = p.db.Exec(`DROP TABLE IF EXISTS entity_change`)
+	if err != nil {
+		log.Debug(err)
+		return errors.Wrap(err, "fail to drop the entity change table")
+	}
+	_, err = p.db.Exec(`DROP TABLE IF EXISTS migration`)
+	if err != nil {
+		log.Debug(err)
+		return errors.Wrap(err, "fail to drop the migration table")
+	}
+	return nil
+}
+
+func dbToEntity(row dbEntity, entity Entity) error {
+
+	err := row.Value.Unmarshal(entity)
+	if err != nil {
+		return errors.Wrap(err, "error unmarshalling from dbEntity to entity")
+	}
+	entity.setID(row.ID)
+	entity.setName(row.Name)
+	entity.setOrganizationID(row.OrganizationID)
+	entity.setCreatedTime(row.CreatedTime)
+	entity.setModifiedTime(row.ModifiedTime)
+	entity.setRevision(row.Revision)
+	entity.setVersion(row.Version)
+	entity.SetStatus(Status(row.Status))
+	entity.SetDelete(row.Delete)
+	entity.SetSpec(row.Spec)
+	entity.SetReason(row.Reason)
+	entity.SetTags(row.Tags)
+	return nil
+}
+
+func entityToDbEntity(e Entity) (*dbEntity, error) {
+
+	row := &dbEntity{
+		Key:            getKey(e),
+		ID:             e.GetID(),
+		Name:           e.GetName(),
+		Type:           string(getDataType(e)),
+		OrganizationID: e.GetOrganizationID(),
+		CreatedTime:    e.GetCreateTime(),
+		ModifiedTime:   e.GetModifiedTime(),
+		Revision:       e.GetRevision(),
+		Version:        e.GetVersion(),
+		Status:         string(e.GetStatus()),
+		Spec:           e.GetSpec(),
+		Reason:         e.GetReason(),
+		Tags:           e.GetTags(),
+		Delete:         e.GetDelete(),
+		Value:          types.JSONText{},
+	}
+
+	value, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling entity")
+	}
+	err = row.Value.Scan(value)
+	if err != nil {
+		log.Debugf("error scanning entity: %s", err)
+		return nil, errors.Wrap(err, "error scanning entity")
+	}
+	return row, nil
+}
+
+type sqlUniqueViolation struct {
+	error
+}
+
+func (*sqlUniqueViolation) UniqueViolation() bool {
+	return true
+}
+
+const insertEntity = `INSERT INTO entity
+		(key, id, name, type, organization_id, created_time, modified_time, revision, version,
+		spec, status, reason, tags, "delete", value)
+	VALUES
+		(:key, :id, :name, :type, :organization_id, :created_time, :modified_time, :revision, :version,
+		:spec, :status, :reason, :tags, :delete, :value)`
+
+func (p *sqlEntityStore) Add(ctx context.Context, entity Entity) (id string, err error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	log.Debugf("Adding Entity: %+v", entity)
+
+	err = precondition(entity)
+	if err != nil {
+		return "", errors.Wrap(err, "Precondition failed")
+	}
+	setNewVersion(entity)
+	id = uuid.NewV4().String()
+	entity.setID(id)
+	now := time.Now()
+	entity.setCreatedTime(now)
+	entity.setModifiedTime(now)
+	row, err := entityToDbEntity(entity)
+	if err != nil {
+		return "", err
+	}
+	err = p.write(ctx, func(exec sqlExecutor) (Event, error) {
+		if _, err := exec.NamedExec(insertEntity, row); err != nil {
+			if p.dialect.isUniqueViolation(err) {
+				return Event{}, &sqlUniqueViolation{err}
+			}
+			return Event{}, errors.Wrap(err, "error adding entity into db")
+		}
+		return newEvent(EventAdd, entity), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Update updates existing entities to the store
+func (p *sqlEntityStore) Update(ctx context.Context, lastRevision uint64, entity Entity) (revision int64, err error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+	log.Debugf("Starting to update entity: %+v", entity)
+	if entity.GetOrganizationID() == "" {
+		return 0, errors.Errorf("organizationID cannot be empty")
+	}
+	entity.setModifiedTime(time.Now())
+	entity.setRevision(lastRevision)
+	sql := `
+	UPDATE entity
+	SET
+		id = :id, name = :name, organization_id = :organization_id, created_time = :created_time,
+		modified_time = :modified_time, revision = revision + 1, version = :version,
+		spec = :spec, status = :status, reason = :reason , tags = :tags, "delete" = :delete, value = :value
+	WHERE
+		key = :key AND
+		revision = :revision
+	`
+	row, err := entityToDbEntity(entity)
+	log.Debugf("Updating with row: %+v", row)
+	if err != nil {
+		return 0, err
+	}
+
+	err = p.write(ctx, func(exec sqlExecutor) (Event, error) {
+		result, err := exec.NamedExec(sql, row)
+		if err != nil {
+			return Event{}, errors.Wrap(err, "error updating entity")
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return Event{}, errors.Wrap(err, "error updating entity")
+		}
+		if rowsAffected != 1 {
+			log.Errorf("rowsAffected != 1: intead %v", rowsAffected)
+			return Event{}, errors.Errorf("error updating entity: no such entity or there's intermidate update")
+		}
+		entity.setRevision(lastRevision + 1)
+		return newEvent(EventUpdate, entity), nil
+	})
+	if err != nil {
+		entity.setRevision(lastRevision)
+		return 0, err
+	}
+	return int64(entity.GetRevision()), nil
+}
+
+// Find gets a single entity by name from the store and returns a tuple of found, error
+func (p *sqlEntityStore) Find(ctx context.Context, organizationID string, name string, opts Options, entity Entity) (bool, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if organizationID == "" {
+		return false, errors.Errorf("organizationID cannot be empty")
+	}
+	key := buildKey(getDataType(entity), organizationID, name)
+	if opts.Filter == nil {
+		opts.Filter = FilterEverything()
+	}
+	opts.Filter.Add(FilterStat{
+		Scope:   FilterScopeField,
+		Subject: "Key",
+		Verb:    FilterVerbEqual,
+		Object:  key,
+	})
+
+	sql, args, err := makeListQuery(p.dialect, organizationID, opts.Filter, reflect.TypeOf(entity).Elem())
+	if err != nil {
+		return false, errors.Wrap(err, "error makeListQuery")
+	}
+	sql = p.exec.Rebind(sql)
+	rows, err := p.exec.Queryx(sql, args...)
+	if err != nil {
+		return false, errors.Wrap(err, "error getting: ")
+	}
+
+	if rows.Next() == false {
+		return false, nil
+	}
+	row := dbEntity{}
+	err = rows.StructScan(&row)
+	if err != nil {
+		return false, errors.Wrap(err, "error getting entity from db")
+	}
+	if rows.Next() != false {
+		return false, errors.New("error getting: get more than one entity")
+	}
+	return true, dbToEntity(row, entity)
+}
+
+// Get gets a single entity by key from the store
+func (p *sqlEntityStore) Get(ctx context.Context, organizationID string, name string, opts Options, entity Entity) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if organizationID == "" {
+		return errors.Errorf("organizationID cannot be empty")
+	}
+	found, err := p.Find(ctx, organizationID, name, opts, entity)
+	if err != nil || !found {
+		return errors.New("error getting: no such entity")
+	}
+	return nil
+}
+
+func makeListQuery(d dialect, organizationID string, filter Filter, entityType reflect.Type) (sql string, args []interface{}, err error) {
+
+	q := &listQuery{
+		dialect:    d,
+		entityType: entityType,
+		args: map[string]interface{}{
+			"type": DataType(entityType.Name()),
+		},
+	}
+	where := []string{
+		"type = :type",
+	}
+
+	if organizationID != "" {
+		q.args["organization_id"] = organizationID
+		where = append(where, "organization_id = :organization_id")
+	}
+
+	if filter != nil {
+		for _, fs := range filter.FilterStats() {
+			var cond string
+			cond, err = q.condition(fs)
+			if err != nil {
+				return
+			}
+			where = append(where, cond)
+		}
+	}
+	sql = fmt.Sprintf("SELECT * FROM entity WHERE %s", strings.Join(where, " AND "))
+	sql, args, err = sqlx.Named(sql, q.args)
+	if err != nil {
+		err = errors.Wrap(err, "error making sql query: sqlx.Named")
+		return
+	}
+	sql, args, err = sqlx.In(sql, args...)
+	if err != nil {
+		err = errors.Wrap(err, "error making sql query: sqlx.In")
+		return
+	}
+	return
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// listQuery holds the named arguments of a list query while its conditions are built
+type listQuery struct {
+	dialect    dialect
+	entityType reflect.Type
+	args       map[string]interface{}
+}
+
+// arg adds a named argument and returns its placeholder
+func (q *listQuery) arg(value interface{}) string {
+	name := fmt.Sprintf("arg%d", len(q.args))
+	q.args[name] = value
+	return ":" + name
+}
+
+// condition translates a filter statement to a sql condition
+func (q *listQuery) condition(fs FilterStat) (string, error) {
+	if fs.Verb.isGroup() {
+		stats, err := fs.subStats()
+		if err != nil {
+			return "", err
+		}
+		var conds []string
+		for _, stat := range stats {
+			cond, err := q.condition(stat)
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, cond)
+		}
+		switch fs.Verb {
+		case FilterVerbNot:
+			return fmt.Sprintf("NOT (%s)", conds[0]), nil
+		case FilterVerbOr:
+			if len(conds) == 0 {
+				return "FALSE", nil
+			}
+			return fmt.Sprintf("(%s)", strings.Join(conds, " OR ")), nil
+		}
+		if len(conds) == 0 {
+			return "TRUE", nil
+		}
+		return fmt.Sprintf("(%s)", strings.Join(conds, " AND ")), nil
+	}
+
+	column := ""
+	switch fs.Scope {
+	case FilterScopeField:
+		field, ok := reflect.TypeOf(dbEntity{}).FieldByName(fs.Subject)
+		if !ok {
+			return "", errors.Errorf("error listing: no such field: %s", fs.Subject)
+		}
+		// find the column name by struct tag, quoted as "delete" is a keyword in some databases
+		column = fmt.Sprintf(`"%s"`, field.Tag.Get("db"))
+	case FilterScopeTag:
+		// the tag name comes from the user, pass it as an argument
+		column = q.dialect.jsonText("tags", q.arg(fs.Subject))
+	case FilterScopeExtra:
+		field, ok := q.entityType.FieldByName(fs.Subject)
+		if !ok {
+			return "", errors.Errorf("error listing: no such extra field: %s", fs.Subject)
+		}
+		// remove the "omitempty"
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		// the value is inside the JSONB field 'value'
+		column = q.dialect.jsonText("value", fmt.Sprintf("'%s'", name))
+	default:
+		return "", errors.Errorf("error listing: invalid filter scope: %s", fs.Scope)
+	}
+
+	switch fs.Verb {
+	case FilterVerbEqual:
+		return fmt.Sprintf("%s = %s", column, q.arg(fs.Object)), nil
+	case FilterVerbNotEqual:
+		// unlike !=, matches a missing tag
+		return fmt.Sprintf("%s IS DISTINCT FROM %s", column, q.arg(fs.Object)), nil
+	case FilterVerbIn:
+		return fmt.Sprintf("%s IN (%s)", column, q.arg(fs.Object)), nil
+	case FilterVerbBefore:
+		return fmt.Sprintf("%s < %s", q.dialect.castTime(column), q.dialect.castTime(q.arg(fs.Object))), nil
+	case FilterVerbAfter:
+		return fmt.Sprintf("%s > %s", q.dialect.castTime(column), q.dialect.castTime(q.arg(fs.Object))), nil
+	case FilterVerbExists:
+		if fs.Scope != FilterScopeTag {
+			return "", errors.Errorf("error listing: '%s' verb only applies to tags", fs.Verb)
+		}
+		return fmt.Sprintf("%s IS NOT NULL", column), nil
+	case FilterVerbPrefix, FilterVerbContains:
+		object, ok := fs.Object.(string)
+		if !ok {
+			return "", errors.Errorf("error listing: object of a '%s' verb must be a string", fs.Verb)
+		}
+		pattern := likeEscaper.Replace(object) + "%"
+		if fs.Verb == FilterVerbContains {
+			pattern = "%" + pattern
+		}
+		return fmt.Sprintf(`CAST(%s AS TEXT) LIKE %s ESCAPE '\'`, column, q.arg(pattern)), nil
+	case FilterVerbGreaterThan, FilterVerbGreaterOrEqual, FilterVerbLessThan, FilterVerbLessOrEqual:
+		object, ok := toNumber(fs.Object)
+		if !ok {
+			return "", errors.Errorf("error listing: object of a '%s' verb must be a number", fs.Verb)
+		}
+		operator := map[Verb]string{
+			FilterVerbGreaterThan:    ">",
+			FilterVerbGreaterOrEqual: ">=",
+			FilterVerbLessThan:       "<",
+			FilterVerbLessOrEqual:    "<=",
+		}[fs.Verb]
+		// values which are not numbers never match, rather than failing the cast
+		return fmt.Sprintf("%s %s %s", q.dialect.castNumber(column), operator, q.arg(object)), nil
+	}
+	return "", errors.Errorf("error listing: invalid filter")
+}
+
+// makePageClause builds the ORDER BY, LIMIT and OFFSET clauses of a list query. Rows are always ordered by key last,
+// which keeps pages stable.
+func makePageClause(d dialect, opts Options) (string, error) {
+	field, descending, err := opts.orderBy()
+	if err != nil {
+		return "", err
+	}
+	offset, err := opts.offset()
+	if err != nil {
+		return "", err
+	}
+	if opts.Limit < 0 {
+		return "", errors.Errorf("invalid limit %d", opts.Limit)
+	}
+
+	order := []string{}
+	if field != "" {
+		f, ok := reflect.TypeOf(dbEntity{}).FieldByName(field)
+		if !ok {
+			return "", errors.Errorf("cannot order by %s", opts.OrderBy)
+		}
+		direction := "ASC"
+		if descending {
+			direction = "DESC"
+		}
+		order = append(order, fmt.Sprintf(`"%s" %s`, f.Tag.Get("db"), direction))
+	}
+	order = append(order, "key ASC")
+
+	return fmt.Sprintf(" ORDER BY %s%s", strings.Join(order, ", "), d.limit(opts.Limit, offset)), nil
+}
+
+// List fetches a list of entities of a single data type satisfying the filter.
+// entities is a placeholder for results and must be a pointer to an empty slice of the desired entity type.
+func (p *sqlEntityStore) List(ctx context.Context, organizationID string, opts Options, entities interface{}) error {
+	if organizationID == "" {
+		return errors.Errorf("organizationID cannot be empty")
+	}
+	return p.list(ctx, organizationID, opts, entities)
+}
+
+// ListGlobal fetches a list of entities of a single data type satisfying the filter across all organizations.
+// entities is a placeholder for results and must be a pointer to an empty slice of the desired entity type.
+func (p *sqlEntityStore) ListGlobal(ctx context.Context, opts Options, entities interface{}) error {
+	return p.list(ctx, "", opts, entities)
+}
+
+func (p *sqlEntityStore) list(ctx context.Context, organizationID string, opts Options, entities interface{}) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	rv := reflect.ValueOf(entities)
+	if entities == nil || rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("need a non-nil entity slice pointer")
+	}
+
+	entityPtrType := rv.Elem().Type().Elem()
+	if !entityPtrType.Implements(reflect.TypeOf((*Entity)(nil)).Elem()) {
+		return errors.New("non-entity element type: maybe use pointers")
+	}
+
+	sql, args, err := makeListQuery(p.dialect, organizationID, opts.Filter, entityPtrType.Elem())
+	if err != nil {
+		return errors.Wrap(err, "error makeListQuery")
+	}
+	page, err := makePageClause(p.dialect, opts)
+	if err != nil {
+		return errors.Wrap(err, "error makePageClause")
+	}
+	sql += page
+
+	sql = p.exec.Rebind(sql)
+	rows, err := p.exec.Queryx(sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "error listing entity from db")
+	}
+
+	slice := reflect.MakeSlice(rv.Elem().Type(), 0, 0)
+	for rows.Next() {
+		row := dbEntity{}
+		err = rows.StructScan(&row)
+		if err != nil {
+			return errors.Wrap(err, "error listing entity from db")
+		}
+
+		entityPtr := reflect.New(entityPtrType.Elem())
+		entity := entityPtr.Interface().(Entity)
+
+		dbToEntity(row, entity)
+		slice = reflect.Append(slice, entityPtr)
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+// Delete deletes a single entity from the store.
+func (p *sqlEntityStore) Delete(ctx context.Context, organizationID string, name string, entity Entity) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if organizationID == "" {
+		return errors.Errorf("organizationID cannot be empty")
+	}
+	key := buildKey(getDataType(entity), organizationID, name)
+
+	return p.write(ctx, func(exec sqlExecutor) (Event, error) {
+		result, err := exec.Exec(exec.Rebind(`DELETE FROM entity WHERE key = ?`), key)
+		if err != nil {
+			return Event{}, errors.Wrap(err, "error deleting an entity")
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return Event{}, errors.Wrap(err, "error deleting an entity")
+		}
+		if rowsAffected == 0 {
+			return Event{}, errors.New("error deleting: no such entity")
+		}
+		if rowsAffected > 1 {
+			return Event{}, errors.New("error deleting: deleted mutiple entities")
+		}
+		return Event{
+			Type:           EventDelete,
+			DataType:       getDataType(entity),
+			OrganizationID: organizationID,
+			Name:           name,
+			ID:             entity.GetID(),
+		}, nil
+	})
+}
+
+// SoftDelete marks a single entity for deletion
+func (p *sqlEntityStore) SoftDelete(ctx context.Context, entity Entity) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	entity.SetDelete(true)
+	entity.SetStatus(StatusDELETING)
+	_, err := p.Update(ctx, entity.GetRevision(), entity)
+	return err
+}
+
+// UpdateWithError is used by entity handlers to save changes and/or error status
+// e.g. `defer func() { h.store.UpdateWithError(e, err) }()`
+func (p *sqlEntityStore) UpdateWithError(ctx context.Context, e Entity, err error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if err != nil {
+		e.SetStatus(StatusERROR)
+		e.SetReason([]string{err.Error()})
+	}
+	if _, err2 := p.Update(ctx, e.GetRevision(), e); err2 != nil {
+		span.LogKV("error", err2)
+		log.Error(err2)
+	}
+	return
+}
+
+// write runs fn in a transaction, which records the change returned by fn, and notifies the watchers of it once
+// committed
+func (p *sqlEntityStore) write(ctx context.Context, fn func(exec sqlExecutor) (Event, error)) error {
+	return p.Transaction(ctx, func(tx EntityStore) error {
+		txStore := tx.(*sqlEntityStore)
+		e, err := fn(txStore.exec)
+		if err != nil {
+			return err
+		}
+		if err := txStore.recordChange(&e); err != nil {
+			return err
+		}
+		txStore.notify(e)
+		return nil
+	})
+}
+
+// recordChange inserts the change in the entity_change table, which assigns its store revision, and deletes the
+// changes which are no longer kept once in a while
+func (p *sqlEntityStore) recordChange(e *Event) error {
+	rows, err := p.exec.Queryx(p.exec.Rebind(`
+	INSERT INTO entity_change (type, data_type, organization_id, name, id, entity_revision)
+	VALUES (?, ?, ?, ?, ?, ?)
+	RETURNING revision`), string(e.Type), string(e.DataType), e.OrganizationID, e.Name, e.ID, e.Revision)
+	if err != nil {
+		return errors.Wrap(err, "error recording an entity change")
+	}
+	if rows.Next() {
+		err = rows.Scan(&e.StoreRevision)
+	} else {
+		err = rows.Err()
+	}
+	rows.Close()
+	if err != nil {
+		return errors.Wrap(err, "error recording an entity change")
+	}
+	if e.StoreRevision%watchHistorySize == 0 {
+		_, err = p.exec.Exec(p.exec.Rebind(`DELETE FROM entity_change WHERE revision <= ?`), e.StoreRevision-watchHistorySize)
+		if err != nil {
+			return errors.Wrap(err, "error deleting old entity changes")
+		}
+	}
+	return nil
+}
+
+// notify publishes the event to every dispatch replica listening on the same database, once the transaction of the
+// store is committed. Errors are only logged, watchers are expected to resync periodically.
+func (p *sqlEntityStore) notify(e Event) {
+	if !p.dialect.notifies() {
+		p.txEvents = append(p.txEvents, e)
+		return
+	}
+	// notifications sent in a transaction are delivered when it is committed
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Errorf("error marshalling entity event: %s", err)
+		return
+	}
+	if _, err := p.exec.Exec(`SELECT pg_notify($1, $2)`, notifyChannel, string(payload)); err != nil {
+		log.Errorf("error notifying entity event: %s", err)
+	}
+}
+
+// dbChange is a row of the entity_change table
+type dbChange struct {
+	Revision       uint64 `db:"revision"`
+	Type           string `db:"type"`
+	DataType       string `db:"data_type"`
+	OrganizationID string `db:"organization_id"`
+	Name           string `db:"name"`
+	ID             string `db:"id"`
+	EntityRevision uint64 `db:"entity_revision"`
+}
+
+// changes returns the recorded changes of the entities of a data type made after the store revision since, of all
+// organizations if organizationID is empty
+func (p *sqlEntityStore) changes(dataType DataType, organizationID string, since uint64) ([]Event, error) {
+	var oldest, last sql.NullInt64
+	err := p.db.QueryRowx(`SELECT MIN(revision), MAX(revision) FROM entity_change`).Scan(&oldest, &last)
+	if err != nil {
+		return nil, errors.Wrap(err, "error selecting the entity change revisions")
+	}
+	if since > uint64(last.Int64) || oldest.Valid && uint64(oldest.Int64) > since+1 {
+		return nil, ErrRevisionTooOld
+	}
+
+	query := `SELECT revision, type, data_type, organization_id, name, id, entity_revision FROM entity_change WHERE revision > ? AND data_type = ?`
+	args := []interface{}{since, string(dataType)}
+	if organizationID != "" {
+		query += ` AND organization_id = ?`
+		args = append(args, organizationID)
+	}
+	rows, err := p.db.Queryx(p.db.Rebind(query+` ORDER BY revision`), args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error selecting entity changes")
+	}
+	defer rows.Close()
+	var events []Event
+	for rows.Next() {
+		var row dbChange
+		if err := rows.StructScan(&row); err != nil {
+			return nil, errors.Wrap(err, "error scanning an entity change")
+		}
+		events = append(events, Event{
+			Type:           EventType(row.Type),
+			DataType:       DataType(row.DataType),
+			OrganizationID: row.OrganizationID,
+			Name:           row.Name,
+			ID:             row.ID,
+			Revision:       row.EntityRevision,
+			StoreRevision:  row.Revision,
+		})
+	}
+	return events, errors.Wrap(rows.Err(), "error selecting entity changes")
+}
+
+// listen starts a single LISTEN connection per store, which forwards notifications to the in-process watchers
+func (p *sqlEntityStore) listen() error {
+	p.listenOnce.Do(func() {
+		listener := pq.NewListener(p.dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Warnf("postgres entity event listener: %s", err)
+			}
+		})
+		if err := listener.Listen(notifyChannel); err != nil {
+			p.listenErr = errors.Wrap(err, "error listening for entity events")
+			listener.Close()
+			return
+		}
+		go func() {
+			for n := range listener.Notify {
+				// nil notification is sent after the connection is re-established, some events may have been lost
+				if n == nil {
+					continue
+				}
+				var e Event
+				if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+					log.Errorf("error unmarshalling entity event %s: %s", n.Extra, err)
+					continue
+				}
+				p.events.publish(e)
+			}
+		}()
+	})
+	return p.listenErr
+}
+
+// Watch streams changes of entities of a single data type, made by any process connected to the same database.
+// Databases which cannot notify other processes only stream the changes made by this process, but replay the changes
+// of all of them, which are recorded in the entity_change table.
+func (p *sqlEntityStore) Watch(ctx context.Context, dataType DataType, organizationID string, sinceRevision uint64) (<-chan Event, error) {
+	if p.txParent != nil {
+		return p.txParent.Watch(ctx, dataType, organizationID, sinceRevision)
+	}
+	if p.dialect.notifies() {
+		if err := p.listen(); err != nil {
+			return nil, err
+		}
+	}
+	return p.events.watch(ctx, dataType, organizationID, sinceRevision, p.changes)
+}
+
+// MigrationVersion returns the version of the last migration applied to the database
+func (p *sqlEntityStore) MigrationVersion(ctx context.Context) (int, error) {
+	return migrationVersion(ctx, p)
+}
+
+// Migrate migrates the database to the target version, each migration being applied in a transaction
+func (p *sqlEntityStore) Migrate(ctx context.Context, target int, dryRun bool) ([]MigrationStep, error) {
+	return migrate(ctx, p, target, dryRun)
+}
+
+func (p *sqlEntityStore) appliedMigrations() (map[int]bool, error) {
+	var versions []int
+	if err := p.db.Select(&versions, `SELECT version FROM migration`); err != nil {
+		return nil, errors.Wrap(err, "error selecting migrations")
+	}
+	applied := make(map[int]bool)
+	for _, v := range versions {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+func (p *sqlEntityStore) applyMigration(step MigrationStep) (err error) {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "error starting a transaction")
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	schema := step.schemaUp
+	if step.Rollback {
+		schema = step.schemaDown
+	}
+	if schema != "" {
+		if _, err = tx.Exec(schema); err != nil {
+			return errors.Wrap(err, "error changing the schema")
+		}
+	}
+
+	if step.DataType != "" {
+		if err = convertEntities(tx, step); err != nil {
+			return err
+		}
+	}
+
+	if step.Rollback {
+		_, err = tx.Exec(tx.Rebind(`DELETE FROM migration WHERE version = ?`), step.Version)
+	} else {
+		_, err = tx.Exec(tx.Rebind(`INSERT INTO migration (version, description, applied_time) VALUES (?, ?, ?)`),
+			step.Version, step.Description, time.Now())
+	}
+	if err != nil {
+		return errors.Wrap(err, "error recording the migration")
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "error committing the migration")
+	}
+	return nil
+}
+
+// convertEntities converts the entities of the data type of a migration step
+func convertEntities(tx *sqlx.Tx, step MigrationStep) error {
+	var rows []dbEntity
+	err := tx.Select(&rows, tx.Rebind(`SELECT key, value FROM entity WHERE type = ?`), string(step.DataType))
+	if err != nil {
+		return errors.Wrap(err, "error selecting entities")
+	}
+	for _, row := range rows {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(row.Value, &doc); err != nil {
+			return errors.Wrapf(err, "error unmarshalling entity %s", row.Key)
+		}
+		converted, err := step.convert(doc)
+		if err != nil {
+			return err
+		}
+		if !converted {
+			continue
+		}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return errors.Wrapf(err, "error marshalling entity %s", row.Key)
+		}
+		_, err = tx.Exec(tx.Rebind(`UPDATE entity SET value = ?, version = ? WHERE key = ?`),
+			types.JSONText(b), doc["version"], row.Key)
+		if err != nil {
+			return errors.Wrapf(err, "error updating entity %s", row.Key)
+		}
+	}
+	return nil
+}
+
+// Export calls fn with each stored entity selected by opts, ordered by data type, organization and name
+func (p *sqlEntityStore) Export(ctx context.Context, opts ExportOptions, fn func(Record) error) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	query := `SELECT * FROM entity WHERE 1 = 1`
+	var args []interface{}
+	if opts.OrganizationID != "" {
+		query += ` AND organization_id = ?`
+		args = append(args, opts.OrganizationID)
+	}
+	if len(opts.DataTypes) > 0 {
+		query += ` AND type IN (?)`
+		args = append(args, opts.DataTypes)
+	}
+	query, args, err := sqlx.In(query+` ORDER BY type, organization_id, name`, args...)
+	if err != nil {
+		return errors.Wrap(err, "error building the export query")
+	}
+	rows, err := p.exec.Queryx(p.exec.Rebind(query), args...)
+	if err != nil {
+		return errors.Wrap(err, "error selecting entities")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row dbEntity
+		if err := rows.StructScan(&row); err != nil {
+			return errors.Wrap(err, "error scanning entity")
+		}
+		record := Record{
+			DataType: DataType(row.Type),
+			Revision: row.Revision,
+			Entity:   json.RawMessage(row.Value),
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(rows.Err(), "error selecting entities")
+}
+
+// Import stores an exported entity as is, replacing the entity with the same key if any
+func (p *sqlEntityStore) Import(ctx context.Context, record Record) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	key, base, err := record.key()
+	if err != nil {
+		return err
+	}
+	row := &dbEntity{
+		Key:            key,
+		ID:             base.ID,
+		Name:           base.Name,
+		Type:           string(record.DataType),
+		OrganizationID: base.OrganizationID,
+		CreatedTime:    base.CreatedTime,
+		ModifiedTime:   base.ModifiedTime,
+		Revision:       record.Revision,
+		Version:        base.Version,
+		Status:         string(base.Status),
+		Spec:           base.Spec,
+		Reason:         base.Reason,
+		Tags:           base.Tags,
+		Delete:         base.Delete,
+		Value:          types.JSONText(record.Entity),
+	}
+
+	return p.Transaction(ctx, func(tx EntityStore) error {
+		exec := tx.(*sqlEntityStore).exec
+		if _, err := exec.Exec(exec.Rebind(`DELETE FROM entity WHERE key = ?`), key); err != nil {
+			return errors.Wrapf(err, "error replacing entity %s", key)
+		}
+		if _, err := exec.NamedExec(insertEntity, row); err != nil {
+			return errors.Wrapf(err, "error importing entity %s", key)
+		}
+		return nil
+	})
+}
+
+// Transaction calls fn with a store bound to a database transaction, which is committed if fn returns nil and
+// rolled back otherwise. Transactions started from fn are part of the enclosing one.
+func (p *sqlEntityStore) Transaction(ctx context.Context, fn func(tx EntityStore) error) (err error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if p.txParent != nil {
+		return fn(p)
+	}
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "error starting a transaction")
+	}
+	txStore := &sqlEntityStore{
+		db:       p.db,
+		dialect:  p.dialect,
+		dsn:      p.dsn,
+		events:   p.events,
+		exec:     tx,
+		txParent: p,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if err = fn(txStore); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "error committing the transaction")
+	}
+	for _, e := range txStore.txEvents {
+		p.events.publish(e)
+	}
+	return nil
+}
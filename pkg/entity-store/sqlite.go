@@ -0,0 +1,139 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+//go:build cgo
+// +build cgo
+
+package entitystore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// sqliteDriverName is the sqlite driver registering the functions used by the entity store queries
+	sqliteDriverName = "sqlite3_entitystore"
+
+	// sqliteBusyTimeout is how long, in milliseconds, a connection waits for another one to release the database
+	sqliteBusyTimeout = 5000
+
+	// sqliteEnabled is false in binaries built without cgo, which the sqlite driver requires
+	sqliteEnabled = true
+)
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("unixnano", unixNano, true)
+		},
+	})
+}
+
+// unixNano converts a time, as written by the sqlite driver or by encoding/json, to nanoseconds since epoch.
+// The sqlite date functions, e.g. julianday, are only precise to the millisecond.
+func unixNano(v interface{}) interface{} {
+	var s string
+	switch t := v.(type) {
+	case string:
+		s = t
+	case []byte:
+		s = string(t)
+	default:
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UnixNano()
+	}
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, strings.TrimSuffix(s, "Z"), time.UTC); err == nil {
+			return t.UnixNano()
+		}
+	}
+	return nil
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) jsonType() string {
+	return "TEXT"
+}
+
+func (sqliteDialect) jsonText(column, key string) string {
+	// the value column is stored as a blob, which json functions refuse
+	return fmt.Sprintf("CAST(%s AS TEXT) ->> %s", column, key)
+}
+
+func (sqliteDialect) castTime(expr string) string {
+	return fmt.Sprintf("unixnano(%s)", expr)
+}
+
+func (sqliteDialect) castNumber(expr string) string {
+	return fmt.Sprintf(`CASE WHEN trim(%[1]s) GLOB '*[0-9]*' AND trim(%[1]s) NOT GLOB '*[^0-9.-]*' THEN CAST(%[1]s AS REAL) END`, expr)
+}
+
+func (sqliteDialect) limit(limit, offset int) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	case limit > 0:
+		return fmt.Sprintf(" LIMIT %d", limit)
+	case offset > 0:
+		// sqlite has no OFFSET without a LIMIT
+		return fmt.Sprintf(" LIMIT -1 OFFSET %d", offset)
+	}
+	return ""
+}
+
+func (sqliteDialect) isUniqueViolation(err error) bool {
+	sqliteErr, ok := err.(sqlite3.Error)
+	return ok && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique)
+}
+
+func (sqliteDialect) notifies() bool {
+	return false
+}
+
+func (sqliteDialect) serialKey() string {
+	// AUTOINCREMENT never reuses the revisions of deleted changes
+	return "INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+// newSQLite creates a sqlite entity store in the database file config.Address.
+// The database is in WAL mode, so other processes, e.g. the sqlite3 shell, can read it while dispatch is running.
+func newSQLite(config BackendConfig) (EntityStore, error) {
+
+	// LIKE is case insensitive by default in sqlite, unlike postgres
+	opts := fmt.Sprintf("%s?_busy_timeout=%d&_journal_mode=WAL&_cslike=true", config.Address, sqliteBusyTimeout)
+	log.Debugf("sqlite database options: %s", opts)
+	db, err := sql.Open(sqliteDriverName, opts)
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open the sqlite database %s", config.Address)
+	}
+	store := &sqlEntityStore{
+		// the driver name tells sqlx how to bind arguments
+		db:      sqlx.NewDb(db, "sqlite3"),
+		dialect: sqliteDialect{},
+		events:  newBroadcaster(),
+	}
+	store.exec = store.db
+
+	// create tables if not exists
+	err = store.createTable()
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
@@ -0,0 +1,21 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+//go:build !cgo
+// +build !cgo
+
+package entitystore
+
+import (
+	"github.com/pkg/errors"
+)
+
+// sqliteEnabled is false in binaries built without cgo, which the sqlite driver requires
+const sqliteEnabled = false
+
+// newSQLite fails, the sqlite driver is C code and the binary was built without cgo
+func newSQLite(config BackendConfig) (EntityStore, error) {
+	return nil, errors.Errorf("unable to open the sqlite database %s: dispatch was built without cgo, which the sqlite backend requires", config.Address)
+}
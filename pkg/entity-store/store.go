@@ -57,6 +57,10 @@ const (
 	// Used when external resources cannot be found
 	StatusMISSING Status = "MISSING"
 
+	// StatusCANCELLED object is CANCELLED
+	//	this status is used by function runs, which were cancelled before they completed
+	StatusCANCELLED Status = "CANCELLED"
+
 	// StatusUNKNOWN is not an error, just that the current status is inderminate
 	StatusUNKNOWN Status = "UNKNOWN"
 )
@@ -285,6 +289,19 @@ type EntityStore interface {
 	// UpdateWithError is used by entity handlers to save changes and/or error status
 	// e.g. `defer func() { h.store.UpdateWithError(e, err) }()`
 	UpdateWithError(ctx context.Context, e Entity, err error)
+	// Watch streams add/update/delete events for entities of a single data type until ctx is done.
+	// If organizationID is empty, events for all organizations are sent. If sinceRevision is not 0, the changes made
+	// after the store revision sinceRevision (see Event.StoreRevision) are sent first, or ErrRevisionTooOld is returned
+	// if they are no longer kept. A watcher which does not keep up with the changes gets an EventError, and its
+	// channel is closed.
+	Watch(ctx context.Context, dataType DataType, organizationID string, sinceRevision uint64) (<-chan Event, error)
+	// Export calls fn with each stored entity selected by opts, ordered by data type, organization and name
+	Export(ctx context.Context, opts ExportOptions, fn func(Record) error) error
+	// Import stores an exported entity as is, replacing the entity with the same key if any
+	Import(ctx context.Context, record Record) error
+	// Transaction calls fn with a store whose writes are committed together if fn returns nil, and discarded
+	// otherwise. Reads made through tx see its writes.
+	Transaction(ctx context.Context, fn func(tx EntityStore) error) error
 }
 
 type uniqueViolation interface {
@@ -317,6 +334,13 @@ func NewFromBackend(config BackendConfig) (EntityStore, error) {
 		}
 		return es, nil
 
+	case "sqlite":
+		es, err := newSQLite(config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error creating a(n) %s entity store", config.Backend)
+		}
+		return es, nil
+
 	case string(store.BOLTDB):
 		boltdb.Register()
 		kv, err := libkv.NewStore(
@@ -6,11 +6,13 @@
 package entitystore
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -56,6 +58,27 @@ func (e *otherEntity) getOther() string {
 	return e.Other
 }
 
+type migratedEntity struct {
+	BaseEntity
+	Value string `json:"value"`
+}
+
+func init() {
+	RegisterMigration(Migration{
+		Version:     1000,
+		Description: "upper case values",
+		DataType:    getDataType(&migratedEntity{}),
+		Up: func(doc map[string]interface{}) error {
+			doc["value"] = strings.ToUpper(doc["value"].(string))
+			return nil
+		},
+		Down: func(doc map[string]interface{}) error {
+			doc["value"] = strings.ToLower(doc["value"].(string))
+			return nil
+		},
+	})
+}
+
 func TestPostgresEntityStore(t *testing.T) {
 
 	dev.EnsureLocal(t)
@@ -69,9 +92,49 @@ func TestPostgresEntityStore(t *testing.T) {
 	testList(t, es)
 	testListWithFilter(t, es)
 	testListWithFilterOnTags(t, es)
+	testListPaging(t, es)
+	testListWithFilterVerbs(t, es)
 	testDelete(t, es)
 	testInvalidNames(t, es)
 	testMixedTypes(t, es)
+	testWatch(t, es)
+	testWatchResume(t, es)
+	testMigrate(t, es)
+	testTransaction(t, es)
+}
+
+func TestSQLiteEntityStore(t *testing.T) {
+	if !sqliteEnabled {
+		t.Skip("sqlite requires cgo")
+	}
+
+	file, err := ioutil.TempFile(os.TempDir(), "test")
+	require.NoError(t, err, "Cannot create temp file")
+	file.Close()
+	defer os.Remove(file.Name())
+
+	es, err := NewFromBackend(BackendConfig{
+		Backend: "sqlite",
+		Address: file.Name(),
+	})
+	require.NoError(t, err, "Cannot create store")
+
+	testGet(t, es)
+	testAdd(t, es)
+	testPut(t, es)
+	testList(t, es)
+	testListSamePrefix(t, es)
+	testListWithFilter(t, es)
+	testListWithFilterOnTags(t, es)
+	testListPaging(t, es)
+	testListWithFilterVerbs(t, es)
+	testDelete(t, es)
+	testInvalidNames(t, es)
+	testMixedTypes(t, es)
+	testWatch(t, es)
+	testWatchResume(t, es)
+	testMigrate(t, es)
+	testTransaction(t, es)
 }
 
 func TestLibkvEntityStore(t *testing.T) {
@@ -95,13 +158,100 @@ func TestLibkvEntityStore(t *testing.T) {
 	testListSamePrefix(t, es)
 	testListWithFilter(t, es)
 	testListWithFilterOnTags(t, es)
+	testListPaging(t, es)
+	testListWithFilterVerbs(t, es)
 	testDelete(t, es)
 	testInvalidNames(t, es)
 	testMixedTypes(t, es)
+	testWatch(t, es)
+	testWatchResume(t, es)
+	testMigrate(t, es)
+	testTransaction(t, es)
+	testTransactionConflict(t, es)
 
 	os.Remove(file.Name())
 }
 
+func TestArchive(t *testing.T) {
+	if !sqliteEnabled {
+		t.Skip("sqlite requires cgo")
+	}
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bolt, err := NewFromBackend(BackendConfig{Backend: "boltdb", Address: dir + "/bolt.db", Bucket: "test"})
+	require.NoError(t, err)
+	sqlite, err := NewFromBackend(BackendConfig{Backend: "sqlite", Address: dir + "/sqlite.db"})
+	require.NoError(t, err)
+
+	first := &testEntity{
+		BaseEntity: BaseEntity{OrganizationID: "org1", Name: "first", Tags: Tags{"role": "test"}},
+		Value:      "first",
+	}
+	_, err = bolt.Add(ctx, first)
+	require.NoError(t, err)
+	first.Value = "updated"
+	_, err = bolt.Update(ctx, first.Revision, first)
+	require.NoError(t, err)
+	require.NoError(t, bolt.Get(ctx, "org1", "first", Options{}, first))
+	_, err = bolt.Add(ctx, &testEntity{BaseEntity: BaseEntity{OrganizationID: "org2", Name: "second"}})
+	require.NoError(t, err)
+	_, err = bolt.Add(ctx, &otherEntity{BaseEntity: BaseEntity{OrganizationID: "org1", Name: "other"}, Other: "o"})
+	require.NoError(t, err)
+	// added before the migration of its type
+	_, err = bolt.Add(ctx, &migratedEntity{BaseEntity: BaseEntity{OrganizationID: "org1", Name: "old", Version: 1}, Value: "old"})
+	require.NoError(t, err)
+
+	allTypes := []DataType{"testEntity", "otherEntity", "migratedEntity"}
+	var archive bytes.Buffer
+	count, err := WriteArchive(ctx, bolt, &archive, ExportOptions{OrganizationID: "org1", DataTypes: allTypes})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	_, err = WriteArchive(ctx, bolt, &bytes.Buffer{}, ExportOptions{})
+	assert.Error(t, err, "boltdb cannot export without data types")
+
+	_, err = sqlite.(Migrator).Migrate(ctx, -1, false)
+	require.NoError(t, err)
+	count, err = ReadArchive(ctx, sqlite, bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	var restored testEntity
+	require.NoError(t, sqlite.Get(ctx, "org1", "first", Options{}, &restored))
+	assert.Equal(t, first.ID, restored.ID)
+	assert.Equal(t, first.Revision, restored.Revision)
+	assert.True(t, first.CreatedTime.Equal(restored.CreatedTime))
+	assert.True(t, first.ModifiedTime.Equal(restored.ModifiedTime))
+	assert.Equal(t, first.Tags, restored.Tags)
+	assert.Equal(t, "updated", restored.Value)
+	found, err := sqlite.Find(ctx, "org2", "second", Options{}, &restored)
+	require.NoError(t, err)
+	assert.False(t, found, "other organizations are not exported")
+
+	var migrated migratedEntity
+	require.NoError(t, sqlite.Get(ctx, "org1", "old", Options{}, &migrated))
+	assert.Equal(t, "OLD", migrated.Value, "entities are upgraded to the version of the store")
+
+	// importing again replaces the entities
+	count, err = ReadArchive(ctx, sqlite, bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// the store is ahead of the archive, which cannot be imported back into the old store
+	archive.Reset()
+	count, err = WriteArchive(ctx, sqlite, &archive, ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	_, err = ReadArchive(ctx, bolt, bytes.NewReader(archive.Bytes()))
+	assert.Error(t, err)
+
+	_, err = ReadArchive(ctx, bolt, strings.NewReader(`{"format":"something else"}`))
+	assert.Error(t, err)
+}
+
 func testGet(t *testing.T, es EntityStore) {
 
 	e := &testEntity{
@@ -569,3 +719,469 @@ func testDelete(t *testing.T, es EntityStore) {
 	err = es.Get(context.Background(), "testOrg", "testEntityDelete", Options{}, &retreived)
 	assert.Error(t, err)
 }
+
+func receiveEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for entity event")
+	}
+	return Event{}
+}
+
+func testWatch(t *testing.T, es EntityStore) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := es.Watch(ctx, DataType("testEntity"), "testOrg", 0)
+	require.NoError(t, err)
+
+	// entities of other types or organizations must be filtered out
+	oe := &otherEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "otherEntityWatch"}}
+	_, err = es.Add(context.Background(), oe)
+	assert.NoError(t, err)
+	te := &testEntity{BaseEntity: BaseEntity{OrganizationID: "otherOrg", Name: "testEntityWatch"}}
+	_, err = es.Add(context.Background(), te)
+	assert.NoError(t, err)
+
+	e := &testEntity{
+		BaseEntity: BaseEntity{
+			OrganizationID: "testOrg",
+			Name:           "testEntityWatch",
+		},
+		Value: "testValue",
+	}
+	id, err := es.Add(context.Background(), e)
+	assert.NoError(t, err)
+
+	event := receiveEvent(t, events)
+	assert.Equal(t, EventAdd, event.Type)
+	assert.Equal(t, "testEntityWatch", event.Name)
+	assert.Equal(t, "testOrg", event.OrganizationID)
+	assert.Equal(t, id, event.ID)
+	assert.Equal(t, e.Revision, event.Revision)
+
+	_, err = es.Update(context.Background(), e.Revision, e)
+	assert.NoError(t, err)
+	event = receiveEvent(t, events)
+	assert.Equal(t, EventUpdate, event.Type)
+	assert.Equal(t, e.Revision, event.Revision)
+
+	err = es.Delete(context.Background(), "testOrg", e.Name, e)
+	assert.NoError(t, err)
+	event = receiveEvent(t, events)
+	assert.Equal(t, EventDelete, event.Type)
+	assert.Equal(t, "testEntityWatch", event.Name)
+
+	// the channel is closed once the context is done
+	cancel()
+	for range events {
+	}
+
+	// clean up
+	es.Delete(context.Background(), "testOrg", oe.Name, oe)
+	es.Delete(context.Background(), "otherOrg", te.Name, te)
+}
+
+func testWatchResume(t *testing.T, es EntityStore) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := es.Watch(ctx, DataType("testEntity"), "testOrg", 0)
+	require.NoError(t, err)
+	e := &testEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testEntityResume"}}
+	_, err = es.Add(context.Background(), e)
+	require.NoError(t, err)
+	added := receiveEvent(t, events)
+	assert.NotZero(t, added.StoreRevision)
+	cancel()
+
+	// the changes made while no watch was running are replayed, in order
+	_, err = es.Update(context.Background(), e.Revision, e)
+	require.NoError(t, err)
+	oe := &otherEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "otherEntityResume"}}
+	_, err = es.Add(context.Background(), oe)
+	require.NoError(t, err)
+	require.NoError(t, es.Delete(context.Background(), "testOrg", e.Name, e))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	events, err = es.Watch(ctx, DataType("testEntity"), "testOrg", added.StoreRevision)
+	require.NoError(t, err)
+	updated := receiveEvent(t, events)
+	assert.Equal(t, EventUpdate, updated.Type)
+	assert.Equal(t, e.Name, updated.Name)
+	assert.True(t, updated.StoreRevision > added.StoreRevision)
+	deleted := receiveEvent(t, events)
+	assert.Equal(t, EventDelete, deleted.Type)
+	assert.True(t, deleted.StoreRevision > updated.StoreRevision)
+
+	// followed by the live changes
+	_, err = es.Add(context.Background(), e)
+	require.NoError(t, err)
+	assert.Equal(t, EventAdd, receiveEvent(t, events).Type)
+
+	// a revision newer than the store one cannot be resumed from
+	_, err = es.Watch(ctx, DataType("testEntity"), "testOrg", deleted.StoreRevision+100)
+	assert.Equal(t, ErrRevisionTooOld, err)
+
+	// clean up
+	es.Delete(context.Background(), "testOrg", e.Name, e)
+	es.Delete(context.Background(), "testOrg", oe.Name, oe)
+}
+
+func TestBroadcasterHistory(t *testing.T) {
+	b := newHistoryBroadcaster()
+	for i := 0; i < watchHistorySize+10; i++ {
+		b.publish(Event{Type: EventAdd, DataType: "testEntity", OrganizationID: "testOrg", Name: fmt.Sprint(i)})
+	}
+	missed, err := b.changes("testEntity", "testOrg", watchHistorySize)
+	require.NoError(t, err)
+	require.Len(t, missed, 10)
+	assert.Equal(t, uint64(watchHistorySize+1), missed[0].StoreRevision)
+
+	// the oldest changes are no longer kept
+	_, err = b.changes("testEntity", "testOrg", 5)
+	assert.Equal(t, ErrRevisionTooOld, err)
+}
+
+func TestBroadcasterSlowWatcher(t *testing.T) {
+	b := newHistoryBroadcaster()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := b.watch(ctx, "testEntity", "", 0, b.changes)
+	require.NoError(t, err)
+
+	// the events are not dropped, the watch fails once the buffer of the watcher is full
+	for i := 0; i <= watchBufferSize; i++ {
+		b.publish(Event{Type: EventAdd, DataType: "testEntity", OrganizationID: "testOrg", Name: fmt.Sprint(i)})
+	}
+	var received []Event
+	for e := range events {
+		received = append(received, e)
+	}
+	require.Len(t, received, watchBufferSize+1)
+	last := received[watchBufferSize]
+	assert.Equal(t, EventError, last.Type)
+	assert.Equal(t, ErrWatcherTooSlow, last.Err)
+
+	// the watch resumes from the last event received
+	events, err = b.watch(ctx, "testEntity", "", received[watchBufferSize-1].StoreRevision, b.changes)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprint(watchBufferSize), receiveEvent(t, events).Name)
+}
+
+func testListPaging(t *testing.T, es EntityStore) {
+	for i, status := range []Status{StatusREADY, StatusERROR, StatusCREATING, StatusREADY, StatusERROR} {
+		e := &testEntitySecond{
+			BaseEntity: BaseEntity{
+				OrganizationID: "testOrg",
+				Name:           fmt.Sprintf("testEntityPaging%d", i),
+				Status:         status,
+			},
+		}
+		_, err := es.Add(context.Background(), e)
+		require.NoError(t, err)
+	}
+
+	names := func(items []*testEntitySecond) (n []string) {
+		for _, item := range items {
+			n = append(n, item.Name)
+		}
+		return
+	}
+
+	// walk through all pages, the last one being partial
+	var all []string
+	opts := Options{Limit: 2}
+	for {
+		var items []*testEntitySecond
+		err := es.List(context.Background(), "testOrg", opts, &items)
+		require.NoError(t, err)
+		assert.True(t, len(items) <= 2)
+		all = append(all, names(items)...)
+		opts.Cursor = NextCursor(opts, len(items))
+		if opts.Cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"testEntityPaging0", "testEntityPaging1", "testEntityPaging2", "testEntityPaging3", "testEntityPaging4"}, all)
+
+	var items []*testEntitySecond
+	err := es.List(context.Background(), "testOrg", Options{OrderBy: "status", Offset: 1, Limit: 3}, &items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"testEntityPaging1", "testEntityPaging4", "testEntityPaging0"}, names(items))
+
+	items = nil
+	err = es.List(context.Background(), "testOrg", Options{OrderBy: "-Status"}, &items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"testEntityPaging0", "testEntityPaging3", "testEntityPaging1", "testEntityPaging4", "testEntityPaging2"}, names(items))
+
+	items = nil
+	err = es.List(context.Background(), "testOrg", Options{Offset: 10}, &items)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	err = es.List(context.Background(), "testOrg", Options{OrderBy: "Tags"}, &items)
+	assert.Error(t, err)
+	err = es.List(context.Background(), "testOrg", Options{Cursor: "garbage"}, &items)
+	assert.Error(t, err)
+
+	// clean up
+	for i := 0; i < 5; i++ {
+		es.Delete(context.Background(), "testOrg", fmt.Sprintf("testEntityPaging%d", i), &testEntitySecond{})
+	}
+}
+
+func testListWithFilterVerbs(t *testing.T, es EntityStore) {
+	entities := []*testEntity{
+		{
+			BaseEntity: BaseEntity{
+				OrganizationID: "testOrg",
+				Name:           "team-a-one",
+				Status:         StatusREADY,
+				Tags:           Tags{"env": "prod", "tier": "gold", "replicas": "3"},
+			},
+		},
+		{
+			BaseEntity: BaseEntity{
+				OrganizationID: "testOrg",
+				Name:           "team-a-two",
+				Status:         StatusERROR,
+				Tags:           Tags{"tier": "silver", "replicas": "10"},
+			},
+		},
+		{
+			BaseEntity: BaseEntity{
+				OrganizationID: "testOrg",
+				Name:           "team-b-one",
+				Status:         StatusREADY,
+				Tags:           Tags{"env": "dev", "tier": "bronze", "replicas": "many"},
+			},
+		},
+	}
+	for _, e := range entities {
+		_, err := es.Add(context.Background(), e)
+		require.NoError(t, err)
+	}
+
+	list := func(stats ...FilterStat) []string {
+		var items []*testEntity
+		err := es.List(context.Background(), "testOrg", Options{Filter: FilterEverything().Add(stats...), OrderBy: "name"}, &items)
+		require.NoError(t, err)
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	tag := func(name string, verb Verb, object interface{}) FilterStat {
+		return FilterStat{Scope: FilterScopeTag, Subject: name, Verb: verb, Object: object}
+	}
+	field := func(name string, verb Verb, object interface{}) FilterStat {
+		return FilterStat{Scope: FilterScopeField, Subject: name, Verb: verb, Object: object}
+	}
+
+	assert.Equal(t, []string{"team-a-two"}, list(field("Status", FilterVerbNotEqual, StatusREADY)))
+	assert.Equal(t, []string{"team-a-one", "team-a-two"}, list(field("Name", FilterVerbPrefix, "team-a-")))
+	assert.Equal(t, []string{}, list(field("Name", FilterVerbPrefix, "team_")))
+	assert.Equal(t, []string{"team-a-one", "team-b-one"}, list(field("Name", FilterVerbContains, "-one")))
+	assert.Equal(t, []string{"team-a-one", "team-b-one"}, list(tag("env", FilterVerbExists, nil)))
+	assert.Equal(t, []string{"team-a-two"}, list(FilterStatNot(tag("env", FilterVerbExists, nil))))
+	assert.Equal(t, []string{"team-a-two", "team-b-one"}, list(tag("env", FilterVerbNotEqual, "prod")))
+	assert.Equal(t, []string{"team-a-one", "team-a-two"}, list(tag("tier", FilterVerbIn, []string{"gold", "silver"})))
+	assert.Equal(t, []string{"team-a-one", "team-b-one"}, list(FilterStatOr(tag("tier", FilterVerbEqual, "gold"), tag("env", FilterVerbEqual, "dev"))))
+	assert.Equal(t, []string{"team-a-one"}, list(FilterStatAnd(tag("tier", FilterVerbEqual, "gold"), field("Status", FilterVerbEqual, StatusREADY))))
+	assert.Equal(t, []string{"team-a-one", "team-a-two"}, list(tag("replicas", FilterVerbGreaterOrEqual, 3)))
+	assert.Equal(t, []string{"team-a-two"}, list(tag("replicas", FilterVerbGreaterThan, 3)))
+	assert.Equal(t, []string{"team-a-one"}, list(tag("replicas", FilterVerbLessThan, 5)))
+	assert.Equal(t, []string{"team-a-one"}, list(tag("replicas", FilterVerbLessOrEqual, 3.5)))
+
+	var items []*testEntity
+	err := es.List(context.Background(), "testOrg", Options{Filter: FilterEverything().Add(field("Name", FilterVerbExists, nil))}, &items)
+	assert.Error(t, err)
+	err = es.List(context.Background(), "testOrg", Options{Filter: FilterEverything().Add(tag("replicas", FilterVerbGreaterThan, "many"))}, &items)
+	assert.Error(t, err)
+
+	// clean up
+	for _, e := range entities {
+		es.Delete(context.Background(), "testOrg", e.Name, e)
+	}
+}
+
+func stepVersions(steps []MigrationStep) []int {
+	var versions []int
+	for _, s := range steps {
+		versions = append(versions, s.Version)
+	}
+	return versions
+}
+
+func testMigrate(t *testing.T, es EntityStore) {
+	ctx := context.Background()
+	m, ok := es.(Migrator)
+	require.True(t, ok, "store does not support migrations")
+
+	// start from a store without migrations, as if it was created by an old version
+	_, err := m.Migrate(ctx, 0, false)
+	require.NoError(t, err)
+
+	old := &migratedEntity{
+		BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testMigrateOld", Version: 1},
+		Value:      "old",
+	}
+	_, err = es.Add(ctx, old)
+	require.NoError(t, err)
+	defer es.Delete(ctx, "testOrg", "testMigrateOld", old)
+
+	planned, err := m.Migrate(ctx, -1, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 1000}, stepVersions(planned))
+	version, err := m.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version, "dry run applied migrations")
+
+	applied, err := m.Migrate(ctx, -1, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 1000}, stepVersions(applied))
+	version, err = m.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, LatestVersion(), version)
+
+	var got migratedEntity
+	require.NoError(t, es.Get(ctx, "testOrg", "testMigrateOld", Options{}, &got))
+	assert.Equal(t, "OLD", got.Value)
+	assert.Equal(t, uint64(1000), got.Version)
+
+	// entities added after the migration are already up to date
+	added := &migratedEntity{
+		BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testMigrateNew"},
+		Value:      "NEW",
+	}
+	_, err = es.Add(ctx, added)
+	require.NoError(t, err)
+	defer es.Delete(ctx, "testOrg", "testMigrateNew", added)
+	assert.Equal(t, uint64(1000), added.Version)
+
+	applied, err = m.Migrate(ctx, -1, false)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	_, err = m.Migrate(ctx, 2000, false)
+	assert.Error(t, err)
+
+	rolledBack, err := m.Migrate(ctx, 1, false)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, 1)
+	assert.True(t, rolledBack[0].Rollback)
+	assert.Equal(t, 1000, rolledBack[0].Version)
+	version, err = m.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	require.NoError(t, es.Get(ctx, "testOrg", "testMigrateOld", Options{}, &got))
+	assert.Equal(t, "old", got.Value)
+	assert.Equal(t, uint64(0), got.Version)
+	require.NoError(t, es.Get(ctx, "testOrg", "testMigrateNew", Options{}, &got))
+	assert.Equal(t, "new", got.Value)
+
+	_, err = m.Migrate(ctx, 0, false)
+	require.NoError(t, err)
+}
+
+func testTransaction(t *testing.T, es EntityStore) {
+	ctx := context.Background()
+	first := &testEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testTxFirst"}, Value: "first"}
+	second := &testEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testTxSecond"}, Value: "second"}
+
+	err := es.Transaction(ctx, func(tx EntityStore) error {
+		if _, err := tx.Add(ctx, first); err != nil {
+			return err
+		}
+		var got testEntity
+		if err := tx.Get(ctx, "testOrg", "testTxFirst", Options{}, &got); err != nil {
+			return err
+		}
+		assert.Equal(t, "first", got.Value, "writes are visible in the transaction")
+		var listed []*testEntity
+		if err := tx.List(ctx, "testOrg", Options{Filter: FilterEverything().Add(FilterStat{
+			Scope: FilterScopeField, Subject: "Name", Verb: FilterVerbPrefix, Object: "testTx",
+		})}, &listed); err != nil {
+			return err
+		}
+		assert.Len(t, listed, 1)
+		_, err := tx.Add(ctx, second)
+		return err
+	})
+	require.NoError(t, err)
+
+	var got testEntity
+	require.NoError(t, es.Get(ctx, "testOrg", "testTxFirst", Options{}, &got))
+	assert.Equal(t, got.Revision, first.Revision, "revisions are set on commit")
+	require.NoError(t, es.Get(ctx, "testOrg", "testTxSecond", Options{}, &got))
+	second.Value = "updated"
+	_, err = es.Update(ctx, second.Revision, second)
+	require.NoError(t, err, "the revision set on commit is the stored one")
+
+	// the failed add of a duplicate discards the update made before
+	err = es.Transaction(ctx, func(tx EntityStore) error {
+		first.Value = "updated"
+		if _, err := tx.Update(ctx, first.Revision, first); err != nil {
+			return err
+		}
+		_, err := tx.Add(ctx, &testEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testTxSecond"}})
+		return err
+	})
+	assert.Error(t, err)
+	require.NoError(t, es.Get(ctx, "testOrg", "testTxFirst", Options{}, &got))
+	assert.Equal(t, "first", got.Value)
+
+	err = es.Transaction(ctx, func(tx EntityStore) error {
+		if err := tx.Delete(ctx, "testOrg", "testTxFirst", &testEntity{}); err != nil {
+			return err
+		}
+		return tx.Delete(ctx, "testOrg", "testTxSecond", &testEntity{})
+	})
+	require.NoError(t, err)
+	found, err := es.Find(ctx, "testOrg", "testTxFirst", Options{}, &got)
+	require.NoError(t, err)
+	assert.False(t, found)
+	found, err = es.Find(ctx, "testOrg", "testTxSecond", Options{}, &got)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+// testTransactionConflict checks that a batch is reverted if an entity is modified before it is committed, which
+// cannot happen with SQL transactions
+func testTransactionConflict(t *testing.T, es EntityStore) {
+	ctx := context.Background()
+	e := &testEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testTxConflict"}, Value: "initial"}
+	_, err := es.Add(ctx, e)
+	require.NoError(t, err)
+	defer es.Delete(ctx, "testOrg", "testTxConflict", e)
+
+	err = es.Transaction(ctx, func(tx EntityStore) error {
+		added := &testEntity{BaseEntity: BaseEntity{OrganizationID: "testOrg", Name: "testTxAdded"}}
+		if _, err := tx.Add(ctx, added); err != nil {
+			return err
+		}
+		updated := *e
+		updated.Value = "in transaction"
+		if _, err := tx.Update(ctx, e.Revision, &updated); err != nil {
+			return err
+		}
+		concurrent := *e
+		concurrent.Value = "concurrent"
+		_, err := es.Update(ctx, e.Revision, &concurrent)
+		return err
+	})
+	assert.Error(t, err)
+
+	var got testEntity
+	require.NoError(t, es.Get(ctx, "testOrg", "testTxConflict", Options{}, &got))
+	assert.Equal(t, "concurrent", got.Value)
+	found, err := es.Find(ctx, "testOrg", "testTxAdded", Options{}, &got)
+	require.NoError(t, err)
+	assert.False(t, found, "the writes of the failed batch are reverted")
+}
@@ -0,0 +1,237 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package entitystore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// EventAdd is sent when an entity is added to the store
+	EventAdd EventType = "add"
+
+	// EventUpdate is sent when an existing entity is updated
+	EventUpdate EventType = "update"
+
+	// EventDelete is sent when an entity is deleted from the store
+	EventDelete EventType = "delete"
+
+	// EventError is the last event sent to a watcher whose watch failed, before its channel is closed
+	EventError EventType = "error"
+
+	// watchBufferSize is the number of events buffered per watcher, whose watch fails with ErrWatcherTooSlow once
+	// its buffer is full
+	watchBufferSize = 100
+
+	// watchHistorySize is the number of changes kept to be replayed to the watchers resuming from a revision
+	watchHistorySize = 1000
+)
+
+var (
+	// ErrRevisionTooOld is returned by Watch when the changes after the requested revision are no longer kept. The
+	// watcher catches up by listing the entities, and watches from revision 0.
+	ErrRevisionTooOld = errors.New("revision too old, the changes since are no longer kept")
+
+	// ErrWatcherTooSlow fails the watches which do not receive their events as fast as they are sent
+	ErrWatcherTooSlow = errors.New("watcher too slow, it lost changes")
+)
+
+// EventType describes the kind of change made to an entity
+type EventType string
+
+// Event describes a single change made to an entity in the store.
+// It carries only the identity of the entity, consumers are expected to Get the entity if they need its content.
+type Event struct {
+	Type           EventType `json:"type"`
+	DataType       DataType  `json:"dataType"`
+	OrganizationID string    `json:"organizationId"`
+	Name           string    `json:"name"`
+	ID             string    `json:"id"`
+	Revision       uint64    `json:"revision"`
+	// StoreRevision orders the changes of the store, a watch resumes from the store revision of its last event
+	StoreRevision uint64 `json:"storeRevision"`
+	// Err is the error of an EventError event
+	Err error `json:"-"`
+}
+
+func newEvent(t EventType, entity Entity) Event {
+	return Event{
+		Type:           t,
+		DataType:       getDataType(entity),
+		OrganizationID: entity.GetOrganizationID(),
+		Name:           entity.GetName(),
+		ID:             entity.GetID(),
+		Revision:       entity.GetRevision(),
+	}
+}
+
+type watcher struct {
+	dataType       DataType
+	organizationID string
+	events         chan Event
+	// closed is set once events is closed
+	closed bool
+}
+
+func (w *watcher) matches(e Event) bool {
+	if w.dataType != e.DataType {
+		return false
+	}
+	return w.organizationID == "" || w.organizationID == e.OrganizationID
+}
+
+// replayFunc returns the changes of the entities of a data type made after the store revision since, of all
+// organizations if organizationID is empty, or ErrRevisionTooOld if they are no longer kept
+type replayFunc func(dataType DataType, organizationID string, since uint64) ([]Event, error)
+
+// broadcaster fans out entity events to all registered watchers within the process
+type broadcaster struct {
+	sync.Mutex
+	watchers map[*watcher]struct{}
+
+	// keepHistory is set for the stores which have no change log: publish assigns the store revisions, and keeps the
+	// last watchHistorySize events in history
+	keepHistory bool
+	revision    uint64
+	history     []Event
+}
+
+// newBroadcaster returns a broadcaster of the events of a store recording its changes, which sets their store revision
+func newBroadcaster() *broadcaster {
+	return &broadcaster{
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// newHistoryBroadcaster returns a broadcaster which orders and keeps the last events of a store
+func newHistoryBroadcaster() *broadcaster {
+	b := newBroadcaster()
+	b.keepHistory = true
+	return b
+}
+
+// watch registers a new watcher, which is removed once ctx is done. If since is not 0, the changes made after it,
+// returned by replay, are sent first.
+func (b *broadcaster) watch(ctx context.Context, dataType DataType, organizationID string, since uint64, replay replayFunc) (<-chan Event, error) {
+	w := &watcher{
+		dataType:       dataType,
+		organizationID: organizationID,
+		// the last slot is kept for the EventError
+		events: make(chan Event, watchBufferSize+1),
+	}
+
+	b.Lock()
+	b.watchers[w] = struct{}{}
+	b.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.Lock()
+		b.stop(w, nil)
+		b.Unlock()
+	}()
+	if since == 0 {
+		return w.events, nil
+	}
+
+	// the watcher is registered first, so that no change is missed between the replay and the live events
+	missed, err := replay(dataType, organizationID, since)
+	if err != nil {
+		b.Lock()
+		b.stop(w, nil)
+		b.Unlock()
+		return nil, err
+	}
+	replayed := make(map[uint64]bool, len(missed))
+	for _, e := range missed {
+		replayed[e.StoreRevision] = true
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for _, e := range missed {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for e := range w.events {
+			if e.Type != EventError && replayed[e.StoreRevision] {
+				delete(replayed, e.StoreRevision)
+				continue
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// stop removes a watcher and closes its channel, after sending it an EventError if err is not nil. The broadcaster
+// must be locked.
+func (b *broadcaster) stop(w *watcher, err error) {
+	if w.closed {
+		return
+	}
+	delete(b.watchers, w)
+	if err != nil {
+		w.events <- Event{Type: EventError, DataType: w.dataType, OrganizationID: w.organizationID, Err: err}
+	}
+	close(w.events)
+	w.closed = true
+}
+
+// publish sends the event to every matching watcher. It never blocks, the watch of a watcher whose buffer is full
+// fails with ErrWatcherTooSlow.
+func (b *broadcaster) publish(e Event) {
+	b.Lock()
+	defer b.Unlock()
+	if b.keepHistory {
+		b.revision++
+		e.StoreRevision = b.revision
+		b.history = append(b.history, e)
+		if len(b.history) > watchHistorySize {
+			b.history = b.history[1:]
+		}
+	}
+	for w := range b.watchers {
+		if !w.matches(e) {
+			continue
+		}
+		if len(w.events) >= watchBufferSize {
+			log.Warnf("watcher for %s is too slow, failing its watch at %s event for %s/%s", w.dataType, e.Type, e.OrganizationID, e.Name)
+			b.stop(w, ErrWatcherTooSlow)
+			continue
+		}
+		w.events <- e
+	}
+}
+
+// changes returns the kept events matching the data type and organization, which were published after the store
+// revision since
+func (b *broadcaster) changes(dataType DataType, organizationID string, since uint64) ([]Event, error) {
+	b.Lock()
+	defer b.Unlock()
+	if since > b.revision || len(b.history) > 0 && b.history[0].StoreRevision > since+1 {
+		return nil, ErrRevisionTooOld
+	}
+	w := &watcher{dataType: dataType, organizationID: organizationID}
+	var events []Event
+	for _, e := range b.history {
+		if e.StoreRevision > since && w.matches(e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
@@ -9,10 +9,10 @@ import (
 	"time"
 
 	"github.com/vmware/dispatch/pkg/controller"
+	"github.com/vmware/dispatch/pkg/coordination"
 	"github.com/vmware/dispatch/pkg/entity-store"
 	"github.com/vmware/dispatch/pkg/event-manager/drivers"
 	"github.com/vmware/dispatch/pkg/event-manager/subscriptions"
-	"github.com/vmware/dispatch/pkg/zookeeper"
 )
 
 // Event manager constants
@@ -23,10 +23,10 @@ const (
 
 // EventControllerConfig defines configuration for controller
 type EventControllerConfig struct {
-	ResyncPeriod      time.Duration
-	WorkerNumber      int
-	ZookeeperLocation string
-	Driver            zookeeper.Driver
+	ResyncPeriod time.Duration
+	WorkerNumber int
+	Coordination coordination.Config
+	Driver       coordination.Driver
 }
 
 // NewEventController creates a new controller to manage the reconciliation of event manager entities
@@ -40,11 +40,12 @@ func NewEventController(manager subscriptions.Manager, backend drivers.Backend,
 	}
 
 	c := controller.NewController(controller.Options{
-		ResyncPeriod:      config.ResyncPeriod,
-		Workers:           config.WorkerNumber,
-		ServiceName:       "events",
-		ZookeeperLocation: config.ZookeeperLocation,
-		Driver:            config.Driver,
+		ResyncPeriod: config.ResyncPeriod,
+		Workers:      config.WorkerNumber,
+		ServiceName:  "events",
+		Coordination: config.Coordination,
+		Driver:       config.Driver,
+		Store:        store,
 	})
 
 	c.AddEntityHandler(drivers.NewEntityHandler(store, backend))
@@ -11,24 +11,24 @@ import (
 
 	"github.com/stretchr/testify/mock"
 
+	coordmock "github.com/vmware/dispatch/pkg/coordination/mocks"
 	"github.com/vmware/dispatch/pkg/entity-store"
 	mocks2 "github.com/vmware/dispatch/pkg/event-manager/drivers/mocks"
 	"github.com/vmware/dispatch/pkg/event-manager/subscriptions/entities"
 	"github.com/vmware/dispatch/pkg/event-manager/subscriptions/mocks"
 	helpers "github.com/vmware/dispatch/pkg/testing/api"
-	zkmock "github.com/vmware/dispatch/pkg/zookeeper/mocks"
 )
 
 const (
-	testZookeeperLocation = "zookeeper.zookeeper.svc.cluster.local"
+	testMember = "member-0000000000"
 )
 
-func getTestDriver() *zkmock.Driver {
-	driver := &zkmock.Driver{}
-	driver.On("CreateNode", mock.Anything, mock.Anything).Return(nil)
-	driver.On("GetConnection").Return(nil)
+func getTestDriver() *coordmock.Driver {
+	driver := &coordmock.Driver{}
 	driver.On("LockEntity", mock.Anything).Return("lock", true)
 	driver.On("ReleaseEntity", "lock").Return(nil)
+	driver.On("Join", mock.Anything).Return(testMember, nil)
+	driver.On("Members", mock.Anything).Return([]string{testMember}, nil, nil)
 	driver.On("Close").Return(nil)
 	return driver
 }
@@ -39,8 +39,7 @@ func TestControllerRun(t *testing.T) {
 	es := helpers.MakeEntityStore(t)
 
 	controller := NewEventController(manager, k8sBackend, es, EventControllerConfig{
-		ZookeeperLocation: testZookeeperLocation,
-		Driver:            getTestDriver(),
+		Driver: getTestDriver(),
 	})
 	controller.Start()
 	controller.Shutdown()
@@ -52,8 +51,7 @@ func TestControllerRunWithSubs(t *testing.T) {
 	es := helpers.MakeEntityStore(t)
 
 	controller := NewEventController(manager, k8sBackend, es, EventControllerConfig{
-		ZookeeperLocation: testZookeeperLocation,
-		Driver:            getTestDriver(),
+		Driver: getTestDriver(),
 	})
 	defer controller.Shutdown()
 	controller.Start()
@@ -115,7 +115,7 @@ func (h *Handlers) getDriver(params driverapi.GetDriverParams, principal interfa
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewDeleteDriverBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -145,7 +145,7 @@ func (h *Handlers) getDrivers(params driverapi.GetDriversParams, principal inter
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewDeleteDriverBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -153,6 +153,15 @@ func (h *Handlers) getDrivers(params driverapi.GetDriversParams, principal inter
 			})
 	}
 	opts := entitystore.Options{Filter: filter}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return driverapi.NewGetDriversDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
 
 	// delete filter
 	err = h.store.List(ctx, params.XDispatchOrg, opts, &drivers)
@@ -168,7 +177,7 @@ func (h *Handlers) getDrivers(params driverapi.GetDriversParams, principal inter
 	for _, driver := range drivers {
 		driverModels = append(driverModels, driver.ToModel())
 	}
-	return driverapi.NewGetDriversOK().WithPayload(driverModels)
+	return driverapi.NewGetDriversOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(drivers))).WithPayload(driverModels)
 }
 
 func (h *Handlers) updateDriver(params driverapi.UpdateDriverParams, principal interface{}) middleware.Responder {
@@ -179,7 +188,7 @@ func (h *Handlers) updateDriver(params driverapi.UpdateDriverParams, principal i
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewUpdateDriverBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -226,7 +235,7 @@ func (h *Handlers) deleteDriver(params driverapi.DeleteDriverParams, principal i
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewDeleteDriverBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -316,7 +325,7 @@ func (h *Handlers) getDriverType(params driverapi.GetDriverTypeParams, principal
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewGetDriverTypeBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -345,7 +354,7 @@ func (h *Handlers) getDriverTypes(params driverapi.GetDriverTypesParams, princip
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewGetDriverTypeBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -353,6 +362,15 @@ func (h *Handlers) getDriverTypes(params driverapi.GetDriverTypesParams, princip
 			})
 	}
 	opts := entitystore.Options{Filter: filter}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return driverapi.NewGetDriverTypesDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
 
 	// delete filter
 	err = h.store.List(ctx, params.XDispatchOrg, opts, &driverTypes)
@@ -369,7 +387,7 @@ func (h *Handlers) getDriverTypes(params driverapi.GetDriverTypesParams, princip
 		driverTypeModels = append(driverTypeModels, dt.ToModel())
 	}
 
-	return driverapi.NewGetDriverTypesOK().WithPayload(driverTypeModels)
+	return driverapi.NewGetDriverTypesOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(driverTypes))).WithPayload(driverTypeModels)
 }
 
 func (h *Handlers) updateDriverType(params driverapi.UpdateDriverTypeParams, principal interface{}) middleware.Responder {
@@ -378,7 +396,7 @@ func (h *Handlers) updateDriverType(params driverapi.UpdateDriverTypeParams, pri
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewUpdateDriverTypeBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -418,7 +436,7 @@ func (h *Handlers) deleteDriverType(params driverapi.DeleteDriverTypeParams, pri
 
 	filter, err := utils.ParseTags(entitystore.FilterEverything(), params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return driverapi.NewDeleteDriverTypeBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -46,6 +46,7 @@ type ConfigOpts struct {
 	SidecarImage    string
 	TransportType   string
 	RabbitMQURL     string
+	NATSURL         string
 	KafkaBrokers    []string
 	Tracer          string
 	K8sConfig       string
@@ -567,6 +568,10 @@ func (k *k8sBackend) buildSidecarEnv(d *entities.Driver) []corev1.EnvVar {
 			Name:  "DISPATCH_RABBITMQ_URL",
 			Value: k.config.RabbitMQURL,
 		},
+		{
+			Name:  "DISPATCH_NATS_URL",
+			Value: k.config.NATSURL,
+		},
 		{
 			Name:  "DISPATCH_ORGANIZATION",
 			Value: d.OrganizationID,
@@ -92,7 +92,7 @@ func (h *Handlers) emitEvent(params eventsapi.EmitEventParams, principal interfa
 			Message: swag.String(errMsg),
 		})
 	}
-	err := h.Transport.Publish(ctx, ev, ev.DefaultTopic(), params.XDispatchOrg)
+	err := events.Publish(ctx, h.Transport, ev, params.XDispatchOrg)
 	if err != nil {
 		errMsg := fmt.Sprintf("error when publishing a message to MQ: %+v", err)
 		log.Error(errMsg)
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -62,6 +63,32 @@ func TestEventsEmitEvent(t *testing.T) {
 	assert.NotEmpty(t, respBody.ID)
 	assert.Equal(t, "test.event", respBody.EventType)
 	queue.AssertCalled(t, "Publish", mock.Anything, mock.Anything, (&testCloudEvent1).DefaultTopic(), "")
+	queue.AssertCalled(t, "Publish", mock.Anything, mock.Anything, eventtypes.AllEventsTopic, "")
+}
+
+func TestEventsEmitEventAllEventsError(t *testing.T) {
+	api := operations.NewEventManagerAPI(nil)
+	es := testhelpers.MakeEntityStore(t)
+	queue := &eventsmocks.Transport{}
+	h := Handlers{Store: es, Transport: queue}
+	testhelpers.MakeAPI(t, h.ConfigureHandlers, api)
+
+	// the event reached the subscriptions to its type, emitting it again would duplicate it
+	queue.On("Publish", mock.Anything, mock.Anything, (&testCloudEvent1).DefaultTopic(), mock.Anything).Return(nil)
+	queue.On("Publish", mock.Anything, mock.Anything, eventtypes.AllEventsTopic, mock.Anything).Return(errors.New("transport error"))
+
+	reqBody := &v1.Emission{
+		CloudEvent: *helpers.CloudEventToAPI(&testCloudEvent1),
+	}
+	r := httptest.NewRequest("POST", "/v1/event/", nil)
+	params := events.EmitEventParams{
+		HTTPRequest: r,
+		Body:        reqBody,
+	}
+	responder := api.EventsEmitEventHandler.Handle(params, "testCookie")
+	var respBody v1.Emission
+	testhelpers.HandlerRequest(t, responder, &respBody, 200)
+	queue.AssertNumberOfCalls(t, "Publish", 2)
 }
 
 func TestEventsEmitError(t *testing.T) {
@@ -17,9 +17,18 @@ import (
 // Subscription struct represents a single subscription of subscriber to publisher
 type Subscription struct {
 	entitystore.BaseEntity
-	EventType string   `json:"eventType"`
-	Function  string   `json:"function"`
-	Secrets   []string `json:"secrets,omitempty"`
+	EventType string              `json:"eventType"`
+	Filter    *SubscriptionFilter `json:"filter,omitempty"`
+	Function  string              `json:"function"`
+	Secrets   []string            `json:"secrets,omitempty"`
+}
+
+// SubscriptionFilter restricts the events of a subscription to those matching all its conditions
+type SubscriptionFilter struct {
+	Source           string            `json:"source,omitempty"`
+	EventTypeVersion string            `json:"eventTypeVersion,omitempty"`
+	Extensions       map[string]string `json:"extensions,omitempty"`
+	Data             []string          `json:"data,omitempty"`
 }
 
 // ToModel converts subscription to swagger model
@@ -32,6 +41,7 @@ func (s *Subscription) ToModel() *v1.Subscription {
 		Name:         swag.String(s.Name),
 		Kind:         utils.SubscriptionKind,
 		EventType:    swag.String(s.EventType),
+		Filter:       s.Filter.toModel(),
 		Function:     &s.Function,
 		Status:       v1.Status(s.Status),
 		Secrets:      s.Secrets,
@@ -53,6 +63,31 @@ func (s *Subscription) FromModel(m *v1.Subscription, orgID string) {
 	s.BaseEntity.Status = entitystore.Status(m.Status)
 	s.BaseEntity.Tags = tags
 	s.EventType = *m.EventType
+	s.Filter = filterFromModel(m.Filter)
 	s.Function = *m.Function
 	s.Secrets = m.Secrets
 }
+
+func (f *SubscriptionFilter) toModel() *v1.SubscriptionFilter {
+	if f == nil {
+		return nil
+	}
+	return &v1.SubscriptionFilter{
+		Source:           f.Source,
+		EventTypeVersion: f.EventTypeVersion,
+		Extensions:       f.Extensions,
+		Data:             f.Data,
+	}
+}
+
+func filterFromModel(m *v1.SubscriptionFilter) *SubscriptionFilter {
+	if m == nil {
+		return nil
+	}
+	return &SubscriptionFilter{
+		Source:           m.Source,
+		EventTypeVersion: m.EventTypeVersion,
+		Extensions:       m.Extensions,
+		Data:             m.Data,
+	}
+}
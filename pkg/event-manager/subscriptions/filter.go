@@ -0,0 +1,299 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package subscriptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/vmware/dispatch/pkg/event-manager/subscriptions/entities"
+	"github.com/vmware/dispatch/pkg/events"
+)
+
+// predicateOperators are the comparison operators of data predicates, the two-character ones first
+var predicateOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// eventFilter is the compiled filter of a subscription. A nil eventFilter matches every event.
+type eventFilter struct {
+	// eventType is set for the subscriptions to an event type pattern, which receive all the events
+	eventType        *regexp.Regexp
+	source           *regexp.Regexp
+	eventTypeVersion *regexp.Regexp
+	extensions       map[string]*regexp.Regexp
+	data             []*predicate
+}
+
+// predicate is a JSONPath predicate over the event data: "$.path op value", or "$.path" which matches if the path
+// exists. The value is a JSON value, or a single-quoted string.
+type predicate struct {
+	// paths pools the compiled JSONPath of the predicate, as a JSONPath is not safe for concurrent use
+	paths sync.Pool
+	op    string
+	value interface{}
+}
+
+// isEventTypePattern tells whether the event type of a subscription is a pattern, in which * matches any characters
+func isEventTypePattern(eventType string) bool {
+	return strings.Contains(eventType, "*")
+}
+
+// newEventFilter compiles the filter of a subscription to eventType
+func newEventFilter(eventType string, f *entities.SubscriptionFilter) (*eventFilter, error) {
+	ef := &eventFilter{
+		extensions: make(map[string]*regexp.Regexp),
+	}
+	if isEventTypePattern(eventType) {
+		ef.eventType = compileGlob(eventType)
+	}
+	if f == nil {
+		if ef.eventType == nil {
+			return nil, nil
+		}
+		return ef, nil
+	}
+	ef.source = compileGlob(f.Source)
+	ef.eventTypeVersion = compileGlob(f.EventTypeVersion)
+	for name, pattern := range f.Extensions {
+		ef.extensions[name] = compileGlob(pattern)
+	}
+	for _, text := range f.Data {
+		p, err := parsePredicate(text)
+		if err != nil {
+			return nil, err
+		}
+		ef.data = append(ef.data, p)
+	}
+	return ef, nil
+}
+
+// compileGlob returns a regexp matching pattern, in which * matches any characters, or nil if pattern is empty
+func compileGlob(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	return regexp.MustCompile("^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$")
+}
+
+// match returns true if the event matches all the conditions of the filter
+func (f *eventFilter) match(event *events.CloudEvent) bool {
+	if f == nil {
+		return true
+	}
+	if f.eventType != nil && !f.eventType.MatchString(event.EventType) {
+		return false
+	}
+	if f.source != nil && !f.source.MatchString(event.Source) {
+		return false
+	}
+	if f.eventTypeVersion != nil && !f.eventTypeVersion.MatchString(event.EventTypeVersion) {
+		return false
+	}
+	for name, pattern := range f.extensions {
+		value, ok := event.Extensions[name]
+		if !ok {
+			return false
+		}
+		if s, ok := value.(string); ok {
+			if !pattern.MatchString(s) {
+				return false
+			}
+		} else if !pattern.MatchString(fmt.Sprint(value)) {
+			return false
+		}
+	}
+	if len(f.data) == 0 {
+		return true
+	}
+	// data predicates only match events with JSON data
+	var data interface{}
+	if err := json.Unmarshal(event.Data, &data); err != nil || data == nil {
+		return false
+	}
+	data = normalizeNumbers(data)
+	for _, p := range f.data {
+		if !p.match(data) {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePredicate parses a data predicate
+func parsePredicate(text string) (*predicate, error) {
+	path, op, literal := splitPredicate(text)
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.Errorf("invalid data predicate '%s': the path must start with $", text)
+	}
+	compiled, err := compilePath(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid data predicate '%s'", text)
+	}
+	p := &predicate{op: op}
+	p.paths.New = func() interface{} {
+		// the path compiled once already, so it compiles again
+		compiled, _ := compilePath(path)
+		return compiled
+	}
+	p.paths.Put(compiled)
+	if op == "" {
+		return p, nil
+	}
+
+	literal = strings.TrimSpace(literal)
+	if len(literal) >= 2 && strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") {
+		p.value = literal[1 : len(literal)-1]
+		return p, nil
+	}
+	if err := json.Unmarshal([]byte(literal), &p.value); err != nil {
+		return nil, errors.Errorf("invalid data predicate '%s': '%s' is not a JSON value", text, literal)
+	}
+	p.value = normalizeNumbers(p.value)
+	return p, nil
+}
+
+// compilePath compiles the JSONPath of a predicate
+func compilePath(path string) (*jsonpath.JSONPath, error) {
+	compiled := jsonpath.New("filter").AllowMissingKeys(true)
+	if err := compiled.Parse("{" + path + "}"); err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// normalizeNumbers converts the integral numbers of a decoded JSON value to int, so that they compare with the
+// integer literals of JSONPath filters
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+			return int(v)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeNumbers(e)
+		}
+	}
+	return value
+}
+
+// toNumber returns the value of a number
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// splitPredicate splits a predicate at its first operator outside brackets and quotes
+func splitPredicate(text string) (path, op, literal string) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+			continue
+		case c == '[' || c == '(':
+			depth++
+			continue
+		case c == ']' || c == ')':
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+		for _, op := range predicateOperators {
+			if strings.HasPrefix(text[i:], op) {
+				return text[:i], op, text[i+len(op):]
+			}
+		}
+	}
+	return text, "", ""
+}
+
+// match returns true if any value of the path in data satisfies the predicate
+func (p *predicate) match(data interface{}) bool {
+	path := p.paths.Get().(*jsonpath.JSONPath)
+	defer p.paths.Put(path)
+	results, err := path.FindResults(data)
+	if err != nil {
+		return false
+	}
+	for _, values := range results {
+		for _, v := range values {
+			if p.op == "" || compareValues(v.Interface(), p.op, p.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compareValues compares two JSON values. Only numbers and strings are ordered.
+func compareValues(left interface{}, op string, right interface{}) bool {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	}
+
+	var c int
+	if l, ok := toNumber(left); ok {
+		r, ok := toNumber(right)
+		if !ok {
+			return false
+		}
+		switch {
+		case l < r:
+			c = -1
+		case l > r:
+			c = 1
+		}
+	} else if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(l, r)
+	} else {
+		return false
+	}
+
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
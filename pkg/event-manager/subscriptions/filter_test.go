@@ -0,0 +1,118 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package subscriptions
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/dispatch/pkg/event-manager/subscriptions/entities"
+	"github.com/vmware/dispatch/pkg/events"
+)
+
+func TestEventFilter(t *testing.T) {
+	event := &events.CloudEvent{
+		EventType:        "vm.created",
+		EventTypeVersion: "6.5",
+		Source:           "vcenter1.corp.local",
+		Extensions:       events.CloudEventExtensions{"region": "us-west", "priority": 2},
+		Data:             json.RawMessage(`{"name":"web-1","cpus":4,"disks":[{"size":10},{"size":100}],"tags":{"env":"prod"}}`),
+	}
+
+	cases := []struct {
+		name    string
+		filter  *entities.SubscriptionFilter
+		matches bool
+	}{
+		{"no filter", nil, true},
+		{"source", &entities.SubscriptionFilter{Source: "vcenter1.corp.local"}, true},
+		{"source wildcard", &entities.SubscriptionFilter{Source: "vcenter*.corp.local"}, true},
+		{"other source", &entities.SubscriptionFilter{Source: "vcenter2.*"}, false},
+		{"event type version", &entities.SubscriptionFilter{EventTypeVersion: "6.*"}, true},
+		{"other event type version", &entities.SubscriptionFilter{EventTypeVersion: "7.*"}, false},
+		{"extensions", &entities.SubscriptionFilter{Extensions: map[string]string{"region": "us-*", "priority": "2"}}, true},
+		{"other extension", &entities.SubscriptionFilter{Extensions: map[string]string{"region": "eu-*"}}, false},
+		{"missing extension", &entities.SubscriptionFilter{Extensions: map[string]string{"zone": "*"}}, false},
+		{"number", &entities.SubscriptionFilter{Data: []string{"$.cpus >= 4"}}, true},
+		{"other number", &entities.SubscriptionFilter{Data: []string{"$.cpus > 4"}}, false},
+		{"decimal number", &entities.SubscriptionFilter{Data: []string{"$.cpus < 4.5", "$.cpus == 4.0"}}, true},
+		{"string", &entities.SubscriptionFilter{Data: []string{"$.tags.env == 'prod'"}}, true},
+		{"JSON string", &entities.SubscriptionFilter{Data: []string{`$.name != "web-1"`}}, false},
+		{"exists", &entities.SubscriptionFilter{Data: []string{"$.tags.env"}}, true},
+		{"missing", &entities.SubscriptionFilter{Data: []string{"$.owner"}}, false},
+		{"array", &entities.SubscriptionFilter{Data: []string{"$.disks[*].size > 50"}}, true},
+		{"array filter", &entities.SubscriptionFilter{Data: []string{"$.disks[?(@.size > 50)].size == 100"}}, true},
+		{"all conditions", &entities.SubscriptionFilter{Source: "vcenter*", Data: []string{"$.cpus >= 4", "$.cpus < 2"}}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := newEventFilter(event.EventType, c.filter)
+			require.NoError(t, err)
+			assert.Equal(t, c.matches, f.match(event))
+		})
+	}
+
+	f, err := newEventFilter("vm.created", &entities.SubscriptionFilter{Data: []string{"$.cpus"}})
+	require.NoError(t, err)
+	assert.False(t, f.match(&events.CloudEvent{Data: json.RawMessage(`not json`)}))
+	assert.False(t, f.match(&events.CloudEvent{}))
+}
+
+func TestEventFilterEventType(t *testing.T) {
+	event := &events.CloudEvent{EventType: "vm.created", Source: "vcenter1"}
+
+	cases := []struct {
+		eventType string
+		filter    *entities.SubscriptionFilter
+		matches   bool
+	}{
+		{"vm.*", nil, true},
+		{"*.created", nil, true},
+		{"*", nil, true},
+		{"vm.*", &entities.SubscriptionFilter{Source: "vcenter*"}, true},
+		{"vm.*", &entities.SubscriptionFilter{Source: "aws*"}, false},
+		{"host.*", nil, false},
+		{"vm.created.*", nil, false},
+	}
+	for _, c := range cases {
+		f, err := newEventFilter(c.eventType, c.filter)
+		require.NoError(t, err)
+		assert.Equal(t, c.matches, f.match(event), c.eventType)
+	}
+
+	// the filter of an exact event type does not check it, the topic only has events of this type
+	f, err := newEventFilter("vm.created", nil)
+	require.NoError(t, err)
+	assert.Nil(t, f)
+}
+
+func TestEventFilterConcurrent(t *testing.T) {
+	f, err := newEventFilter("vm.*", &entities.SubscriptionFilter{Data: []string{"$.disks[*].size > 50"}})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.True(t, f.match(&events.CloudEvent{EventType: "vm.created", Data: json.RawMessage(`{"disks":[{"size":100}]}`)}))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestEventFilterInvalid(t *testing.T) {
+	for _, predicate := range []string{"cpus > 4", "$.cpus > four", "$.disks[", ""} {
+		_, err := newEventFilter("vm.created", &entities.SubscriptionFilter{Data: []string{predicate}})
+		assert.Error(t, err, predicate)
+	}
+}
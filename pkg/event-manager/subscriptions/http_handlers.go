@@ -70,6 +70,12 @@ func (h *Handlers) addSubscription(params subscriptionsapi.AddSubscriptionParams
 
 	s := &entities.Subscription{}
 	s.FromModel(params.Body, params.XDispatchOrg)
+	if _, err := newEventFilter(s.EventType, s.Filter); err != nil {
+		return subscriptionsapi.NewAddSubscriptionBadRequest().WithPayload(&v1.Error{
+			Code:    http.StatusBadRequest,
+			Message: swag.String(fmt.Sprintf("error validating the payload: %s", err)),
+		})
+	}
 	s.Status = entitystore.StatusCREATING
 	_, err := h.store.Add(ctx, s)
 	if err != nil {
@@ -103,7 +109,7 @@ func (h *Handlers) getSubscription(params subscriptionsapi.GetSubscriptionParams
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return subscriptionsapi.NewGetSubscriptionBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -135,7 +141,16 @@ func (h *Handlers) getSubscriptions(params subscriptionsapi.GetSubscriptionsPara
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
+		return subscriptionsapi.NewGetSubscriptionsBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
 		return subscriptionsapi.NewGetSubscriptionsBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -156,7 +171,7 @@ func (h *Handlers) getSubscriptions(params subscriptionsapi.GetSubscriptionsPara
 	for _, sub := range subscriptions {
 		subscriptionModels = append(subscriptionModels, sub.ToModel())
 	}
-	return subscriptionsapi.NewGetSubscriptionsOK().WithPayload(subscriptionModels)
+	return subscriptionsapi.NewGetSubscriptionsOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(subscriptions))).WithPayload(subscriptionModels)
 }
 
 func (h *Handlers) updateSubscription(params subscriptionsapi.UpdateSubscriptionParams, principal interface{}) middleware.Responder {
@@ -171,7 +186,7 @@ func (h *Handlers) updateSubscription(params subscriptionsapi.UpdateSubscription
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return subscriptionsapi.NewUpdateSubscriptionBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -198,6 +213,13 @@ func (h *Handlers) updateSubscription(params subscriptionsapi.UpdateSubscription
 	}
 
 	s.FromModel(params.Body, s.OrganizationID)
+	if _, err := newEventFilter(s.EventType, s.Filter); err != nil {
+		return subscriptionsapi.NewUpdateSubscriptionBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(fmt.Sprintf("error validating the payload: %s", err)),
+			})
+	}
 	s.Status = entitystore.StatusUPDATING
 	if _, err = h.store.Update(ctx, s.Revision, s); err != nil {
 		log.Errorf("store error when updating a subscription %s: %+v", s.Name, err)
@@ -225,7 +247,7 @@ func (h *Handlers) deleteSubscription(params subscriptionsapi.DeleteSubscription
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return subscriptionsapi.NewDeleteSubscriptionBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -8,6 +8,7 @@ package subscriptions
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -121,8 +122,12 @@ func (m *defaultManager) Update(ctx context.Context, sub *entities.Subscription)
 
 func (m *defaultManager) createSubscription(ctx context.Context, sub *entities.Subscription) (events.Subscription, error) {
 	topic := sub.EventType
-	// subscribe
-	eventSub, err := m.queue.Subscribe(ctx, topic, sub.OrganizationID, m.handler(ctx, sub))
+	if isEventTypePattern(sub.EventType) {
+		// the handler filters the events of all types
+		topic = events.AllEventsTopic
+	}
+	// subscribe, the events of the subscription are shared by all the replicas of the event manager
+	eventSub, err := m.queue.Subscribe(events.WithSubscriptionID(ctx, sub.ID), topic, sub.OrganizationID, m.handler(ctx, sub))
 	if err != nil {
 		err = errors.Wrapf(err, "unable to create a subscription for event %s and function %s", sub.EventType, sub.Function)
 
@@ -162,25 +167,40 @@ func (m *defaultManager) Shutdown() {
 }
 
 // handler creates a function to handle the incoming event. it takes name of the function to be invoked as an argument.
-func (m *defaultManager) handler(ctx context.Context, sub *entities.Subscription) func(context.Context, *events.CloudEvent) {
+// The events which do not match the filter of the subscription are acknowledged without running the function.
+func (m *defaultManager) handler(ctx context.Context, sub *entities.Subscription) events.Handler {
 	span, _ := trace.Trace(ctx, "")
 	defer span.Finish()
 
 	span.SetTag("eventType", sub.EventType)
 	span.SetTag("functionName", sub.Function)
 
-	return func(ctx context.Context, event *events.CloudEvent) {
+	filter, filterErr := newEventFilter(sub.EventType, sub.Filter)
+	if filterErr != nil {
+		filterErr = errors.Wrapf(filterErr, "invalid filter of subscription %s", sub.Name)
+		log.Error(filterErr)
+	}
+
+	return func(ctx context.Context, event *events.CloudEvent) error {
 		span, ctx := trace.Trace(ctx, "EventHandler")
 		defer span.Finish()
 		span.SetTag("eventType", sub.EventType)
 		span.SetTag("functionName", sub.Function)
 
-		m.runFunction(ctx, sub.OrganizationID, sub.Function, event, sub.Secrets)
+		if filterErr != nil {
+			return events.Permanent(filterErr)
+		}
+		if !filter.match(event) {
+			log.Debugf("Event %s does not match the filter of subscription %s", event.EventID, sub.Name)
+			return nil
+		}
+		return m.runFunction(ctx, sub.OrganizationID, sub.Function, event, sub.Secrets)
 	}
 }
 
-// executes a function by connecting to function manager
-func (m *defaultManager) runFunction(ctx context.Context, organizationID string, fnName string, event *events.CloudEvent, secrets []string) {
+// executes a function by connecting to function manager. The error is permanent if the function manager rejected the
+// run, and the event is redelivered otherwise.
+func (m *defaultManager) runFunction(ctx context.Context, organizationID string, fnName string, event *events.CloudEvent, secrets []string) error {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
 
@@ -201,11 +221,14 @@ func (m *defaultManager) runFunction(ctx context.Context, organizationID string,
 		errorMsg := fmt.Sprintf("Unable to run function %s, error from function manager: %+v", fnName, err)
 		span.LogKV("error", errorMsg)
 		log.Error(errorMsg)
-		return
+		if clientErr, ok := err.(client.Error); ok && clientErr.Code() < http.StatusInternalServerError {
+			return events.Permanent(err)
+		}
+		return err
 	}
 	span.LogKV("functionName", result.FunctionName,
 		"functionResult", result.Output)
 	log.Debugf("Function %s returned %+v", result.FunctionName, result.Output)
 
-	return
+	return nil
 }
@@ -10,11 +10,13 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/vmware/dispatch/pkg/api/v1"
 	"github.com/vmware/dispatch/pkg/client"
 	clientmocks "github.com/vmware/dispatch/pkg/client/mocks"
+	"github.com/vmware/dispatch/pkg/event-manager/subscriptions/entities"
 	"github.com/vmware/dispatch/pkg/events"
 	eventsmocks "github.com/vmware/dispatch/pkg/events/mocks"
 )
@@ -33,9 +35,41 @@ func TestRunFunction(t *testing.T) {
 	manager := mockSubscriptionManager(queue, fnClient)
 	ev := &events.CloudEvent{}
 	fnClient.On("RunFunction", mock.Anything, testOrgID, mock.AnythingOfType("*v1.Run")).Return(&v1.Run{}, nil).Once()
-	manager.runFunction(context.Background(), testOrgID, "testFunction", ev, []string{"secret1", "secret2"})
+	err := manager.runFunction(context.Background(), testOrgID, "testFunction", ev, []string{"secret1", "secret2"})
+	assert.NoError(t, err)
 
 	fnClient.On("RunFunction", mock.Anything, testOrgID, mock.AnythingOfType("*v1.Run")).Return(&v1.Run{}, errors.New("testerror")).Once()
-	manager.runFunction(context.Background(), testOrgID, "testFunction", ev, nil)
-	fnClient.AssertNumberOfCalls(t, "RunFunction", 2)
+	err = manager.runFunction(context.Background(), testOrgID, "testFunction", ev, nil)
+	assert.Error(t, err)
+	assert.False(t, events.IsPermanent(err))
+
+	// the run was rejected, the event is not redelivered
+	fnClient.On("RunFunction", mock.Anything, testOrgID, mock.AnythingOfType("*v1.Run")).Return(nil, client.NewErrorBadRequest(&v1.Error{})).Once()
+	err = manager.runFunction(context.Background(), testOrgID, "testFunction", ev, nil)
+	assert.True(t, events.IsPermanent(err))
+	fnClient.AssertNumberOfCalls(t, "RunFunction", 3)
+}
+
+func TestHandlerFilter(t *testing.T) {
+	fnClient := &clientmocks.FunctionsClient{}
+	queue := &eventsmocks.Transport{}
+	manager := mockSubscriptionManager(queue, fnClient)
+	fnClient.On("RunFunction", mock.Anything, testOrgID, mock.AnythingOfType("*v1.Run")).Return(&v1.Run{}, nil)
+
+	sub := &entities.Subscription{
+		EventType: "vm.created",
+		Function:  "testFunction",
+		Filter:    &entities.SubscriptionFilter{Source: "vcenter*"},
+	}
+	sub.OrganizationID = testOrgID
+	handler := manager.handler(context.Background(), sub)
+	assert.NoError(t, handler(context.Background(), &events.CloudEvent{Source: "vcenter1"}))
+	// the event is acknowledged without running the function
+	assert.NoError(t, handler(context.Background(), &events.CloudEvent{Source: "vsphere1"}))
+	fnClient.AssertNumberOfCalls(t, "RunFunction", 1)
+
+	sub.Filter = &entities.SubscriptionFilter{Data: []string{"cpus"}}
+	handler = manager.handler(context.Background(), sub)
+	assert.True(t, events.IsPermanent(handler(context.Background(), &events.CloudEvent{})))
+	fnClient.AssertNumberOfCalls(t, "RunFunction", 1)
 }
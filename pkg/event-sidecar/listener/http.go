@@ -12,6 +12,8 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/events"
 )
 
 // HTTPListener implements EventListener using HTTP server
@@ -102,7 +104,7 @@ func (l *HTTPListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		log.Debugf("Pushing event %+v using topic %s and organization %s", ev, ev.DefaultTopic(), l.organization)
-		err = l.transport.Publish(spCtx, &ev, ev.DefaultTopic(), l.organization)
+		err = events.Publish(spCtx, l.transport, &ev, l.organization)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error publishing event with ID %s: %s", ev.EventID, err), http.StatusInternalServerError)
 			return
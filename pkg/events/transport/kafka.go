@@ -8,21 +8,52 @@ package transport
 import (
 	"context"
 	"encoding/json"
+	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/bsm/sarama-cluster"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/vmware/dispatch/pkg/events"
 	"github.com/vmware/dispatch/pkg/trace"
 )
 
+// Kafka start positions of the subscriptions without committed offsets
+const (
+	KafkaStartEarliest = "earliest"
+	KafkaStartLatest   = "latest"
+)
+
 // Kafka Implements transport interface using Kafka broker.
+//
+// Each subscription is a Kafka consumer group, which consumes all the partitions of the topic. The subscribers with
+// the same subscription ID (see events.WithSubscriptionID) share the partitions, and the offset of an event is
+// committed once it is acknowledged or given up, so a subscription resumes where it stopped. The events of a subscriber
+// are handled one at a time: the next ones wait while an event is redelivered.
 type Kafka struct {
 	producer     sarama.SyncProducer
-	consumer     sarama.Consumer
 	producerOnly bool
+
+	brokerAddrs   []string
+	redelivery    Redelivery
+	initialOffset int64
+	startTime     time.Time
+	newConsumer   func(group, topic string) (kafkaConsumer, error)
+
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+	consumers     sync.WaitGroup
+}
+
+// kafkaConsumer is the consumer of a consumer group, implemented by cluster.Consumer
+type kafkaConsumer interface {
+	Messages() <-chan *sarama.ConsumerMessage
+	MarkOffset(msg *sarama.ConsumerMessage, metadata string)
+	Close() error
 }
 
 // OptKafkaSendOnly creates producer only. Subscribe operation will panic
@@ -33,8 +64,51 @@ func OptKafkaSendOnly() func(k *Kafka) error {
 	}
 }
 
+// OptKafkaStartPosition sets where the subscriptions without committed offsets start consuming their topic: from the
+// earliest event kept by Kafka, from the latest (the default), or from the first event after an RFC3339 timestamp.
+func OptKafkaStartPosition(position string) func(k *Kafka) error {
+	return func(k *Kafka) error {
+		switch position {
+		case KafkaStartEarliest:
+			k.initialOffset = sarama.OffsetOldest
+		case "", KafkaStartLatest:
+			k.initialOffset = sarama.OffsetNewest
+		default:
+			t, err := time.Parse(time.RFC3339, position)
+			if err != nil {
+				return errors.Errorf("invalid Kafka start position %s, must be %s, %s or an RFC3339 timestamp", position, KafkaStartEarliest, KafkaStartLatest)
+			}
+			// the offsets of the new consumer groups are set from the timestamp, if the topic exists already
+			k.initialOffset = sarama.OffsetOldest
+			k.startTime = t
+		}
+		return nil
+	}
+}
+
+// OptKafkaRedelivery sets how the events whose handler failed are redelivered
+func OptKafkaRedelivery(redelivery Redelivery) func(k *Kafka) error {
+	return func(k *Kafka) error {
+		k.redelivery = redelivery
+		return nil
+	}
+}
+
 // NewKafka creates an instance of transport based on Kafka broker.
 func NewKafka(brokerAddrs []string, options ...func(k *Kafka) error) (*Kafka, error) {
+	k := Kafka{
+		brokerAddrs:   brokerAddrs,
+		redelivery:    DefaultRedelivery,
+		initialOffset: sarama.OffsetNewest,
+		subscriptions: make(map[*subscription]struct{}),
+	}
+	k.newConsumer = k.newGroupConsumer
+	for _, option := range options {
+		if err := option(&k); err != nil {
+			return nil, err
+		}
+	}
+
 	config := sarama.NewConfig()
 	config.Version = sarama.V0_11_0_0
 	config.Producer.Return.Successes = true
@@ -42,21 +116,83 @@ func NewKafka(brokerAddrs []string, options ...func(k *Kafka) error) (*Kafka, er
 	if err != nil {
 		return nil, err
 	}
+	k.producer = syncProducer
+	return &k, nil
+}
 
-	k := Kafka{
-		producer: syncProducer,
+// newGroupConsumer joins the consumer group of a subscription
+func (k *Kafka) newGroupConsumer(group, topic string) (kafkaConsumer, error) {
+	config := cluster.NewConfig()
+	config.Version = sarama.V0_11_0_0
+	config.Consumer.Offsets.Initial = k.initialOffset
+	if !k.startTime.IsZero() {
+		client, err := sarama.NewClient(k.brokerAddrs, &config.Config)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating Kafka client")
+		}
+		err = seedKafkaOffsets(client, group, topic, k.startTime)
+		client.Close()
+		if err != nil {
+			log.Warnf("Unable to start consumer group %s of topic %s at %s, starting at the earliest offsets: %+v", group, topic, k.startTime, err)
+		}
 	}
-	for _, option := range options {
-		// TODO: handle errors from options
-		option(&k)
+	return cluster.NewConsumer(k.brokerAddrs, group, []string{topic}, config)
+}
+
+// seedKafkaOffsets commits the offsets of the first events after start to a consumer group without committed offsets
+func seedKafkaOffsets(client sarama.Client, group, topic string, start time.Time) error {
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return errors.Wrapf(err, "error getting partitions of topic %s", topic)
+	}
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return errors.Wrapf(err, "error getting coordinator of consumer group %s", group)
 	}
-	if k.producerOnly {
-		return &k, nil
+
+	fetch := &sarama.OffsetFetchRequest{Version: 1, ConsumerGroup: group}
+	for _, partition := range partitions {
+		fetch.AddPartition(topic, partition)
 	}
-	config = sarama.NewConfig()
-	config.Version = sarama.V0_11_0_0
-	k.consumer, err = sarama.NewConsumer(brokerAddrs, config)
-	return &k, err
+	committed, err := coordinator.FetchOffset(fetch)
+	if err != nil {
+		return errors.Wrapf(err, "error fetching offsets of consumer group %s", group)
+	}
+	for _, partition := range partitions {
+		if block := committed.GetBlock(topic, partition); block != nil && block.Offset >= 0 {
+			// the group started already, it resumes from its offsets
+			return nil
+		}
+	}
+
+	commit := &sarama.OffsetCommitRequest{
+		Version:                 1,
+		ConsumerGroup:           group,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+	}
+	for _, partition := range partitions {
+		offset, err := client.GetOffset(topic, partition, start.UnixNano()/int64(time.Millisecond))
+		if err == nil && offset < 0 {
+			// no event after start
+			offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "error getting offset of partition %d of topic %s", partition, topic)
+		}
+		commit.AddBlock(topic, partition, offset, sarama.ReceiveTime, "")
+	}
+	res, err := coordinator.CommitOffset(commit)
+	if err != nil {
+		return errors.Wrapf(err, "error committing offsets of consumer group %s", group)
+	}
+	for _, partitionErrors := range res.Errors {
+		for partition, kerr := range partitionErrors {
+			if kerr != sarama.ErrNoError {
+				return errors.Wrapf(kerr, "error committing offset of partition %d of consumer group %s", partition, group)
+			}
+		}
+	}
+	return nil
 }
 
 // Publish publishes an event
@@ -106,21 +242,39 @@ func (k *Kafka) Subscribe(ctx context.Context, topic string, organization string
 
 	topicWithOrg := organization + "." + topic
 
-	doneChan := make(chan struct{})
+	// the replicas of a subscription share its consumer group, a subscriber without subscription ID gets all events
+	group := events.SubscriptionIDFrom(ctx)
+	if group == "" {
+		group = uuid.NewV4().String()
+	}
+	group = "dispatch." + organization + "." + group
 
-	// create partition consumer. Since we are creating only one consumer, we should automatically consume messages
-	// from all partitions regardless of the partition number we select
-	partitionConsumer, err := k.consumer.ConsumePartition(topicWithOrg, 0, sarama.OffsetNewest)
+	consumer, err := k.newConsumer(group, topicWithOrg)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error creating a partition consumer for topic %s in organization %s", topic, organization)
+		return nil, errors.Wrapf(err, "error creating a consumer for topic %s in organization %s", topic, organization)
 	}
 
+	doneChan := make(chan struct{})
+	sub := &subscription{done: doneChan, topic: topic, organization: organization}
+	k.mu.Lock()
+	k.subscriptions[sub] = struct{}{}
+	k.mu.Unlock()
+
+	k.consumers.Add(1)
 	go func() {
+		defer k.consumers.Done()
+		defer func() {
+			if err := consumer.Close(); err != nil {
+				log.Warnf("error when closing Kafka consumer group %s: %+v", group, err)
+			}
+			k.mu.Lock()
+			delete(k.subscriptions, sub)
+			k.mu.Unlock()
+		}()
 		for {
 			select {
-			case msg, open := <-partitionConsumer.Messages():
+			case msg, open := <-consumer.Messages():
 				if !open {
-					partitionConsumer.Close()
 					return
 				}
 				log.Debugf("Received a kafka message %+v", *msg)
@@ -132,27 +286,32 @@ func (k *Kafka) Subscribe(ctx context.Context, topic string, organization string
 					"Kafka.SubscriptionHandler",
 					opentracing.FollowsFrom(spCtx),
 				)
-				func() {
+				err = func() error {
 					defer spSub.Finish()
 					// Update the context with the span for the subsequent reference.
 					ctx = opentracing.ContextWithSpan(context.Background(), spSub)
 					event, err := toEvent(msg)
 					if err != nil {
 						log.Errorf("Error when converting Kafka message to event: %+v", err)
-						return
+						return nil
 					}
 					log.Debugf("Got an event %+v", event)
-					handler(ctx, event)
+					return k.redelivery.deliver(ctx, handler, event, 1, doneChan)
 				}()
+				if err == errUnsubscribed {
+					// the offset is not committed, the event is delivered again to the next consumer of the group
+					return
+				}
+				// the event was handled or given up, its offset is committed with the next commit of the group
+				consumer.MarkOffset(msg, "")
 
 			case <-doneChan:
-				partitionConsumer.Close()
 				return
 			}
 		}
 	}()
 
-	return &subscription{done: doneChan, topic: topic, organization: organization}, nil
+	return sub, nil
 }
 
 // Close closes the transport
@@ -160,12 +319,15 @@ func (k *Kafka) Close() {
 	if err := k.producer.Close(); err != nil {
 		log.Warnf("error when closing Kafka producer: %+v", err)
 	}
-	if k.consumer == nil {
-		return
-	}
-	if err := k.consumer.Close(); err != nil {
-		log.Warnf("error when closing Kafka consumer: %+v", err)
+	k.mu.Lock()
+	subscriptions := k.subscriptions
+	k.subscriptions = make(map[*subscription]struct{})
+	k.mu.Unlock()
+	for sub := range subscriptions {
+		sub.Unsubscribe()
 	}
+	// the consumers commit their offsets when they are closed
+	k.consumers.Wait()
 }
 
 // injectSpan injects OpenTracing Span into sarama.ProducerMessage.Headers structure.
@@ -9,7 +9,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/Shopify/sarama/mocks"
@@ -45,29 +47,187 @@ func TestKafkaPublish(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// fakeKafkaConsumer is a consumer group yielding messages and recording the marked offsets
+type fakeKafkaConsumer struct {
+	group    string
+	topic    string
+	messages chan *sarama.ConsumerMessage
+
+	mu     sync.Mutex
+	marked []int64
+	closed bool
+}
+
+func (c *fakeKafkaConsumer) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func (c *fakeKafkaConsumer) MarkOffset(msg *sarama.ConsumerMessage, metadata string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.marked = append(c.marked, msg.Offset)
+}
+
+func (c *fakeKafkaConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeKafkaConsumer) markedOffsets() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.marked
+}
+
+func (c *fakeKafkaConsumer) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func newTestKafka(t *testing.T, consumers chan *fakeKafkaConsumer) *Kafka {
+	return &Kafka{
+		producer:      mocks.NewSyncProducer(t, nil),
+		subscriptions: make(map[*subscription]struct{}),
+		newConsumer: func(group, topic string) (kafkaConsumer, error) {
+			c := &fakeKafkaConsumer{group: group, topic: topic, messages: make(chan *sarama.ConsumerMessage, 10)}
+			consumers <- c
+			return c, nil
+		},
+	}
+}
+
 func TestKafkaSubscribe(t *testing.T) {
-	consumer := mocks.NewConsumer(t, nil)
-	pc := consumer.ExpectConsumePartition(testOrg+"."+testTopic, 0, sarama.OffsetNewest)
-	pc.ExpectMessagesDrainedOnClose()
+	consumers := make(chan *fakeKafkaConsumer, 1)
+	kafka := newTestKafka(t, consumers)
 
 	event := events.NewCloudEventWithDefaults(testTopic)
 	eventBytes, _ := json.Marshal(event)
-	pc.YieldMessage(&sarama.ConsumerMessage{
-		Value: eventBytes,
-	})
-
-	kafka := &Kafka{
-		consumer: consumer,
-	}
 
 	done := make(chan struct{})
-
-	_, err := kafka.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) {
+	ctx := events.WithSubscriptionID(context.Background(), "sub-id")
+	sub, err := kafka.Subscribe(ctx, testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
 		assert.Equal(t, event.EventID, e.EventID)
+		assert.Equal(t, 1, events.DeliveryAttempt(e))
 		done <- struct{}{}
+		return nil
 	})
 	assert.NoError(t, err)
 
+	consumer := <-consumers
+	assert.Equal(t, "dispatch."+testOrg+".sub-id", consumer.group)
+	assert.Equal(t, testOrg+"."+testTopic, consumer.topic)
+
+	consumer.messages <- &sarama.ConsumerMessage{Value: eventBytes, Partition: 2, Offset: 7}
 	<-done
 
+	sub.Unsubscribe()
+	kafka.consumers.Wait()
+	// the offset is marked once the handler returned
+	assert.Equal(t, []int64{7}, consumer.markedOffsets())
+	assert.True(t, consumer.isClosed())
+}
+
+func TestKafkaSubscribeUnsubscribedDuringRedelivery(t *testing.T) {
+	consumers := make(chan *fakeKafkaConsumer, 1)
+	kafka := newTestKafka(t, consumers)
+	kafka.redelivery = Redelivery{InitialBackoff: time.Hour}
+
+	eventBytes, _ := json.Marshal(events.NewCloudEventWithDefaults(testTopic))
+	failed := make(chan struct{})
+	sub, err := kafka.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+		close(failed)
+		return errors.New("function manager unavailable")
+	})
+	assert.NoError(t, err)
+
+	consumer := <-consumers
+	consumer.messages <- &sarama.ConsumerMessage{Value: eventBytes, Offset: 3}
+	<-failed
+	sub.Unsubscribe()
+	kafka.consumers.Wait()
+	// the event was not handled, it is delivered again to the next consumer of the group
+	assert.Empty(t, consumer.markedOffsets())
+	assert.True(t, consumer.isClosed())
+}
+
+func TestKafkaSubscribeWithoutSubscriptionID(t *testing.T) {
+	consumers := make(chan *fakeKafkaConsumer, 2)
+	kafka := newTestKafka(t, consumers)
+
+	handler := func(ctx context.Context, e *events.CloudEvent) error { return nil }
+	_, err := kafka.Subscribe(context.Background(), testTopic, testOrg, handler)
+	assert.NoError(t, err)
+	_, err = kafka.Subscribe(context.Background(), testTopic, testOrg, handler)
+	assert.NoError(t, err)
+
+	// each subscriber gets all the events
+	first, second := <-consumers, <-consumers
+	assert.NotEqual(t, first.group, second.group)
+
+	kafka.Close()
+	assert.True(t, first.isClosed())
+	assert.True(t, second.isClosed())
+}
+
+func TestKafkaStartPosition(t *testing.T) {
+	k := &Kafka{}
+	assert.NoError(t, OptKafkaStartPosition(KafkaStartEarliest)(k))
+	assert.Equal(t, sarama.OffsetOldest, k.initialOffset)
+
+	assert.NoError(t, OptKafkaStartPosition(KafkaStartLatest)(k))
+	assert.Equal(t, sarama.OffsetNewest, k.initialOffset)
+
+	assert.NoError(t, OptKafkaStartPosition("2018-06-01T10:00:00Z")(k))
+	assert.Equal(t, sarama.OffsetOldest, k.initialOffset)
+	assert.Equal(t, time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC), k.startTime.UTC())
+
+	assert.Error(t, OptKafkaStartPosition("yesterday")(&Kafka{}))
+}
+
+func TestSeedKafkaOffsets(t *testing.T) {
+	const group = "dispatch.org.sub-id"
+	const topic = "org.topic"
+	start := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	startMillis := start.UnixNano() / int64(time.Millisecond)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()).
+			SetLeader(topic, 1, broker.BrokerID()),
+		"ConsumerMetadataRequest": sarama.NewMockConsumerMetadataResponse(t).
+			SetCoordinator(group, broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(group, topic, 0, -1, "", sarama.ErrNoError).
+			SetOffset(group, topic, 1, -1, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset(topic, 0, startMillis, 12).
+			SetOffset(topic, 1, startMillis, -1).
+			SetOffset(topic, 1, sarama.OffsetNewest, 30),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_11_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assert.NoError(t, seedKafkaOffsets(client, group, topic, start))
+
+	var committed *sarama.OffsetCommitRequest
+	for _, rr := range broker.History() {
+		if req, ok := rr.Request.(*sarama.OffsetCommitRequest); ok {
+			committed = req
+		}
+	}
+	if assert.NotNil(t, committed) {
+		assert.Equal(t, group, committed.ConsumerGroup)
+	}
 }
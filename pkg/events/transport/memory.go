@@ -8,83 +8,296 @@ package transport
 import (
 	"context"
 	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/vmware/dispatch/pkg/events"
 )
 
 const (
-	defaultQueueSize = 20
+	defaultBufferSize   = 1000
+	defaultDrainTimeout = 30 * time.Second
 )
 
-type queue map[string]chan events.CloudEvent
+// Overflow policies of the InMemory transport, applied when an event is published to a subscription whose buffer is
+// full
+const (
+	// OverflowReject drops the event for the subscription, and Publish returns an error
+	OverflowReject = "reject"
+	// OverflowDropNewest drops the event for the subscription
+	OverflowDropNewest = "drop-newest"
+	// OverflowDropOldest drops the oldest event of the buffer to make room for the event
+	OverflowDropOldest = "drop-oldest"
+)
 
 // InMemory provides event transport implemented completely in memory.
+//
+// Every subscription gets a copy of the events published to its topic, the subscribers with the same subscription ID
+// (see events.WithSubscriptionID) sharing the events of the subscription. The events of a subscription are buffered
+// until a subscriber handles them, up to bufferSize events. The events are lost when the process exits, and the
+// events published to a topic without subscription are dropped.
 type InMemory struct {
-	exchanges map[string]queue
-	mu        sync.Mutex
+	mu     sync.Mutex
+	topics map[string]map[string]*memoryGroup
+	closed bool
+	// closing is closed by Close, the subscribers then handle the events buffered and stop
+	closing     chan struct{}
+	subscribers sync.WaitGroup
+
+	bufferSize   int
+	overflow     string
+	redelivery   Redelivery
+	drainTimeout time.Duration
+}
 
-	queueSize int
+// memoryGroup is a subscription, with the events buffered for it
+type memoryGroup struct {
+	events      chan events.CloudEvent
+	subscribers int
+}
+
+// OptInMemoryBufferSize sets the number of events buffered for each subscription
+func OptInMemoryBufferSize(size int) func(m *InMemory) error {
+	return func(m *InMemory) error {
+		if size <= 0 {
+			return errors.New("buffer size must be positive")
+		}
+		m.bufferSize = size
+		return nil
+	}
+}
+
+// OptInMemoryOverflow sets what happens to the events published to a subscription whose buffer is full
+func OptInMemoryOverflow(policy string) func(m *InMemory) error {
+	return func(m *InMemory) error {
+		switch policy {
+		case OverflowReject, OverflowDropNewest, OverflowDropOldest:
+			m.overflow = policy
+			return nil
+		}
+		return errors.Errorf("invalid overflow policy %s, must be %s, %s or %s", policy, OverflowReject, OverflowDropNewest, OverflowDropOldest)
+	}
+}
+
+// OptInMemoryRedelivery sets how the events whose handler failed are redelivered
+func OptInMemoryRedelivery(redelivery Redelivery) func(m *InMemory) error {
+	return func(m *InMemory) error {
+		m.redelivery = redelivery
+		return nil
+	}
+}
+
+// OptInMemoryDrainTimeout sets how long Close waits for the subscribers to handle the events buffered
+func OptInMemoryDrainTimeout(timeout time.Duration) func(m *InMemory) error {
+	return func(m *InMemory) error {
+		if timeout <= 0 {
+			return errors.New("drain timeout must be positive")
+		}
+		m.drainTimeout = timeout
+		return nil
+	}
 }
 
 // NewInMemory returns an initialized instance of InMemory event transport.
-func NewInMemory() *InMemory {
-	return &InMemory{
-		exchanges: make(map[string]queue),
-		queueSize: defaultQueueSize,
+func NewInMemory(options ...func(m *InMemory) error) (*InMemory, error) {
+	m := &InMemory{
+		topics:     make(map[string]map[string]*memoryGroup),
+		closing:    make(chan struct{}),
+		bufferSize: defaultBufferSize,
+		overflow:   OverflowReject,
+		redelivery: DefaultRedelivery,
+
+		drainTimeout: defaultDrainTimeout,
+	}
+	for _, option := range options {
+		if err := option(m); err != nil {
+			return nil, err
+		}
 	}
+	return m, nil
 }
 
 // Publish implements Transport interface publish method
 func (m *InMemory) Publish(ctx context.Context, event *events.CloudEvent, topic string, organization string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	exchange, ok := m.exchanges[organization]
-	if !ok || exchange == nil {
-		return nil
+	if m.closed {
+		return errors.New("transport is closed")
 	}
-	queue, ok := exchange[topic]
-	if !ok || queue == nil {
+
+	groups := m.topics[organization+"."+topic]
+	if len(groups) == 0 {
+		log.Debugf("No subscription to topic %s in organization %s, event %s dropped", topic, organization, event.EventID)
 		return nil
 	}
-	queue <- *event
-
+	rejected := 0
+	for _, group := range groups {
+		if !m.enqueue(group, *event) {
+			rejected++
+		}
+	}
+	if rejected > 0 {
+		return errors.Errorf("buffer of %d subscription(s) to topic %s in organization %s is full, event %s dropped for them",
+			rejected, topic, organization, event.EventID)
+	}
 	return nil
 }
 
+// enqueue adds an event to the buffer of a subscription, applying the overflow policy. It returns false if the event
+// was rejected.
+func (m *InMemory) enqueue(group *memoryGroup, event events.CloudEvent) bool {
+	select {
+	case group.events <- event:
+		return true
+	default:
+	}
+	switch m.overflow {
+	case OverflowDropNewest:
+		log.Warnf("Subscription buffer full, event %s of type %s dropped", event.EventID, event.EventType)
+		return true
+	case OverflowDropOldest:
+		// the buffer is only filled with the lock held, there is room once an event is removed
+		select {
+		case dropped := <-group.events:
+			log.Warnf("Subscription buffer full, event %s of type %s dropped", dropped.EventID, dropped.EventType)
+		default:
+		}
+		select {
+		case group.events <- event:
+		default:
+		}
+		return true
+	}
+	return false
+}
+
 // Subscribe implements Transport interface subscribe method
 func (m *InMemory) Subscribe(ctx context.Context, topic string, organization string, handler events.Handler) (events.Subscription, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	exchange, ok := m.exchanges[organization]
-	if !ok || exchange == nil {
-		exchange = make(queue)
-		m.exchanges[organization] = exchange
+	if m.closed {
+		return nil, errors.New("transport is closed")
+	}
+
+	key := organization + "." + topic
+	groups := m.topics[key]
+	if groups == nil {
+		groups = make(map[string]*memoryGroup)
+		m.topics[key] = groups
+	}
+	id := events.SubscriptionIDFrom(ctx)
+	if id == "" {
+		id = uuid.NewV4().String()
 	}
-	queue, ok := exchange[topic]
-	if !ok || queue == nil {
-		queue = make(chan events.CloudEvent, m.queueSize)
-		exchange[topic] = queue
+	group := groups[id]
+	if group == nil {
+		group = &memoryGroup{events: make(chan events.CloudEvent, m.bufferSize)}
+		groups[id] = group
 	}
+	group.subscribers++
 
 	doneChan := make(chan struct{})
+	// stop interrupts the redelivery of an event when the subscription stops or the transport is closed, and the
+	// context of the handler is cancelled once the drain timeout has passed after Close
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	handlerCtx, cancel := context.WithCancel(ctx)
 	go func() {
+		defer cancel()
+		select {
+		case <-doneChan:
+		case <-m.closing:
+		}
+		close(stop)
+		select {
+		case <-stopped:
+			return
+		case <-m.closing:
+		}
+		timer := time.NewTimer(m.drainTimeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-stopped:
+		}
+	}()
+	m.subscribers.Add(1)
+	go func() {
+		defer m.subscribers.Done()
+		defer close(stopped)
+		defer m.unsubscribe(key, id, group)
 		for {
+			// stopping takes precedence over the buffered events
 			select {
-			case event := <-queue:
-				handler(ctx, &event)
 			case <-doneChan:
-				m.mu.Lock()
-				close(queue)
-				delete(exchange, topic)
-				m.mu.Unlock()
 				return
+			case <-m.closing:
+				m.drain(handlerCtx, group, handler, stop)
+				return
+			default:
+			}
+			select {
+			case event := <-group.events:
+				if m.redelivery.deliver(handlerCtx, handler, &event, 1, stop) == errUnsubscribed {
+					log.Warnf("Subscription to topic %s stopped, event %s of type %s dropped", key, event.EventID, event.EventType)
+				}
+			case <-doneChan:
+			case <-m.closing:
 			}
 		}
 	}()
 	return &subscription{done: doneChan, topic: topic, organization: organization}, nil
 }
 
-// Close implements Transport interface close method.
-func (m *InMemory) Close() {
+// drain handles the events left in the buffer of a subscription, each one once, until ctx is cancelled at the drain
+// timeout. The events left are dropped.
+func (m *InMemory) drain(ctx context.Context, group *memoryGroup, handler events.Handler, done chan struct{}) {
+	once := Redelivery{MaxAttempts: 1}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		select {
+		case event := <-group.events:
+			once.deliver(ctx, handler, &event, 1, done)
+		default:
+			return
+		}
+	}
+}
+
+// unsubscribe removes a subscriber, and the subscription with its buffered events once it has no subscriber left
+func (m *InMemory) unsubscribe(key, id string, group *memoryGroup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	group.subscribers--
+	if group.subscribers > 0 {
+		return
+	}
+	if n := len(group.events); n > 0 {
+		log.Warnf("Subscription to topic %s stopped, %d buffered events dropped", key, n)
+	}
+	delete(m.topics[key], id)
+	if len(m.topics[key]) == 0 {
+		delete(m.topics, key)
+	}
+}
 
+// Close implements Transport interface close method. The subscribers stop redelivering events, handle the events
+// already published once, within the drain timeout, then stop.
+func (m *InMemory) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.closing)
+	m.mu.Unlock()
+	m.subscribers.Wait()
 }
@@ -8,8 +8,11 @@ package transport
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/vmware/dispatch/pkg/events"
 )
@@ -17,20 +20,25 @@ import (
 func TestInMemoryPublish(t *testing.T) {
 	event := events.NewCloudEventWithDefaults(testTopic)
 
-	memory := NewInMemory()
+	memory, err := NewInMemory()
+	require.NoError(t, err)
+	defer memory.Close()
 
-	err := memory.Publish(context.Background(), &event, testTopic, testOrg)
+	err = memory.Publish(context.Background(), &event, testTopic, testOrg)
 	assert.NoError(t, err)
 }
 
 func TestInMemorySubscribe(t *testing.T) {
 	event := events.NewCloudEventWithDefaults(testTopic)
-	memory := NewInMemory()
+	memory, err := NewInMemory()
+	require.NoError(t, err)
+	defer memory.Close()
 
 	done := make(chan struct{})
-	_, err := memory.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) {
+	_, err = memory.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
 		assert.Equal(t, event.EventID, e.EventID)
 		done <- struct{}{}
+		return nil
 	})
 	assert.NoError(t, err)
 
@@ -38,5 +46,155 @@ func TestInMemorySubscribe(t *testing.T) {
 	assert.NoError(t, err)
 
 	<-done
+}
+
+func TestInMemoryFanOut(t *testing.T) {
+	memory, err := NewInMemory()
+	require.NoError(t, err)
+	defer memory.Close()
+
+	received := make(chan string, 10)
+	subscribe := func(id, name string) events.Subscription {
+		ctx := events.WithSubscriptionID(context.Background(), id)
+		sub, err := memory.Subscribe(ctx, testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+			received <- name
+			return nil
+		})
+		require.NoError(t, err)
+		return sub
+	}
+	subscribe("sub1", "sub1")
+	subscribe("sub2", "sub2-a")
+	subscribe("sub2", "sub2-b")
+
+	event := events.NewCloudEventWithDefaults(testTopic)
+	require.NoError(t, memory.Publish(context.Background(), &event, testTopic, testOrg))
+
+	// sub1 gets the event, and one of the subscribers of sub2
+	var got []string
+	for i := 0; i < 2; i++ {
+		got = append(got, <-received)
+	}
+	assert.Contains(t, got, "sub1")
+	assert.True(t, got[0] != got[1] && (got[0] != "sub1" || got[1] != "sub1"))
+	select {
+	case name := <-received:
+		t.Errorf("event delivered twice to a subscription, last to %s", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestInMemoryOverflow(t *testing.T) {
+	cases := []struct {
+		policy   string
+		rejected bool
+		expected []string
+	}{
+		{OverflowReject, true, []string{"1", "2"}},
+		{OverflowDropNewest, false, []string{"1", "2"}},
+		{OverflowDropOldest, false, []string{"2", "3"}},
+	}
+	for _, c := range cases {
+		t.Run(c.policy, func(t *testing.T) {
+			memory, err := NewInMemory(OptInMemoryBufferSize(2), OptInMemoryOverflow(c.policy))
+			require.NoError(t, err)
+
+			block := make(chan struct{})
+			var received []string
+			_, err = memory.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+				<-block
+				if e.EventID != "0" {
+					received = append(received, e.EventID)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+
+			// the first event is blocked in the handler, the next ones are buffered
+			event := events.NewCloudEventWithDefaults(testTopic)
+			event.EventID = "0"
+			require.NoError(t, memory.Publish(context.Background(), &event, testTopic, testOrg))
+			time.Sleep(50 * time.Millisecond)
+			for _, id := range []string{"1", "2", "3"} {
+				event.EventID = id
+				err = memory.Publish(context.Background(), &event, testTopic, testOrg)
+			}
+			assert.Equal(t, c.rejected, err != nil)
+
+			close(block)
+			memory.Close()
+			assert.Equal(t, c.expected, received)
+		})
+	}
+}
+
+func TestInMemoryClose(t *testing.T) {
+	memory, err := NewInMemory()
+	require.NoError(t, err)
+
+	handled := 0
+	_, err = memory.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+		time.Sleep(time.Millisecond)
+		handled++
+		return nil
+	})
+	require.NoError(t, err)
+
+	event := events.NewCloudEventWithDefaults(testTopic)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, memory.Publish(context.Background(), &event, testTopic, testOrg))
+	}
+	// the buffered events are handled before Close returns
+	memory.Close()
+	assert.Equal(t, 10, handled)
+
+	assert.Error(t, memory.Publish(context.Background(), &event, testTopic, testOrg))
+	_, err = memory.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestInMemoryCloseBounded(t *testing.T) {
+	memory, err := NewInMemory(OptInMemoryRedelivery(Redelivery{InitialBackoff: time.Millisecond}),
+		OptInMemoryDrainTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	handled := make(chan string, 100)
+	_, err = memory.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+		handled <- e.EventID
+		if e.EventID == "hang" {
+			<-ctx.Done()
+		}
+		return errors.New("handler failed")
+	})
+	require.NoError(t, err)
+
+	event := events.NewCloudEventWithDefaults(testTopic)
+	for _, id := range []string{"retried", "once", "hang", "dropped"} {
+		event.EventID = id
+		require.NoError(t, memory.Publish(context.Background(), &event, testTopic, testOrg))
+	}
+	// the first event is redelivered until Close, which neither retries the buffered events nor waits for them
+	time.Sleep(20 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		memory.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
 
+	close(handled)
+	counts := make(map[string]int)
+	for id := range handled {
+		counts[id]++
+	}
+	assert.True(t, counts["retried"] > 1)
+	assert.Equal(t, 1, counts["once"])
+	assert.Equal(t, 1, counts["hang"])
+	assert.Equal(t, 0, counts["dropped"])
 }
@@ -0,0 +1,364 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/events"
+	"github.com/vmware/dispatch/pkg/trace"
+)
+
+const (
+	// NATSDefaultStream is the JetStream stream holding the events, unless configured otherwise
+	NATSDefaultStream = "dispatch"
+	// natsDefaultAckWait is how long a delivered event waits for its acknowledgement before it is redelivered
+	natsDefaultAckWait = 30 * time.Second
+	// natsConsumerExpiry is how long the durable consumer of a subscription is kept once it has no subscriber
+	natsConsumerExpiry = 24 * time.Hour
+)
+
+// NATS implements transport interface using NATS JetStream.
+//
+// The events of an organization are published to the subject <stream>.<organization>.<topic> of a stream keeping them
+// until every consumer interested in them acknowledged them. The subscribers with the same subscription ID (see
+// events.WithSubscriptionID) share a durable consumer, delivered to a queue group of the same name, so each event goes
+// to one of them and a subscription resumes where it stopped. A subscriber without subscription ID gets an ephemeral
+// consumer deleted when it unsubscribes. The events of a subscriber are handled one at a time.
+type NATS struct {
+	conn       *nats.Conn
+	js         nats.JetStreamContext
+	stream     string
+	redelivery Redelivery
+	ackWait    time.Duration
+	closed     chan struct{}
+
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+}
+
+// OptNATSStream sets the name of the JetStream stream, which is also the prefix of the subjects of the events.
+func OptNATSStream(stream string) func(n *NATS) error {
+	return func(n *NATS) error {
+		if stream == "" || strings.ContainsAny(stream, ".*> \t") {
+			return errors.Errorf("invalid NATS stream name %q", stream)
+		}
+		n.stream = stream
+		return nil
+	}
+}
+
+// OptNATSRedelivery sets how the events whose handler failed are redelivered
+func OptNATSRedelivery(redelivery Redelivery) func(n *NATS) error {
+	return func(n *NATS) error {
+		n.redelivery = redelivery
+		return nil
+	}
+}
+
+// NewNATS creates an instance of transport based on NATS JetStream, and creates its stream if it does not exist.
+func NewNATS(url string, options ...func(n *NATS) error) (*NATS, error) {
+	n := NATS{
+		stream:        NATSDefaultStream,
+		redelivery:    DefaultRedelivery,
+		ackWait:       natsDefaultAckWait,
+		closed:        make(chan struct{}),
+		subscriptions: make(map[*subscription]struct{}),
+	}
+	for _, option := range options {
+		if err := option(&n); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := nats.Connect(url,
+		nats.Name("dispatch"),
+		nats.MaxReconnects(-1),
+		nats.ClosedHandler(func(*nats.Conn) { close(n.closed) }),
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			if err == nats.ErrConsumerNotFound {
+				// the ephemeral consumer of a stopped subscription was deleted
+				log.Debugf("NATS subscription to %s stopped: %v", sub.Subject, err)
+				return
+			}
+			log.Warnf("NATS error on subscription to %s: %+v", sub.Subject, err)
+		}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to establish a connection with NATS")
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "error getting the NATS JetStream context")
+	}
+	n.conn = conn
+	n.js = js
+
+	if _, err = js.StreamInfo(n.stream); err == nats.ErrStreamNotFound {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:      n.stream,
+			Subjects:  []string{n.stream + ".>"},
+			Retention: nats.InterestPolicy,
+			Storage:   nats.FileStorage,
+		})
+		if err == nats.ErrStreamNameAlreadyInUse {
+			// created by another replica in the meantime
+			err = nil
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "error creating NATS stream %s", n.stream)
+	}
+	return &n, nil
+}
+
+// subject returns the NATS subject of the events of topic in organization
+func (n *NATS) subject(topic, organization string) string {
+	return n.stream + "." + organization + "." + topic
+}
+
+// Publish publishes an event
+func (n *NATS) Publish(ctx context.Context, event *events.CloudEvent, topic string, organization string) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if organization == "" {
+		return errors.New("organization cannot be empty")
+	}
+
+	if topic == "" {
+		return errors.New("topic cannot be empty")
+	}
+
+	msg := nats.NewMsg(n.subject(topic, organization))
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "error when creating NATS message from CloudEvent for topic %s in organization %s", topic, organization)
+	}
+	msg.Data = data
+
+	if err = injectNATSSpan(span, msg); err != nil {
+		return errors.Wrap(err, "error injecting opentracing span to NATS message")
+	}
+	if _, err = n.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+		return errors.Wrapf(err, "error when publishing a message, topic: %s, organization: %s", topic, organization)
+	}
+	return nil
+}
+
+// Subscribe subscribes to an event
+func (n *NATS) Subscribe(ctx context.Context, topic string, organization string, handler events.Handler) (events.Subscription, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if organization == "" {
+		return nil, errors.New("organization cannot be empty")
+	}
+
+	if topic == "" {
+		return nil, errors.New("topic cannot be empty")
+	}
+
+	subject := n.subject(topic, organization)
+	doneChan := make(chan struct{})
+	cb := func(msg *nats.Msg) {
+		n.handle(msg, handler, doneChan)
+	}
+
+	var natsSub *nats.Subscription
+	var err error
+	if id := events.SubscriptionIDFrom(ctx); id != "" {
+		// the replicas of a subscription share its durable consumer through a queue group
+		group := natsName("dispatch-" + organization + "-" + id)
+		if err = n.addDurableConsumer(group, subject); err != nil {
+			return nil, err
+		}
+		// bound to an existing consumer, the subscription does not delete it when it stops
+		natsSub, err = n.js.QueueSubscribe(subject, group, cb, nats.Bind(n.stream, group), nats.ManualAck())
+	} else {
+		natsSub, err = n.js.Subscribe(subject, cb,
+			nats.ManualAck(),
+			nats.AckExplicit(),
+			nats.AckWait(n.ackWait),
+			nats.DeliverNew(),
+		)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error subscribing to topic %s in organization %s", topic, organization)
+	}
+
+	sub := &subscription{done: doneChan, topic: topic, organization: organization}
+	n.mu.Lock()
+	n.subscriptions[sub] = struct{}{}
+	n.mu.Unlock()
+
+	go func() {
+		<-doneChan
+		// the event being handled, if any, is not acknowledged and goes to another subscriber of the group
+		if err := natsSub.Drain(); err != nil && err != nats.ErrConnectionClosed {
+			log.Warnf("error when stopping NATS subscription to %s: %+v", subject, err)
+		}
+		n.mu.Lock()
+		delete(n.subscriptions, sub)
+		n.mu.Unlock()
+	}()
+	return sub, nil
+}
+
+// addDurableConsumer creates the durable consumer of a subscription, if it does not exist
+func (n *NATS) addDurableConsumer(name, subject string) error {
+	_, err := n.js.AddConsumer(n.stream, &nats.ConsumerConfig{
+		Durable:           name,
+		DeliverSubject:    n.stream + "-deliver." + name,
+		DeliverGroup:      name,
+		DeliverPolicy:     nats.DeliverNewPolicy,
+		AckPolicy:         nats.AckExplicitPolicy,
+		AckWait:           n.ackWait,
+		FilterSubject:     subject,
+		InactiveThreshold: natsConsumerExpiry,
+	})
+	if err == nil {
+		return nil
+	}
+	// the consumer exists with another configuration, e.g. created by a previous version, it is used as it is
+	if _, infoErr := n.js.ConsumerInfo(n.stream, name); infoErr == nil {
+		log.Warnf("Using existing NATS consumer %s: %v", name, err)
+		return nil
+	}
+	return errors.Wrapf(err, "error creating NATS consumer %s", name)
+}
+
+// handle delivers a message to handler, and acknowledges it once it is handled or given up
+func (n *NATS) handle(msg *nats.Msg, handler events.Handler, done <-chan struct{}) {
+	spCtx, err := extractNATSSpan(msg)
+	if err != nil {
+		log.Debugf("Unable to extract tracing span for message on subject %s: %+v", msg.Subject, err)
+	}
+	spSub := opentracing.StartSpan(
+		"NATS.SubscriptionHandler",
+		opentracing.FollowsFrom(spCtx),
+	)
+	defer spSub.Finish()
+	// Update the context with the span for the subsequent reference.
+	ctx := opentracing.ContextWithSpan(context.Background(), spSub)
+
+	event := &events.CloudEvent{}
+	if err := json.Unmarshal(msg.Data, event); err != nil {
+		log.Errorf("Error when converting NATS message to event: %+v", err)
+		msg.Term()
+		return
+	}
+	log.Debugf("Got an event %+v", event)
+
+	attempt := 1
+	if meta, err := msg.Metadata(); err == nil {
+		attempt = int(meta.NumDelivered)
+	}
+	stopProgress := n.keepInProgress(msg)
+	err = n.redelivery.deliver(ctx, handler, event, attempt, done)
+	stopProgress()
+	switch err {
+	case nil:
+		msg.Ack()
+	case errUnsubscribed:
+		// redelivered right away to another subscriber of the group
+		msg.Nak()
+	default:
+		msg.Term()
+	}
+}
+
+// keepInProgress keeps the message from being redelivered while its handler runs or waits for its redelivery. It
+// returns a function to call once the handler returned.
+func (n *NATS) keepInProgress(msg *nats.Msg) func() {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(n.ackWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					log.Debugf("Unable to extend the acknowledgement deadline of a message on %s: %v", msg.Subject, err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// Close stops all subscriptions, waiting for the events being handled, and closes the connection
+func (n *NATS) Close() {
+	n.mu.Lock()
+	subscriptions := n.subscriptions
+	n.subscriptions = make(map[*subscription]struct{})
+	n.mu.Unlock()
+	for sub := range subscriptions {
+		sub.Unsubscribe()
+	}
+	if err := n.conn.Drain(); err != nil {
+		log.Warnf("error when closing NATS connection: %+v", err)
+		n.conn.Close()
+	}
+	<-n.closed
+}
+
+// natsName replaces the characters not allowed in the names of NATS consumers and queue groups
+func natsName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// injectNATSSpan injects OpenTracing Span into nats.Msg.Header structure.
+func injectNATSSpan(span opentracing.Span, msg *nats.Msg) error {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	return span.Tracer().Inject(span.Context(), opentracing.TextMap, natsMsgHeaders(msg.Header))
+}
+
+// extractNATSSpan extracts OpenTracing Span from nats.Msg.Header structure.
+func extractNATSSpan(msg *nats.Msg) (opentracing.SpanContext, error) {
+	return opentracing.GlobalTracer().Extract(opentracing.TextMap, natsMsgHeaders(msg.Header))
+}
+
+// natsMsgHeaders implements opentracing.TextMapWriter and opentracing.TextMapReader interfaces
+type natsMsgHeaders nats.Header
+
+// Set sets value val for given key.
+func (h natsMsgHeaders) Set(key, val string) {
+	nats.Header(h).Set(key, val)
+}
+
+// ForeachKey executes handler for each key/value  tuple in headers
+func (h natsMsgHeaders) ForeachKey(handler func(key, val string) error) error {
+	for key, values := range h {
+		for _, val := range values {
+			if err := handler(key, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/dispatch/pkg/events"
+)
+
+// runNATSServer starts an embedded NATS server with JetStream, and returns a function stopping it
+func runNATSServer(t *testing.T) (*server.Server, func()) {
+	dir, err := ioutil.TempDir("", "nats")
+	require.NoError(t, err)
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  dir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	return s, func() {
+		s.Shutdown()
+		s.WaitForShutdown()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestNATS(t *testing.T, s *server.Server, options ...func(n *NATS) error) *NATS {
+	n, err := NewNATS(s.ClientURL(), options...)
+	require.NoError(t, err)
+	return n
+}
+
+// receive returns the next event received on events, failing the test if there is none
+func receive(t *testing.T, received <-chan *events.CloudEvent) *events.CloudEvent {
+	select {
+	case e := <-received:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func TestNATSPublishSubscribe(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	s, stop := runNATSServer(t)
+	defer stop()
+	n := newTestNATS(t, s)
+	defer n.Close()
+
+	received := make(chan *events.CloudEvent, 1)
+	parents := make(chan int, 1)
+	ctx := events.WithSubscriptionID(context.Background(), "sub-id")
+	_, err := n.Subscribe(ctx, testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+		parents <- opentracing.SpanFromContext(ctx).(*mocktracer.MockSpan).ParentID
+		received <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	event := events.NewCloudEventWithDefaults(testTopic)
+	require.NoError(t, n.Publish(context.Background(), &event, testTopic, testOrg))
+
+	e := receive(t, received)
+	assert.Equal(t, event.EventID, e.EventID)
+	assert.Equal(t, 1, events.DeliveryAttempt(e))
+
+	// the span of the handler follows from the span of Publish, propagated through the message headers
+	parent := <-parents
+	var published bool
+	for _, span := range tracer.FinishedSpans() {
+		published = published || span.SpanContext.SpanID == parent
+	}
+	assert.True(t, published)
+}
+
+func TestNATSSubscribeOrganization(t *testing.T) {
+	s, stop := runNATSServer(t)
+	defer stop()
+	n := newTestNATS(t, s)
+	defer n.Close()
+
+	received := make(chan *events.CloudEvent, 2)
+	_, err := n.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+		received <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	other := events.NewCloudEventWithDefaults(testTopic)
+	require.NoError(t, n.Publish(context.Background(), &other, testTopic, "other-org"))
+	event := events.NewCloudEventWithDefaults(testTopic)
+	require.NoError(t, n.Publish(context.Background(), &event, testTopic, testOrg))
+
+	assert.Equal(t, event.EventID, receive(t, received).EventID)
+	select {
+	case e := <-received:
+		t.Errorf("unexpected event %s of another organization", e.EventID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNATSSubscribeQueueGroup(t *testing.T) {
+	s, stop := runNATSServer(t)
+	defer stop()
+	n := newTestNATS(t, s)
+	defer n.Close()
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	received := make(chan *events.CloudEvent, 10)
+	subscribe := func(ctx context.Context, tr *NATS) {
+		_, err := tr.Subscribe(ctx, testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+			mu.Lock()
+			counts[e.EventID]++
+			mu.Unlock()
+			received <- e
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	// two replicas of the same subscription
+	ctx := events.WithSubscriptionID(context.Background(), "sub-id")
+	subscribe(ctx, n)
+	replica := newTestNATS(t, s)
+	defer replica.Close()
+	subscribe(ctx, replica)
+
+	for i := 0; i < 10; i++ {
+		event := events.NewCloudEventWithDefaults(testTopic)
+		require.NoError(t, n.Publish(context.Background(), &event, testTopic, testOrg))
+	}
+	for i := 0; i < 10; i++ {
+		receive(t, received)
+	}
+	// each event went to one of the replicas
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, counts, 10)
+	for id, count := range counts {
+		assert.Equal(t, 1, count, "event %s", id)
+	}
+}
+
+func TestNATSSubscribeRedelivery(t *testing.T) {
+	s, stop := runNATSServer(t)
+	defer stop()
+	n := newTestNATS(t, s, OptNATSRedelivery(Redelivery{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}))
+	defer n.Close()
+
+	attempts := make(chan int, 3)
+	_, err := n.Subscribe(context.Background(), testTopic, testOrg, func(ctx context.Context, e *events.CloudEvent) error {
+		attempts <- events.DeliveryAttempt(e)
+		if events.DeliveryAttempt(e) == 1 {
+			return errors.New("function manager unavailable")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	event := events.NewCloudEventWithDefaults(testTopic)
+	require.NoError(t, n.Publish(context.Background(), &event, testTopic, testOrg))
+	assert.Equal(t, 1, <-attempts)
+	assert.Equal(t, 2, <-attempts)
+}
+
+func TestNATSSubscribeResume(t *testing.T) {
+	s, stop := runNATSServer(t)
+	defer stop()
+
+	received := make(chan *events.CloudEvent, 1)
+	handler := func(ctx context.Context, e *events.CloudEvent) error {
+		received <- e
+		return nil
+	}
+	ctx := events.WithSubscriptionID(context.Background(), "sub-id")
+	stopped := newTestNATS(t, s)
+	_, err := stopped.Subscribe(ctx, testTopic, testOrg, handler)
+	require.NoError(t, err)
+	stopped.Close()
+
+	// the events published while the subscription is stopped are kept by its durable consumer
+	n := newTestNATS(t, s)
+	defer n.Close()
+	event := events.NewCloudEventWithDefaults(testTopic)
+	require.NoError(t, n.Publish(context.Background(), &event, testTopic, testOrg))
+
+	_, err = n.Subscribe(ctx, testTopic, testOrg, handler)
+	require.NoError(t, err)
+	assert.Equal(t, event.EventID, receive(t, received).EventID)
+}
+
+func TestNATSName(t *testing.T) {
+	assert.Equal(t, "dispatch-my_org-sub_1", natsName("dispatch-my.org-sub*1"))
+	assert.Error(t, OptNATSStream("dispatch.events")(&NATS{}))
+}
@@ -9,6 +9,7 @@ package transport
 
 import (
 	"context"
+	"time"
 
 	"github.com/opentracing-contrib/go-amqp/amqptracer"
 	"github.com/opentracing/opentracing-go"
@@ -23,6 +24,10 @@ import (
 const (
 	// rabbitMQDefaultExchange is the default exchange name when using the rabbitmq transport
 	rabbitMQDefaultExchange = "dispatch"
+	// rabbitMQDefaultQueueExpiry is how long the queue of a subscription is kept once it has no consumer
+	rabbitMQDefaultQueueExpiry = 24 * time.Hour
+	// RabbitMQDefaultDeadLetterExchange is the default exchange of the events given up by subscriptions
+	RabbitMQDefaultDeadLetterExchange = "dispatch.dead-letter"
 )
 
 // RabbitMQ implements transport over AMQP protocol and RabbitMQ messaging service
@@ -34,6 +39,10 @@ type RabbitMQ struct {
 	sendOnly     bool
 	sendConn     *amqp.Connection
 	recvConn     *amqp.Connection
+	redelivery   Redelivery
+	queueExpiry  time.Duration
+	// deadLetterExchange receives the events given up by the durable queues, they are dropped if it is empty
+	deadLetterExchange string
 }
 
 // OptRabbitMQSendOnly creates only sending connection. Subscribe operation will panic.
@@ -55,6 +64,35 @@ func OptRabbitMQExchangeName(exchangeName string) func(mq *RabbitMQ) error {
 	}
 }
 
+// OptRabbitMQRedelivery sets how the events whose handler failed are redelivered
+func OptRabbitMQRedelivery(redelivery Redelivery) func(mq *RabbitMQ) error {
+	return func(mq *RabbitMQ) error {
+		mq.redelivery = redelivery
+		return nil
+	}
+}
+
+// OptRabbitMQQueueExpiry sets how long the durable queue of a subscription is kept once it has no consumer.
+func OptRabbitMQQueueExpiry(expiry time.Duration) func(mq *RabbitMQ) error {
+	return func(mq *RabbitMQ) error {
+		if expiry <= 0 {
+			return errors.New("queue expiry must be positive")
+		}
+		mq.queueExpiry = expiry
+		return nil
+	}
+}
+
+// OptRabbitMQDeadLetterExchange sets the exchange the events given up by subscriptions are sent to. The exchange is
+// declared with a durable queue of the same name, which keeps them. If exchangeName is empty, given up events are
+// dropped.
+func OptRabbitMQDeadLetterExchange(exchangeName string) func(mq *RabbitMQ) error {
+	return func(mq *RabbitMQ) error {
+		mq.deadLetterExchange = exchangeName
+		return nil
+	}
+}
+
 // NewRabbitMQ creates new instance of RabbitMQ MessageQueue driver. Accepts
 // variadic list of function options.
 func NewRabbitMQ(url string, options ...func(mq *RabbitMQ) error) (mq *RabbitMQ, err error) {
@@ -62,6 +100,10 @@ func NewRabbitMQ(url string, options ...func(mq *RabbitMQ) error) (mq *RabbitMQ,
 		url:          url,
 		exchangeName: rabbitMQDefaultExchange,
 		done:         make(chan struct{}),
+		redelivery:   DefaultRedelivery,
+		queueExpiry:  rabbitMQDefaultQueueExpiry,
+
+		deadLetterExchange: RabbitMQDefaultDeadLetterExchange,
 	}
 
 	for _, option := range options {
@@ -109,10 +151,46 @@ func NewRabbitMQ(url string, options ...func(mq *RabbitMQ) error) (mq *RabbitMQ,
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to to declare an exchange")
 	}
+	if !mq.sendOnly && mq.deadLetterExchange != "" {
+		if err := mq.declareDeadLetterExchange(ch); err != nil {
+			return nil, err
+		}
+	}
 
 	return mq, nil
 }
 
+// declareDeadLetterExchange declares the dead-letter exchange, and the durable queue keeping its events
+func (mq *RabbitMQ) declareDeadLetterExchange(ch *amqp.Channel) error {
+	err := ch.ExchangeDeclare(
+		mq.deadLetterExchange, // name
+		"fanout",              // kind
+		true,                  // durable
+		false,                 // delete when unused
+		false,                 // internal
+		false,                 // no-wait
+		nil,                   // arguments
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed to declare the dead-letter exchange %s", mq.deadLetterExchange)
+	}
+	q, err := ch.QueueDeclare(
+		mq.deadLetterExchange, // name
+		true,                  // durable
+		false,                 // delete when unused
+		false,                 // exclusive
+		false,                 // no-wait
+		nil,                   // arguments
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed to declare the dead-letter queue %s", mq.deadLetterExchange)
+	}
+	if err := ch.QueueBind(q.Name, "", mq.deadLetterExchange, false, nil); err != nil {
+		return errors.Wrapf(err, "failed to bind the dead-letter queue %s", q.Name)
+	}
+	return nil
+}
+
 // Publish sends an event to RabbitMQ. Both topic and organization must be non-empty strings.
 func (mq *RabbitMQ) Publish(ctx context.Context, event *events.CloudEvent, topic string, organization string) error {
 	span, ctx := trace.Trace(ctx, "")
@@ -157,7 +235,9 @@ func (mq *RabbitMQ) Publish(ctx context.Context, event *events.CloudEvent, topic
 }
 
 // Subscribe creates an active subscription on specified topic, and invokes handler function
-// for every event received on given topic.
+// for every event received on given topic. The subscribers with the same subscription ID (see
+// events.WithSubscriptionID) share a durable queue, whose events are kept while no subscriber is running, otherwise
+// the subscriber gets an exclusive queue deleted when it unsubscribes.
 func (mq *RabbitMQ) Subscribe(ctx context.Context, topic string, organization string, handler events.Handler) (events.Subscription, error) {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
@@ -171,19 +251,23 @@ func (mq *RabbitMQ) Subscribe(ctx context.Context, topic string, organization st
 	}
 
 	topicWithOrg := organization + "." + topic
-	ch, q, err := mq.initQueue(topicWithOrg)
+	var queueName string
+	if id := events.SubscriptionIDFrom(ctx); id != "" {
+		queueName = "dispatch." + topicWithOrg + "." + id
+	}
+	ch, q, err := mq.initQueue(topicWithOrg, queueName)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error initializing from queue %s", q.Name)
+		return nil, errors.Wrapf(err, "error initializing queue for topic %s", topicWithOrg)
 	}
 
 	msgs, err := ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		false,  // auto ack
-		true,   // exclusive
-		false,  // no local
-		false,  // no wait
-		nil,    // args
+		q.Name,          // queue
+		"",              // consumer
+		false,           // auto ack
+		queueName == "", // exclusive
+		false,           // no local
+		false,           // no wait
+		nil,             // args
 	)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error when creating consume channel for queue %s", q.Name)
@@ -207,9 +291,19 @@ func (mq *RabbitMQ) Subscribe(ctx context.Context, topic string, organization st
 				ctx = opentracing.ContextWithSpan(context.Background(), spSub)
 				log.Debugf("Got an event: %s, %s, %s", msg.Exchange, msg.MessageId, msg.ContentType)
 				event := mq.msgToEvent(msg)
-				handler(ctx, event)
-				msg.Ack(false)
+				err := mq.redelivery.deliver(ctx, handler, event, deliveryAttempt(msg), doneChan)
 				spSub.Finish()
+				switch err {
+				case nil:
+					msg.Ack(false)
+				case errUnsubscribed:
+					// closing the channel requeues the message
+					ch.Close()
+					return
+				default:
+					// the message is sent to the dead-letter exchange of durable queues, and dropped otherwise
+					msg.Nack(false, false)
+				}
 			case <-doneChan:
 				ch.Close()
 				return
@@ -219,8 +313,46 @@ func (mq *RabbitMQ) Subscribe(ctx context.Context, topic string, organization st
 	return &subscription{done: doneChan, topic: topic, organization: organization}, nil
 }
 
+// deliveryAttempt returns the attempt of a delivery, counting its previous deliveries. Quorum queues count them in
+// the x-delivery-count header, while classic queues only count the times the message was dead-lettered in x-death,
+// and flag a message requeued since, e.g. as its subscriber stopped, as redelivered.
+func deliveryAttempt(msg amqp.Delivery) int {
+	if count, ok := headerCount(msg.Headers["x-delivery-count"]); ok {
+		return count + 1
+	}
+	attempt := 1
+	if deaths, ok := msg.Headers["x-death"].([]interface{}); ok {
+		for _, death := range deaths {
+			if table, ok := death.(amqp.Table); ok {
+				count, _ := headerCount(table["count"])
+				attempt += count
+			}
+		}
+	}
+	if msg.Redelivered {
+		attempt++
+	}
+	return attempt
+}
+
+// headerCount converts the integer value of an AMQP header
+func headerCount(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	}
+	return 0, false
+}
+
 func (mq *RabbitMQ) eventToMsg(event *events.CloudEvent) amqp.Publishing {
 	return amqp.Publishing{
+		DeliveryMode:  amqp.Persistent,
 		CorrelationId: event.Source,
 		ContentType:   event.ContentType,
 		MessageId:     event.EventID,
@@ -248,21 +380,48 @@ func (mq *RabbitMQ) msgToEvent(message amqp.Delivery) *events.CloudEvent {
 	}
 }
 
-// initQueue initializes and binds to a queue
-func (mq *RabbitMQ) initQueue(topic string) (*amqp.Channel, *amqp.Queue, error) {
+// initQueue initializes and binds to a queue. The queue is durable if it is named, and exclusive otherwise.
+func (mq *RabbitMQ) initQueue(topic string, name string) (*amqp.Channel, *amqp.Queue, error) {
 	ch, err := mq.recvConn.Channel()
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to acquire a RabbitMQ channel")
 	}
+	// the events are handled one at a time, the next ones can go to the other subscribers of the queue
+	if err := ch.Qos(1, 0, false); err != nil {
+		ch.Close()
+		return nil, nil, errors.Wrap(err, "error when setting the prefetch count of a RabbitMQ channel")
+	}
+	durable := name != ""
+	var args amqp.Table
+	if durable {
+		args = amqp.Table{"x-expires": int64(mq.queueExpiry / time.Millisecond)}
+		if mq.deadLetterExchange != "" {
+			args["x-dead-letter-exchange"] = mq.deadLetterExchange
+		}
+	}
 	q, err := ch.QueueDeclare(
-		"",    // name
-		false, // durable
-		true,  // delete when unused
-		true,  // exclusive
-		false, // no-wait
-		nil,   // arguments
+		name,     // name
+		durable,  // durable
+		!durable, // delete when unused
+		!durable, // exclusive
+		false,    // no-wait
+		args,     // arguments
 	)
+	if amqpErr, ok := err.(*amqp.Error); ok && durable && amqpErr.Code == amqp.PreconditionFailed {
+		// the queue exists with other arguments, e.g. it was declared before the dead-letter exchange was set. The
+		// failure closed the channel, the queue is used as it is.
+		log.Warnf("Queue %s exists with other arguments, its events may be dropped when given up: %v", name, err)
+		if ch, err = mq.recvConn.Channel(); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to acquire a RabbitMQ channel")
+		}
+		if err := ch.Qos(1, 0, false); err != nil {
+			ch.Close()
+			return nil, nil, errors.Wrap(err, "error when setting the prefetch count of a RabbitMQ channel")
+		}
+		q, err = ch.QueueDeclarePassive(name, durable, false, false, false, nil)
+	}
 	if err != nil {
+		ch.Close()
 		return nil, nil, errors.Wrap(err, "error when declaring a queue")
 	}
 
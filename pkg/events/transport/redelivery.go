@@ -0,0 +1,77 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package transport
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/events"
+)
+
+// Redelivery configures how the events whose handler failed are redelivered
+type Redelivery struct {
+	// MaxAttempts is the number of deliveries of an event before it is given up (0 means no limit)
+	MaxAttempts int
+	// InitialBackoff is the delay before the first redelivery, it doubles on every redelivery up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRedelivery is the redelivery of the transports, unless configured otherwise
+var DefaultRedelivery = Redelivery{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+// errUnsubscribed is returned by deliver when the subscription stopped before the event was handled
+var errUnsubscribed = errors.New("subscription stopped")
+
+// backoff returns the delay after the delivery attempt of an event failed
+func (r Redelivery) backoff(attempt int) time.Duration {
+	backoff := r.InitialBackoff
+	for i := 1; i < attempt && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return backoff
+}
+
+// deliver runs handler on event, starting at delivery attempt, until it succeeds. It returns nil once the event is
+// handled, the error of the last attempt if the event is given up, or errUnsubscribed if done is closed before. The
+// event must not be acknowledged in the latter case, so it is redelivered to the next subscriber.
+func (r Redelivery) deliver(ctx context.Context, handler events.Handler, event *events.CloudEvent, attempt int, done <-chan struct{}) error {
+	extensions := make(events.CloudEventExtensions, len(event.Extensions)+1)
+	for k, v := range event.Extensions {
+		extensions[k] = v
+	}
+	event.Extensions = extensions
+
+	for ; ; attempt++ {
+		event.Extensions[events.DeliveryAttemptExtension] = attempt
+		err := handler(ctx, event)
+		if err == nil {
+			return nil
+		}
+		if events.IsPermanent(err) || (r.MaxAttempts > 0 && attempt >= r.MaxAttempts) {
+			log.Errorf("Giving up event %s of type %s after %d attempts: %+v", event.EventID, event.EventType, attempt, err)
+			return err
+		}
+		backoff := r.backoff(attempt)
+		log.Debugf("Redelivering event %s of type %s in %s after error: %v", event.EventID, event.EventType, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-done:
+			return errUnsubscribed
+		}
+	}
+}
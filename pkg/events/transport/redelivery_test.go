@@ -0,0 +1,76 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/dispatch/pkg/events"
+)
+
+func TestRedeliveryBackoff(t *testing.T) {
+	r := Redelivery{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, r.backoff(1))
+	assert.Equal(t, 2*time.Second, r.backoff(2))
+	assert.Equal(t, 4*time.Second, r.backoff(3))
+	assert.Equal(t, 5*time.Second, r.backoff(4))
+	assert.Equal(t, 5*time.Second, r.backoff(10))
+}
+
+func TestRedeliveryDeliver(t *testing.T) {
+	r := Redelivery{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	event := events.NewCloudEventWithDefaults(testTopic)
+	event.Extensions = events.CloudEventExtensions{"key": "value"}
+	original := event.Extensions
+
+	var attempts []int
+	err := r.deliver(context.Background(), func(ctx context.Context, e *events.CloudEvent) error {
+		attempts = append(attempts, events.DeliveryAttempt(e))
+		assert.Equal(t, "value", e.Extensions["key"])
+		if len(attempts) < 2 {
+			return errors.New("not yet")
+		}
+		return nil
+	}, &event, 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, attempts)
+	// the extensions of the published event are left alone
+	assert.NotContains(t, original, events.DeliveryAttemptExtension)
+
+	// given up after MaxAttempts
+	attempts = nil
+	err = r.deliver(context.Background(), func(ctx context.Context, e *events.CloudEvent) error {
+		attempts = append(attempts, events.DeliveryAttempt(e))
+		return errors.New("failed")
+	}, &event, 1, nil)
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+
+	// a permanent error is not redelivered
+	attempts = nil
+	err = r.deliver(context.Background(), func(ctx context.Context, e *events.CloudEvent) error {
+		attempts = append(attempts, events.DeliveryAttempt(e))
+		return events.Permanent(errors.New("rejected"))
+	}, &event, 1, nil)
+	assert.True(t, events.IsPermanent(err))
+	assert.Equal(t, []int{1}, attempts)
+}
+
+func TestRedeliveryDeliverUnsubscribed(t *testing.T) {
+	r := Redelivery{InitialBackoff: time.Hour}
+	event := events.NewCloudEventWithDefaults(testTopic)
+	done := make(chan struct{})
+	err := r.deliver(context.Background(), func(ctx context.Context, e *events.CloudEvent) error {
+		close(done)
+		return errors.New("failed")
+	}, &event, 1, done)
+	assert.Equal(t, errUnsubscribed, err)
+}
@@ -5,22 +5,23 @@
 
 package transport
 
+import "sync"
+
 // NO TESTS
 
 // subscription is an implementation of Subscription interface, used to
 // stop not needed subscriptions
 type subscription struct {
 	done         chan struct{}
-	unsubscribed bool
+	unsubscribe  sync.Once
 	topic        string
 	organization string
 }
 
 func (sub *subscription) Unsubscribe() error {
-	if !sub.unsubscribed {
+	sub.unsubscribe.Do(func() {
 		close(sub.done)
-		sub.unsubscribed = true
-	}
+	})
 	return nil
 }
 
@@ -8,6 +8,8 @@ package events
 import (
 	"context"
 	"io"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // NO TESTS
@@ -18,13 +20,81 @@ type Transport interface {
 	// Publish publishes the event using the underlying transport
 	Publish(ctx context.Context, event *CloudEvent, topic string, organization string) error
 
-	// Subscribe takes a handler to run on every event received on topic. Returns a cancelable Subscription
+	// Subscribe takes a handler to run on every event received on topic. Returns a cancelable Subscription.
+	// An event is acknowledged once the handler returned nil. If the handler fails, the event is redelivered with
+	// backoff, with its delivery attempt in the DeliveryAttemptExtension, until the transport gives up on it.
 	Subscribe(ctx context.Context, topic string, organization string, handler Handler) (Subscription, error)
 	Close()
 }
 
-// Handler is a callback function used to handle received event
-type Handler func(context.Context, *CloudEvent)
+// AllEventsTopic is the topic every event is also published to, for the subscriptions to an event type pattern such
+// as vm.*. The validator rejects it as event type.
+const AllEventsTopic = "dispatch.all-events"
+
+// Publish publishes event to the topic of its type, then to AllEventsTopic. Once the event is published to the topic
+// of its type, a failure to publish it to AllEventsTopic is only logged: returning it would have the caller publish
+// the event again to the subscriptions to its type.
+func Publish(ctx context.Context, transport Transport, event *CloudEvent, organization string) error {
+	if err := transport.Publish(ctx, event, event.DefaultTopic(), organization); err != nil {
+		return err
+	}
+	if err := transport.Publish(ctx, event, AllEventsTopic, organization); err != nil {
+		log.Errorf("Error publishing event %s to the subscriptions to event type patterns: %+v", event.EventID, err)
+	}
+	return nil
+}
+
+// Handler is a callback function used to handle received event. It returns nil to acknowledge the event, and an
+// error to have it redelivered, unless the error is Permanent.
+type Handler func(context.Context, *CloudEvent) error
+
+// DeliveryAttemptExtension is the CloudEvent extension holding the delivery attempt of an event, starting at 1
+const DeliveryAttemptExtension = "dispatchDeliveryAttempt"
+
+// DeliveryAttempt returns the delivery attempt of event, or 0 if it is unknown
+func DeliveryAttempt(event *CloudEvent) int {
+	switch v := event.Extensions[DeliveryAttemptExtension].(type) {
+	case int:
+		return v
+	case float64:
+		// decoded from JSON
+		return int(v)
+	}
+	return 0
+}
+
+type permanentError struct {
+	error
+}
+
+// Permanent marks the error of a handler as permanent: the event is not redelivered, since it would fail again
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent tells whether the error of a handler is permanent
+func IsPermanent(err error) bool {
+	_, ok := err.(permanentError)
+	return ok
+}
+
+type subscriptionIDKey struct{}
+
+// WithSubscriptionID returns a copy of ctx holding the ID of the subscription Subscribe is called for. Transports
+// supporting it share the events of a subscription between all the subscribers with the same ID (e.g. the replicas of
+// the event manager), and resume it from the last event handled.
+func WithSubscriptionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, subscriptionIDKey{}, id)
+}
+
+// SubscriptionIDFrom returns the subscription ID of ctx, or an empty string if there is none
+func SubscriptionIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(subscriptionIDKey{}).(string)
+	return id
+}
 
 // Subscription represents an active subscription within Transport. Subscription can be stopped
 // by calling Unsubscribe()
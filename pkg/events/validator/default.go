@@ -15,7 +15,8 @@ var extraValidators = map[string]govalidator.Func{
 }
 
 func eventType(fl govalidator.FieldLevel) bool {
-	return eventTypeRegex.MatchString(fl.Field().String())
+	// the topic of all events is reserved
+	return eventTypeRegex.MatchString(fl.Field().String()) && fl.Field().String() != events.AllEventsTopic
 }
 
 var validator = NewDefaultValidator()
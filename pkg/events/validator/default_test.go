@@ -36,4 +36,7 @@ func TestDefaultValidate(t *testing.T) {
 	incorrect := testEvent1
 	incorrect.EventID = ""
 	assert.Error(t, v.Validate(&incorrect))
+	reserved := testEvent1
+	reserved.EventType = events.AllEventsTopic
+	assert.Error(t, v.Validate(&reserved))
 }
@@ -0,0 +1,169 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package functionmanager
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/functions"
+)
+
+// runCancelPollPeriod is how often the runs are checked for cancellation, in case a store event was missed
+const runCancelPollPeriod = 10 * time.Second
+
+// runCancellations notifies the running runs of their cancellation through the API. The runs share a single store
+// watch, which runs while there is a run to notify, and their status is also polled in case the store dropped an event.
+type runCancellations struct {
+	store      entitystore.EntityStore
+	pollPeriod time.Duration
+
+	mu sync.Mutex
+	// runs are the watched runs by ID
+	runs map[string]*cancellableRun
+	// stop stops the watch, it is nil when no run is watched
+	stop context.CancelFunc
+}
+
+// cancellableRun is a run watched for cancellation
+type cancellableRun struct {
+	organizationID string
+	name           string
+	cancelled      chan struct{}
+}
+
+func newRunCancellations(store entitystore.EntityStore) *runCancellations {
+	return &runCancellations{
+		store:      store,
+		pollPeriod: runCancelPollPeriod,
+		runs:       make(map[string]*cancellableRun),
+	}
+}
+
+// watch returns a channel which is closed once the run is cancelled, and a function which stops watching the run.
+// The returned channel is closed right away if the run was already cancelled.
+func (c *runCancellations) watch(run *functions.FnRun) (<-chan struct{}, func()) {
+	r := &cancellableRun{organizationID: run.OrganizationID, name: run.Name, cancelled: make(chan struct{})}
+	c.mu.Lock()
+	c.runs[run.ID] = r
+	if c.stop == nil {
+		var ctx context.Context
+		ctx, c.stop = context.WithCancel(context.Background())
+		go c.run(ctx, c.watchRuns(ctx, 0))
+	}
+	c.mu.Unlock()
+
+	// the run may have been cancelled before it was watched
+	c.check(context.Background(), run.ID)
+	return r.cancelled, func() { c.unwatch(run.ID, r) }
+}
+
+// unwatch stops watching a run, and stops the watch once no run is watched
+func (c *runCancellations) unwatch(id string, r *cancellableRun) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.runs[id] == r {
+		delete(c.runs, id)
+	}
+	if len(c.runs) == 0 && c.stop != nil {
+		c.stop()
+		c.stop = nil
+	}
+}
+
+// watchRuns watches the runs from the store revision since. If the changes since are no longer kept, the runs are
+// watched from the current revision and checked.
+func (c *runCancellations) watchRuns(ctx context.Context, since uint64) <-chan entitystore.Event {
+	dataType := entitystore.DataType(reflect.TypeOf(functions.FnRun{}).Name())
+	events, err := c.store.Watch(ctx, dataType, "", since)
+	if errors.Cause(err) == entitystore.ErrRevisionTooOld {
+		events, err = c.store.Watch(ctx, dataType, "", 0)
+		if err == nil {
+			go c.checkAll(ctx)
+		}
+	}
+	if err != nil {
+		log.Warnf("Unable to watch runs, their cancellation is checked every %s: %v", c.pollPeriod, err)
+	}
+	return events
+}
+
+// run dispatches the store events of the runs to the watched runs, and polls them, until ctx is done
+func (c *runCancellations) run(ctx context.Context, events <-chan entitystore.Event) {
+	ticker := time.NewTicker(c.pollPeriod)
+	defer ticker.Stop()
+	var revision uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// the watch ended, e.g. the store connection was lost
+				if ctx.Err() == nil {
+					log.Warnf("Watch of runs ended, their cancellation is checked every %s", c.pollPeriod)
+				}
+				events = nil
+				continue
+			}
+			if event.Type == entitystore.EventError {
+				// the watch failed, e.g. it lost changes as it was too slow
+				log.Warnf("Watch of runs failed, resuming it: %v", event.Err)
+				events = c.watchRuns(ctx, revision)
+				continue
+			}
+			revision = event.StoreRevision
+			if event.Type != entitystore.EventDelete {
+				c.check(ctx, event.ID)
+			}
+		case <-ticker.C:
+			c.checkAll(ctx)
+		}
+	}
+}
+
+// checkAll notifies the watched runs which were cancelled
+func (c *runCancellations) checkAll(ctx context.Context) {
+	c.mu.Lock()
+	ids := make([]string, 0, len(c.runs))
+	for id := range c.runs {
+		ids = append(ids, id)
+	}
+	c.mu.Unlock()
+	for _, id := range ids {
+		c.check(ctx, id)
+	}
+}
+
+// check notifies a watched run if it was cancelled
+func (c *runCancellations) check(ctx context.Context, id string) {
+	c.mu.Lock()
+	r, ok := c.runs[id]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	current := new(functions.FnRun)
+	if err := c.store.Get(ctx, r.organizationID, r.name, entitystore.Options{}, current); err != nil {
+		return
+	}
+	if current.Status != entitystore.StatusCANCELLED {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// a run is only notified once
+	if c.runs[id] == r {
+		delete(c.runs, id)
+		close(r.cancelled)
+	}
+}
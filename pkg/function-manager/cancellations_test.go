@@ -0,0 +1,155 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package functionmanager
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/functions"
+	helpers "github.com/vmware/dispatch/pkg/testing/api"
+)
+
+// unwatchableStore is a store whose watches fail
+type unwatchableStore struct {
+	entitystore.EntityStore
+}
+
+func (unwatchableStore) Watch(ctx context.Context, dataType entitystore.DataType, organizationID string, sinceRevision uint64) (<-chan entitystore.Event, error) {
+	return nil, errors.New("watch not supported")
+}
+
+func addTestRun(t *testing.T, store entitystore.EntityStore, name string) *functions.FnRun {
+	run := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           name,
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+	}
+	_, err := store.Add(context.Background(), run)
+	require.NoError(t, err)
+	return run
+}
+
+func cancelTestRun(t *testing.T, store entitystore.EntityStore, name string) {
+	run := new(functions.FnRun)
+	require.NoError(t, store.Get(context.Background(), testOrgID, name, entitystore.Options{}, run))
+	run.Status = entitystore.StatusCANCELLED
+	_, err := store.Update(context.Background(), run.Revision, run)
+	require.NoError(t, err)
+}
+
+func TestRunCancellationsWatch(t *testing.T) {
+	store := helpers.MakeEntityStore(t)
+	c := newRunCancellations(store)
+	c.pollPeriod = time.Hour
+
+	first, second := addTestRun(t, store, "first"), addTestRun(t, store, "second")
+	firstCancelled, unwatchFirst := c.watch(first)
+	defer unwatchFirst()
+	secondCancelled, unwatchSecond := c.watch(second)
+
+	// the runs are notified through the shared watch
+	cancelTestRun(t, store, "first")
+	select {
+	case <-firstCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("run not notified of its cancellation")
+	}
+	select {
+	case <-secondCancelled:
+		t.Fatal("run notified of the cancellation of another run")
+	default:
+	}
+
+	// the watch stops with the last run
+	unwatchSecond()
+	c.mu.Lock()
+	assert.Empty(t, c.runs)
+	assert.Nil(t, c.stop)
+	c.mu.Unlock()
+
+	// a run cancelled before it is watched is notified right away
+	cancelled, unwatch := c.watch(second)
+	defer unwatch()
+	select {
+	case <-cancelled:
+		t.Fatal("run notified before it was cancelled")
+	default:
+	}
+	cancelTestRun(t, store, "second")
+	cancelled, unwatch = c.watch(second)
+	defer unwatch()
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("cancelled run not notified")
+	}
+}
+
+func TestRunCancellationsPoll(t *testing.T) {
+	store := unwatchableStore{helpers.MakeEntityStore(t)}
+	c := newRunCancellations(store)
+	c.pollPeriod = 10 * time.Millisecond
+
+	run := addTestRun(t, store, "run")
+	cancelled, unwatch := c.watch(run)
+	defer unwatch()
+
+	// the cancellation is found by polling the run when the store cannot be watched
+	cancelTestRun(t, store, "run")
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("run not notified of its cancellation")
+	}
+}
+
+// failingWatchStore fails its first watch
+type failingWatchStore struct {
+	entitystore.EntityStore
+	sinces chan uint64
+	calls  int32
+}
+
+func (s *failingWatchStore) Watch(ctx context.Context, dataType entitystore.DataType, organizationID string, sinceRevision uint64) (<-chan entitystore.Event, error) {
+	s.sinces <- sinceRevision
+	if atomic.AddInt32(&s.calls, 1) == 1 {
+		events := make(chan entitystore.Event, 1)
+		events <- entitystore.Event{Type: entitystore.EventError, Err: entitystore.ErrWatcherTooSlow}
+		close(events)
+		return events, nil
+	}
+	return s.EntityStore.Watch(ctx, dataType, organizationID, sinceRevision)
+}
+
+func TestRunCancellationsWatchResume(t *testing.T) {
+	store := &failingWatchStore{EntityStore: helpers.MakeEntityStore(t), sinces: make(chan uint64, 2)}
+	c := newRunCancellations(store)
+	c.pollPeriod = time.Hour
+
+	run := addTestRun(t, store, "run")
+	cancelled, unwatch := c.watch(run)
+	defer unwatch()
+
+	// the failed watch is resumed
+	assert.Equal(t, uint64(0), <-store.sinces)
+	assert.Equal(t, uint64(0), <-store.sinces)
+	cancelTestRun(t, store, "run")
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("run not notified of its cancellation")
+	}
+}
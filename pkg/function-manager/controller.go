@@ -7,9 +7,12 @@ package functionmanager
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,15 +21,29 @@ import (
 
 	"github.com/vmware/dispatch/pkg/api/v1"
 	"github.com/vmware/dispatch/pkg/controller"
+	"github.com/vmware/dispatch/pkg/coordination"
 	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/events"
 	"github.com/vmware/dispatch/pkg/functions"
 	"github.com/vmware/dispatch/pkg/trace"
 )
 
 // ControllerConfig is the function manager controller configuration
 type ControllerConfig struct {
-	ResyncPeriod      time.Duration
-	ZookeeperLocation string
+	ResyncPeriod time.Duration
+	Coordination coordination.Config
+
+	// DeadLetterTopic is the topic failed asynchronous runs are published to, if set
+	DeadLetterTopic     string
+	DeadLetterTransport events.Transport
+
+	// RunRetention is the default retention of finished runs, OrgRunRetention overrides it per organization.
+	// Both are overridden by the retention of each function.
+	RunRetention    *functions.RunRetention
+	OrgRunRetention map[string]*functions.RunRetention
+
+	// Logs receives the log lines of the runs while they execute, if set
+	Logs *LogStreams
 }
 
 type funcEntityHandler struct {
@@ -131,8 +148,11 @@ func (h *funcEntityHandler) Update(ctx context.Context, obj entitystore.Entity)
 		return errors.Wrap(err, "error when deleting function from faas driver")
 	}
 
-	if err := h.ImageBuilder.RemoveImage(ctx, e); err != nil {
-		log.Errorf("Failed to remove function image from docker client: %+v", err)
+	// the image is kept as long as a published version of the function is deployed with it
+	if !h.imageInUse(ctx, e) {
+		if err := h.ImageBuilder.RemoveImage(ctx, e); err != nil {
+			log.Errorf("Failed to remove function image from docker client: %+v", err)
+		}
 	}
 
 	// generating a new UUID will force the creation of a new function in the underlying FaaS
@@ -157,7 +177,7 @@ func (h *funcEntityHandler) Delete(ctx context.Context, obj entitystore.Entity)
 		return errors.Wrapf(err, "Driver error when deleting a FaaS function")
 	}
 
-	runs, err := getFilteredRuns(ctx, h.Store, e.OrganizationID, &e.Name, nil, nil)
+	runs, err := getFilteredRuns(ctx, h.Store, e.OrganizationID, entitystore.Options{}, &e.Name, nil, nil)
 	if err != nil {
 		return errors.Wrapf(err, "store error listing runs for function %s", e.Name)
 	}
@@ -168,6 +188,26 @@ func (h *funcEntityHandler) Delete(ctx context.Context, obj entitystore.Entity)
 		}
 	}
 
+	versions, err := listVersions(ctx, h.Store, e.OrganizationID, e.Name)
+	if err != nil {
+		return err
+	}
+	removed := map[string]bool{e.FunctionImageURL: true}
+	for _, v := range versions {
+		if err := h.FaaS.Delete(ctx, v.Deployment()); err != nil {
+			return errors.Wrapf(err, "Driver error when deleting version %d of FaaS function %s", v.Number, e.Name)
+		}
+		if !removed[v.Function.FunctionImageURL] {
+			removed[v.Function.FunctionImageURL] = true
+			if err := h.ImageBuilder.RemoveImage(ctx, &v.Function); err != nil {
+				log.Errorf("Failed to remove function image from docker client: %+v", err)
+			}
+		}
+		if err := h.Store.Delete(ctx, e.OrganizationID, v.Name, v); err != nil {
+			return errors.Wrapf(err, "store error when deleting version %d of function %s", v.Number, e.Name)
+		}
+	}
+
 	if err := h.ImageBuilder.RemoveImage(ctx, e); err != nil {
 		log.Errorf("Failed to remove function image from docker client: %+v", err)
 	}
@@ -207,6 +247,114 @@ func (h *funcEntityHandler) Error(ctx context.Context, obj entitystore.Entity) e
 	return nil
 }
 
+// listVersions returns the published versions of a function
+func listVersions(ctx context.Context, store entitystore.EntityStore, organizationID, functionName string) ([]*functions.FunctionVersion, error) {
+	var versions []*functions.FunctionVersion
+	opts := entitystore.Options{
+		Filter: entitystore.FilterEverything().Add(entitystore.FilterStat{
+			Scope:   entitystore.FilterScopeExtra,
+			Subject: "FunctionName",
+			Verb:    entitystore.FilterVerbEqual,
+			Object:  functionName,
+		}),
+	}
+	if err := store.List(ctx, organizationID, opts, &versions); err != nil {
+		return nil, errors.Wrapf(err, "store error listing versions of function %s", functionName)
+	}
+	return versions, nil
+}
+
+// imageInUse returns true if a published version of the function is deployed with the image of f
+func (h *funcEntityHandler) imageInUse(ctx context.Context, f *functions.Function) bool {
+	if f.FunctionImageURL == "" {
+		return false
+	}
+	versions, err := listVersions(ctx, h.Store, f.OrganizationID, f.Name)
+	if err != nil {
+		log.Errorf("Error listing versions of function %s, keeping its image: %+v", f.Name, err)
+		return true
+	}
+	for _, v := range versions {
+		if v.Function.FunctionImageURL == f.FunctionImageURL {
+			return true
+		}
+	}
+	return false
+}
+
+type versionEntityHandler struct {
+	FaaS  functions.FaaSDriver
+	Store entitystore.EntityStore
+}
+
+// Type returns the reflect.Type of a functions.FunctionVersion
+func (h *versionEntityHandler) Type() reflect.Type {
+	return reflect.TypeOf(&functions.FunctionVersion{})
+}
+
+// Add deploys a published version of a function, using the image of the function when it was published
+func (h *versionEntityHandler) Add(ctx context.Context, obj entitystore.Entity) (err error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	v := obj.(*functions.FunctionVersion)
+
+	defer func() {
+		log.Debugf("function version org=%s, name=%s, id=%s, status=%s", v.OrganizationID, v.Name, v.ID, v.Status)
+		h.Store.UpdateWithError(ctx, v, err)
+	}()
+
+	if err := h.FaaS.Create(ctx, v.Deployment()); err != nil {
+		return errors.Wrapf(err, "Driver error when creating version %d of FaaS function %s", v.Number, v.FunctionName)
+	}
+
+	v.Status = entitystore.StatusREADY
+	return nil
+}
+
+// Update handles updates of function versions, which are immutable
+func (h *versionEntityHandler) Update(ctx context.Context, obj entitystore.Entity) (err error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	v := obj.(*functions.FunctionVersion)
+	defer func() { h.Store.UpdateWithError(ctx, v, err) }()
+	return errors.Errorf("updating function versions not supported, version: '%s'", v.Name)
+}
+
+// Delete deletes a version of a function from the configured FaaS
+func (h *versionEntityHandler) Delete(ctx context.Context, obj entitystore.Entity) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	v := obj.(*functions.FunctionVersion)
+
+	if err := h.FaaS.Delete(ctx, v.Deployment()); err != nil {
+		return errors.Wrapf(err, "Driver error when deleting version %d of FaaS function %s", v.Number, v.FunctionName)
+	}
+	if err := h.Store.Delete(ctx, v.OrganizationID, v.Name, v); err != nil {
+		return errors.Wrapf(err, "store error when deleting version %d of function %s", v.Number, v.FunctionName)
+	}
+	return nil
+}
+
+// Error handles errors with regards to function versions (currently a no-op)
+func (h *versionEntityHandler) Error(ctx context.Context, obj entitystore.Entity) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	// TODO implement me
+	return nil
+}
+
+// Sync compares actual and desired state to return a list of function version entities which must be resolved
+func (h *versionEntityHandler) Sync(ctx context.Context, resyncPeriod time.Duration) ([]entitystore.Entity, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	return controller.DefaultSync(ctx, h.Store, h.Type(), resyncPeriod, syncFilter(resyncPeriod))
+}
+
 // Only return entities in INITIALIZED, UPDATING or DELETING status
 // This is kind of a hack as smarter filtering is required.
 func syncFilter(resyncPeriod time.Duration) entitystore.Filter {
@@ -247,10 +395,32 @@ func (h *funcEntityHandler) getImage(ctx context.Context, organizationID string,
 	return nil, errors.Wrapf(err, "failed to get image: '%s'", imageName)
 }
 
+const (
+	deadLetterEventType = "dispatch.run.failed"
+
+	// runPurgePeriod is the minimum period between two purges of expired runs
+	runPurgePeriod = time.Minute
+	// runDeleteBatchSize is the number of runs listed and deleted at once when runs are purged
+	runDeleteBatchSize = 100
+)
+
 type runEntityHandler struct {
 	FaaS   functions.FaaSDriver
 	Runner functions.Runner
 	Store  entitystore.EntityStore
+
+	DeadLetterTopic     string
+	DeadLetterTransport events.Transport
+
+	RunRetention    *functions.RunRetention
+	OrgRunRetention map[string]*functions.RunRetention
+
+	Logs *LogStreams
+
+	purgedAt time.Time
+
+	cancellationsOnce sync.Once
+	cancellations     *runCancellations
 }
 
 // Type returns the reflect.Type of a functions.FnRun
@@ -258,6 +428,23 @@ func (h *runEntityHandler) Type() reflect.Type {
 	return reflect.TypeOf(&functions.FnRun{})
 }
 
+// watchCancelled returns a channel which is closed once the run is cancelled, and a function which stops watching it
+func (h *runEntityHandler) watchCancelled(run *functions.FnRun) (<-chan struct{}, func()) {
+	h.cancellationsOnce.Do(func() {
+		h.cancellations = newRunCancellations(h.Store)
+	})
+	return h.cancellations.watch(run)
+}
+
+// runFinished returns true if the run completed, failed or was cancelled
+func runFinished(run *functions.FnRun) bool {
+	switch run.Status {
+	case entitystore.StatusREADY, entitystore.StatusERROR, entitystore.StatusCANCELLED:
+		return true
+	}
+	return false
+}
+
 type invocationError struct {
 	Err *v1.InvocationError `json:"err"`
 }
@@ -275,18 +462,101 @@ func (h *runEntityHandler) Add(ctx context.Context, obj entitystore.Entity) (err
 	defer span.Finish()
 
 	run := obj.(*functions.FnRun)
+	// a run waiting for its next attempt, e.g. processed early after a store event, is requeued until it is due
+	if wait := time.Until(run.RetryAt); wait > 0 {
+		return controller.Requeue(wait)
+	}
 	defer run.Done()
 
-	defer func() { h.Store.UpdateWithError(ctx, run, err) }()
+	// the log stream is closed once the run is updated in the store, so followers find its final logs
+	runCtx := ctx
+	if h.Logs != nil {
+		logs := h.Logs.open(run)
+		defer h.Logs.close(run)
+		runCtx = functions.WithLogSink(ctx, logs.write)
+	}
+
+	// a run cancelled through the API is interrupted, the cancellation is already recorded in the store
+	cancelCtx, cancel := context.WithCancel(runCtx)
+	defer cancel()
+	cancelled, unwatch := h.watchCancelled(run)
+	defer unwatch()
+	select {
+	case <-cancelled:
+		log.Infof("Run %s was cancelled before it started", run.Name)
+		h.finishCancelled(ctx, run)
+		return nil
+	default:
+	}
+	go func() {
+		select {
+		case <-cancelled:
+			cancel()
+		case <-cancelCtx.Done():
+		}
+	}()
+
+	defer func() {
+		select {
+		case <-cancelled:
+			log.Infof("Run %s was cancelled", run.Name)
+			h.finishCancelled(ctx, run)
+			err = nil
+		default:
+			if _, retry := controller.RequeueDelay(err); retry {
+				// the run is stored for its next attempt, its failure is not an error of the entity
+				h.Store.UpdateWithError(ctx, run, nil)
+				return
+			}
+			h.Store.UpdateWithError(ctx, run, err)
+			if err != nil {
+				h.publishDeadLetter(ctx, run)
+			}
+		}
+	}()
 
 	run.Status = entitystore.StatusCREATING
 	h.Store.UpdateWithError(ctx, run, nil)
 
 	f := new(functions.Function)
-	if err = h.Store.Get(ctx, run.OrganizationID, run.FunctionName, entitystore.Options{}, f); err != nil {
+	if run.FunctionVersion != 0 {
+		v := new(functions.FunctionVersion)
+		if err = h.Store.Get(ctx, run.OrganizationID, versionName(run.FunctionName, run.FunctionVersion), entitystore.Options{}, v); err != nil {
+			return errors.Wrapf(err, "Error getting version %d of function from store: '%s'", run.FunctionVersion, run.FunctionName)
+		}
+		f = v.Deployment()
+	} else if err = h.Store.Get(ctx, run.OrganizationID, run.FunctionName, entitystore.Options{}, f); err != nil {
 		return errors.Wrapf(err, "Error getting function from store: '%s'", run.FunctionName)
 	}
 
+	attempt := len(run.Attempts) + 1
+	executedTime := time.Now()
+	err = h.runAttempt(cancelCtx, run, f)
+	run.Attempts = append(run.Attempts, &functions.RunAttempt{
+		Attempt:      attempt,
+		ExecutedTime: executedTime,
+		FinishedTime: time.Now(),
+		Error:        run.Error,
+	})
+	run.RetryAt = time.Time{}
+	if err != nil {
+		delay, retry := retryDelay(run, attempt)
+		if !retry {
+			return err
+		}
+		// the next attempt is made by the controller, so that the run does not hold its lock while waiting
+		log.Infof("Attempt %d of run %s failed, retrying in %s: %v", attempt, run.Name, delay, err)
+		run.RetryAt = time.Now().Add(delay)
+		return controller.Requeue(delay)
+	}
+
+	run.Status = entitystore.StatusREADY
+	run.FinishedTime = time.Now()
+	return
+}
+
+// runAttempt executes the function of the run once, recording its output, logs and error in the run
+func (h *runEntityHandler) runAttempt(ctx context.Context, run *functions.FnRun, f *functions.Function) error {
 	fctx := functions.Context{}
 
 	if run.Event != nil {
@@ -298,10 +568,14 @@ func (h *runEntityHandler) Add(ctx context.Context, obj entitystore.Entity) (err
 	}
 
 	if f.Timeout != 0 {
-		fctx[functions.DeadlineKey] = time.Now().Add(time.Duration(f.Timeout) * time.Millisecond)
+		deadline := time.Now().Add(time.Duration(f.Timeout) * time.Millisecond)
+		fctx[functions.DeadlineKey] = deadline
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithDeadline(ctx, deadline)
+		defer cancelTimeout()
 	}
 
-	output, err := h.Runner.Run(&functions.FunctionExecution{
+	output, err := h.Runner.Run(ctx, &functions.FunctionExecution{
 		Context:        fctx,
 		OrganizationID: run.OrganizationID,
 		RunID:          run.ID,
@@ -316,33 +590,17 @@ func (h *runEntityHandler) Add(ctx context.Context, obj entitystore.Entity) (err
 		Services: run.Services,
 	}, run.Input)
 
-	log.Infof("Successfully received results of run %v", obj.GetID())
+	log.Infof("Successfully received results of run %v", run.ID)
 
 	logs := fctx.Logs()
 	run.Logs = &logs
 	run.Output = output
+	if coldStart := fctx.ColdStart(); coldStart != 0 {
+		run.ColdStart = coldStart
+	}
 
 	if err != nil {
-		var stacktrace []string
-		if e, ok := err.(functions.StackTracer); ok {
-			if st := e.StackTrace(); st != nil {
-				st := fmt.Sprintf("%+v", st)
-				st = strings.Trim(st, "\n")
-				stacktrace = strings.Split(st, "\n")
-			}
-		}
-		message := err.Error()
-		switch err.(type) {
-		case functions.InputError:
-			run.Error = &v1.InvocationError{Message: &message, Type: v1.ErrorTypeInputError, Stacktrace: stacktrace}
-		case functions.FunctionError:
-			run.Error = &v1.InvocationError{Message: &message, Type: v1.ErrorTypeFunctionError, Stacktrace: stacktrace}
-		case functions.SystemError:
-			run.Error = &v1.InvocationError{Message: &message, Type: v1.ErrorTypeSystemError, Stacktrace: stacktrace}
-		default:
-			log.Debugf("No invocation error type provided for error %s", err)
-			run.Error = &v1.InvocationError{Message: &message, Stacktrace: stacktrace}
-		}
+		run.Error = newInvocationError(err)
 		return errors.Wrapf(&invocationError{run.Error}, "error running function: %s", run.FunctionName)
 	}
 
@@ -350,10 +608,93 @@ func (h *runEntityHandler) Add(ctx context.Context, obj entitystore.Entity) (err
 	if run.Error != nil {
 		return errors.Wrapf(&invocationError{run.Error}, "error running function: %s", run.FunctionName)
 	}
+	return nil
+}
 
-	run.Status = entitystore.StatusREADY
-	run.FinishedTime = time.Now()
-	return
+// newInvocationError converts an error returned by a runnable to an invocation error
+func newInvocationError(err error) *v1.InvocationError {
+	var stacktrace []string
+	if e, ok := err.(functions.StackTracer); ok {
+		if st := e.StackTrace(); st != nil {
+			st := fmt.Sprintf("%+v", st)
+			st = strings.Trim(st, "\n")
+			stacktrace = strings.Split(st, "\n")
+		}
+	}
+	message := err.Error()
+	switch err.(type) {
+	case functions.TimeoutError:
+		return &v1.InvocationError{Message: &message, Type: v1.ErrorTypeTimeoutError, Stacktrace: stacktrace}
+	case functions.InputError:
+		return &v1.InvocationError{Message: &message, Type: v1.ErrorTypeInputError, Stacktrace: stacktrace}
+	case functions.FunctionError:
+		return &v1.InvocationError{Message: &message, Type: v1.ErrorTypeFunctionError, Stacktrace: stacktrace}
+	case functions.SystemError:
+		return &v1.InvocationError{Message: &message, Type: v1.ErrorTypeSystemError, Stacktrace: stacktrace}
+	default:
+		log.Debugf("No invocation error type provided for error %s", err)
+		return &v1.InvocationError{Message: &message, Stacktrace: stacktrace}
+	}
+}
+
+// retryDelay returns the delay before the next attempt of a failed run, and false if the run must not be retried.
+// Only asynchronous runs are retried.
+func retryDelay(run *functions.FnRun, attempt int) (time.Duration, bool) {
+	policy := run.RetryPolicy
+	if run.Blocking || policy == nil || attempt >= policy.MaxAttempts || run.Error == nil {
+		return 0, false
+	}
+	retryable := len(policy.RetryOn) == 0 && run.Error.Type != v1.ErrorTypeInputError
+	for _, t := range policy.RetryOn {
+		if t == string(run.Error.Type) {
+			retryable = true
+		}
+	}
+	if !retryable {
+		return 0, false
+	}
+	delay := policy.Backoff
+	for i := 1; i < attempt && delay < math.MaxInt64/2 && (policy.MaxBackoff == 0 || delay < policy.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if policy.MaxBackoff != 0 && delay > policy.MaxBackoff {
+		delay = policy.MaxBackoff
+	}
+	return delay, true
+}
+
+// publishDeadLetter publishes a failed asynchronous run as a CloudEvent to the dead-letter topic, if configured
+func (h *runEntityHandler) publishDeadLetter(ctx context.Context, run *functions.FnRun) {
+	if run.Blocking || h.DeadLetterTransport == nil || h.DeadLetterTopic == "" {
+		return
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		log.Errorf("Error marshalling run %s for the dead-letter topic: %+v", run.Name, err)
+		return
+	}
+	event := events.NewCloudEventWithDefaults(deadLetterEventType)
+	event.ContentType = "application/json"
+	event.Data = data
+	if err := h.DeadLetterTransport.Publish(ctx, &event, h.DeadLetterTopic, run.OrganizationID); err != nil {
+		log.Errorf("Error publishing run %s to the dead-letter topic %s: %+v", run.Name, h.DeadLetterTopic, err)
+	}
+}
+
+// finishCancelled reloads a run cancelled through the API, recording the logs of its execution if any
+func (h *runEntityHandler) finishCancelled(ctx context.Context, run *functions.FnRun) {
+	logs, waitChan := run.Logs, run.WaitChan
+	current := new(functions.FnRun)
+	if err := h.Store.Get(ctx, run.OrganizationID, run.Name, entitystore.Options{}, current); err != nil {
+		log.Errorf("Error getting cancelled run %s: %+v", run.Name, err)
+		return
+	}
+	*run = *current
+	run.WaitChan = waitChan
+	if logs != nil {
+		run.Logs = logs
+		h.Store.UpdateWithError(ctx, run, nil)
+	}
 }
 
 // Update updates a function execution (run)
@@ -376,12 +717,279 @@ func (h *runEntityHandler) Delete(ctx context.Context, obj entitystore.Entity) (
 	return errors.Errorf("deleting runs not supported, fn: '%s'", run.FunctionName)
 }
 
-// Sync compares actual and desired state to return a list of function execution (run) entities which must be resolved
+// Sync compares actual and desired state to return a list of function execution (run) entities which must be resolved.
+// Finished runs which expired according to their retention are deleted, at most once per purge period.
 func (h *runEntityHandler) Sync(ctx context.Context, resyncPeriod time.Duration) ([]entitystore.Entity, error) {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
 
-	return controller.DefaultSync(ctx, h.Store, h.Type(), resyncPeriod, syncFilter(resyncPeriod))
+	entities, err := controller.DefaultSync(ctx, h.Store, h.Type(), resyncPeriod, syncFilter(resyncPeriod))
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(h.purgedAt) < runPurgePeriod {
+		return entities, nil
+	}
+	deleted, err := h.purgeRuns(ctx, time.Now())
+	if deleted > 0 {
+		log.Infof("Deleted %d expired runs", deleted)
+	}
+	if err != nil {
+		log.Errorf("Error purging expired runs: %+v", err)
+		return entities, nil
+	}
+	h.purgedAt = time.Now()
+	return entities, nil
+}
+
+// runRetention returns the retention of the runs of a function, the retention of the function takes precedence over
+// the one of its organization, which takes precedence over the default one. f is nil if the function was deleted.
+func (h *runEntityHandler) runRetention(organizationID string, f *functions.Function) functions.RunRetention {
+	var retention functions.RunRetention
+	for _, r := range []*functions.RunRetention{h.RunRetention, h.OrgRunRetention[organizationID]} {
+		if r == nil {
+			continue
+		}
+		if r.MaxAge != 0 {
+			retention.MaxAge = r.MaxAge
+		}
+		if r.MaxCount != 0 {
+			retention.MaxCount = r.MaxCount
+		}
+	}
+	if f != nil && f.RunRetention != nil {
+		if f.RunRetention.MaxAge != 0 {
+			retention.MaxAge = f.RunRetention.MaxAge
+		}
+		if f.RunRetention.MaxCount != 0 {
+			retention.MaxCount = f.RunRetention.MaxCount
+		}
+	}
+	return retention
+}
+
+// purgeRuns deletes the finished runs which are older than the maximum age of their function, or exceed its maximum
+// count of runs, and returns the number of deleted runs. The runs of deleted functions are only purged by age, according
+// to the retention of their organization.
+func (h *runEntityHandler) purgeRuns(ctx context.Context, now time.Time) (int64, error) {
+	var fns []*functions.Function
+	if err := h.Store.ListGlobal(ctx, entitystore.Options{}, &fns); err != nil {
+		return 0, errors.Wrap(err, "store error when listing functions")
+	}
+
+	var deleted int64
+	// the runs of existing functions are left out of the purge of the runs of deleted functions
+	existing := make(map[functionKey]bool)
+	for _, f := range fns {
+		existing[functionKey{organizationID: f.OrganizationID, name: f.Name}] = true
+		fnRuns := functionRunStats(f.OrganizationID, f.Name)
+		retention := h.runRetention(f.OrganizationID, f)
+		if retention.MaxAge != 0 {
+			opts := entitystore.Options{
+				Filter: finishedRunsFilter(append(fnRuns, createdBeforeStat(now.Add(-retention.MaxAge)))...),
+			}
+			n, err := deleteRunBatches(ctx, h.Store, f.OrganizationID, opts)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+		if retention.MaxCount != 0 {
+			// newest runs first, those beyond the count are deleted
+			opts := entitystore.Options{
+				Filter:  finishedRunsFilter(fnRuns...),
+				OrderBy: "-createdTime",
+				Offset:  retention.MaxCount,
+			}
+			n, err := deleteRunBatches(ctx, h.Store, f.OrganizationID, opts)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+
+	var orgsWithMaxAge []string
+	for org, retention := range h.OrgRunRetention {
+		if retention == nil || retention.MaxAge == 0 {
+			continue
+		}
+		orgsWithMaxAge = append(orgsWithMaxAge, org)
+		n, err := h.purgeOrphanRuns(ctx, org, finishedRunsFilter(), now.Add(-retention.MaxAge), existing)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if h.RunRetention != nil && h.RunRetention.MaxAge != 0 {
+		filter := finishedRunsFilter()
+		if len(orgsWithMaxAge) > 0 {
+			filter = filter.Add(entitystore.FilterStatNot(entitystore.FilterStat{
+				Scope:   entitystore.FilterScopeField,
+				Subject: "OrganizationID",
+				Verb:    entitystore.FilterVerbIn,
+				Object:  orgsWithMaxAge,
+			}))
+		}
+		n, err := h.purgeOrphanRuns(ctx, "", filter, now.Add(-h.RunRetention.MaxAge), existing)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// functionKey identifies a function by organization and name
+type functionKey struct {
+	organizationID string
+	name           string
+}
+
+// purgeOrphanRuns deletes the runs selected by filter which were created before t and whose function was deleted, of
+// all organizations if organizationID is empty, and returns the number of deleted runs. existing tells whether the
+// functions already looked up exist.
+func (h *runEntityHandler) purgeOrphanRuns(ctx context.Context, organizationID string, filter entitystore.Filter, t time.Time, existing map[functionKey]bool) (int64, error) {
+	filter = filter.Add(createdBeforeStat(t))
+	orphans, err := h.deletedFunctions(ctx, organizationID, entitystore.Options{Filter: filter}, existing)
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, key := range orphans {
+		opts := entitystore.Options{
+			Filter: finishedRunsFilter(append(functionRunStats(key.organizationID, key.name), createdBeforeStat(t))...),
+		}
+		n, err := deleteRunBatches(ctx, h.Store, key.organizationID, opts)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// deletedFunctions returns the distinct deleted functions of the runs selected by opts, of all organizations if
+// organizationID is empty. The runs are listed by pages, and the functions missing from existing are looked up in the
+// store once.
+func (h *runEntityHandler) deletedFunctions(ctx context.Context, organizationID string, opts entitystore.Options, existing map[functionKey]bool) ([]functionKey, error) {
+	opts.Limit = runDeleteBatchSize
+	var deleted []functionKey
+	for {
+		var runs []*functions.FnRun
+		var err error
+		if organizationID == "" {
+			err = h.Store.ListGlobal(ctx, opts, &runs)
+		} else {
+			err = h.Store.List(ctx, organizationID, opts, &runs)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "store error when listing runs")
+		}
+		for _, run := range runs {
+			key := functionKey{organizationID: run.OrganizationID, name: run.FunctionName}
+			if _, ok := existing[key]; ok {
+				continue
+			}
+			found, err := h.Store.Find(ctx, key.organizationID, key.name, entitystore.Options{}, &functions.Function{})
+			if err != nil {
+				return nil, errors.Wrapf(err, "store error when getting function %s", key.name)
+			}
+			existing[key] = found
+			if !found {
+				deleted = append(deleted, key)
+			}
+		}
+		if len(runs) < runDeleteBatchSize {
+			return deleted, nil
+		}
+		opts.Offset += len(runs)
+	}
+}
+
+// finishedRunsFilter returns a filter of the finished runs which match stats
+func finishedRunsFilter(stats ...entitystore.FilterStat) entitystore.Filter {
+	return entitystore.FilterEverything().Add(entitystore.FilterStat{
+		Scope:   entitystore.FilterScopeField,
+		Subject: "Status",
+		Verb:    entitystore.FilterVerbIn,
+		Object: []entitystore.Status{
+			entitystore.StatusREADY, entitystore.StatusERROR, entitystore.StatusCANCELLED,
+		},
+	}).Add(stats...)
+}
+
+// functionRunStats returns the filter statements of the runs of a function
+func functionRunStats(organizationID, functionName string) []entitystore.FilterStat {
+	return []entitystore.FilterStat{
+		{
+			Scope:   entitystore.FilterScopeField,
+			Subject: "OrganizationID",
+			Verb:    entitystore.FilterVerbEqual,
+			Object:  organizationID,
+		},
+		{
+			Scope:   entitystore.FilterScopeExtra,
+			Subject: "FunctionName",
+			Verb:    entitystore.FilterVerbEqual,
+			Object:  functionName,
+		},
+	}
+}
+
+// createdBeforeStat returns the filter statement of the entities created before t
+func createdBeforeStat(t time.Time) entitystore.FilterStat {
+	return entitystore.FilterStat{
+		Scope:   entitystore.FilterScopeField,
+		Subject: "CreatedTime",
+		Verb:    entitystore.FilterVerbBefore,
+		Object:  t,
+	}
+}
+
+// deleteRunBatches deletes the runs selected by opts, of all organizations if organizationID is empty, and returns the
+// number of deleted runs. Runs are listed by batches of runDeleteBatchSize, each deleted in a transaction. If the
+// transaction fails, the runs of the batch are deleted one by one, and those failing to delete are logged and skipped:
+// the offset of opts advances past them, while the deleted runs leave the selection.
+func deleteRunBatches(ctx context.Context, store entitystore.EntityStore, organizationID string, opts entitystore.Options) (int64, error) {
+	opts.Limit = runDeleteBatchSize
+	var deleted int64
+	for {
+		var runs []*functions.FnRun
+		var err error
+		if organizationID == "" {
+			err = store.ListGlobal(ctx, opts, &runs)
+		} else {
+			err = store.List(ctx, organizationID, opts, &runs)
+		}
+		if err != nil {
+			return deleted, errors.Wrap(err, "store error when listing runs")
+		}
+		err = store.Transaction(ctx, func(tx entitystore.EntityStore) error {
+			for _, run := range runs {
+				if err := tx.Delete(ctx, run.OrganizationID, run.Name, run); err != nil {
+					return errors.Wrapf(err, "store error when deleting run %s", run.Name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Warnf("Error deleting a batch of %d runs, deleting them one by one: %+v", len(runs), err)
+			for _, run := range runs {
+				if err := store.Delete(ctx, run.OrganizationID, run.Name, run); err != nil {
+					log.Warnf("Error deleting run %s of function %s: %+v", run.Name, run.FunctionName, err)
+					opts.Offset++
+					continue
+				}
+				deleted++
+			}
+		} else {
+			deleted += int64(len(runs))
+		}
+		if len(runs) < runDeleteBatchSize {
+			return deleted, nil
+		}
+	}
 }
 
 // Error handles errors with regards to function execution entities (currently a no-op)
@@ -397,13 +1005,24 @@ func (h *runEntityHandler) Error(ctx context.Context, obj entitystore.Entity) er
 func NewController(config *ControllerConfig, store entitystore.EntityStore, faas functions.FaaSDriver, runner functions.Runner, imgClient ImageGetter, imageBuilder functions.ImageBuilder) controller.Controller {
 
 	c := controller.NewController(controller.Options{
-		ResyncPeriod:      config.ResyncPeriod,
-		Workers:           1000, // want more functions concurrently? add more workers // TODO configure workers
-		ServiceName:       "functions",
-		ZookeeperLocation: config.ZookeeperLocation,
+		ResyncPeriod: config.ResyncPeriod,
+		Workers:      1000, // want more functions concurrently? add more workers // TODO configure workers
+		ServiceName:  "functions",
+		Coordination: config.Coordination,
+		Store:        store,
 	})
 	c.AddEntityHandler(&funcEntityHandler{Store: store, FaaS: faas, ImgClient: imgClient, ImageBuilder: imageBuilder})
-	c.AddEntityHandler(&runEntityHandler{Store: store, FaaS: faas, Runner: runner})
+	c.AddEntityHandler(&versionEntityHandler{Store: store, FaaS: faas})
+	c.AddEntityHandler(&runEntityHandler{
+		Store:               store,
+		FaaS:                faas,
+		Runner:              runner,
+		DeadLetterTopic:     config.DeadLetterTopic,
+		DeadLetterTransport: config.DeadLetterTransport,
+		RunRetention:        config.RunRetention,
+		OrgRunRetention:     config.OrgRunRetention,
+		Logs:                config.Logs,
+	})
 
 	return c
 }
@@ -7,14 +7,21 @@ package functionmanager
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/controller"
 	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/events"
+	evmocks "github.com/vmware/dispatch/pkg/events/mocks"
 	"github.com/vmware/dispatch/pkg/function-manager/mocks"
 	"github.com/vmware/dispatch/pkg/functions"
 	fnmocks "github.com/vmware/dispatch/pkg/functions/mocks"
@@ -181,6 +188,61 @@ func TestFuncEntityHandler_Delete(t *testing.T) {
 	faas.AssertExpectations(t)
 }
 
+func TestVersionEntityHandler(t *testing.T) {
+	faas := &fnmocks.FaaSDriver{}
+	store := helpers.MakeEntityStore(t)
+	function := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testFunction",
+			Status:         entitystore.StatusREADY,
+			OrganizationID: testOrgID,
+		},
+		FaasID:           "function-faas-id",
+		FunctionImageURL: "fake-image:v1",
+		LatestVersion:    1,
+	}
+	_, err := store.Add(context.Background(), function)
+	require.NoError(t, err)
+
+	version := &functions.FunctionVersion{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           versionName(function.Name, 1),
+			Status:         entitystore.StatusINITIALIZED,
+			OrganizationID: testOrgID,
+		},
+		FunctionName: function.Name,
+		Number:       1,
+		Function:     *function,
+	}
+	version.Function.FaasID = "version-faas-id"
+	_, err = store.Add(context.Background(), version)
+	require.NoError(t, err)
+
+	isVersion := mock.MatchedBy(func(f *functions.Function) bool {
+		return f.ID == version.ID && f.FaasID == "version-faas-id"
+	})
+	faas.On("Create", mock.Anything, isVersion).Return(nil)
+
+	h := &versionEntityHandler{Store: store, FaaS: faas}
+	require.NoError(t, h.Add(context.Background(), version))
+	assert.Equal(t, entitystore.StatusREADY, version.Status)
+
+	// the image of the function is kept while the version uses it, and removed with the function
+	imgBuilder := &fnmocks.ImageBuilder{}
+	imgBuilder.On("RemoveImage", mock.Anything, function).Return(nil).Once()
+	faas.On("Delete", mock.Anything, function).Return(nil)
+	faas.On("Delete", mock.Anything, isVersion).Return(nil)
+	fh := &funcEntityHandler{Store: store, FaaS: faas, ImageBuilder: imgBuilder}
+	assert.True(t, fh.imageInUse(context.Background(), function))
+	require.NoError(t, fh.Delete(context.Background(), function))
+
+	versions, err := listVersions(context.Background(), store, testOrgID, function.Name)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+	faas.AssertExpectations(t)
+	imgBuilder.AssertExpectations(t)
+}
+
 func TestRunEntityHandler_Add(t *testing.T) {
 	faas := &fnmocks.FaaSDriver{}
 	function := &functions.Function{
@@ -202,7 +264,7 @@ func TestRunEntityHandler_Add(t *testing.T) {
 	}
 
 	functionCalled := false
-	var runnable functions.Runnable = func(ctx functions.Context, in interface{}) (interface{}, error) {
+	var runnable functions.Runnable = func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
 		functionCalled = true
 		return nil, nil
 	}
@@ -238,3 +300,468 @@ func TestRunEntityHandler_Add(t *testing.T) {
 	secretInjector.AssertExpectations(t)
 	assert.True(t, functionCalled)
 }
+
+func TestRunEntityHandler_AddTimeout(t *testing.T) {
+	faas := &fnmocks.FaaSDriver{}
+	function := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testFunction",
+			OrganizationID: testOrgID,
+		},
+		ImageName: "testImage",
+		Handler:   "main",
+		Schema:    &functions.Schema{},
+		Timeout:   10,
+	}
+	fnRun := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testRun",
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+	}
+
+	var runnable functions.Runnable = func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	faas.On("GetRunnable", mock.Anything).Return(runnable)
+
+	var simw functions.Middleware = func(f functions.Runnable) functions.Runnable {
+		return f
+	}
+	secretInjector := &fnmocks.SecretInjector{}
+	secretInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+	serviceInjector := &fnmocks.ServiceInjector{}
+	serviceInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+
+	h := &runEntityHandler{
+		Store: helpers.MakeEntityStore(t),
+		FaaS:  faas,
+		Runner: runner.New(&runner.Config{
+			Faas:            faas,
+			Validator:       validator.NoOp(),
+			SecretInjector:  secretInjector,
+			ServiceInjector: serviceInjector,
+		}),
+	}
+
+	_, err := h.Store.Add(context.Background(), function)
+	require.NoError(t, err)
+	_, err = h.Store.Add(context.Background(), fnRun)
+	require.NoError(t, err)
+
+	assert.Error(t, h.Add(context.Background(), fnRun))
+	require.NotNil(t, fnRun.Error)
+	assert.Equal(t, v1.ErrorTypeTimeoutError, fnRun.Error.Type)
+	assert.Equal(t, entitystore.StatusERROR, fnRun.Status)
+}
+
+func TestRunEntityHandler_AddCancel(t *testing.T) {
+	faas := &fnmocks.FaaSDriver{}
+	function := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testFunction",
+			OrganizationID: testOrgID,
+		},
+		ImageName: "testImage",
+		Handler:   "main",
+		Schema:    &functions.Schema{},
+	}
+	fnRun := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testRun",
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+	}
+
+	started := make(chan struct{})
+	var runnable functions.Runnable = func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	faas.On("GetRunnable", mock.Anything).Return(runnable)
+
+	var simw functions.Middleware = func(f functions.Runnable) functions.Runnable {
+		return f
+	}
+	secretInjector := &fnmocks.SecretInjector{}
+	secretInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+	serviceInjector := &fnmocks.ServiceInjector{}
+	serviceInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+
+	h := &runEntityHandler{
+		Store: helpers.MakeEntityStore(t),
+		FaaS:  faas,
+		Runner: runner.New(&runner.Config{
+			Faas:            faas,
+			Validator:       validator.NoOp(),
+			SecretInjector:  secretInjector,
+			ServiceInjector: serviceInjector,
+		}),
+	}
+
+	_, err := h.Store.Add(context.Background(), function)
+	require.NoError(t, err)
+	_, err = h.Store.Add(context.Background(), fnRun)
+	require.NoError(t, err)
+
+	go func() {
+		<-started
+		run := new(functions.FnRun)
+		require.NoError(t, h.Store.Get(context.Background(), testOrgID, "testRun", entitystore.Options{}, run))
+		run.Status = entitystore.StatusCANCELLED
+		_, err := h.Store.Update(context.Background(), run.Revision, run)
+		require.NoError(t, err)
+	}()
+
+	assert.NoError(t, h.Add(context.Background(), fnRun))
+	assert.Equal(t, entitystore.StatusCANCELLED, fnRun.Status)
+	assert.NotNil(t, fnRun.Logs)
+
+	// the run is not executed once cancelled
+	queued := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "queuedRun",
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+	}
+	_, err = h.Store.Add(context.Background(), queued)
+	require.NoError(t, err)
+	cancelled := *queued
+	cancelled.Status = entitystore.StatusCANCELLED
+	_, err = h.Store.Update(context.Background(), cancelled.Revision, &cancelled)
+	require.NoError(t, err)
+
+	assert.NoError(t, h.Add(context.Background(), queued))
+	assert.Equal(t, entitystore.StatusCANCELLED, queued.Status)
+	faas.AssertNumberOfCalls(t, "GetRunnable", 1)
+}
+
+func TestRunEntityHandler_AddRetry(t *testing.T) {
+	faas := &fnmocks.FaaSDriver{}
+	function := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testFunction",
+			OrganizationID: testOrgID,
+		},
+		ImageName: "testImage",
+		Handler:   "main",
+		Schema:    &functions.Schema{},
+	}
+	fnRun := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testRun",
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+		RetryPolicy: &functions.RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     time.Hour,
+		},
+	}
+
+	calls := 0
+	var runnable functions.Runnable = func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("temporary failure")
+		}
+		return "done", nil
+	}
+	faas.On("GetRunnable", mock.Anything).Return(runnable)
+
+	var simw functions.Middleware = func(f functions.Runnable) functions.Runnable {
+		return f
+	}
+	secretInjector := &fnmocks.SecretInjector{}
+	secretInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+	serviceInjector := &fnmocks.ServiceInjector{}
+	serviceInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+
+	h := &runEntityHandler{
+		Store: helpers.MakeEntityStore(t),
+		FaaS:  faas,
+		Runner: runner.New(&runner.Config{
+			Faas:            faas,
+			Validator:       validator.NoOp(),
+			SecretInjector:  secretInjector,
+			ServiceInjector: serviceInjector,
+		}),
+	}
+
+	_, err := h.Store.Add(context.Background(), function)
+	require.NoError(t, err)
+	_, err = h.Store.Add(context.Background(), fnRun)
+	require.NoError(t, err)
+
+	// failed attempts are requeued with the backoff of the policy, the run being reloaded as the controller does
+	run := fnRun
+	for attempt := 1; attempt < 3; attempt++ {
+		delay, requeued := controller.RequeueDelay(h.Add(context.Background(), run))
+		require.True(t, requeued, "attempt %d not requeued", attempt)
+		assert.Equal(t, time.Duration(attempt)*time.Hour, delay)
+
+		run = new(functions.FnRun)
+		require.NoError(t, h.Store.Get(context.Background(), testOrgID, "testRun", entitystore.Options{}, run))
+		assert.Equal(t, entitystore.StatusCREATING, run.Status)
+		assert.Len(t, run.Attempts, attempt)
+		assert.True(t, run.RetryAt.After(time.Now()))
+
+		// the run is not executed before its next attempt is due
+		_, requeued = controller.RequeueDelay(h.Add(context.Background(), run))
+		assert.True(t, requeued)
+		assert.Equal(t, attempt, calls)
+		run.RetryAt = time.Now()
+	}
+
+	require.NoError(t, h.Add(context.Background(), run))
+	assert.Equal(t, entitystore.StatusREADY, run.Status)
+	assert.Equal(t, "done", run.Output)
+	assert.True(t, run.RetryAt.IsZero())
+	require.Len(t, run.Attempts, 3)
+	for i, attempt := range run.Attempts {
+		assert.Equal(t, i+1, attempt.Attempt)
+	}
+	assert.NotNil(t, run.Attempts[0].Error)
+	assert.NotNil(t, run.Attempts[1].Error)
+	assert.Nil(t, run.Attempts[2].Error)
+
+	stored := new(functions.FnRun)
+	require.NoError(t, h.Store.Get(context.Background(), testOrgID, "testRun", entitystore.Options{}, stored))
+	assert.Len(t, stored.Attempts, 3)
+}
+
+func TestRunEntityHandler_AddDeadLetter(t *testing.T) {
+	faas := &fnmocks.FaaSDriver{}
+	function := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testFunction",
+			OrganizationID: testOrgID,
+		},
+		ImageName: "testImage",
+		Handler:   "main",
+		Schema:    &functions.Schema{},
+	}
+	fnRun := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "testRun",
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+		RetryPolicy:  &functions.RetryPolicy{MaxAttempts: 2},
+	}
+
+	var runnable functions.Runnable = func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		return nil, errors.New("permanent failure")
+	}
+	faas.On("GetRunnable", mock.Anything).Return(runnable)
+
+	var simw functions.Middleware = func(f functions.Runnable) functions.Runnable {
+		return f
+	}
+	secretInjector := &fnmocks.SecretInjector{}
+	secretInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+	serviceInjector := &fnmocks.ServiceInjector{}
+	serviceInjector.On("GetMiddleware", testOrgID, mock.Anything, "cookie").Return(simw)
+
+	transport := &evmocks.Transport{}
+	var published *events.CloudEvent
+	transport.On("Publish", mock.Anything, mock.Anything, "dead-letters", testOrgID).Return(nil).Run(func(args mock.Arguments) {
+		published = args.Get(1).(*events.CloudEvent)
+	})
+
+	h := &runEntityHandler{
+		Store: helpers.MakeEntityStore(t),
+		FaaS:  faas,
+		Runner: runner.New(&runner.Config{
+			Faas:            faas,
+			Validator:       validator.NoOp(),
+			SecretInjector:  secretInjector,
+			ServiceInjector: serviceInjector,
+		}),
+		DeadLetterTopic:     "dead-letters",
+		DeadLetterTransport: transport,
+	}
+
+	_, err := h.Store.Add(context.Background(), function)
+	require.NoError(t, err)
+	_, err = h.Store.Add(context.Background(), fnRun)
+	require.NoError(t, err)
+
+	_, requeued := controller.RequeueDelay(h.Add(context.Background(), fnRun))
+	require.True(t, requeued)
+	transport.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	retried := new(functions.FnRun)
+	require.NoError(t, h.Store.Get(context.Background(), testOrgID, "testRun", entitystore.Options{}, retried))
+	assert.Error(t, h.Add(context.Background(), retried))
+	assert.Equal(t, entitystore.StatusERROR, retried.Status)
+	assert.Len(t, retried.Attempts, 2)
+
+	transport.AssertExpectations(t)
+	require.NotNil(t, published)
+	assert.Equal(t, deadLetterEventType, published.EventType)
+	var run functions.FnRun
+	require.NoError(t, json.Unmarshal(published.Data, &run))
+	assert.Equal(t, "testRun", run.Name)
+	assert.Equal(t, entitystore.StatusERROR, run.Status)
+	assert.Len(t, run.Attempts, 2)
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := &functions.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  300 * time.Millisecond,
+	}
+	run := &functions.FnRun{
+		RetryPolicy: policy,
+		Error:       &v1.InvocationError{Type: v1.ErrorTypeFunctionError},
+	}
+
+	delay, retry := retryDelay(run, 1)
+	assert.True(t, retry)
+	assert.Equal(t, 100*time.Millisecond, delay)
+	delay, retry = retryDelay(run, 2)
+	assert.True(t, retry)
+	assert.Equal(t, 200*time.Millisecond, delay)
+	delay, retry = retryDelay(run, 3)
+	assert.True(t, retry)
+	assert.Equal(t, 300*time.Millisecond, delay)
+	_, retry = retryDelay(run, 5)
+	assert.False(t, retry)
+
+	// input errors are not retried by default
+	run.Error = &v1.InvocationError{Type: v1.ErrorTypeInputError}
+	_, retry = retryDelay(run, 1)
+	assert.False(t, retry)
+
+	policy.RetryOn = []string{string(v1.ErrorTypeTimeoutError)}
+	_, retry = retryDelay(run, 1)
+	assert.False(t, retry)
+	run.Error = &v1.InvocationError{Type: v1.ErrorTypeTimeoutError}
+	_, retry = retryDelay(run, 1)
+	assert.True(t, retry)
+
+	// blocking runs are never retried
+	run.Blocking = true
+	_, retry = retryDelay(run, 1)
+	assert.False(t, retry)
+}
+
+func TestRunEntityHandler_SyncExpired(t *testing.T) {
+	h := &runEntityHandler{
+		Store:        helpers.MakeEntityStore(t),
+		RunRetention: &functions.RunRetention{MaxAge: time.Hour},
+	}
+	ctx := context.Background()
+
+	for _, f := range []*functions.Function{
+		{
+			BaseEntity:   entitystore.BaseEntity{Name: "counted", OrganizationID: testOrgID},
+			RunRetention: &functions.RunRetention{MaxCount: 1},
+		},
+		{
+			BaseEntity: entitystore.BaseEntity{Name: "aged", OrganizationID: testOrgID},
+		},
+	} {
+		_, err := h.Store.Add(ctx, f)
+		require.NoError(t, err)
+	}
+	for _, run := range []*functions.FnRun{
+		{BaseEntity: entitystore.BaseEntity{Name: "counted1", Status: entitystore.StatusREADY}, FunctionName: "counted"},
+		{BaseEntity: entitystore.BaseEntity{Name: "counted2", Status: entitystore.StatusERROR}, FunctionName: "counted"},
+		{BaseEntity: entitystore.BaseEntity{Name: "counted3", Status: entitystore.StatusCREATING}, FunctionName: "counted"},
+		{BaseEntity: entitystore.BaseEntity{Name: "aged1", Status: entitystore.StatusREADY}, FunctionName: "aged"},
+		{BaseEntity: entitystore.BaseEntity{Name: "deleted1", Status: entitystore.StatusCANCELLED}, FunctionName: "deleted"},
+	} {
+		run.OrganizationID = testOrgID
+		_, err := h.Store.Add(ctx, run)
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	remaining := func() []string {
+		var runs []*functions.FnRun
+		require.NoError(t, h.Store.List(ctx, testOrgID, entitystore.Options{OrderBy: "name"}, &runs))
+		var names []string
+		for _, run := range runs {
+			names = append(names, run.Name)
+		}
+		return names
+	}
+
+	// only the runs exceeding the count of their function expired
+	deleted, err := h.purgeRuns(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Equal(t, []string{"aged1", "counted2", "counted3", "deleted1"}, remaining())
+
+	// the organization retention overrides the default one
+	h.OrgRunRetention = map[string]*functions.RunRetention{testOrgID: {MaxAge: 3 * time.Hour}}
+	deleted, err = h.purgeRuns(ctx, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	// runs which have not finished never expire, the runs of deleted functions expire by age
+	h.OrgRunRetention = nil
+	deleted, err = h.purgeRuns(ctx, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.Equal(t, []string{"counted3"}, remaining())
+
+	entities, err := h.Sync(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, entities)
+	assert.False(t, h.purgedAt.IsZero())
+
+	// expired runs are purged at most once per purge period
+	purgedAt := h.purgedAt
+	_, err = h.Sync(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, purgedAt, h.purgedAt)
+}
+
+// failingDeleteStore fails to delete the entity named failing, and runs transactions without isolation
+type failingDeleteStore struct {
+	entitystore.EntityStore
+	failing string
+}
+
+func (s *failingDeleteStore) Delete(ctx context.Context, organizationID string, name string, entity entitystore.Entity) error {
+	if name == s.failing {
+		return errors.New("delete failed")
+	}
+	return s.EntityStore.Delete(ctx, organizationID, name, entity)
+}
+
+func (s *failingDeleteStore) Transaction(ctx context.Context, fn func(tx entitystore.EntityStore) error) error {
+	return fn(s)
+}
+
+func TestDeleteRunBatchesFailure(t *testing.T) {
+	store := &failingDeleteStore{EntityStore: helpers.MakeEntityStore(t), failing: "run000"}
+	ctx := context.Background()
+	for i := 0; i < runDeleteBatchSize+1; i++ {
+		run := &functions.FnRun{
+			BaseEntity:   entitystore.BaseEntity{Name: fmt.Sprintf("run%03d", i), OrganizationID: testOrgID, Status: entitystore.StatusREADY},
+			FunctionName: "hello",
+		}
+		_, err := store.Add(ctx, run)
+		require.NoError(t, err)
+	}
+
+	// the run failing to delete is skipped, instead of failing every batch
+	deleted, err := deleteRunBatches(ctx, store, testOrgID, entitystore.Options{Filter: finishedRunsFilter()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(runDeleteBatchSize), deleted)
+
+	var runs []*functions.FnRun
+	require.NoError(t, store.List(ctx, testOrgID, entitystore.Options{}, &runs))
+	require.Len(t, runs, 1)
+	assert.Equal(t, "run000", runs[0].Name)
+}
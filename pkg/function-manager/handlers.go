@@ -10,11 +10,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-openapi/runtime"
 	"github.com/go-openapi/runtime/middleware"
 	"github.com/go-openapi/spec"
 	"github.com/go-openapi/strfmt"
@@ -22,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/vmware/dispatch/pkg/api/v1"
 	"github.com/vmware/dispatch/pkg/controller"
@@ -39,6 +43,12 @@ import (
 const (
 	schemeSeparator = "://"
 	entityScheme    = "es"
+
+	// cancelRunAttempts is the number of times cancelling a run is attempted when it is updated concurrently
+	cancelRunAttempts = 3
+
+	// followRunInterval is the interval at which a followed run is polled until it is executed
+	followRunInterval = time.Second
 )
 
 func getScheme(sourceURL string) (string, error) {
@@ -77,12 +87,178 @@ func functionEntityToModel(f *functions.Function) *v1.Function {
 			In:  f.Schema.In,
 			Out: f.Schema.Out,
 		},
-		Reason:   f.Reason,
-		Secrets:  f.Secrets,
-		Services: f.Services,
-		Timeout:  f.Timeout,
-		Tags:     tags,
-		Status:   v1.Status(f.Status),
+		Reason:        f.Reason,
+		Secrets:       f.Secrets,
+		Services:      f.Services,
+		Timeout:       f.Timeout,
+		Limits:        resourcesEntityToModel(f.Limits),
+		Sandbox:       sandboxEntityToModel(f.Sandbox),
+		RetryPolicy:   retryPolicyEntityToModel(f.RetryPolicy),
+		RunRetention:  runRetentionEntityToModel(f.RunRetention),
+		PreWarm:       int64(f.PreWarm),
+		LatestVersion: int64(f.LatestVersion),
+		Aliases:       aliasesEntityToModel(f.Aliases),
+		Tags:          tags,
+		Status:        v1.Status(f.Status),
+	}
+}
+
+func aliasesEntityToModel(aliases []*functions.Alias) []*v1.FunctionAlias {
+	var m []*v1.FunctionAlias
+	for _, a := range aliases {
+		m = append(m, &v1.FunctionAlias{
+			Name:     a.Name,
+			Routes:   routesEntityToModel(a.Routes),
+			Previous: routesEntityToModel(a.Previous),
+		})
+	}
+	return m
+}
+
+func routesEntityToModel(routes []*functions.VersionWeight) []*v1.VersionWeight {
+	var m []*v1.VersionWeight
+	for _, r := range routes {
+		m = append(m, &v1.VersionWeight{
+			Version: swag.Int64(int64(r.Version)),
+			Weight:  int64(r.Weight),
+		})
+	}
+	return m
+}
+
+func versionEntityToModel(v *functions.FunctionVersion) *v1.FunctionVersion {
+	return &v1.FunctionVersion{
+		CreatedTime:  v.CreatedTime.Unix(),
+		ID:           strfmt.UUID(v.ID),
+		FunctionName: v.FunctionName,
+		Version:      int64(v.Number),
+		Function:     functionEntityToModel(&v.Function),
+		Reason:       v.Reason,
+		Status:       v1.Status(v.Status),
+	}
+}
+
+func resourcesEntityToModel(r *functions.FunctionResources) *v1.FunctionResources {
+	if r == nil {
+		return nil
+	}
+	return &v1.FunctionResources{
+		CPU:    r.CPU,
+		Memory: r.Memory,
+		Pids:   r.Pids,
+	}
+}
+
+func sandboxEntityToModel(s *functions.FunctionSandbox) *v1.FunctionSandbox {
+	if s == nil {
+		return nil
+	}
+	return &v1.FunctionSandbox{
+		DropCapabilities: s.DropCapabilities,
+		NoNetwork:        s.NoNetwork,
+		ReadOnlyRootFS:   s.ReadOnlyRootFS,
+		SeccompProfile:   s.SeccompProfile,
+	}
+}
+
+func retryPolicyEntityToModel(p *functions.RetryPolicy) *v1.RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	var retryOn []v1.ErrorType
+	for _, t := range p.RetryOn {
+		retryOn = append(retryOn, v1.ErrorType(t))
+	}
+	return &v1.RetryPolicy{
+		Backoff:     int64(p.Backoff / time.Millisecond),
+		MaxAttempts: int64(p.MaxAttempts),
+		MaxBackoff:  int64(p.MaxBackoff / time.Millisecond),
+		RetryOn:     retryOn,
+	}
+}
+
+func runRetentionEntityToModel(r *functions.RunRetention) *v1.RunRetention {
+	if r == nil {
+		return nil
+	}
+	return &v1.RunRetention{
+		MaxAge:   int64(r.MaxAge / time.Second),
+		MaxCount: int64(r.MaxCount),
+	}
+}
+
+func attemptsEntityToModel(attempts []*functions.RunAttempt) []*v1.RunAttempt {
+	var m []*v1.RunAttempt
+	for _, a := range attempts {
+		m = append(m, &v1.RunAttempt{
+			Attempt:      int64(a.Attempt),
+			Error:        a.Error,
+			ExecutedTime: a.ExecutedTime.Unix(),
+			FinishedTime: a.FinishedTime.Unix(),
+		})
+	}
+	return m
+}
+
+func resourcesModelToEntity(m *v1.FunctionResources) (*functions.FunctionResources, error) {
+	if m == nil {
+		return nil, nil
+	}
+	if m.CPU != "" {
+		if _, err := resource.ParseQuantity(m.CPU); err != nil {
+			return nil, errors.Wrapf(err, "invalid CPU limit %s", m.CPU)
+		}
+	}
+	if m.Memory != "" {
+		if _, err := resource.ParseQuantity(m.Memory); err != nil {
+			return nil, errors.Wrapf(err, "invalid memory limit %s", m.Memory)
+		}
+	}
+	return &functions.FunctionResources{
+		CPU:    m.CPU,
+		Memory: m.Memory,
+		Pids:   m.Pids,
+	}, nil
+}
+
+func sandboxModelToEntity(m *v1.FunctionSandbox) (*functions.FunctionSandbox, error) {
+	if m == nil {
+		return nil, nil
+	}
+	if m.SeccompProfile != "" && m.SeccompProfile != "unconfined" && !json.Valid([]byte(m.SeccompProfile)) {
+		return nil, errors.New("seccomp profile must be a JSON profile or unconfined")
+	}
+	return &functions.FunctionSandbox{
+		DropCapabilities: m.DropCapabilities,
+		NoNetwork:        m.NoNetwork,
+		ReadOnlyRootFS:   m.ReadOnlyRootFS,
+		SeccompProfile:   m.SeccompProfile,
+	}, nil
+}
+
+func retryPolicyModelToEntity(m *v1.RetryPolicy) *functions.RetryPolicy {
+	if m == nil {
+		return nil
+	}
+	var retryOn []string
+	for _, t := range m.RetryOn {
+		retryOn = append(retryOn, string(t))
+	}
+	return &functions.RetryPolicy{
+		Backoff:     time.Duration(m.Backoff) * time.Millisecond,
+		MaxAttempts: int(m.MaxAttempts),
+		MaxBackoff:  time.Duration(m.MaxBackoff) * time.Millisecond,
+		RetryOn:     retryOn,
+	}
+}
+
+func runRetentionModelToEntity(m *v1.RunRetention) *functions.RunRetention {
+	if m == nil {
+		return nil
+	}
+	return &functions.RunRetention{
+		MaxAge:   time.Duration(m.MaxAge) * time.Second,
+		MaxCount: int(m.MaxCount),
 	}
 }
 
@@ -124,6 +300,15 @@ func functionModelOntoEntity(m *v1.Function, sourceURL string, e *functions.Func
 	e.ImageName = *m.Image
 	e.FaasID = string(m.FaasID)
 	e.Timeout = m.Timeout
+	if e.Limits, err = resourcesModelToEntity(m.Limits); err != nil {
+		return err
+	}
+	if e.Sandbox, err = sandboxModelToEntity(m.Sandbox); err != nil {
+		return err
+	}
+	e.RetryPolicy = retryPolicyModelToEntity(m.RetryPolicy)
+	e.RunRetention = runRetentionModelToEntity(m.RunRetention)
+	e.PreWarm = int(m.PreWarm)
 	e.Tags = map[string]string{}
 	for _, t := range m.Tags {
 		e.Tags[t.Key] = t.Value
@@ -165,6 +350,11 @@ func runModelToEntity(m *v1.Run, f *functions.Function) *functions.FnRun {
 	if m.Blocking {
 		waitChan = make(chan struct{})
 	}
+	// the retry policy of the run takes precedence over the one of the function
+	retryPolicy := f.RetryPolicy
+	if m.RetryPolicy != nil {
+		retryPolicy = retryPolicyModelToEntity(m.RetryPolicy)
+	}
 	return &functions.FnRun{
 		BaseEntity: entitystore.BaseEntity{
 			Name:   uuid.NewV4().String(),
@@ -181,6 +371,7 @@ func runModelToEntity(m *v1.Run, f *functions.Function) *functions.FnRun {
 		FunctionID:   f.ID,
 		FaasID:       f.FaasID,
 		Event:        helpers.CloudEventFromAPI(m.Event),
+		RetryPolicy:  retryPolicy,
 		WaitChan:     waitChan,
 	}
 }
@@ -208,6 +399,11 @@ func runEntityToModel(f *functions.FnRun) *v1.Run {
 		Event:        (*v1.CloudEvent)(helpers.CloudEventToAPI(f.Event)),
 		Reason:       f.Reason,
 		Tags:         tags,
+		RetryPolicy:  retryPolicyEntityToModel(f.RetryPolicy),
+		Attempts:     attemptsEntityToModel(f.Attempts),
+		ColdStart:    int64(f.ColdStart / time.Millisecond),
+		Alias:        f.Alias,
+		Version:      int64(f.FunctionVersion),
 	}
 }
 
@@ -224,6 +420,20 @@ type Handlers struct {
 	Watcher controller.Watcher
 
 	Store entitystore.EntityStore
+
+	// Logs holds the log lines of the runs executed by this instance, followed while the runs execute
+	Logs *LogStreams
+
+	cancellationsOnce sync.Once
+	cancellations     *runCancellations
+}
+
+// watchCancelled returns a channel which is closed once the run is cancelled, and a function which stops watching it
+func (h *Handlers) watchCancelled(run *functions.FnRun) (<-chan struct{}, func()) {
+	h.cancellationsOnce.Do(func() {
+		h.cancellations = newRunCancellations(h.Store)
+	})
+	return h.cancellations.watch(run)
 }
 
 // NewHandlers is the constructor for the function manager API handlers
@@ -289,9 +499,16 @@ func (h *Handlers) ConfigureHandlers(api middleware.RoutableAPI) {
 	a.StoreDeleteFunctionHandler = fnstore.DeleteFunctionHandlerFunc(h.deleteFunction)
 	a.StoreGetFunctionsHandler = fnstore.GetFunctionsHandlerFunc(h.getFunctions)
 	a.StoreUpdateFunctionHandler = fnstore.UpdateFunctionHandlerFunc(h.updateFunction)
+	a.StorePublishFunctionHandler = fnstore.PublishFunctionHandlerFunc(h.publishFunction)
+	a.StoreSetAliasHandler = fnstore.SetAliasHandlerFunc(h.setAlias)
+	a.StoreDeleteAliasHandler = fnstore.DeleteAliasHandlerFunc(h.deleteAlias)
+	a.StoreRollbackAliasHandler = fnstore.RollbackAliasHandlerFunc(h.rollbackAlias)
 	a.RunnerRunFunctionHandler = fnrunner.RunFunctionHandlerFunc(h.runFunction)
 	a.RunnerGetRunHandler = fnrunner.GetRunHandlerFunc(h.getRun)
+	a.RunnerGetRunLogsHandler = fnrunner.GetRunLogsHandlerFunc(h.getRunLogs)
+	a.RunnerCancelRunHandler = fnrunner.CancelRunHandlerFunc(h.cancelRun)
 	a.RunnerGetRunsHandler = fnrunner.GetRunsHandlerFunc(h.getRuns)
+	a.RunnerDeleteRunsHandler = fnrunner.DeleteRunsHandlerFunc(h.deleteRuns)
 }
 
 func (h *Handlers) addFunction(params fnstore.AddFunctionParams, principal interface{}) middleware.Responder {
@@ -307,23 +524,6 @@ func (h *Handlers) addFunction(params fnstore.AddFunctionParams, principal inter
 		s.OrganizationID = params.XDispatchOrg
 		s.Status = entitystore.StatusREADY
 
-		if _, err := h.Store.Add(ctx, s); err != nil {
-			log.Errorf("Store error when adding new source %s: %+v", s.Name, err)
-			return fnstore.NewAddFunctionDefault(500).WithPayload(&v1.Error{
-				Code:    http.StatusInternalServerError,
-				Message: utils.ErrorMsgInternalError("source", s.Name),
-			})
-		}
-
-		defer func() {
-			if err != nil {
-				log.Debugf("failed to add function, deleting orphaned source %s", s.Name)
-				if err := h.Store.Delete(ctx, s.OrganizationID, s.Name, s); err != nil {
-					log.Errorf("Store error when deleting source %s: %+v", s.Name, err)
-				}
-			}
-		}()
-
 		sourceURL = entityScheme + schemeSeparator + s.Name
 	} else if len(functionModel.SourceURL) > 0 {
 		sourceURL = functionModel.SourceURL
@@ -348,7 +548,17 @@ func (h *Handlers) addFunction(params fnstore.AddFunctionParams, principal inter
 
 	log.Debugf("trying to add entity to store")
 	log.Debugf("entity org=%s, name=%s, id=%s, status=%s", e.OrganizationID, e.Name, e.ID, e.Status)
-	_, err = h.Store.Add(ctx, e)
+	// the source and the function are added together, so that no source is left behind if the function is not
+	err = h.Store.Transaction(ctx, func(tx entitystore.EntityStore) error {
+		if s != nil {
+			if _, err := tx.Add(ctx, s); err != nil {
+				log.Errorf("Store error when adding new source %s: %+v", s.Name, err)
+				return err
+			}
+		}
+		_, err := tx.Add(ctx, e)
+		return err
+	})
 	if err != nil {
 		if entitystore.IsUniqueViolation(err) {
 			return fnstore.NewAddFunctionConflict().WithPayload(&v1.Error{
@@ -380,7 +590,7 @@ func (h *Handlers) getFunction(params fnstore.GetFunctionParams, principal inter
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return fnstore.NewGetFunctionBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -443,7 +653,16 @@ func (h *Handlers) getFunctions(params fnstore.GetFunctionsParams, principal int
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
+		return fnstore.NewGetFunctionsBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
 		return fnstore.NewGetFunctionsBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -460,7 +679,7 @@ func (h *Handlers) getFunctions(params fnstore.GetFunctionsParams, principal int
 			Message: swag.String("error when listing functions"),
 		})
 	}
-	return fnstore.NewGetFunctionsOK().WithPayload(functionListToModel(funcs))
+	return fnstore.NewGetFunctionsOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(funcs))).WithPayload(functionListToModel(funcs))
 }
 
 func (h *Handlers) updateFunction(params fnstore.UpdateFunctionParams, principal interface{}) middleware.Responder {
@@ -481,7 +700,7 @@ func (h *Handlers) updateFunction(params fnstore.UpdateFunctionParams, principal
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return fnstore.NewUpdateFunctionBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -509,6 +728,8 @@ func (h *Handlers) updateFunction(params fnstore.UpdateFunctionParams, principal
 		})
 	}
 
+	// the source changes are written with the function, in a transaction
+	var addSource, updateSource, deleteSource *functions.Source
 	s := new(functions.Source)
 	var sourceURL string
 	if scheme == entityScheme {
@@ -538,25 +759,12 @@ func (h *Handlers) updateFunction(params fnstore.UpdateFunctionParams, principal
 			updateEntity.ID = s.ID
 			updateEntity.OrganizationID = s.OrganizationID
 			updateEntity.Status = entitystore.StatusREADY
-
-			if _, err := h.Store.Update(ctx, s.Revision, updateEntity); err != nil {
-				log.Errorf("Store error when updating source %s: %+v", updateEntity.Name, err)
-				return fnstore.NewUpdateFunctionDefault(500).WithPayload(&v1.Error{
-					Code:    http.StatusInternalServerError,
-					Message: utils.ErrorMsgInternalError("source", updateEntity.Name),
-				})
-			}
+			updateEntity.Revision = s.Revision
+			updateSource = updateEntity
 
 			sourceURL = entityScheme + schemeSeparator + updateEntity.Name
 		} else {
-			defer func() {
-				if err == nil {
-					log.Debugf("successfully updated function %s, deleting old source %s", e.Name, s.Name)
-					if err := h.Store.Delete(ctx, s.OrganizationID, s.Name, s); err != nil {
-						log.Errorf("Store error when deleting source %s: %+v", s.Name, err)
-					}
-				}
-			}()
+			deleteSource = s
 
 			sourceURL = functionModel.SourceURL
 		}
@@ -565,23 +773,7 @@ func (h *Handlers) updateFunction(params fnstore.UpdateFunctionParams, principal
 			s = functionModelToSourceEntity(functionModel)
 			s.OrganizationID = params.XDispatchOrg
 			s.Status = entitystore.StatusREADY
-
-			if _, err := h.Store.Add(ctx, s); err != nil {
-				log.Errorf("Store error when adding new source %s: %+v", s.Name, err)
-				return fnstore.NewUpdateFunctionDefault(500).WithPayload(&v1.Error{
-					Code:    http.StatusInternalServerError,
-					Message: utils.ErrorMsgInternalError("source", s.Name),
-				})
-			}
-
-			defer func() {
-				if err != nil {
-					log.Debugf("failed to update function %s, deleting orphaned source %s", e.Name, s.Name)
-					if err := h.Store.Delete(ctx, s.OrganizationID, s.Name, s); err != nil {
-						log.Errorf("Store error when deleting source %s: %+v", s.Name, err)
-					}
-				}
-			}()
+			addSource = s
 
 			sourceURL = entityScheme + schemeSeparator + s.Name
 		} else {
@@ -601,7 +793,24 @@ func (h *Handlers) updateFunction(params fnstore.UpdateFunctionParams, principal
 	e.FaasID = faasID
 	e.Status = entitystore.StatusUPDATING
 
-	_, err = h.Store.Update(ctx, e.Revision, e)
+	err = h.Store.Transaction(ctx, func(tx entitystore.EntityStore) error {
+		switch {
+		case addSource != nil:
+			if _, err := tx.Add(ctx, addSource); err != nil {
+				return errors.Wrapf(err, "error adding source %s", addSource.Name)
+			}
+		case updateSource != nil:
+			if _, err := tx.Update(ctx, updateSource.Revision, updateSource); err != nil {
+				return errors.Wrapf(err, "error updating source %s", updateSource.Name)
+			}
+		case deleteSource != nil:
+			if err := tx.Delete(ctx, deleteSource.OrganizationID, deleteSource.Name, deleteSource); err != nil {
+				return errors.Wrapf(err, "error deleting source %s", deleteSource.Name)
+			}
+		}
+		_, err := tx.Update(ctx, e.Revision, e)
+		return err
+	})
 	if err != nil {
 		log.Errorf("Store error when updating function %s: %+v", params.FunctionName, err)
 		return fnstore.NewUpdateFunctionDefault(500).WithPayload(&v1.Error{
@@ -616,6 +825,236 @@ func (h *Handlers) updateFunction(params fnstore.UpdateFunctionParams, principal
 	return fnstore.NewUpdateFunctionOK().WithPayload(m)
 }
 
+// versionName returns the entity name of a version of a function
+func versionName(functionName string, number int) string {
+	return fmt.Sprintf("%s-v%d", functionName, number)
+}
+
+// findAlias returns the alias of a function with the given name, or nil if there is none
+func findAlias(f *functions.Function, name string) *functions.Alias {
+	for _, a := range f.Aliases {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// pickVersion picks one of the versions of the routes of an alias, with a probability proportional to its weight.
+// Versions are picked uniformly if all weights are 0.
+func pickVersion(routes []*functions.VersionWeight) int {
+	total := 0
+	for _, r := range routes {
+		total += r.Weight
+	}
+	if total == 0 {
+		return routes[rand.Intn(len(routes))].Version
+	}
+	n := rand.Intn(total)
+	for _, r := range routes {
+		if n < r.Weight {
+			return r.Version
+		}
+		n -= r.Weight
+	}
+	return routes[len(routes)-1].Version
+}
+
+func (h *Handlers) publishFunction(params fnstore.PublishFunctionParams, principal interface{}) middleware.Responder {
+	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
+	defer span.Finish()
+
+	f := new(functions.Function)
+	if err := h.Store.Get(ctx, params.XDispatchOrg, params.FunctionName, entitystore.Options{}, f); err != nil {
+		log.Debugf("Error returned by h.Store.Get: %+v", err)
+		log.Infof("Received publish for non-existent function %s", params.FunctionName)
+		return fnstore.NewPublishFunctionNotFound().WithPayload(&v1.Error{
+			Code:    http.StatusNotFound,
+			Message: utils.ErrorMsgNotFound("function", params.FunctionName),
+		})
+	}
+	// the image of the function is only built once it is READY
+	if f.Status != entitystore.StatusREADY {
+		return fnstore.NewPublishFunctionConflict().WithPayload(&v1.Error{
+			Code:    http.StatusConflict,
+			Message: swag.String(fmt.Sprintf("function %s is not READY", f.Name)),
+		})
+	}
+
+	f.LatestVersion++
+	v := &functions.FunctionVersion{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           versionName(f.Name, f.LatestVersion),
+			OrganizationID: f.OrganizationID,
+			Status:         entitystore.StatusINITIALIZED,
+			Tags:           f.Tags,
+		},
+		FunctionName: f.Name,
+		Number:       f.LatestVersion,
+		Function:     *f,
+	}
+	// the version is deployed with the image of the function, under a FaaS ID of its own
+	v.Function.FaasID = uuid.NewV4().String()
+	v.Function.LatestVersion = 0
+	v.Function.Aliases = nil
+
+	err := h.Store.Transaction(ctx, func(tx entitystore.EntityStore) error {
+		if _, err := tx.Update(ctx, f.Revision, f); err != nil {
+			return errors.Wrapf(err, "error updating function %s", f.Name)
+		}
+		_, err := tx.Add(ctx, v)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Store error when publishing function %s: %+v", f.Name, err)
+		return fnstore.NewPublishFunctionDefault(500).WithPayload(&v1.Error{
+			Code:    http.StatusInternalServerError,
+			Message: utils.ErrorMsgInternalError("function version", v.Name),
+		})
+	}
+
+	h.Watcher.OnAction(ctx, v)
+
+	return fnstore.NewPublishFunctionCreated().WithPayload(versionEntityToModel(v))
+}
+
+// updateAliases updates the aliases of a READY function and saves it. Failures are returned as API errors, with the
+// HTTP status code of the failure.
+func (h *Handlers) updateAliases(ctx context.Context, organizationID, functionName string, update func(f *functions.Function) *v1.Error) (*functions.Function, *v1.Error) {
+	f := new(functions.Function)
+	if err := h.Store.Get(ctx, organizationID, functionName, entitystore.Options{}, f); err != nil {
+		log.Debugf("Error returned by h.Store.Get: %+v", err)
+		log.Infof("Received alias update for non-existent function %s", functionName)
+		return nil, &v1.Error{
+			Code:    http.StatusNotFound,
+			Message: utils.ErrorMsgNotFound("function", functionName),
+		}
+	}
+	// a function which is not READY is saved by the controller, which would discard the update
+	if f.Status != entitystore.StatusREADY {
+		return nil, &v1.Error{
+			Code:    http.StatusConflict,
+			Message: swag.String(fmt.Sprintf("function %s is not READY", functionName)),
+		}
+	}
+	if apiErr := update(f); apiErr != nil {
+		return nil, apiErr
+	}
+	if _, err := h.Store.Update(ctx, f.Revision, f); err != nil {
+		log.Errorf("Store error when updating aliases of function %s: %+v", functionName, err)
+		return nil, &v1.Error{
+			Code:    http.StatusInternalServerError,
+			Message: utils.ErrorMsgInternalError("function", functionName),
+		}
+	}
+	return f, nil
+}
+
+func (h *Handlers) setAlias(params fnstore.SetAliasParams, principal interface{}) middleware.Responder {
+	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
+	defer span.Finish()
+
+	f, apiErr := h.updateAliases(ctx, params.XDispatchOrg, params.FunctionName, func(f *functions.Function) *v1.Error {
+		var routes []*functions.VersionWeight
+		published := make(map[int]bool)
+		for _, r := range params.Body.Routes {
+			version := int(*r.Version)
+			if version > f.LatestVersion || published[version] {
+				return &v1.Error{
+					Code:    http.StatusBadRequest,
+					Message: swag.String(fmt.Sprintf("version %d of function %s is not published or is repeated", version, f.Name)),
+				}
+			}
+			published[version] = true
+			routes = append(routes, &functions.VersionWeight{Version: version, Weight: int(r.Weight)})
+		}
+		if alias := findAlias(f, params.AliasName); alias != nil {
+			alias.Previous = alias.Routes
+			alias.Routes = routes
+			return nil
+		}
+		f.Aliases = append(f.Aliases, &functions.Alias{Name: params.AliasName, Routes: routes})
+		return nil
+	})
+	if apiErr != nil {
+		switch apiErr.Code {
+		case http.StatusBadRequest:
+			return fnstore.NewSetAliasBadRequest().WithPayload(apiErr)
+		case http.StatusNotFound:
+			return fnstore.NewSetAliasNotFound().WithPayload(apiErr)
+		case http.StatusConflict:
+			return fnstore.NewSetAliasConflict().WithPayload(apiErr)
+		default:
+			return fnstore.NewSetAliasDefault(int(apiErr.Code)).WithPayload(apiErr)
+		}
+	}
+	return fnstore.NewSetAliasOK().WithPayload(functionEntityToModel(f))
+}
+
+func (h *Handlers) deleteAlias(params fnstore.DeleteAliasParams, principal interface{}) middleware.Responder {
+	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
+	defer span.Finish()
+
+	f, apiErr := h.updateAliases(ctx, params.XDispatchOrg, params.FunctionName, func(f *functions.Function) *v1.Error {
+		for i, a := range f.Aliases {
+			if a.Name == params.AliasName {
+				f.Aliases = append(f.Aliases[:i], f.Aliases[i+1:]...)
+				return nil
+			}
+		}
+		return &v1.Error{
+			Code:    http.StatusNotFound,
+			Message: utils.ErrorMsgNotFound("alias", params.AliasName),
+		}
+	})
+	if apiErr != nil {
+		switch apiErr.Code {
+		case http.StatusNotFound:
+			return fnstore.NewDeleteAliasNotFound().WithPayload(apiErr)
+		case http.StatusConflict:
+			return fnstore.NewDeleteAliasConflict().WithPayload(apiErr)
+		default:
+			return fnstore.NewDeleteAliasDefault(int(apiErr.Code)).WithPayload(apiErr)
+		}
+	}
+	return fnstore.NewDeleteAliasOK().WithPayload(functionEntityToModel(f))
+}
+
+func (h *Handlers) rollbackAlias(params fnstore.RollbackAliasParams, principal interface{}) middleware.Responder {
+	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
+	defer span.Finish()
+
+	f, apiErr := h.updateAliases(ctx, params.XDispatchOrg, params.FunctionName, func(f *functions.Function) *v1.Error {
+		alias := findAlias(f, params.AliasName)
+		if alias == nil {
+			return &v1.Error{
+				Code:    http.StatusNotFound,
+				Message: utils.ErrorMsgNotFound("alias", params.AliasName),
+			}
+		}
+		if len(alias.Previous) == 0 {
+			return &v1.Error{
+				Code:    http.StatusConflict,
+				Message: swag.String(fmt.Sprintf("alias %s of function %s has no previous versions", alias.Name, f.Name)),
+			}
+		}
+		// rolling back twice restores the versions the alias was rolled back from
+		alias.Routes, alias.Previous = alias.Previous, alias.Routes
+		return nil
+	})
+	if apiErr != nil {
+		switch apiErr.Code {
+		case http.StatusNotFound:
+			return fnstore.NewRollbackAliasNotFound().WithPayload(apiErr)
+		case http.StatusConflict:
+			return fnstore.NewRollbackAliasConflict().WithPayload(apiErr)
+		default:
+			return fnstore.NewRollbackAliasDefault(int(apiErr.Code)).WithPayload(apiErr)
+		}
+	}
+	return fnstore.NewRollbackAliasOK().WithPayload(functionEntityToModel(f))
+}
+
 func (h *Handlers) runFunction(params fnrunner.RunFunctionParams, principal interface{}) middleware.Responder {
 	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
 	defer span.Finish()
@@ -642,7 +1081,7 @@ func (h *Handlers) runFunction(params fnrunner.RunFunctionParams, principal inte
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return fnrunner.NewRunFunctionBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -659,16 +1098,48 @@ func (h *Handlers) runFunction(params fnrunner.RunFunctionParams, principal inte
 		})
 	}
 
-	if f.Status != entitystore.StatusREADY {
+	// runs of an alias are sent to one of its versions, picked by weight
+	number := int(params.Body.Version)
+	if params.Body.Alias != "" {
+		alias := findAlias(f, params.Body.Alias)
+		if alias == nil {
+			return fnrunner.NewRunFunctionNotFound().WithPayload(&v1.Error{
+				Code:    http.StatusNotFound,
+				Message: swag.String(fmt.Sprintf("alias %s of function %s not found", params.Body.Alias, f.Name)),
+			})
+		}
+		number = pickVersion(alias.Routes)
+	}
+
+	target := f
+	if number != 0 {
+		v := new(functions.FunctionVersion)
+		if err := h.Store.Get(ctx, params.XDispatchOrg, versionName(f.Name, number), entitystore.Options{}, v); err != nil {
+			log.Debugf("Error returned by h.Store.Get: %+v", err)
+			return fnrunner.NewRunFunctionNotFound().WithPayload(&v1.Error{
+				Code:    http.StatusNotFound,
+				Message: swag.String(fmt.Sprintf("version %d of function %s not found", number, f.Name)),
+			})
+		}
+		if v.Status != entitystore.StatusREADY {
+			return fnrunner.NewRunFunctionNotFound().WithPayload(&v1.Error{
+				Code:    http.StatusNotFound,
+				Message: swag.String(fmt.Sprintf("version %d of function %s is not READY", number, f.Name)),
+			})
+		}
+		target = v.Deployment()
+	} else if f.Status != entitystore.StatusREADY {
 		return fnrunner.NewRunFunctionNotFound().WithPayload(&v1.Error{
 			Code:    http.StatusNotFound,
 			Message: swag.String(fmt.Sprintf("function %s is not READY", *params.FunctionName)),
 		})
 	}
 
-	run := runModelToEntity(params.Body, f)
+	run := runModelToEntity(params.Body, target)
 	run.OrganizationID = params.XDispatchOrg
 	run.Status = entitystore.StatusINITIALIZED
+	run.Alias = params.Body.Alias
+	run.FunctionVersion = number
 
 	if _, err := h.Store.Add(ctx, run); err != nil {
 		log.Errorf("Store error when adding new function run %s: %+v", run.Name, err)
@@ -678,13 +1149,31 @@ func (h *Handlers) runFunction(params fnrunner.RunFunctionParams, principal inte
 		})
 	}
 
-	h.Watcher.OnAction(ctx, run)
-
 	if run.Blocking {
-		run.Wait()
-		return fnrunner.NewRunFunctionOK().WithPayload(runEntityToModel(run))
+		// a cancelled run may never be executed, the cancellation is watched while waiting for the run to finish
+		cancelled, unwatch := h.watchCancelled(run)
+		defer unwatch()
+
+		h.Watcher.OnAction(ctx, run)
+
+		select {
+		case <-run.WaitChan:
+			return fnrunner.NewRunFunctionOK().WithPayload(runEntityToModel(run))
+		case <-cancelled:
+			current := new(functions.FnRun)
+			if err := h.Store.Get(ctx, run.OrganizationID, run.Name, entitystore.Options{}, current); err != nil {
+				log.Errorf("Store error when getting cancelled function run %s: %+v", run.Name, err)
+				return fnrunner.NewRunFunctionDefault(500).WithPayload(&v1.Error{
+					Code:    http.StatusInternalServerError,
+					Message: utils.ErrorMsgInternalError("function run", run.Name),
+				})
+			}
+			return fnrunner.NewRunFunctionOK().WithPayload(runEntityToModel(current))
+		}
 	}
 
+	h.Watcher.OnAction(ctx, run)
+
 	return fnrunner.NewRunFunctionAccepted().WithPayload(runEntityToModel(run))
 }
 
@@ -711,7 +1200,7 @@ func (h *Handlers) getRun(params fnrunner.GetRunParams, principal interface{}) m
 
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return fnrunner.NewGetRunBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -735,12 +1224,108 @@ func (h *Handlers) getRun(params fnrunner.GetRunParams, principal interface{}) m
 	return fnrunner.NewGetRunOK().WithPayload(runEntityToModel(&run))
 }
 
-func getFilteredRuns(ctx context.Context, store entitystore.EntityStore, orgID string, functionName *string, since *int64, tags []string) ([]*functions.FnRun, error) {
+func (h *Handlers) getRunLogs(params fnrunner.GetRunLogsParams, principal interface{}) middleware.Responder {
+	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
+	defer span.Finish()
+
+	run := new(functions.FnRun)
+	err := h.Store.Get(ctx, params.XDispatchOrg, params.RunName.String(), entitystore.Options{}, run)
+	if err != nil || (params.FunctionName != nil && run.FunctionName != *params.FunctionName) {
+		log.Debugf("Error returned by h.Store.Get: %+v", err)
+		log.Infof("Get logs failed for function run %s", params.RunName.String())
+		return fnrunner.NewGetRunLogsNotFound().WithPayload(&v1.Error{
+			Code:    http.StatusNotFound,
+			Message: utils.ErrorMsgNotFound("function run", params.RunName.String()),
+		})
+	}
+
+	follow := params.Follow != nil && *params.Follow
+	return middleware.ResponderFunc(func(rw http.ResponseWriter, _ runtime.Producer) {
+		rw.WriteHeader(http.StatusOK)
+		if err := h.writeRunLogs(params.HTTPRequest.Context(), newLogLineWriter(rw), run, follow); err != nil {
+			log.Debugf("Error writing the logs of function run %s: %v", run.Name, err)
+		}
+	})
+}
+
+// writeRunLogs writes the log lines of a run. The lines of a run being executed by this instance are written as
+// they are logged, until the run finishes if follow is set. Otherwise, a followed run is polled until it finishes
+// or starts being executed by this instance, and the logs recorded in the run are written.
+func (h *Handlers) writeRunLogs(ctx context.Context, w *logLineWriter, run *functions.FnRun, follow bool) error {
+	organizationID, name := run.OrganizationID, run.Name
+	for {
+		if logs := h.Logs.get(organizationID, name); logs != nil {
+			if !follow {
+				return w.write(logs.snapshot()...)
+			}
+			for offset := 0; ; {
+				lines, done := logs.next(ctx, offset)
+				if err := w.write(lines...); err != nil || done {
+					return err
+				}
+				offset += len(lines)
+			}
+		}
+		if !follow || runFinished(run) {
+			return w.writeStored(run.Logs)
+		}
+		select {
+		case <-time.After(followRunInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		run = new(functions.FnRun)
+		if err := h.Store.Get(ctx, organizationID, name, entitystore.Options{}, run); err != nil {
+			return err
+		}
+	}
+}
+
+func (h *Handlers) cancelRun(params fnrunner.CancelRunParams, principal interface{}) middleware.Responder {
+	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
+	defer span.Finish()
+
+	run := new(functions.FnRun)
+	// the run may be updated by its execution meanwhile, in which case cancelling is attempted again
+	for attempt := 1; ; attempt++ {
+		err := h.Store.Get(ctx, params.XDispatchOrg, params.RunName.String(), entitystore.Options{}, run)
+		if err != nil || (params.FunctionName != nil && run.FunctionName != *params.FunctionName) {
+			log.Debugf("Error returned by h.Store.Get: %+v", err)
+			log.Infof("Received cancel for non-existent function run %s", params.RunName.String())
+			return fnrunner.NewCancelRunNotFound().WithPayload(&v1.Error{
+				Code:    http.StatusNotFound,
+				Message: utils.ErrorMsgNotFound("function run", params.RunName.String()),
+			})
+		}
+		if runFinished(run) {
+			return fnrunner.NewCancelRunConflict().WithPayload(&v1.Error{
+				Code:    http.StatusConflict,
+				Message: swag.String(fmt.Sprintf("function run %s already finished with status %s", run.Name, run.Status)),
+			})
+		}
+
+		run.Status = entitystore.StatusCANCELLED
+		run.Reason = []string{"cancelled"}
+		run.FinishedTime = time.Now()
+		if _, err = h.Store.Update(ctx, run.Revision, run); err == nil {
+			break
+		}
+		if attempt == cancelRunAttempts {
+			log.Errorf("Store error when cancelling function run %s: %+v", run.Name, err)
+			return fnrunner.NewCancelRunDefault(500).WithPayload(&v1.Error{
+				Code:    http.StatusInternalServerError,
+				Message: utils.ErrorMsgInternalError("function run", run.Name),
+			})
+		}
+		run = new(functions.FnRun)
+	}
+	return fnrunner.NewCancelRunOK().WithPayload(runEntityToModel(run))
+}
+
+func getFilteredRuns(ctx context.Context, store entitystore.EntityStore, orgID string, opts entitystore.Options, functionName *string, since *int64, tags []string) ([]*functions.FnRun, error) {
 	var runs []*functions.FnRun
 	var err error
-	opts := entitystore.Options{
-		Filter: entitystore.FilterEverything(),
-	}
+	opts.Filter = entitystore.FilterEverything()
 
 	if functionName != nil {
 		opts.Filter.Add(
@@ -782,7 +1367,16 @@ func (h *Handlers) getRuns(params fnrunner.GetRunsParams, principal interface{})
 	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
 	defer span.Finish()
 
-	runs, err := getFilteredRuns(ctx, h.Store, params.XDispatchOrg, params.FunctionName, params.Since, params.Tags)
+	opts, err := utils.ParsePage(entitystore.Options{}, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		return fnrunner.NewGetRunsBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+
+	runs, err := getFilteredRuns(ctx, h.Store, params.XDispatchOrg, opts, params.FunctionName, params.Since, params.Tags)
 
 	switch err.(type) {
 	case *dispatcherrors.RequestError:
@@ -797,5 +1391,43 @@ func (h *Handlers) getRuns(params fnrunner.GetRunsParams, principal interface{})
 			Message: swag.String("error when listing function runs"),
 		})
 	}
-	return fnrunner.NewGetRunsOK().WithPayload(runListToModel(runs))
+	return fnrunner.NewGetRunsOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(runs))).WithPayload(runListToModel(runs))
+}
+
+func (h *Handlers) deleteRuns(params fnrunner.DeleteRunsParams, principal interface{}) middleware.Responder {
+	span, ctx := trace.Trace(params.HTTPRequest.Context(), "")
+	defer span.Finish()
+
+	// runs which have not finished yet are left to the controller
+	olderThan := time.Unix(params.OlderThan, 0)
+	filter := finishedRunsFilter(createdBeforeStat(olderThan))
+	if params.FunctionName != nil {
+		filter.Add(entitystore.FilterStat{
+			Scope:   entitystore.FilterScopeExtra,
+			Subject: "FunctionName",
+			Verb:    entitystore.FilterVerbEqual,
+			Object:  *params.FunctionName,
+		})
+	}
+	filter, err := utils.ParseTags(filter, params.Tags)
+	if err != nil {
+		return fnrunner.NewDeleteRunsBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+
+	deleted, err := deleteRunBatches(ctx, h.Store, params.XDispatchOrg, entitystore.Options{Filter: filter})
+	if deleted > 0 {
+		log.Infof("Deleted %d runs created before %s", deleted, olderThan)
+	}
+	if err != nil {
+		log.Errorf("Error when deleting runs: %+v", err)
+		return fnrunner.NewDeleteRunsDefault(500).WithPayload(&v1.Error{
+			Code:    http.StatusInternalServerError,
+			Message: swag.String("error when deleting function runs"),
+		})
+	}
+	return fnrunner.NewDeleteRunsOK().WithPayload(deleted)
 }
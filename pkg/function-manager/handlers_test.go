@@ -14,6 +14,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -194,6 +197,303 @@ func TestHandlers_runFunction_READY(t *testing.T) {
 	assert.Equal(t, runEntityToModel((<-watcher).Entity.(*functions.FnRun)), &respBody)
 }
 
+func TestHandlers_cancelRun(t *testing.T) {
+	store := helpers.MakeEntityStore(t)
+	handlers := &Handlers{
+		Store: store,
+	}
+
+	run := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "3f6f6b2c-4a1e-4c1b-9a57-2f8c43f3a7d1",
+			Status:         entitystore.StatusINITIALIZED,
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+	}
+	_, err := store.Add(context.Background(), run)
+	require.NoError(t, err)
+
+	api := operations.NewFunctionManagerAPI(nil)
+	handlers.ConfigureHandlers(api)
+
+	cancel := func(runName string) middleware.Responder {
+		r := httptest.NewRequest("DELETE", "/v1/runs/"+runName, nil)
+		params := fnrunner.CancelRunParams{
+			HTTPRequest:  r,
+			RunName:      strfmt.UUID(runName),
+			XDispatchOrg: testOrgID,
+		}
+		return api.RunnerCancelRunHandler.Handle(params, "testCookie")
+	}
+
+	var respBody v1.Run
+	helpers.HandlerRequest(t, cancel(run.Name), &respBody, 200)
+	assert.EqualValues(t, entitystore.StatusCANCELLED, respBody.Status)
+
+	stored := new(functions.FnRun)
+	require.NoError(t, store.Get(context.Background(), testOrgID, run.Name, entitystore.Options{}, stored))
+	assert.Equal(t, entitystore.StatusCANCELLED, stored.Status)
+
+	var errBody v1.Error
+	helpers.HandlerRequest(t, cancel(run.Name), &errBody, 409)
+	helpers.HandlerRequest(t, cancel("8b3f1d0e-5c2a-4f4e-8d1b-6a9e7c5b4a30"), &errBody, 404)
+}
+
+func TestHandlers_getRunLogs(t *testing.T) {
+	store := helpers.MakeEntityStore(t)
+	handlers := &Handlers{
+		Store: store,
+		Logs:  NewLogStreams(),
+	}
+
+	finished := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "3f6f6b2c-4a1e-4c1b-9a57-2f8c43f3a7d1",
+			Status:         entitystore.StatusREADY,
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+		Logs:         &v1.Logs{Stdout: []string{"out"}, Stderr: []string{"err"}},
+	}
+	running := &functions.FnRun{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           "8b3f1d0e-5c2a-4f4e-8d1b-6a9e7c5b4a30",
+			Status:         entitystore.StatusCREATING,
+			OrganizationID: testOrgID,
+		},
+		FunctionName: "testFunction",
+	}
+	for _, run := range []*functions.FnRun{finished, running} {
+		_, err := store.Add(context.Background(), run)
+		require.NoError(t, err)
+	}
+
+	api := operations.NewFunctionManagerAPI(nil)
+	handlers.ConfigureHandlers(api)
+
+	getLogs := func(runName string, follow bool) *httptest.ResponseRecorder {
+		params := fnrunner.GetRunLogsParams{
+			HTTPRequest:  httptest.NewRequest("GET", "/v1/runs/"+runName+"/logs", nil),
+			RunName:      strfmt.UUID(runName),
+			Follow:       &follow,
+			XDispatchOrg: testOrgID,
+		}
+		rw := httptest.NewRecorder()
+		api.RunnerGetRunLogsHandler.Handle(params, "testCookie").WriteResponse(rw, runtime.ByteStreamProducer())
+		return rw
+	}
+
+	rw := getLogs(finished.Name, true)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "{\"line\":\"out\",\"stream\":\"stdout\"}\n{\"line\":\"err\",\"stream\":\"stderr\"}\n", rw.Body.String())
+
+	// the lines of a run being executed are written until it finishes
+	logs := handlers.Logs.open(running)
+	logs.write(v1.LogLineStreamStdout, "first")
+	assert.Equal(t, "{\"line\":\"first\",\"stream\":\"stdout\"}\n", getLogs(running.Name, false).Body.String())
+
+	followed := make(chan string)
+	go func() {
+		followed <- getLogs(running.Name, true).Body.String()
+	}()
+	logs.write(v1.LogLineStreamStderr, "second")
+	// as the controller does, the run is updated before its log stream is closed
+	running.Status = entitystore.StatusREADY
+	running.Logs = &v1.Logs{Stdout: []string{"first"}, Stderr: []string{"second"}}
+	_, err := store.Update(context.Background(), running.Revision, running)
+	require.NoError(t, err)
+	handlers.Logs.close(running)
+	assert.Equal(t, "{\"line\":\"first\",\"stream\":\"stdout\"}\n{\"line\":\"second\",\"stream\":\"stderr\"}\n", <-followed)
+
+	assert.Equal(t, http.StatusNotFound, getLogs("6d1f3a52-9b0e-4c7d-8e2f-1a4b5c6d7e8f", false).Code)
+}
+
+func TestHandlers_versionsAndAliases(t *testing.T) {
+	store := helpers.MakeEntityStore(t)
+	watcher := make(chan controller.WatchEvent, 10)
+	handlers := &Handlers{
+		Watcher: watcher,
+		Store:   store,
+	}
+
+	testFuncName := "testFunction"
+	function := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name:           testFuncName,
+			Status:         entitystore.StatusREADY,
+			OrganizationID: testOrgID,
+		},
+		FaasID: "faas-id",
+		Schema: &functions.Schema{},
+	}
+	_, err := store.Add(context.Background(), function)
+	require.NoError(t, err)
+
+	api := operations.NewFunctionManagerAPI(nil)
+	handlers.ConfigureHandlers(api)
+
+	publish := func() middleware.Responder {
+		r := httptest.NewRequest("POST", "/v1/function/"+testFuncName+"/versions", nil)
+		params := fnstore.PublishFunctionParams{
+			HTTPRequest:  r,
+			FunctionName: testFuncName,
+			XDispatchOrg: testOrgID,
+		}
+		return api.StorePublishFunctionHandler.Handle(params, "testCookie")
+	}
+	setAlias := func(routes ...*v1.VersionWeight) middleware.Responder {
+		r := httptest.NewRequest("PUT", "/v1/function/"+testFuncName+"/aliases/prod", nil)
+		params := fnstore.SetAliasParams{
+			HTTPRequest:  r,
+			AliasName:    "prod",
+			Body:         &v1.FunctionAlias{Routes: routes},
+			FunctionName: testFuncName,
+			XDispatchOrg: testOrgID,
+		}
+		return api.StoreSetAliasHandler.Handle(params, "testCookie")
+	}
+	rollback := func() middleware.Responder {
+		r := httptest.NewRequest("POST", "/v1/function/"+testFuncName+"/aliases/prod/rollback", nil)
+		params := fnstore.RollbackAliasParams{
+			HTTPRequest:  r,
+			AliasName:    "prod",
+			FunctionName: testFuncName,
+			XDispatchOrg: testOrgID,
+		}
+		return api.StoreRollbackAliasHandler.Handle(params, "testCookie")
+	}
+
+	var version v1.FunctionVersion
+	for i := 1; i <= 2; i++ {
+		helpers.HandlerRequest(t, publish(), &version, 201)
+		assert.EqualValues(t, i, version.Version)
+		assert.Equal(t, testFuncName, version.FunctionName)
+		assert.EqualValues(t, entitystore.StatusINITIALIZED, version.Status)
+		published := (<-watcher).Entity.(*functions.FunctionVersion)
+		assert.NotEqual(t, function.FaasID, published.Function.FaasID)
+		published.Status = entitystore.StatusREADY
+		_, err = store.Update(context.Background(), published.Revision, published)
+		require.NoError(t, err)
+	}
+
+	var errBody v1.Error
+	helpers.HandlerRequest(t, setAlias(&v1.VersionWeight{Version: swag.Int64(3)}), &errBody, 400)
+	helpers.HandlerRequest(t, rollback(), &errBody, 404)
+
+	var respBody v1.Function
+	helpers.HandlerRequest(t, setAlias(&v1.VersionWeight{Version: swag.Int64(1)}), &respBody, 200)
+	helpers.HandlerRequest(t, setAlias(&v1.VersionWeight{Version: swag.Int64(2)}), &respBody, 200)
+	assert.EqualValues(t, 2, respBody.LatestVersion)
+	require.Len(t, respBody.Aliases, 1)
+	assert.EqualValues(t, 2, *respBody.Aliases[0].Routes[0].Version)
+	assert.EqualValues(t, 1, *respBody.Aliases[0].Previous[0].Version)
+
+	run := func() *functions.FnRun {
+		r := httptest.NewRequest("POST", fmt.Sprintf("/v1/runs?functionName=%s", testFuncName), nil)
+		params := fnrunner.RunFunctionParams{
+			HTTPRequest:  r,
+			Body:         &v1.Run{Alias: "prod"},
+			FunctionName: &testFuncName,
+			XDispatchOrg: testOrgID,
+		}
+		var respBody v1.Run
+		helpers.HandlerRequest(t, api.RunnerRunFunctionHandler.Handle(params, "testCookie"), &respBody, 202)
+		return (<-watcher).Entity.(*functions.FnRun)
+	}
+	fnRun := run()
+	assert.Equal(t, "prod", fnRun.Alias)
+	assert.Equal(t, 2, fnRun.FunctionVersion)
+	assert.NotEqual(t, function.FaasID, fnRun.FaasID)
+
+	helpers.HandlerRequest(t, rollback(), &respBody, 200)
+	assert.EqualValues(t, 1, *respBody.Aliases[0].Routes[0].Version)
+	assert.Equal(t, 1, run().FunctionVersion)
+
+	r := httptest.NewRequest("DELETE", "/v1/function/"+testFuncName+"/aliases/prod", nil)
+	params := fnstore.DeleteAliasParams{
+		HTTPRequest:  r,
+		AliasName:    "prod",
+		FunctionName: testFuncName,
+		XDispatchOrg: testOrgID,
+	}
+	helpers.HandlerRequest(t, api.StoreDeleteAliasHandler.Handle(params, "testCookie"), &respBody, 200)
+	assert.Empty(t, respBody.Aliases)
+	helpers.HandlerRequest(t, api.StoreDeleteAliasHandler.Handle(params, "testCookie"), &errBody, 404)
+}
+
+func Test_pickVersion(t *testing.T) {
+	routes := []*functions.VersionWeight{{Version: 1, Weight: 0}, {Version: 2, Weight: 1}}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, 2, pickVersion(routes))
+	}
+	routes[1].Weight = 0
+	picked := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		picked[pickVersion(routes)] = true
+	}
+	assert.Len(t, picked, 2)
+}
+
+func TestHandlers_deleteRuns(t *testing.T) {
+	store := helpers.MakeEntityStore(t)
+	handlers := &Handlers{
+		Store: store,
+	}
+
+	for _, run := range []*functions.FnRun{
+		{BaseEntity: entitystore.BaseEntity{Name: "finished", Status: entitystore.StatusREADY}, FunctionName: "testFunction"},
+		{BaseEntity: entitystore.BaseEntity{Name: "running", Status: entitystore.StatusCREATING}, FunctionName: "testFunction"},
+		{BaseEntity: entitystore.BaseEntity{Name: "other", Status: entitystore.StatusERROR}, FunctionName: "otherFunction"},
+	} {
+		run.OrganizationID = testOrgID
+		_, err := store.Add(context.Background(), run)
+		require.NoError(t, err)
+	}
+
+	api := operations.NewFunctionManagerAPI(nil)
+	handlers.ConfigureHandlers(api)
+
+	deleteRuns := func(functionName *string, olderThan time.Time) middleware.Responder {
+		r := httptest.NewRequest("DELETE", "/v1/runs", nil)
+		params := fnrunner.DeleteRunsParams{
+			HTTPRequest:  r,
+			FunctionName: functionName,
+			OlderThan:    olderThan.Unix(),
+			XDispatchOrg: testOrgID,
+		}
+		return api.RunnerDeleteRunsHandler.Handle(params, "testCookie")
+	}
+
+	var deleted int64
+	helpers.HandlerRequest(t, deleteRuns(nil, time.Now().Add(-time.Hour)), &deleted, 200)
+	assert.Equal(t, int64(0), deleted)
+
+	helpers.HandlerRequest(t, deleteRuns(swag.String("testFunction"), time.Now().Add(time.Hour)), &deleted, 200)
+	assert.Equal(t, int64(1), deleted)
+
+	var runs []*functions.FnRun
+	require.NoError(t, store.List(context.Background(), testOrgID, entitystore.Options{}, &runs))
+	assert.Len(t, runs, 2)
+
+	// runs are deleted by batches
+	for i := 0; i < runDeleteBatchSize; i++ {
+		run := &functions.FnRun{
+			BaseEntity:   entitystore.BaseEntity{Name: fmt.Sprintf("batched%d", i), OrganizationID: testOrgID, Status: entitystore.StatusREADY},
+			FunctionName: "otherFunction",
+		}
+		_, err := store.Add(context.Background(), run)
+		require.NoError(t, err)
+	}
+	helpers.HandlerRequest(t, deleteRuns(nil, time.Now().Add(time.Hour)), &deleted, 200)
+	assert.Equal(t, int64(runDeleteBatchSize+1), deleted)
+
+	runs = nil
+	require.NoError(t, store.List(context.Background(), testOrgID, entitystore.Options{}, &runs))
+	require.Len(t, runs, 1)
+	assert.Equal(t, "running", runs[0].Name)
+}
+
 func TestHandlers_getRuns(t *testing.T) {
 	store := helpers.MakeEntityStore(t)
 	handlers := &Handlers{
@@ -345,3 +645,51 @@ func Test_runModelToEntitySecret(t *testing.T) {
 	fnRun := runModelToEntity(&runModel, &f)
 	assert.Equal(t, secrets, fnRun.Secrets)
 }
+
+func Test_functionModelOntoEntityLimits(t *testing.T) {
+	m := &v1.Function{
+		Name:    swag.String("testFunction"),
+		Image:   swag.String("testImage"),
+		Limits:  &v1.FunctionResources{Memory: "128Mi", CPU: "500m", Pids: 64},
+		Sandbox: &v1.FunctionSandbox{ReadOnlyRootFS: true, DropCapabilities: []string{"ALL"}, SeccompProfile: "unconfined"},
+	}
+	var e functions.Function
+	require.NoError(t, functionModelOntoEntity(m, "", &e))
+	assert.Equal(t, &functions.FunctionResources{Memory: "128Mi", CPU: "500m", Pids: 64}, e.Limits)
+	assert.Equal(t, &functions.FunctionSandbox{ReadOnlyRootFS: true, DropCapabilities: []string{"ALL"}, SeccompProfile: "unconfined"}, e.Sandbox)
+
+	f := functionEntityToModel(&e)
+	assert.Equal(t, m.Limits, f.Limits)
+	assert.Equal(t, m.Sandbox, f.Sandbox)
+
+	m.Limits.Memory = "a lot"
+	assert.Error(t, functionModelOntoEntity(m, "", &e))
+	m.Limits.Memory = "128Mi"
+	m.Sandbox.SeccompProfile = "{"
+	assert.Error(t, functionModelOntoEntity(m, "", &e))
+}
+
+func Test_runModelToEntityRetryPolicy(t *testing.T) {
+	f := &functions.Function{
+		RetryPolicy: &functions.RetryPolicy{MaxAttempts: 2},
+	}
+	fnRun := runModelToEntity(&v1.Run{}, f)
+	assert.Equal(t, f.RetryPolicy, fnRun.RetryPolicy)
+
+	m := &v1.Run{
+		RetryPolicy: &v1.RetryPolicy{
+			MaxAttempts: 5,
+			Backoff:     100,
+			MaxBackoff:  1000,
+			RetryOn:     []v1.ErrorType{v1.ErrorTypeSystemError},
+		},
+	}
+	fnRun = runModelToEntity(m, f)
+	assert.Equal(t, &functions.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		RetryOn:     []string{string(v1.ErrorTypeSystemError)},
+	}, fnRun.RetryPolicy)
+	assert.Equal(t, m.RetryPolicy, runEntityToModel(fnRun).RetryPolicy)
+}
@@ -0,0 +1,300 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package functionmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/functions"
+	"github.com/vmware/dispatch/pkg/trace"
+	"github.com/vmware/dispatch/pkg/utils"
+)
+
+// LifecycleConfig is the configuration of the lifecycle of function instances
+type LifecycleConfig struct {
+	// IdleTTL is how long a function stays idle before its instances are stopped, 0 means they are never stopped
+	IdleTTL time.Duration
+}
+
+// instance is the lifecycle state of the instances of a function
+type instance struct {
+	f *functions.Function
+	// scaleMu serializes the scaling of the function
+	scaleMu sync.Mutex
+
+	// the fields below are guarded by LifecycleManager.mu
+	inflight int
+	lastUsed time.Time
+	// cold is true if the instances of the function are stopped, or their state is unknown
+	cold bool
+}
+
+// LifecycleManager is a FaaS driver which wraps another one to manage the lifecycle of function instances. Functions
+// keep PreWarm instances running, and the other instances are stopped once functions are idle for IdleTTL. Stopped
+// functions are cold-started on their next run, and the cold start latency is recorded in the function context.
+// Only drivers implementing functions.Scaler are managed.
+type LifecycleManager struct {
+	LifecycleConfig
+
+	faas   functions.FaaSDriver
+	scaler functions.Scaler
+	store  entitystore.EntityStore
+
+	mu        sync.Mutex
+	instances map[string]*instance
+	done      chan struct{}
+}
+
+// NewLifecycleManager creates a lifecycle manager for the functions of a FaaS driver
+func NewLifecycleManager(config LifecycleConfig, faas functions.FaaSDriver, store entitystore.EntityStore) *LifecycleManager {
+	m := &LifecycleManager{
+		LifecycleConfig: config,
+		faas:            faas,
+		store:           store,
+		instances:       make(map[string]*instance),
+		done:            make(chan struct{}),
+	}
+	m.scaler, _ = faas.(functions.Scaler)
+	if m.scaler == nil && m.IdleTTL != 0 {
+		log.Warnf("The FaaS driver cannot stop idle functions, ignoring the idle TTL")
+	}
+	if m.scaler != nil && m.IdleTTL != 0 {
+		go m.stopIdleLoop()
+	}
+	return m
+}
+
+// Create creates the function in the FaaS driver, and starts its PreWarm instances
+func (m *LifecycleManager) Create(ctx context.Context, f *functions.Function) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if err := m.faas.Create(ctx, f); err != nil {
+		return err
+	}
+	if m.scaler == nil {
+		if f.PreWarm > 0 {
+			log.Warnf("The FaaS driver cannot pre-warm functions, ignoring the pre-warmed instances of function %s", f.Name)
+		}
+		return nil
+	}
+	if f.PreWarm > 0 {
+		if err := m.scaler.Scale(ctx, f, f.PreWarm); err != nil {
+			return errors.Wrapf(err, "error pre-warming function %s", f.Name)
+		}
+	}
+
+	fn := *f
+	m.mu.Lock()
+	m.instances[f.ID] = &instance{f: &fn, lastUsed: time.Now()}
+	m.mu.Unlock()
+	return nil
+}
+
+// Delete deletes the function in the FaaS driver
+func (m *LifecycleManager) Delete(ctx context.Context, f *functions.Function) error {
+	m.mu.Lock()
+	delete(m.instances, f.ID)
+	m.mu.Unlock()
+
+	return m.faas.Delete(ctx, f)
+}
+
+// GetRunnable returns the runnable of the FaaS driver, which cold-starts the function if needed
+func (m *LifecycleManager) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
+	run := m.faas.GetRunnable(e)
+	if m.scaler == nil || m.IdleTTL == 0 {
+		return run
+	}
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		i, coldStart, err := m.acquire(ctx, e)
+		if err != nil {
+			return nil, &systemError{errors.Wrapf(err, "error starting function %s", e.FunctionID)}
+		}
+		defer m.release(i)
+
+		out, err := run(ctx, fctx, in)
+		if coldStart != 0 {
+			fctx.SetColdStart(coldStart)
+		}
+		return out, err
+	}
+}
+
+// acquire marks the function as in use, starting its instances if it is cold. It returns the time spent starting
+// them.
+func (m *LifecycleManager) acquire(ctx context.Context, e *functions.FunctionExecution) (*instance, time.Duration, error) {
+	m.mu.Lock()
+	i, ok := m.instances[e.FunctionID]
+	if ok && !i.cold && i.f.FaasID == e.FaasID {
+		i.inflight++
+		i.lastUsed = time.Now()
+		m.mu.Unlock()
+		return i, 0, nil
+	}
+	m.mu.Unlock()
+
+	if !ok || i.f.FaasID != e.FaasID {
+		// the state of the function instances is unknown, e.g. after a restart
+		f, err := m.getFunction(ctx, e)
+		if err != nil {
+			return nil, 0, err
+		}
+		m.mu.Lock()
+		if i, ok = m.instances[e.FunctionID]; !ok || i.f.FaasID != f.FaasID {
+			i = &instance{f: f, cold: true}
+			m.instances[e.FunctionID] = i
+		}
+		m.mu.Unlock()
+	}
+
+	i.scaleMu.Lock()
+	defer i.scaleMu.Unlock()
+
+	m.mu.Lock()
+	cold := i.cold
+	m.mu.Unlock()
+
+	var coldStart time.Duration
+	if cold {
+		replicas := i.f.PreWarm
+		if replicas == 0 {
+			replicas = 1
+		}
+		log.Debugf("Cold-starting function %s", i.f.ID)
+		start := time.Now()
+		if err := m.scaler.Scale(ctx, i.f, replicas); err != nil {
+			return nil, 0, err
+		}
+		coldStart = time.Since(start)
+	}
+
+	m.mu.Lock()
+	i.cold = false
+	i.inflight++
+	i.lastUsed = time.Now()
+	m.mu.Unlock()
+	return i, coldStart, nil
+}
+
+// release marks the end of a run of the function
+func (m *LifecycleManager) release(i *instance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i.inflight--
+	i.lastUsed = time.Now()
+}
+
+// getFunction gets the function of an execution from the store
+func (m *LifecycleManager) getFunction(ctx context.Context, e *functions.FunctionExecution) (*functions.Function, error) {
+	var fns []*functions.Function
+	opts := entitystore.Options{
+		Filter: entitystore.FilterEverything().Add(entitystore.FilterStat{
+			Scope:   entitystore.FilterScopeField,
+			Subject: "ID",
+			Verb:    entitystore.FilterVerbEqual,
+			Object:  e.FunctionID,
+		}),
+	}
+	if err := m.store.List(ctx, e.OrganizationID, opts, &fns); err != nil {
+		return nil, errors.Wrapf(err, "store error getting function %s", e.FunctionID)
+	}
+	if len(fns) > 0 {
+		return fns[0], nil
+	}
+	// published versions are deployed as functions with the ID of the version
+	var versions []*functions.FunctionVersion
+	if err := m.store.List(ctx, e.OrganizationID, opts, &versions); err != nil {
+		return nil, errors.Wrapf(err, "store error getting function version %s", e.FunctionID)
+	}
+	if len(versions) == 0 {
+		return nil, errors.Errorf("function %s not found", e.FunctionID)
+	}
+	return versions[0].Deployment(), nil
+}
+
+// stopIdleLoop stops idle functions until the lifecycle manager is closed
+func (m *LifecycleManager) stopIdleLoop() {
+	ticker := time.NewTicker(m.IdleTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.stopIdle(context.Background(), now)
+		}
+	}
+}
+
+// stopIdle stops the instances of the functions which are idle for IdleTTL, and have no pre-warmed instances
+func (m *LifecycleManager) stopIdle(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	var idle []*instance
+	for _, i := range m.instances {
+		if !i.cold && i.inflight == 0 && i.f.PreWarm == 0 && now.Sub(i.lastUsed) >= m.IdleTTL {
+			i.cold = true
+			idle = append(idle, i)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, i := range idle {
+		m.stop(ctx, i)
+	}
+}
+
+// stop stops the instances of a function marked as cold
+func (m *LifecycleManager) stop(ctx context.Context, i *instance) {
+	i.scaleMu.Lock()
+	defer i.scaleMu.Unlock()
+
+	m.mu.Lock()
+	// the function may have been cold-started meanwhile
+	stop := i.cold && i.inflight == 0
+	m.mu.Unlock()
+	if !stop {
+		return
+	}
+
+	log.Debugf("Stopping idle function %s", i.f.ID)
+	if err := m.scaler.Scale(ctx, i.f, 0); err != nil {
+		log.Errorf("Error stopping idle function %s: %+v", i.f.ID, err)
+	}
+}
+
+// Close stops the lifecycle manager and closes the FaaS driver
+func (m *LifecycleManager) Close() error {
+	close(m.done)
+	utils.Close(m.faas)
+	return nil
+}
+
+type systemError struct {
+	Err error `json:"err"`
+}
+
+func (err *systemError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *systemError) AsSystemErrorObject() interface{} {
+	return err
+}
+
+func (err *systemError) StackTrace() errors.StackTrace {
+	if e, ok := err.Err.(functions.StackTracer); ok {
+		return e.StackTrace()
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package functionmanager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/functions"
+	helpers "github.com/vmware/dispatch/pkg/testing/api"
+)
+
+// scalingDriver is a FaaS driver recording the number of instances of each function
+type scalingDriver struct {
+	mu       sync.Mutex
+	replicas map[string]int
+	scaled   int
+}
+
+func (d *scalingDriver) Create(ctx context.Context, f *functions.Function) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.replicas[f.ID] = 1
+	return nil
+}
+
+func (d *scalingDriver) Delete(ctx context.Context, f *functions.Function) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.replicas, f.ID)
+	return nil
+}
+
+func (d *scalingDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.replicas[e.FunctionID], nil
+	}
+}
+
+func (d *scalingDriver) Scale(ctx context.Context, f *functions.Function, replicas int) error {
+	time.Sleep(time.Millisecond)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.replicas[f.ID] = replicas
+	d.scaled++
+	return nil
+}
+
+func TestLifecycleManager(t *testing.T) {
+	driver := &scalingDriver{replicas: map[string]int{}}
+	m := NewLifecycleManager(LifecycleConfig{IdleTTL: time.Hour}, driver, helpers.MakeEntityStore(t))
+	defer m.Close()
+
+	f := &functions.Function{BaseEntity: entitystore.BaseEntity{ID: "f1", Name: "f1"}, FaasID: "r1"}
+	prewarmed := &functions.Function{BaseEntity: entitystore.BaseEntity{ID: "f2", Name: "f2"}, FaasID: "r1", PreWarm: 2}
+	require.NoError(t, m.Create(context.Background(), f))
+	require.NoError(t, m.Create(context.Background(), prewarmed))
+	assert.Equal(t, 2, driver.replicas[prewarmed.ID])
+
+	run := m.GetRunnable(&functions.FunctionExecution{FunctionID: f.ID, FaasID: f.FaasID})
+	fctx := functions.Context{}
+	out, err := run(context.Background(), fctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, out)
+	assert.Equal(t, time.Duration(0), fctx.ColdStart())
+
+	// only the function without pre-warmed instances is stopped
+	m.stopIdle(context.Background(), time.Now().Add(time.Hour))
+	assert.Equal(t, 0, driver.replicas[f.ID])
+	assert.Equal(t, 2, driver.replicas[prewarmed.ID])
+	assert.Equal(t, 2, driver.scaled)
+
+	fctx = functions.Context{}
+	out, err = run(context.Background(), fctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, out)
+	assert.NotEqual(t, time.Duration(0), fctx.ColdStart())
+}
+
+func TestLifecycleManagerUnknownFunction(t *testing.T) {
+	store := helpers.MakeEntityStore(t)
+	driver := &scalingDriver{replicas: map[string]int{}}
+	m := NewLifecycleManager(LifecycleConfig{IdleTTL: time.Hour}, driver, store)
+	defer m.Close()
+
+	f := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{OrganizationID: testOrgID, Name: "f1"},
+		FaasID:     "r1",
+		PreWarm:    3,
+	}
+	_, err := store.Add(context.Background(), f)
+	require.NoError(t, err)
+
+	// the function instances are started, as their state is unknown
+	run := m.GetRunnable(&functions.FunctionExecution{OrganizationID: testOrgID, FunctionID: f.ID, FaasID: f.FaasID})
+	fctx := functions.Context{}
+	out, err := run(context.Background(), fctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, out)
+	assert.NotEqual(t, time.Duration(0), fctx.ColdStart())
+
+	run = m.GetRunnable(&functions.FunctionExecution{OrganizationID: testOrgID, FunctionID: "missing"})
+	_, err = run(context.Background(), functions.Context{}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package functionmanager
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/go-openapi/swag"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/functions"
+)
+
+// LogStreams holds the log lines of the runs being executed, so they can be followed while the runs execute
+type LogStreams struct {
+	mu   sync.Mutex
+	runs map[string]*runLogs
+}
+
+// NewLogStreams is the constructor for LogStreams
+func NewLogStreams() *LogStreams {
+	return &LogStreams{runs: make(map[string]*runLogs)}
+}
+
+func logStreamKey(organizationID, runName string) string {
+	return organizationID + "/" + runName
+}
+
+// open starts collecting the log lines of a run
+func (s *LogStreams) open(run *functions.FnRun) *runLogs {
+	l := &runLogs{changed: make(chan struct{})}
+	s.mu.Lock()
+	s.runs[logStreamKey(run.OrganizationID, run.Name)] = l
+	s.mu.Unlock()
+	return l
+}
+
+// close marks the logs of a run as complete and stops tracking them
+func (s *LogStreams) close(run *functions.FnRun) {
+	key := logStreamKey(run.OrganizationID, run.Name)
+	s.mu.Lock()
+	l := s.runs[key]
+	delete(s.runs, key)
+	s.mu.Unlock()
+	if l != nil {
+		l.finish()
+	}
+}
+
+// get returns the logs of a run being executed, or nil if the run is not executed by this instance
+func (s *LogStreams) get(organizationID, runName string) *runLogs {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[logStreamKey(organizationID, runName)]
+}
+
+// runLogs are the log lines written by a run so far
+type runLogs struct {
+	mu      sync.Mutex
+	lines   []*v1.LogLine
+	done    bool
+	changed chan struct{}
+}
+
+// write records a line written by the run, it implements functions.LogSink
+func (l *runLogs) write(stream, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return
+	}
+	l.lines = append(l.lines, &v1.LogLine{Stream: swag.String(stream), Line: swag.String(line)})
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *runLogs) finish() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return
+	}
+	l.done = true
+	close(l.changed)
+}
+
+// snapshot returns the lines written so far
+func (l *runLogs) snapshot() []*v1.LogLine {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lines
+}
+
+// next waits for the lines written after offset, it returns true once the run finished and all its lines were returned
+func (l *runLogs) next(ctx context.Context, offset int) ([]*v1.LogLine, bool) {
+	for {
+		l.mu.Lock()
+		lines, done, changed := l.lines[offset:], l.done, l.changed
+		l.mu.Unlock()
+		if len(lines) > 0 || done {
+			return lines, done
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, true
+		}
+	}
+}
+
+// logLineWriter writes log lines as a sequence of JSON objects, one per line, flushing them to the client
+type logLineWriter struct {
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+func newLogLineWriter(w io.Writer) *logLineWriter {
+	flusher, _ := w.(http.Flusher)
+	return &logLineWriter{encoder: json.NewEncoder(w), flusher: flusher}
+}
+
+func (w *logLineWriter) write(lines ...*v1.LogLine) error {
+	for _, line := range lines {
+		if err := w.encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return nil
+}
+
+// writeStored writes the logs recorded in a finished run
+func (w *logLineWriter) writeStored(logs *v1.Logs) error {
+	if logs == nil {
+		return nil
+	}
+	var lines []*v1.LogLine
+	for _, line := range logs.Stdout {
+		lines = append(lines, &v1.LogLine{Stream: swag.String(v1.LogLineStreamStdout), Line: swag.String(line)})
+	}
+	for _, line := range logs.Stderr {
+		lines = append(lines, &v1.LogLine{Stream: swag.String(v1.LogLineStreamStderr), Line: swag.String(line)})
+	}
+	return w.write(lines...)
+}
@@ -0,0 +1,57 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package functionmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/functions"
+)
+
+func TestLogStreams(t *testing.T) {
+	streams := NewLogStreams()
+	run := &functions.FnRun{BaseEntity: entitystore.BaseEntity{OrganizationID: testOrgID, Name: "run1"}}
+	assert.Nil(t, streams.get(testOrgID, run.Name))
+
+	logs := streams.open(run)
+	assert.Equal(t, logs, streams.get(testOrgID, run.Name))
+	assert.Nil(t, streams.get("otherOrg", run.Name))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		logs.write("stdout", "first")
+	}()
+	lines, done := logs.next(context.Background(), 0)
+	assert.False(t, done)
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, "first", swag.StringValue(lines[0].Line))
+		assert.Equal(t, "stdout", swag.StringValue(lines[0].Stream))
+	}
+
+	logs.write("stderr", "second")
+	streams.close(run)
+	logs.write("stdout", "ignored")
+	assert.Nil(t, streams.get(testOrgID, run.Name))
+
+	lines, done = logs.next(context.Background(), 1)
+	assert.True(t, done)
+	assert.Len(t, lines, 1)
+	lines, done = logs.next(context.Background(), 2)
+	assert.True(t, done)
+	assert.Empty(t, lines)
+
+	// following stops when the request is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, done = streams.open(run).next(ctx, 0)
+	assert.True(t, done)
+}
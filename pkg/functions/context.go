@@ -7,7 +7,10 @@ package functions
 
 import (
 	"bufio"
+	"context"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	log "github.com/sirupsen/logrus"
@@ -22,6 +25,7 @@ const (
 	ErrorKey       = "error"
 	HTTPContextKey = "httpContext"
 	DeadlineKey    = "deadline"
+	ColdStartKey   = "coldStart"
 )
 
 // Logs returns the logs as a list of strings
@@ -66,10 +70,36 @@ func (ctx Context) Logs() v1.Logs {
 
 // ReadLogs reads the logs into the context
 func (ctx Context) ReadLogs(stderrReader io.Reader, stdoutReader io.Reader) {
-	ctx[LogsKey] = v1.Logs{
-		Stderr: readLogs(stderrReader),
-		Stdout: readLogs(stdoutReader),
+	ctx.FollowLogs(context.Background(), stderrReader, stdoutReader)
+}
+
+// FollowLogs reads the logs into the context until both readers are closed, sending each line to the log sink of c
+// as soon as it is read
+func (ctx Context) FollowLogs(c context.Context, stderrReader io.Reader, stdoutReader io.Reader) {
+	var logs v1.Logs
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		logs.Stderr = readLogs(stderrReader, func(line string) { StreamLog(c, v1.LogLineStreamStderr, line) })
+	}()
+	go func() {
+		defer wg.Done()
+		logs.Stdout = readLogs(stdoutReader, func(line string) { StreamLog(c, v1.LogLineStreamStdout, line) })
+	}()
+	wg.Wait()
+	ctx[LogsKey] = logs
+}
+
+// StreamLogs adds the logs into the context, and sends them to the log sink of c
+func (ctx Context) StreamLogs(c context.Context, logs v1.Logs) {
+	for _, line := range logs.Stdout {
+		StreamLog(c, v1.LogLineStreamStdout, line)
+	}
+	for _, line := range logs.Stderr {
+		StreamLog(c, v1.LogLineStreamStderr, line)
 	}
+	ctx.AddLogs(logs)
 }
 
 // AddLogs adds the logs into the context
@@ -81,11 +111,12 @@ func (ctx Context) AddLogs(logs v1.Logs) {
 	ctx[LogsKey] = l
 }
 
-func readLogs(reader io.Reader) []string {
+func readLogs(reader io.Reader, onLine func(line string)) []string {
 	scanner := bufio.NewScanner(reader)
 	var logs []string
 	for scanner.Scan() {
 		logs = append(logs, scanner.Text())
+		onLine(scanner.Text())
 	}
 	return logs
 }
@@ -105,6 +136,17 @@ func (ctx Context) GetError() *v1.InvocationError {
 	return nil
 }
 
+// ColdStart returns the time spent starting instances of the function before it was run, if any
+func (ctx Context) ColdStart() time.Duration {
+	d, _ := ctx[ColdStartKey].(time.Duration)
+	return d
+}
+
+// SetColdStart sets the time spent starting instances of the function before it was run
+func (ctx Context) SetColdStart(d time.Duration) {
+	ctx[ColdStartKey] = d
+}
+
 // SetError sets the error into the context
 func (ctx Context) SetError(invocationError *v1.InvocationError) {
 
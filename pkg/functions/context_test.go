@@ -6,7 +6,11 @@ package functions
 
 import (
 	"bytes"
+	"context"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vmware/dispatch/pkg/api/v1"
@@ -22,6 +26,39 @@ func TestContext_Logs(t *testing.T) {
 	assert.Equal(t, logs, ctx.Logs())
 }
 
+func TestContext_FollowLogs(t *testing.T) {
+	var mu sync.Mutex
+	var streamed []string
+	c := WithLogSink(context.Background(), func(stream, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed = append(streamed, stream+": "+line)
+	})
+	ctx := Context{}
+	ctx.FollowLogs(c, bytes.NewReader([]byte("foo\n")), bytes.NewReader([]byte("bar\n")))
+	assert.Equal(t, v1.Logs{Stderr: []string{"foo"}, Stdout: []string{"bar"}}, ctx.Logs())
+	sort.Strings(streamed)
+	assert.Equal(t, []string{"stderr: foo", "stdout: bar"}, streamed)
+}
+
+func TestReadFrames(t *testing.T) {
+	var streamed []string
+	c := WithLogSink(context.Background(), func(stream, line string) {
+		streamed = append(streamed, stream+": "+line)
+	})
+	ctx := Context{}
+	frames := `{"context":{"logs":{"stdout":["foo"]}}}
+{"context":{"logs":{"stdout":["bar"]},"error":null},"payload":{"out":1}}`
+	out, err := ReadFrames(c, ctx, bytes.NewReader([]byte(frames)))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"out": float64(1)}, out.Payload)
+	assert.Equal(t, v1.Logs{Stdout: []string{"foo", "bar"}}, ctx.Logs())
+	assert.Equal(t, []string{"stdout: foo", "stdout: bar"}, streamed)
+
+	_, err = ReadFrames(c, ctx, bytes.NewReader(nil))
+	assert.Error(t, err)
+}
+
 func TestContext_LogsBS(t *testing.T) {
 	ctx := Context{}
 	ctx["logs"] = &struct {
@@ -111,3 +148,10 @@ func TestContext_GetErrorNil(t *testing.T) {
 	ctx := Context{}
 	assert.Nil(t, ctx.GetError())
 }
+
+func TestContext_ColdStart(t *testing.T) {
+	ctx := Context{}
+	assert.Equal(t, time.Duration(0), ctx.ColdStart())
+	ctx.SetColdStart(2 * time.Second)
+	assert.Equal(t, 2*time.Second, ctx.ColdStart())
+}
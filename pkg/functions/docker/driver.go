@@ -8,10 +8,13 @@ package docker
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,8 +24,12 @@ import (
 	dockerclient "github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
 
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/entity-store"
 	"github.com/vmware/dispatch/pkg/functions"
 	"github.com/vmware/dispatch/pkg/trace"
 	"github.com/vmware/dispatch/pkg/utils"
@@ -34,8 +41,21 @@ const (
 	healthcheckEndpoint   = "/healthz"
 	labelFunctionID       = "dispatch-function-id"
 	labelFunctionRevision = "dispatch-function-revision"
-	defaultBackoff        = time.Second * 60
-	defaultHost           = "127.0.0.1"
+	labelFunctionName     = "dispatch-function-name"
+	// labelFunctionLimits and labelFunctionSandbox hold the JSON limits and sandbox of the function, restored with
+	// its pool after a restart
+	labelFunctionLimits  = "dispatch-function-limits"
+	labelFunctionSandbox = "dispatch-function-sandbox"
+	// internalNetwork is the network of the containers of functions without network access. It is internal, so that
+	// the containers can only be reached by Dispatch, on their address on the network.
+	internalNetwork = "dispatch-functions-internal"
+	defaultBackoff  = time.Second * 60
+	defaultHost     = "127.0.0.1"
+
+	defaultMinReplicas          = 1
+	defaultMaxReplicas          = 4
+	defaultContainerConcurrency = 10
+	defaultScaleDownCooldown    = 5 * time.Minute
 )
 
 // dockerContainer represents a basic information about function container
@@ -47,95 +67,203 @@ type dockerContainer struct {
 	FunctionRevision string
 	Port             string
 	Host             string
+	// Internal is true if the container has no network access, and is reachable on Host instead of ExternalHost
+	Internal bool
 }
 
 // Client specifies the Docker client API interface required by docker driver
 type Client interface {
 	dockerclient.ContainerAPIClient
 	dockerclient.ImageAPIClient
+	dockerclient.NetworkAPIClient
 }
 
-// Driver implements a FaaSDriver using Docker daemon. It's a simple driver without scaling or fault tolerance
-// and is not recommended for production usage. It's goal is to provide a simple driver for demos, PoCs, and development
-// use cases.
+// Driver implements a FaaSDriver using Docker daemon. It runs a pool of containers per function, scaled with the
+// number of concurrent requests, but without fault tolerance, and is not recommended for production usage. It's goal
+// is to provide a simple driver for demos, PoCs, and development use cases.
 type Driver struct {
 	// ExternalHost is a ip/hostname that function containers will be exposed with, and that is reachable to Dispatch.
 	ExternalHost string
 	// RetryTimeout specifies the maximum amount of time we should spend retrying calls to docker.
 	RetryTimeout time.Duration
+	// MinReplicas is the number of containers started when a function is created, and kept when it is idle.
+	MinReplicas int
+	// MaxReplicas is the maximum number of containers of a function.
+	MaxReplicas int
+	// ContainerConcurrency is the maximum number of concurrent requests sent to a container, 0 means no limit.
+	// A container is started when all containers of a function serve that many requests.
+	ContainerConcurrency int
+	// ScaleDownCooldown is how long a container above MinReplicas stays idle before it is removed.
+	ScaleDownCooldown time.Duration
+
+	docker Client
+
+	mu    sync.Mutex
+	pools map[string]*pool
+	// loads are the pools being rebuilt from the running containers, by function ID and revision
+	loads map[string]*poolLoad
+}
 
-	docker         Client
-	containerCache *sync.Map
+// poolLoad is the rebuilding of a pool, shared by the requests of the function which need it meanwhile
+type poolLoad struct {
+	done chan struct{}
+	pool *pool
+	err  error
 }
 
 // New creates a new Docker driver
 func New(dockerClient Client) *Driver {
 
 	d := &Driver{
-		docker:         dockerClient,
-		ExternalHost:   defaultHost,
-		RetryTimeout:   defaultBackoff,
-		containerCache: new(sync.Map),
+		docker:               dockerClient,
+		ExternalHost:         defaultHost,
+		RetryTimeout:         defaultBackoff,
+		MinReplicas:          defaultMinReplicas,
+		MaxReplicas:          defaultMaxReplicas,
+		ContainerConcurrency: defaultContainerConcurrency,
+		ScaleDownCooldown:    defaultScaleDownCooldown,
+		pools:                make(map[string]*pool),
+		loads:                make(map[string]*poolLoad),
 	}
 
 	return d
 }
 
-// Create creates new Docker container for a particular function. Currently, there is 1:1 mapping for a function.
+// Create starts the MinReplicas containers of a function, replacing the containers of its previous revision.
 func (d *Driver) Create(ctx context.Context, f *functions.Function) error {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
 
-	containerName := getID(f.Name, f.FaasID)
+	p := newPool(d, f)
+	var wg sync.WaitGroup
+	errs := make([]error, d.MinReplicas)
+	for i := 0; i < d.MinReplicas; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := d.startContainer(ctx, f)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			p.add(c)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			d.removeContainers(p.close()...)
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	old := d.pools[f.ID]
+	d.pools[f.ID] = p
+	d.mu.Unlock()
+	if old != nil {
+		ids := old.close()
+		// containers of other revisions are removed below
+		if old.f.FaasID == f.FaasID {
+			go d.removeContainers(ids...)
+		}
+	}
+
+	// clear any containers that could have been created before (e.g. before update)
+	go d.deleteContainers(ctx, f, false)
+
+	return nil
+}
+
+// Scale sets the number of containers of a function kept when it is idle, in place of MinReplicas. Idle containers
+// above it are removed, and missing containers are started, e.g. to cold-start a function scaled to zero.
+func (d *Driver) Scale(ctx context.Context, f *functions.Function, replicas int) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	p, err := d.getPool(ctx, f.ID, f.FaasID)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		// no container is running, e.g. after a restart
+		d.mu.Lock()
+		if p = d.pools[f.ID]; p == nil {
+			p = newPool(d, f)
+			d.pools[f.ID] = p
+		}
+		d.mu.Unlock()
+	}
+	return p.scale(replicas)
+}
+
+// startContainer creates a container for a function and waits for it to be healthy
+func (d *Driver) startContainer(ctx context.Context, f *functions.Function) (*dockerContainer, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	containerName := getID(f.Name, f.FaasID) + "-" + uuid.NewV4().String()[:8]
+
+	hostConfig, err := d.hostConfig(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{
+		labelFunctionID:       f.ID,
+		labelFunctionRevision: f.FaasID,
+		labelFunctionName:     f.Name,
+	}
+	if f.Limits != nil {
+		limits, _ := json.Marshal(f.Limits)
+		labels[labelFunctionLimits] = string(limits)
+	}
+	if f.Sandbox != nil {
+		sandbox, _ := json.Marshal(f.Sandbox)
+		labels[labelFunctionSandbox] = string(sandbox)
+	}
 
 	resp, err := d.docker.ContainerCreate(ctx, &container.Config{
 		Image:        f.FunctionImageURL,
 		ExposedPorts: nat.PortSet{functionAPIPort: {}},
-		Labels: map[string]string{
-			labelFunctionID:       f.ID,
-			labelFunctionRevision: f.FaasID,
-		},
-	}, &container.HostConfig{
-		NetworkMode:  "bridge",
-		PortBindings: nat.PortMap{functionAPIPort: []nat.PortBinding{{HostPort: "0"}}},
-	}, nil, containerName)
+		Labels:       labels,
+	}, hostConfig, nil, containerName)
 	if err != nil {
-		return errors.Wrapf(err, "error creating container %s", containerName)
+		return nil, errors.Wrapf(err, "error creating container %s", containerName)
 	}
 
 	containerID := resp.ID
 
 	if err := d.docker.ContainerStart(ctx, containerID, types.ContainerStartOptions{}); err != nil {
-		return errors.Wrapf(err, "error starting container %s with ID %s", containerName, containerID)
+		d.removeContainers(containerID)
+		return nil, errors.Wrapf(err, "error starting container %s with ID %s", containerName, containerID)
 	}
 
 	// We bind to port 0, we need to extract the actual port assigned to us.
 	cDetails, err := d.docker.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return errors.Wrapf(err, "error when inspecting container %s with ID %s", containerName, containerID)
+		d.removeContainers(containerID)
+		return nil, errors.Wrapf(err, "error when inspecting container %s with ID %s", containerName, containerID)
 	}
-	binding, ok := cDetails.NetworkSettings.Ports[functionAPIPort]
-	if !ok || len(binding) < 1 {
-		return errors.Errorf("No port assigned to function container, docker error or no more ports available")
+	host, port, internal, err := containerAddress(cDetails)
+	if err != nil {
+		d.removeContainers(containerID)
+		return nil, err
 	}
 
-	c := dockerContainer{
+	c := &dockerContainer{
 		ID:               cDetails.ID,
 		ImageID:          cDetails.Image,
 		ImageName:        f.FunctionImageURL,
 		FunctionID:       f.ID,
 		FunctionRevision: f.FaasID,
-		Port:             binding[0].HostPort,
-		Host:             binding[0].HostIP,
+		Port:             port,
+		Host:             host,
+		Internal:         internal,
 	}
 
-	d.containerCache.Store(f.ID, c)
-
-	// clear any containers that could have been created before (e.g. before update)
-	go d.deleteContainers(ctx, f, false)
-
 	// make sure the function has started
-	return utils.Backoff(d.RetryTimeout, func() error {
+	err = utils.Backoff(d.RetryTimeout, func() error {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
 		cDetails, err := d.docker.ContainerInspect(ctx, containerID)
@@ -146,10 +274,11 @@ func (d *Driver) Create(ctx context.Context, f *functions.Function) error {
 			return errors.Errorf("container %s for function %s not running", containerID, f.Name)
 		}
 
-		resp, err := http.Get("http://" + d.ExternalHost + ":" + c.Port + healthcheckEndpoint)
+		resp, err := http.Get(d.containerURL(c) + healthcheckEndpoint)
 		if err != nil {
 			return errors.Wrapf(err, "error when checking health for function %s container %s", f.Name, containerID)
 		}
+		resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			return errors.Errorf("incorrect status code %d when checking health for function %s container %s", resp.StatusCode, f.Name, containerID)
@@ -157,12 +286,148 @@ func (d *Driver) Create(ctx context.Context, f *functions.Function) error {
 
 		return nil
 	})
+	if err != nil {
+		d.removeContainers(containerID)
+		return nil, err
+	}
+	return c, nil
+}
+
+// hostConfig returns the configuration of the containers of a function, which applies its limits and sandbox
+func (d *Driver) hostConfig(ctx context.Context, f *functions.Function) (*container.HostConfig, error) {
+	hostConfig := &container.HostConfig{
+		NetworkMode:  "bridge",
+		PortBindings: nat.PortMap{functionAPIPort: []nat.PortBinding{{HostPort: "0"}}},
+	}
+
+	if limits := f.Limits; limits != nil {
+		if limits.Memory != "" {
+			memory, err := resource.ParseQuantity(limits.Memory)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid memory limit %s for function %s", limits.Memory, f.Name)
+			}
+			hostConfig.Memory = memory.Value()
+			// no swap, the function is killed once it exceeds its memory limit
+			hostConfig.MemorySwap = memory.Value()
+		}
+		if limits.CPU != "" {
+			cpu, err := resource.ParseQuantity(limits.CPU)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid CPU limit %s for function %s", limits.CPU, f.Name)
+			}
+			hostConfig.NanoCPUs = cpu.MilliValue() * 1000000
+		}
+		hostConfig.PidsLimit = limits.Pids
+	}
+
+	if sandbox := f.Sandbox; sandbox != nil {
+		if sandbox.ReadOnlyRootFS {
+			hostConfig.ReadonlyRootfs = true
+			// function runtimes and libraries need a writable temporary directory
+			hostConfig.Tmpfs = map[string]string{"/tmp": ""}
+		}
+		hostConfig.CapDrop = sandbox.DropCapabilities
+		if sandbox.SeccompProfile != "" {
+			hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+sandbox.SeccompProfile)
+		}
+		if sandbox.NoNetwork {
+			if err := d.ensureInternalNetwork(ctx); err != nil {
+				return nil, err
+			}
+			hostConfig.NetworkMode = internalNetwork
+			hostConfig.PortBindings = nil
+		}
+	}
+
+	return hostConfig, nil
+}
+
+// ensureInternalNetwork creates the internal network unless it exists
+func (d *Driver) ensureInternalNetwork(ctx context.Context) error {
+	_, err := d.docker.NetworkInspect(ctx, internalNetwork)
+	if err == nil {
+		return nil
+	}
+	if !dockerclient.IsErrNetworkNotFound(err) {
+		return errors.Wrapf(err, "error when inspecting network %s", internalNetwork)
+	}
+	_, err = d.docker.NetworkCreate(ctx, internalNetwork, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+		Internal:       true,
+	})
+	// the network may have been created concurrently
+	if err != nil {
+		if _, inspectErr := d.docker.NetworkInspect(ctx, internalNetwork); inspectErr != nil {
+			return errors.Wrapf(err, "error when creating network %s", internalNetwork)
+		}
+	}
+	return nil
+}
+
+// containerAddress returns the address of a function container: its published port, or its address on the internal
+// network if it has no network access
+func containerAddress(c types.ContainerJSON) (host, port string, internal bool, err error) {
+	if binding, ok := c.NetworkSettings.Ports[functionAPIPort]; ok && len(binding) > 0 {
+		return binding[0].HostIP, binding[0].HostPort, false, nil
+	}
+	if endpoint, ok := c.NetworkSettings.Networks[internalNetwork]; ok && endpoint.IPAddress != "" {
+		return endpoint.IPAddress, nat.Port(functionAPIPort).Port(), true, nil
+	}
+	return "", "", false, errors.Errorf("No port assigned to function container, docker error or no more ports available")
+}
+
+// containerURL returns the URL of the function server of a container
+func (d *Driver) containerURL(c *dockerContainer) string {
+	if c.Internal {
+		return "http://" + c.Host + ":" + c.Port
+	}
+	return "http://" + d.ExternalHost + ":" + c.Port
+}
+
+// containerFailure returns the error of a container which failed to serve a request, if it was killed
+func (d *Driver) containerFailure(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	cDetails, err := d.docker.ContainerInspect(ctx, id)
+	if err != nil || cDetails.ContainerJSONBase == nil || cDetails.State == nil {
+		return nil
+	}
+	if cDetails.State.OOMKilled {
+		return &functionError{errors.Errorf("function container %s was killed: out of memory", id)}
+	}
+	if !cDetails.State.Running {
+		return &systemError{errors.Errorf("function container %s exited with code %d", id, cDetails.State.ExitCode)}
+	}
+	return nil
 }
 
-// Delete deletes the function container.
+// removeContainers removes containers, logging errors
+func (d *Driver) removeContainers(ids ...string) {
+	for _, id := range ids {
+		log.Debugf("Deleting container %s", id)
+		err := d.docker.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{
+			Force: true,
+		})
+		if err != nil {
+			log.Errorf("Error when deleting container %s: %+v", id, err)
+		}
+	}
+}
+
+// Delete deletes the function containers.
 func (d *Driver) Delete(ctx context.Context, f *functions.Function) error {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
+
+	// Stop the pool, its containers are deleted with the others
+	d.mu.Lock()
+	if p, ok := d.pools[f.ID]; ok {
+		p.close()
+		delete(d.pools, f.ID)
+	}
+	d.mu.Unlock()
+
 	if err := d.deleteContainers(ctx, f, true); err != nil {
 		// no error wrapping, delete already does that
 		return err
@@ -196,15 +461,97 @@ func (d *Driver) deleteContainers(ctx context.Context, f *functions.Function, de
 		}
 	}
 
-	// Clear cache if active is also to be deleted
-	if _, ok := d.containerCache.Load(f.ID); ok && deleteActive {
-		d.containerCache.Delete(f.ID)
+	return nil
+}
+
+// getPool returns the pool of a function, rebuilding it from the running containers of the function if needed
+// (e.g. after a restart). The lock is not held while docker is queried, concurrent requests of the function wait for
+// the same rebuild instead.
+func (d *Driver) getPool(ctx context.Context, functionID, functionRevision string) (*pool, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	key := functionID + "/" + functionRevision
+	d.mu.Lock()
+	if p, ok := d.pools[functionID]; ok {
+		d.mu.Unlock()
+		return p, nil
+	}
+	load, loading := d.loads[key]
+	if !loading {
+		load = &poolLoad{done: make(chan struct{})}
+		d.loads[key] = load
+	}
+	d.mu.Unlock()
+
+	if loading {
+		select {
+		case <-load.done:
+			return load.pool, load.err
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "error when finding containers for function ID %s", functionID)
+		}
+	}
+
+	load.pool, load.err = d.loadPool(ctx, functionID, functionRevision)
+	d.mu.Lock()
+	delete(d.loads, key)
+	if load.pool != nil {
+		if p, ok := d.pools[functionID]; ok {
+			// the function was created meanwhile, its new pool replaces the containers found
+			load.pool.close()
+			load.pool = p
+		} else {
+			d.pools[functionID] = load.pool
+		}
 	}
+	d.mu.Unlock()
+	close(load.done)
+	return load.pool, load.err
+}
 
-	return nil
+// loadPool rebuilds the pool of a function revision from its running containers, or returns nil if there are none
+func (d *Driver) loadPool(ctx context.Context, functionID, functionRevision string) (*pool, error) {
+	containers, err := d.findActiveContainers(ctx, functionID, functionRevision)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	labels := containers[0].Labels
+	f := &functions.Function{
+		BaseEntity:       entitystore.BaseEntity{ID: functionID, Name: labels[labelFunctionName]},
+		FaasID:           functionRevision,
+		FunctionImageURL: containers[0].ImageName,
+	}
+	// the containers started by the pool must be restricted as the running ones
+	if limits, ok := labels[labelFunctionLimits]; ok {
+		f.Limits = &functions.FunctionResources{}
+		if err := json.Unmarshal([]byte(limits), f.Limits); err != nil {
+			return nil, errors.Wrapf(err, "invalid limits label of container %s", containers[0].ID)
+		}
+	}
+	if sandbox, ok := labels[labelFunctionSandbox]; ok {
+		f.Sandbox = &functions.FunctionSandbox{}
+		if err := json.Unmarshal([]byte(sandbox), f.Sandbox); err != nil {
+			return nil, errors.Wrapf(err, "invalid sandbox label of container %s", containers[0].ID)
+		}
+	}
+	p := newPool(d, f)
+	for _, c := range containers {
+		p.add(&c.dockerContainer)
+	}
+	return p, nil
+}
+
+// labeledContainer is a function container with its labels, which describe its function
+type labeledContainer struct {
+	dockerContainer
+	Labels map[string]string
 }
 
-func (d *Driver) findActiveContainer(ctx context.Context, functionID, functionRevision string) (*dockerContainer, error) {
+func (d *Driver) findActiveContainers(ctx context.Context, functionID, functionRevision string) ([]labeledContainer, error) {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
 
@@ -219,58 +566,90 @@ func (d *Driver) findActiveContainer(ctx context.Context, functionID, functionRe
 		return nil, errors.Wrapf(err, "error when finding container for function ID %s", functionID)
 	}
 
-	if len(containers) == 0 {
-		return nil, nil
-	}
+	var active []labeledContainer
+	for _, c := range containers {
+		// We need to inspect container to get networking details
+		cDetails, err := d.docker.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error when inspecting container %s", c.ID)
+		}
 
-	// We need to inspect container to get networking details
-	cDetails, err := d.docker.ContainerInspect(ctx, containers[0].ID)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error when inspecting container %s", containers[0].ID)
-	}
+		host, port, internal, err := containerAddress(cDetails)
+		if err != nil {
+			return nil, err
+		}
 
-	binding, ok := cDetails.NetworkSettings.Ports[functionAPIPort]
-	if !ok || len(binding) < 1 {
-		return nil, errors.Errorf("No port assigned to function container, docker error or no more ports available")
+		active = append(active, labeledContainer{
+			dockerContainer: dockerContainer{
+				ID:               cDetails.ID,
+				ImageID:          cDetails.Image,
+				ImageName:        cDetails.Config.Image,
+				FunctionID:       functionID,
+				FunctionRevision: functionRevision,
+				Port:             port,
+				Host:             host,
+				Internal:         internal,
+			},
+			Labels: cDetails.Config.Labels,
+		})
 	}
-
-	return &dockerContainer{
-		ID:               cDetails.ID,
-		ImageID:          cDetails.Image,
-		ImageName:        cDetails.Config.Image,
-		FunctionID:       functionID,
-		FunctionRevision: functionRevision,
-		Port:             binding[0].HostPort,
-		Host:             binding[0].HostIP,
-	}, nil
+	return active, nil
 }
 
 // GetRunnable creates runnable representation of the function
 func (d *Driver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
-	return func(ctx functions.Context, in interface{}) (interface{}, error) {
-		bytesIn, _ := json.Marshal(functions.Message{Context: ctx, Payload: in})
-		var c dockerContainer
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		bytesIn, _ := json.Marshal(functions.Message{Context: fctx, Payload: in})
 
-		ci, ok := d.containerCache.Load(e.FunctionID)
-		if ok {
-			c = ci.(dockerContainer)
-		} else {
-			cRef, err := d.findActiveContainer(context.Background(), e.FunctionID, e.FaasID)
-			if err != nil {
-				return nil, &systemError{errors.Errorf("error retrieving container for function %s", e.FunctionID)}
-			}
-			if cRef == nil {
-				return nil, &systemError{errors.Errorf("missing container for function %s", e.FunctionID)}
+		p, err := d.getPool(ctx, e.FunctionID, e.FaasID)
+		if err != nil {
+			return nil, &systemError{errors.Errorf("error retrieving container for function %s", e.FunctionID)}
+		}
+		if p == nil {
+			return nil, &systemError{errors.Errorf("missing container for function %s", e.FunctionID)}
+		}
+		// the output of the container is streamed while the function runs, if the logs of the run are followed. The
+		// container then serves no other run, whose output would be mixed in.
+		follow := functions.LogSinkFrom(ctx) != nil
+		c, err := p.acquire(ctx, follow)
+		if err != nil {
+			return nil, &systemError{errors.Wrapf(err, "no container available for function %s", e.FunctionID)}
+		}
+		var failed bool
+		defer func() {
+			p.release(c)
+			// the container may still be running the function, it is replaced by a new one
+			if ctx.Err() != nil {
+				log.Warnf("Recycling container %s of function %s: %v", c.ID, e.FunctionID, ctx.Err())
+				p.evict(c)
+			} else if failed {
+				log.Warnf("Replacing failed container %s of function %s", c.ID, e.FunctionID)
+				p.evict(c)
 			}
-			c = *cRef
-			// TODO this is racy, need to synchronize it
-			d.containerCache.Store(e.FunctionID, c)
+		}()
+
+		if follow {
+			logsCtx, stopLogs := context.WithCancel(ctx)
+			defer stopLogs()
+			go d.followContainerLogs(logsCtx, c, time.Now())
 		}
 
-		postURL := "http://" + d.ExternalHost + ":" + c.Port + "/"
-		res, err := http.Post(postURL, jsonContentType, bytes.NewReader(bytesIn))
+		postURL := d.containerURL(&c.dockerContainer) + "/"
+		req, err := http.NewRequest("POST", postURL, bytes.NewReader(bytesIn))
+		if err != nil {
+			return nil, &systemError{errors.Wrapf(err, "invalid function container URL %s", postURL)}
+		}
+		req.Header.Set("Content-Type", jsonContentType)
+		req.Header.Set("Accept", functions.FramesContentType+", "+jsonContentType)
+		res, err := http.DefaultClient.Do(req.WithContext(ctx))
 		if err != nil {
 			log.Errorf("Error when sending POST request to %s: %+v", postURL, err)
+			if ctx.Err() == nil {
+				if cerr := d.containerFailure(c.ID); cerr != nil {
+					failed = true
+					return nil, cerr
+				}
+			}
 			return nil, &systemError{errors.Wrapf(err, "request to function container on %s failed", postURL)}
 		}
 		defer res.Body.Close()
@@ -278,19 +657,19 @@ func (d *Driver) GetRunnable(e *functions.FunctionExecution) functions.Runnable
 		log.Debugf("docker.run.%s: status code: %v", e.FunctionID, res.StatusCode)
 		switch res.StatusCode {
 		case 200:
-			resBytes, err := ioutil.ReadAll(res.Body)
+			// the function may send its logs in frames while it runs, before the frame holding its output
+			out, err := functions.ReadFrames(ctx, fctx, res.Body)
 			if err != nil {
-				return nil, &systemError{errors.Errorf("cannot read result from function container on URL: %s %s", postURL, err)}
-			}
-			var out functions.Message
-			if err := json.Unmarshal(resBytes, &out); err != nil {
-				return nil, &systemError{errors.Errorf("cannot JSON-parse result from function container: %s %s", err, string(resBytes))}
+				return nil, &systemError{errors.Wrapf(err, "cannot read result from function container on URL: %s", postURL)}
 			}
-			ctx.AddLogs(out.Context.Logs())
-			ctx.SetError(out.Context.GetError())
+			fctx.SetError(out.Context.GetError())
 			return out.Payload, nil
 
 		default:
+			if cerr := d.containerFailure(c.ID); cerr != nil {
+				failed = true
+				return nil, cerr
+			}
 			bytesOut, err := ioutil.ReadAll(res.Body)
 			if err == nil {
 				return nil, &systemError{errors.Errorf("Server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))}
@@ -300,6 +679,64 @@ func (d *Driver) GetRunnable(e *functions.FunctionExecution) functions.Runnable
 	}
 }
 
+// followContainerLogs sends the output of a container written after since to the log sink of ctx, until ctx is done.
+// The container must be acquired in exclusive mode, so that its output belongs to the run.
+func (d *Driver) followContainerLogs(ctx context.Context, r *replica, since time.Time) {
+	logs, err := d.docker.ContainerLogs(ctx, r.ID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Since:      since.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warnf("Unable to follow the logs of container %s: %v", r.ID, err)
+		}
+		return
+	}
+	defer logs.Close()
+	err = readContainerLogs(logs, func(stream, line string) {
+		functions.StreamLog(ctx, stream, line)
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Warnf("Error following the logs of container %s: %v", r.ID, err)
+	}
+}
+
+// readContainerLogs reads a multiplexed stream of container logs, calling onLine for each line of stdout or stderr
+func readContainerLogs(reader io.Reader, onLine func(stream, line string)) error {
+	header := make([]byte, 8)
+	partial := make(map[string]string)
+	defer func() {
+		for stream, line := range partial {
+			if line != "" {
+				onLine(stream, line)
+			}
+		}
+	}()
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		stream := v1.LogLineStreamStdout
+		if header[0] == 2 {
+			stream = v1.LogLineStreamStderr
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return err
+		}
+		lines := strings.Split(partial[stream]+string(payload), "\n")
+		partial[stream] = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			onLine(stream, line)
+		}
+	}
+}
+
 func getID(functionName string, id string) string {
 	return fmt.Sprintf("dispatch-%s-%s", functionName, id)
 }
@@ -323,3 +760,23 @@ func (err *systemError) StackTrace() errors.StackTrace {
 
 	return nil
 }
+
+type functionError struct {
+	Err error `json:"err"`
+}
+
+func (err *functionError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *functionError) AsFunctionErrorObject() interface{} {
+	return err
+}
+
+func (err *functionError) StackTrace() errors.StackTrace {
+	if e, ok := err.Err.(functions.StackTracer); ok {
+		return e.StackTrace()
+	}
+
+	return nil
+}
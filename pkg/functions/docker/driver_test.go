@@ -5,15 +5,24 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -81,14 +90,19 @@ func TestDriverCreate(t *testing.T) {
 		[]types.Container{}, nil,
 	)
 
+	dockerMock.On("ContainerRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
 	err := d.Create(context.Background(), &f)
 	assert.NoError(t, err)
+	dockerMock.AssertNotCalled(t, "ContainerRemove", mock.Anything, mock.Anything, mock.Anything)
 
 	server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		rw.WriteHeader(http.StatusInternalServerError)
 	})
 	err = d.Create(context.Background(), &f)
 	assert.Error(t, err)
+	// the unhealthy container is removed
+	dockerMock.AssertCalled(t, "ContainerRemove", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestDriverGetRunnableMissing(t *testing.T) {
@@ -101,7 +115,7 @@ func TestDriverGetRunnableMissing(t *testing.T) {
 
 	f := d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef"})
 	ctx := functions.Context{}
-	_, err := f(ctx, map[string]interface{}{"name": "Me", "place": "Here"})
+	_, err := f(context.Background(), ctx, map[string]interface{}{"name": "Me", "place": "Here"})
 
 	assert.Error(t, err)
 }
@@ -155,12 +169,184 @@ func TestDriverGetRunnable(t *testing.T) {
 	f := d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef"})
 	ctx := functions.Context{}
 
-	r, err := f(ctx, map[string]interface{}{"name": "Me", "place": "Here"})
+	r, err := f(context.Background(), ctx, map[string]interface{}{"name": "Me", "place": "Here"})
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{"myField": "Hello, Me from Here"}, r)
 	assert.Equal(t, v1.Logs{Stdout: []string{"log log log", "log log log"}}, ctx["logs"])
 }
 
+func TestDriverGetRunnableFrames(t *testing.T) {
+	dockerMock := &mocks.CommonAPIClient{}
+	d := New(dockerMock)
+
+	dockerMock.On("ContainerList", mock.Anything, mock.Anything).Return(
+		[]types.Container{{}}, nil,
+	)
+	dockerMock.On("ContainerLogs", mock.Anything, mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(bytes.NewReader(nil)), nil,
+	)
+
+	server, port := startHTTPServer()
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Contains(t, req.Header.Get("Accept"), functions.FramesContentType)
+		rw.Header().Set("Content-Type", functions.FramesContentType)
+		rw.WriteHeader(http.StatusOK)
+
+		encoder := json.NewEncoder(rw)
+		encoder.Encode(&functions.Message{
+			Context: functions.Context{functions.LogsKey: v1.Logs{Stdout: []string{"starting"}}},
+		})
+		rw.(http.Flusher).Flush()
+		encoder.Encode(&functions.Message{
+			Context: functions.Context{functions.LogsKey: v1.Logs{Stderr: []string{"done"}}},
+			Payload: "result",
+		})
+	})
+
+	dockerMock.On("ContainerInspect", mock.Anything, mock.Anything).Return(
+		types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Running: true}},
+			NetworkSettings: &types.NetworkSettings{
+				NetworkSettingsBase: types.NetworkSettingsBase{
+					Ports: nat.PortMap{
+						functionAPIPort: []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: port}},
+					},
+				},
+			},
+			Config: &container.Config{},
+		}, nil,
+	)
+
+	var streamed []string
+	ctx := functions.WithLogSink(context.Background(), func(stream, line string) {
+		streamed = append(streamed, stream+": "+line)
+	})
+	fctx := functions.Context{}
+	r, err := d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef"})(ctx, fctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "result", r)
+	assert.Equal(t, v1.Logs{Stdout: []string{"starting"}, Stderr: []string{"done"}}, fctx.Logs())
+	assert.Equal(t, []string{"stdout: starting", "stderr: done"}, streamed)
+}
+
+func TestDriverGetPoolConcurrent(t *testing.T) {
+	dockerMock := &mocks.CommonAPIClient{}
+	d := New(dockerMock)
+
+	release := make(chan struct{})
+	dockerMock.On("ContainerList", mock.Anything, mock.Anything).Return(
+		[]types.Container{{ID: "container-1"}}, nil,
+	).Run(func(mock.Arguments) {
+		<-release
+	})
+	dockerMock.On("ContainerInspect", mock.Anything, mock.Anything).Return(types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: "container-1"},
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{
+				Ports: nat.PortMap{functionAPIPort: []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "8080"}}},
+			},
+		},
+		Config: &container.Config{Labels: map[string]string{labelFunctionName: "hello"}},
+	}, nil)
+
+	pools := make(chan *pool, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			p, err := d.getPool(context.Background(), "deadbeef", "revision")
+			assert.NoError(t, err)
+			pools <- p
+		}()
+	}
+
+	// the driver is not locked while docker is queried
+	time.Sleep(50 * time.Millisecond)
+	locked := make(chan struct{})
+	go func() {
+		d.mu.Lock()
+		d.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("driver locked while listing containers")
+	}
+
+	close(release)
+	first := <-pools
+	assert.NotNil(t, first)
+	assert.Equal(t, "hello", first.f.Name)
+	assert.Equal(t, first, <-pools)
+	assert.Equal(t, first, <-pools)
+	// the containers are listed once for all the requests
+	dockerMock.AssertNumberOfCalls(t, "ContainerList", 1)
+}
+
+func TestDriverGetPoolRestoresOptions(t *testing.T) {
+	f := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{Name: "hello", ID: "deadbeef"},
+		FaasID:     "revision",
+		Limits:     &functions.FunctionResources{Memory: "128Mi", Pids: 100},
+		Sandbox:    &functions.FunctionSandbox{ReadOnlyRootFS: true, DropCapabilities: []string{"ALL"}},
+	}
+	server, port := startHTTPServer()
+	defer server.Close()
+
+	// the containers are labeled with the options of the function
+	dockerMock := &mocks.CommonAPIClient{}
+	mockContainers(dockerMock, port)
+	d := New(dockerMock)
+	d.MinReplicas = 1
+	assert.NoError(t, d.Create(context.Background(), f))
+	var labels map[string]string
+	for _, call := range dockerMock.Calls {
+		if call.Method == "ContainerCreate" {
+			labels = call.Arguments.Get(1).(*container.Config).Labels
+		}
+	}
+	assert.Equal(t, "hello", labels[labelFunctionName])
+
+	// the pool rebuilt after a restart starts containers with the same options
+	dockerMock = &mocks.CommonAPIClient{}
+	dockerMock.On("ContainerList", mock.Anything, mock.Anything).Return([]types.Container{{ID: "container-1"}}, nil)
+	dockerMock.On("ContainerInspect", mock.Anything, mock.Anything).Return(types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: "container-1"},
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{
+				Ports: nat.PortMap{functionAPIPort: []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: port}}},
+			},
+		},
+		Config: &container.Config{Labels: labels},
+	}, nil)
+	d = New(dockerMock)
+	p, err := d.getPool(context.Background(), f.ID, f.FaasID)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", p.f.Name)
+	assert.Equal(t, f.Limits, p.f.Limits)
+	assert.Equal(t, f.Sandbox, p.f.Sandbox)
+}
+
+func TestReadContainerLogs(t *testing.T) {
+	frame := func(stream byte, payload string) []byte {
+		header := []byte{stream, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+		return append(header, payload...)
+	}
+	var logs []byte
+	logs = append(logs, frame(1, "first li")...)
+	logs = append(logs, frame(2, "error\n")...)
+	logs = append(logs, frame(1, "ne\nsecond line\nlast")...)
+
+	var lines []string
+	err := readContainerLogs(bytes.NewReader(logs), func(stream, line string) {
+		lines = append(lines, stream+": "+line)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stderr: error", "stdout: first line", "stdout: second line", "stdout: last"}, lines)
+}
+
 func TestOfDriverDelete(t *testing.T) {
 	f := functions.Function{
 		BaseEntity: entitystore.BaseEntity{
@@ -181,3 +367,328 @@ func TestOfDriverDelete(t *testing.T) {
 	assert.NoError(t, err)
 
 }
+
+// mockContainers mocks running containers numbered in creation order, all listening on port. It returns the number
+// of created containers and a channel receiving the IDs of removed containers.
+func mockContainers(dockerMock *mocks.CommonAPIClient, port string) (*int32, <-chan string) {
+	var created int32
+	dockerMock.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(context.Context, *container.Config, *container.HostConfig, *network.NetworkingConfig, string) container.ContainerCreateCreatedBody {
+			return container.ContainerCreateCreatedBody{ID: fmt.Sprintf("container-%d", atomic.AddInt32(&created, 1))}
+		}, nil,
+	)
+	dockerMock.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dockerMock.On("ContainerInspect", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, id string) types.ContainerJSON {
+			return types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					ID:    id,
+					State: &types.ContainerState{Running: true},
+				},
+				NetworkSettings: &types.NetworkSettings{
+					NetworkSettingsBase: types.NetworkSettingsBase{
+						Ports: nat.PortMap{
+							functionAPIPort: []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: port}},
+						},
+					},
+				},
+			}
+		}, nil,
+	)
+	dockerMock.On("ContainerList", mock.Anything, mock.Anything).Return([]types.Container{}, nil)
+	removed := make(chan string, 10)
+	dockerMock.On("ContainerRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		removed <- args.String(1)
+	})
+	return &created, removed
+}
+
+func TestDriverScaling(t *testing.T) {
+	f := functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name: "hello",
+			ID:   "deadbeef",
+		},
+		FaasID: "cafe",
+	}
+	dockerMock := &mocks.CommonAPIClient{}
+	d := New(dockerMock)
+	d.RetryTimeout = 0
+	d.MaxReplicas = 2
+	d.ContainerConcurrency = 1
+	d.ScaleDownCooldown = 10 * time.Millisecond
+
+	block := make(chan struct{})
+	running := make(chan struct{}, 3)
+	server, port := startHTTPServer()
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == healthcheckEndpoint {
+			rw.WriteHeader(http.StatusOK)
+			return
+		}
+		running <- struct{}{}
+		<-block
+		result, _ := json.Marshal(&functions.Message{Payload: "done"})
+		rw.Write(result)
+	})
+
+	created, removed := mockContainers(dockerMock, port)
+
+	assert.NoError(t, d.Create(context.Background(), &f))
+	assert.Equal(t, 1, d.pools[f.ID].size())
+
+	// the second concurrent run starts a second container, the third waits for one of them
+	run := d.GetRunnable(&functions.FunctionExecution{FunctionID: f.ID, FaasID: f.FaasID})
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := run(context.Background(), functions.Context{}, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, "done", r)
+		}()
+	}
+	<-running
+	<-running
+	select {
+	case <-running:
+		t.Fatal("a container serves more than ContainerConcurrency requests")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, 2, d.pools[f.ID].size())
+	assert.EqualValues(t, 2, atomic.LoadInt32(created))
+
+	close(block)
+	wg.Wait()
+
+	// the idle container above MinReplicas is removed after the cooldown
+	select {
+	case <-removed:
+	case <-time.After(time.Second):
+		t.Fatal("idle container was not removed")
+	}
+	assert.Equal(t, 1, d.pools[f.ID].size())
+}
+
+func TestDriverScale(t *testing.T) {
+	f := functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name: "hello",
+			ID:   "deadbeef",
+		},
+		FaasID: "cafe",
+	}
+	dockerMock := &mocks.CommonAPIClient{}
+	d := New(dockerMock)
+	d.RetryTimeout = 0
+	server, port := startHTTPServer()
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		result, _ := json.Marshal(&functions.Message{Payload: "done"})
+		rw.Write(result)
+	})
+
+	created, removed := mockContainers(dockerMock, port)
+
+	// the function is cold-started after a restart
+	assert.NoError(t, d.Scale(context.Background(), &f, 2))
+	assert.Equal(t, 2, d.pools[f.ID].size())
+	assert.EqualValues(t, 2, atomic.LoadInt32(created))
+
+	// idle containers are removed right away
+	assert.NoError(t, d.Scale(context.Background(), &f, 0))
+	assert.Equal(t, 0, d.pools[f.ID].size())
+	<-removed
+	<-removed
+
+	// the next run starts a container
+	run := d.GetRunnable(&functions.FunctionExecution{FunctionID: f.ID, FaasID: f.FaasID})
+	r, err := run(context.Background(), functions.Context{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", r)
+	assert.EqualValues(t, 3, atomic.LoadInt32(created))
+}
+
+func TestPoolPick(t *testing.T) {
+	d := New(&mocks.CommonAPIClient{})
+	d.ContainerConcurrency = 2
+	d.MaxReplicas = 3
+	p := newPool(d, &functions.Function{})
+	for _, id := range []string{"a", "b", "c"} {
+		p.add(&dockerContainer{ID: id})
+	}
+
+	// requests are balanced across the containers
+	var picked []string
+	for i := 0; i < 6; i++ {
+		r, err := p.acquire(context.Background(), false)
+		assert.NoError(t, err)
+		picked = append(picked, r.ID)
+	}
+	sort.Strings(picked)
+	assert.Equal(t, []string{"a", "a", "b", "b", "c", "c"}, picked)
+	assert.Nil(t, p.pick(false))
+
+	// a released container is picked again
+	p.release(p.replicas[1])
+	r, err := p.acquire(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", r.ID)
+
+	// an exclusive request is served by an idle container, which serves no other request until it is released
+	for _, r := range p.replicas {
+		for r.inflight > 1 {
+			p.release(r)
+		}
+	}
+	p.release(r)
+	exclusive, err := p.acquire(context.Background(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, r.ID, exclusive.ID)
+	assert.Nil(t, p.pick(true))
+	for i := 0; i < 2; i++ {
+		r, err := p.acquire(context.Background(), false)
+		assert.NoError(t, err)
+		assert.NotEqual(t, exclusive.ID, r.ID)
+	}
+	assert.Nil(t, p.pick(false))
+	p.release(exclusive)
+	assert.Equal(t, exclusive, p.pick(false))
+
+	// requests waiting for a container fail once the pool is closed
+	errs := make(chan error)
+	go func() {
+		_, err := p.acquire(context.Background(), false)
+		errs <- err
+	}()
+	assert.Equal(t, []string{"a", "b", "c"}, p.close())
+	assert.Error(t, <-errs)
+}
+
+func TestDriverTimeout(t *testing.T) {
+	f := functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name: "hello",
+			ID:   "deadbeef",
+		},
+		FaasID: "cafe",
+	}
+	dockerMock := &mocks.CommonAPIClient{}
+	d := New(dockerMock)
+	d.RetryTimeout = 0
+
+	block := make(chan struct{})
+	server, port := startHTTPServer()
+	defer server.Close()
+	defer close(block)
+	server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != healthcheckEndpoint {
+			<-block
+		}
+		rw.WriteHeader(http.StatusOK)
+	})
+	created, removed := mockContainers(dockerMock, port)
+
+	assert.NoError(t, d.Create(context.Background(), &f))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	run := d.GetRunnable(&functions.FunctionExecution{FunctionID: f.ID, FaasID: f.FaasID})
+	_, err := run(ctx, functions.Context{}, nil)
+	assert.Error(t, err)
+
+	// the container which timed out is replaced
+	select {
+	case id := <-removed:
+		assert.Equal(t, "container-1", id)
+	case <-time.After(time.Second):
+		t.Fatal("container was not removed")
+	}
+	for i := 0; i < 100 && d.pools[f.ID].size() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 1, d.pools[f.ID].size())
+	assert.EqualValues(t, 2, atomic.LoadInt32(created))
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "not found" }
+func (notFoundError) NotFound() bool { return true }
+
+func TestDriverHostConfig(t *testing.T) {
+	dockerMock := &mocks.CommonAPIClient{}
+	d := New(dockerMock)
+
+	f := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{Name: "hello"},
+		Limits:     &functions.FunctionResources{Memory: "128Mi", CPU: "500m", Pids: 64},
+		Sandbox: &functions.FunctionSandbox{
+			ReadOnlyRootFS:   true,
+			DropCapabilities: []string{"ALL"},
+			SeccompProfile:   "unconfined",
+			NoNetwork:        true,
+		},
+	}
+
+	dockerMock.On("NetworkInspect", mock.Anything, internalNetwork).Return(types.NetworkResource{}, notFoundError{})
+	dockerMock.On("NetworkCreate", mock.Anything, internalNetwork, mock.MatchedBy(func(options types.NetworkCreate) bool {
+		return options.Internal
+	})).Return(types.NetworkCreateResponse{}, nil)
+
+	hostConfig, err := d.hostConfig(context.Background(), f)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 128*1024*1024, hostConfig.Memory)
+	assert.Equal(t, hostConfig.Memory, hostConfig.MemorySwap)
+	assert.EqualValues(t, 500000000, hostConfig.NanoCPUs)
+	assert.EqualValues(t, 64, hostConfig.PidsLimit)
+	assert.True(t, hostConfig.ReadonlyRootfs)
+	assert.Contains(t, hostConfig.Tmpfs, "/tmp")
+	assert.Equal(t, []string{"ALL"}, []string(hostConfig.CapDrop))
+	assert.Equal(t, []string{"seccomp=unconfined"}, hostConfig.SecurityOpt)
+	assert.EqualValues(t, internalNetwork, hostConfig.NetworkMode)
+	assert.Empty(t, hostConfig.PortBindings)
+	dockerMock.AssertExpectations(t)
+
+	f.Limits.Memory = "a lot"
+	_, err = d.hostConfig(context.Background(), f)
+	assert.Error(t, err)
+}
+
+func TestDriverGetRunnableOOMKilled(t *testing.T) {
+	dockerMock := &mocks.CommonAPIClient{}
+	d := New(dockerMock)
+	d.MinReplicas = 0
+
+	server, port := startHTTPServer()
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+
+	p := newPool(d, &functions.Function{BaseEntity: entitystore.BaseEntity{ID: "deadbeef"}})
+	p.add(&dockerContainer{ID: "container", Port: port})
+	d.pools["deadbeef"] = p
+
+	dockerMock.On("ContainerInspect", mock.Anything, "container").Return(types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State: &types.ContainerState{OOMKilled: true},
+		},
+	}, nil)
+	removed := make(chan string, 1)
+	dockerMock.On("ContainerRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		removed <- args.String(1)
+	})
+
+	f := d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef"})
+	_, err := f(context.Background(), functions.Context{}, nil)
+	assert.Error(t, err)
+	assert.Implements(t, (*functions.FunctionError)(nil), err)
+	assert.Contains(t, err.Error(), "out of memory")
+
+	// the killed container is removed from the pool
+	assert.Equal(t, "container", <-removed)
+	assert.Equal(t, 0, p.size())
+}
@@ -0,0 +1,275 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package docker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/functions"
+)
+
+// replica is a container of a pool, with the number of requests it is serving
+type replica struct {
+	dockerContainer
+	inflight int
+	// exclusive is set while the replica serves a request acquired in exclusive mode, and no other one
+	exclusive bool
+	lastUsed  time.Time
+}
+
+// pool is the set of containers running a function revision. Requests are sent to the least loaded container,
+// a container is started when all containers serve ContainerConcurrency requests, and idle containers above
+// minReplicas are removed after ScaleDownCooldown.
+type pool struct {
+	d *Driver
+	f *functions.Function
+
+	mu sync.Mutex
+	// cond is signaled when a replica is released or added, or when a replica failed to start
+	cond     *sync.Cond
+	replicas []*replica
+	// starting is the number of replicas being started
+	starting int
+	// next is the replica preferred when several are equally loaded
+	next int
+	// minReplicas is the number of replicas kept when the pool is idle, MinReplicas unless the function was scaled
+	minReplicas int
+	err         error
+	closed      bool
+}
+
+func newPool(d *Driver, f *functions.Function) *pool {
+	p := &pool{d: d, f: f, minReplicas: d.MinReplicas}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// add adds a started container to the pool
+func (p *pool) add(c *dockerContainer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replicas = append(p.replicas, &replica{dockerContainer: *c, lastUsed: time.Now()})
+	p.cond.Broadcast()
+}
+
+// size returns the number of replicas of the pool
+func (p *pool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.replicas)
+}
+
+// pick returns the least loaded replica which can serve one more request, or an idle one if exclusive is set, or nil
+// if all replicas are busy
+func (p *pool) pick(exclusive bool) *replica {
+	var picked *replica
+	n := len(p.replicas)
+	for i := 0; i < n; i++ {
+		r := p.replicas[(p.next+i)%n]
+		if r.exclusive || exclusive && r.inflight > 0 {
+			continue
+		}
+		if p.d.ContainerConcurrency > 0 && r.inflight >= p.d.ContainerConcurrency {
+			continue
+		}
+		if picked == nil || r.inflight < picked.inflight {
+			picked = r
+		}
+	}
+	if n > 0 {
+		p.next = (p.next + 1) % n
+	}
+	return picked
+}
+
+// acquire reserves a replica for a request, scaling the pool up if all replicas are busy. It waits for a replica to
+// be released once the pool has MaxReplicas replicas, until ctx is done. In exclusive mode, the replica serves no
+// other request until it is released, so that its output belongs to the request.
+func (p *pool) acquire(ctx context.Context, exclusive bool) (*replica, error) {
+	acquired := make(chan struct{})
+	defer close(acquired)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.mu.Lock()
+			p.cond.Broadcast()
+			p.mu.Unlock()
+		case <-acquired:
+		}
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		if p.closed {
+			return nil, errors.Errorf("function %s was deleted or updated", p.f.ID)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if r := p.pick(exclusive); r != nil {
+			r.inflight++
+			r.exclusive = exclusive
+			r.lastUsed = time.Now()
+			return r, nil
+		}
+		if len(p.replicas) == 0 && p.starting == 0 && p.err != nil {
+			err := p.err
+			p.err = nil
+			return nil, err
+		}
+		if len(p.replicas)+p.starting < p.d.MaxReplicas {
+			p.starting++
+			go p.scaleUp()
+		}
+		p.cond.Wait()
+	}
+}
+
+// release frees a replica reserved by acquire
+func (p *pool) release(r *replica) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r.inflight--
+	r.exclusive = false
+	r.lastUsed = time.Now()
+	// the released replica may be idle, which exclusive requests wait for
+	p.cond.Broadcast()
+	if r.inflight == 0 && len(p.replicas) > p.minReplicas {
+		time.AfterFunc(p.d.ScaleDownCooldown, p.scaleDown)
+	}
+}
+
+// evict removes a replica from the pool and its container, starting a new replica if the pool has less than
+// minReplicas replicas
+func (p *pool) evict(r *replica) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, pr := range p.replicas {
+		if pr == r {
+			p.replicas = append(p.replicas[:i], p.replicas[i+1:]...)
+			go p.d.removeContainers(r.ID)
+			break
+		}
+	}
+	if !p.closed && len(p.replicas)+p.starting < p.minReplicas {
+		p.starting++
+		go p.scaleUp()
+	}
+}
+
+// scaleUp starts a new replica
+func (p *pool) scaleUp() error {
+	log.Debugf("Scaling up function %s", p.f.ID)
+	c, err := p.d.startContainer(context.Background(), p.f)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.starting--
+	p.cond.Broadcast()
+	if err != nil {
+		log.Errorf("Error scaling up function %s: %+v", p.f.ID, err)
+		p.err = err
+		return err
+	}
+	if p.closed {
+		go p.d.removeContainers(c.ID)
+		return errors.Errorf("function %s was deleted or updated", p.f.ID)
+	}
+	p.err = nil
+	p.replicas = append(p.replicas, &replica{dockerContainer: *c, lastUsed: time.Now()})
+	if len(p.replicas) > p.minReplicas {
+		time.AfterFunc(p.d.ScaleDownCooldown, p.scaleDown)
+	}
+	return nil
+}
+
+// removeIdle removes the replicas which have been idle for cooldown from the pool, keeping at least keep replicas,
+// and returns the IDs of their containers. p.mu must be held.
+func (p *pool) removeIdle(keep int, cooldown time.Duration) []string {
+	var idle []string
+	now := time.Now()
+	for i := 0; i < len(p.replicas) && len(p.replicas) > keep; {
+		r := p.replicas[i]
+		if r.inflight == 0 && now.Sub(r.lastUsed) >= cooldown {
+			idle = append(idle, r.ID)
+			p.replicas = append(p.replicas[:i], p.replicas[i+1:]...)
+			continue
+		}
+		i++
+	}
+	return idle
+}
+
+// scaleDown removes the replicas which have been idle for ScaleDownCooldown, keeping at least minReplicas replicas
+func (p *pool) scaleDown() {
+	p.mu.Lock()
+	idle := p.removeIdle(p.minReplicas, p.d.ScaleDownCooldown)
+	p.mu.Unlock()
+
+	if len(idle) > 0 {
+		log.Debugf("Scaling down function %s by %d replicas", p.f.ID, len(idle))
+		p.d.removeContainers(idle...)
+	}
+}
+
+// scale sets the number of replicas kept when the pool is idle. Idle replicas above it are removed right away, and
+// missing replicas are started before scale returns.
+func (p *pool) scale(replicas int) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errors.Errorf("function %s was deleted or updated", p.f.ID)
+	}
+	p.minReplicas = replicas
+	idle := p.removeIdle(replicas, 0)
+	missing := replicas - len(p.replicas) - p.starting
+	if missing > 0 {
+		p.starting += missing
+	}
+	p.mu.Unlock()
+
+	if len(idle) > 0 {
+		log.Debugf("Scaling down function %s by %d replicas", p.f.ID, len(idle))
+		p.d.removeContainers(idle...)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var err error
+	for i := 0; i < missing; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e := p.scaleUp(); e != nil {
+				mu.Lock()
+				err = e
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
+
+// close stops the pool, failing the requests waiting for a replica, and returns the IDs of its containers
+func (p *pool) close() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+	var ids []string
+	for _, r := range p.replicas {
+		ids = append(ids, r.ID)
+	}
+	p.replicas = nil
+	return ids
+}
@@ -30,6 +30,50 @@ type Function struct {
 	Secrets          []string `json:"secrets,omitempty"`
 	Services         []string `json:"services,omitempty"`
 	Timeout          int64    `json:"timeout,omitempty"`
+
+	Limits       *FunctionResources `json:"limits,omitempty"`
+	Sandbox      *FunctionSandbox   `json:"sandbox,omitempty"`
+	RetryPolicy  *RetryPolicy       `json:"retryPolicy,omitempty"`
+	RunRetention *RunRetention      `json:"runRetention,omitempty"`
+	PreWarm      int                `json:"preWarm,omitempty"`
+
+	LatestVersion int      `json:"latestVersion,omitempty"`
+	Aliases       []*Alias `json:"aliases,omitempty"`
+}
+
+// FunctionVersion struct represents an immutable published version of a function. The version is deployed as a
+// function of its own, distinct from the function it was published from.
+type FunctionVersion struct {
+	entitystore.BaseEntity
+	FunctionName string   `json:"functionName"`
+	Number       int      `json:"number"`
+	Function     Function `json:"function"`
+}
+
+// Deployment returns the function deployed for the version, which has the ID of the version
+func (v *FunctionVersion) Deployment() *Function {
+	f := v.Function
+	f.ID = v.ID
+	f.OrganizationID = v.OrganizationID
+	f.Status = v.Status
+	f.Reason = v.Reason
+	f.LatestVersion = 0
+	f.Aliases = nil
+	return &f
+}
+
+// Alias struct represents a named set of weighted versions of a function
+type Alias struct {
+	Name   string           `json:"name"`
+	Routes []*VersionWeight `json:"routes"`
+	// Previous are the routes replaced by the last update of the alias, restored by a rollback
+	Previous []*VersionWeight `json:"previous,omitempty"`
+}
+
+// VersionWeight struct represents the share of the runs of an alias sent to a version
+type VersionWeight struct {
+	Version int `json:"version"`
+	Weight  int `json:"weight"`
 }
 
 // Schema struct stores input and output validation schemas
@@ -54,10 +98,26 @@ type FnRun struct {
 	Logs         *v1.Logs               `json:"logs,omitempty"`
 	Error        *v1.InvocationError    `json:"error,omitempty"`
 	FinishedTime time.Time              `json:"finishedTime,omitempty"`
+	RetryPolicy  *RetryPolicy           `json:"retryPolicy,omitempty"`
+	Attempts     []*RunAttempt          `json:"attempts,omitempty"`
+	ColdStart    time.Duration          `json:"coldStart,omitempty"`
+	// Alias and FunctionVersion identify the version of the function which was run, if any
+	Alias           string `json:"alias,omitempty"`
+	FunctionVersion int    `json:"functionVersion,omitempty"`
+	// RetryAt is the time of the next attempt of a failed run which is retried
+	RetryAt time.Time `json:"retryAt,omitempty"`
 
 	WaitChan chan struct{} `json:"-"`
 }
 
+// RunAttempt struct represents a single execution of a function run
+type RunAttempt struct {
+	Attempt      int                 `json:"attempt"`
+	ExecutedTime time.Time           `json:"executedTime"`
+	FinishedTime time.Time           `json:"finishedTime"`
+	Error        *v1.InvocationError `json:"error,omitempty"`
+}
+
 // Source struct represents function source code that is stored in entity store
 type Source struct {
 	entitystore.BaseEntity
@@ -49,14 +49,14 @@ func getSecrets(client client.SecretsClient, organizationID string, secretNames
 
 func (i *secretInjector) GetMiddleware(organizationID string, secretNames []string, cookie string) functions.Middleware {
 	return func(f functions.Runnable) functions.Runnable {
-		return func(ctx functions.Context, in interface{}) (interface{}, error) {
+		return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
 			secrets, err := getSecrets(i.secretClient, organizationID, secretNames)
 			if err != nil {
 				log.Errorf("error when getting secrets from secret store %+v", err)
 				return nil, &injectorError{errors.Wrap(err, "error when retrieving secrets from secret store")}
 			}
-			ctx["secrets"] = secrets
-			out, err := f(ctx, in)
+			fctx["secrets"] = secrets
+			out, err := f(ctx, fctx, in)
 			if err != nil {
 				return nil, err
 			}
@@ -5,6 +5,7 @@
 package injectors
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,12 +35,12 @@ func TestInjectSecret(t *testing.T) {
 
 	cookie := "testCookie"
 
-	printSecretsFn := func(ctx functions.Context, _ interface{}) (interface{}, error) {
-		return ctx["secrets"], nil
+	printSecretsFn := func(ctx context.Context, fctx functions.Context, _ interface{}) (interface{}, error) {
+		return fctx["secrets"], nil
 	}
 
 	ctx := functions.Context{}
-	output, err := injector.GetMiddleware("testOrg", []string{expectedSecretName}, cookie)(printSecretsFn)(ctx, nil)
+	output, err := injector.GetMiddleware("testOrg", []string{expectedSecretName}, cookie)(printSecretsFn)(context.Background(), ctx, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedOutput, output)
 }
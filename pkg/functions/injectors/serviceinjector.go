@@ -54,14 +54,14 @@ func getServiceBindings(serviceClient client.ServicesClient, secretClient client
 
 func (i *serviceInjector) GetMiddleware(organizationID string, serviceNames []string, cookie string) functions.Middleware {
 	return func(f functions.Runnable) functions.Runnable {
-		return func(ctx functions.Context, in interface{}) (interface{}, error) {
+		return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
 			bindings, err := getServiceBindings(i.serviceClient, i.secretClient, organizationID, serviceNames)
 			if err != nil {
 				log.Errorf("error when getting service bindings from service manager %+v", err)
 				return nil, &injectorError{errors.Wrap(err, "error when retrieving bindings from service manager")}
 			}
-			ctx["serviceBindings"] = bindings
-			out, err := f(ctx, in)
+			fctx["serviceBindings"] = bindings
+			out, err := f(ctx, fctx, in)
 			if err != nil {
 				return nil, err
 			}
@@ -5,6 +5,7 @@
 package injectors
 
 import (
+	"context"
 	"testing"
 
 	"github.com/go-openapi/strfmt"
@@ -45,12 +46,12 @@ func TestInjectService(t *testing.T) {
 
 	cookie := "testCookie"
 
-	printServiceFn := func(ctx functions.Context, _ interface{}) (interface{}, error) {
-		return ctx["serviceBindings"].(map[string]interface{})[expectedServiceName], nil
+	printServiceFn := func(ctx context.Context, fctx functions.Context, _ interface{}) (interface{}, error) {
+		return fctx["serviceBindings"].(map[string]interface{})[expectedServiceName], nil
 	}
 
 	ctx := functions.Context{}
-	output, err := injector.GetMiddleware("testOrg", []string{expectedServiceName}, cookie)(printServiceFn)(ctx, nil)
+	output, err := injector.GetMiddleware("testOrg", []string{expectedServiceName}, cookie)(printServiceFn)(context.Background(), ctx, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedOutput, output)
 }
@@ -0,0 +1,183 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package k8s
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedNetworkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
+
+	"github.com/vmware/dispatch/pkg/functions"
+)
+
+const (
+	// tmpVolume is mounted on /tmp in the containers of functions with a read-only root filesystem, as function
+	// runtimes and libraries need a writable temporary directory
+	tmpVolume = "dispatch-tmp"
+
+	oomKilledReason = "OOMKilled"
+)
+
+// ApplyFunctionSettings applies the limits and sandbox of a function to the template of its pods: the resource limits
+// and security context of their containers, and their seccomp profile. It fails for the settings Kubernetes cannot
+// enforce on a pod, no network is enforced by the network policy of the function.
+func ApplyFunctionSettings(template *v1.PodTemplateSpec, f *functions.Function) error {
+	limits := v1.ResourceList{}
+	if l := f.Limits; l != nil {
+		if l.CPU != "" {
+			cpu, err := resource.ParseQuantity(l.CPU)
+			if err != nil {
+				return errors.Wrapf(err, "invalid CPU limit %s for function %s", l.CPU, f.Name)
+			}
+			limits[v1.ResourceCPU] = cpu
+		}
+		if l.Memory != "" {
+			memory, err := resource.ParseQuantity(l.Memory)
+			if err != nil {
+				return errors.Wrapf(err, "invalid memory limit %s for function %s", l.Memory, f.Name)
+			}
+			limits[v1.ResourceMemory] = memory
+		}
+		if l.Pids > 0 {
+			return errors.Errorf("invalid limits for function %s: Kubernetes does not limit the number of processes of a pod", f.Name)
+		}
+	}
+
+	var securityContext *v1.SecurityContext
+	if s := f.Sandbox; s != nil {
+		switch s.SeccompProfile {
+		case "":
+		case "unconfined":
+			if template.Annotations == nil {
+				template.Annotations = map[string]string{}
+			}
+			template.Annotations[v1.SeccompPodAnnotationKey] = "unconfined"
+		default:
+			return errors.Errorf("invalid sandbox for function %s: Kubernetes only loads seccomp profiles from the nodes, only unconfined is supported", f.Name)
+		}
+		readOnly := s.ReadOnlyRootFS
+		securityContext = &v1.SecurityContext{ReadOnlyRootFilesystem: &readOnly}
+		if len(s.DropCapabilities) > 0 {
+			securityContext.Capabilities = &v1.Capabilities{}
+			for _, c := range s.DropCapabilities {
+				securityContext.Capabilities.Drop = append(securityContext.Capabilities.Drop, v1.Capability(c))
+			}
+		}
+		if readOnly && !hasVolume(template.Spec.Volumes, tmpVolume) {
+			template.Spec.Volumes = append(template.Spec.Volumes, v1.Volume{
+				Name:         tmpVolume,
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory}},
+			})
+		}
+	}
+
+	for i := range template.Spec.Containers {
+		c := &template.Spec.Containers[i]
+		for name, limit := range limits {
+			if c.Resources.Limits == nil {
+				c.Resources.Limits = v1.ResourceList{}
+			}
+			c.Resources.Limits[name] = limit
+			// the default request of the FaaS may exceed the limit of the function, which Kubernetes rejects
+			if request, ok := c.Resources.Requests[name]; ok && request.Cmp(limit) > 0 {
+				c.Resources.Requests[name] = limit
+			}
+		}
+		if securityContext == nil {
+			continue
+		}
+		c.SecurityContext = securityContext.DeepCopy()
+		if *securityContext.ReadOnlyRootFilesystem && !hasVolumeMount(c.VolumeMounts, tmpVolume) {
+			c.VolumeMounts = append(c.VolumeMounts, v1.VolumeMount{Name: tmpVolume, MountPath: "/tmp"})
+		}
+	}
+	return nil
+}
+
+func hasVolume(volumes []v1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolumeMount(mounts []v1.VolumeMount, name string) bool {
+	for _, m := range mounts {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateNetworkPolicy creates the network policy named name of a function without network, which denies all outgoing
+// connections of the pods labeled with podLabels. The pods stay reachable, so that the FaaS runs the function. The
+// policy is only enforced by the network plugins which support network policies.
+func CreateNetworkPolicy(policies typedNetworkingv1.NetworkPolicyInterface, name string, podLabels map[string]string, f *functions.Function) error {
+	if f.Sandbox == nil || !f.Sandbox.NoNetwork {
+		return nil
+	}
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			// an egress policy without egress rules denies all outgoing connections
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		},
+	}
+	_, err := policies.Create(policy)
+	if k8sErrors.IsAlreadyExists(err) {
+		// the policy of a function which failed to be created
+		_, err = policies.Update(policy)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the network policy of function %s", f.Name)
+	}
+	return nil
+}
+
+// DeleteNetworkPolicy deletes the network policy named name of a function, if any
+func DeleteNetworkPolicy(policies typedNetworkingv1.NetworkPolicyInterface, name string) error {
+	err := policies.Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete network policy %s", name)
+	}
+	return nil
+}
+
+// OOMKilledPod returns the name of a pod labeled with podLabels whose container was killed for exceeding its memory
+// limit since a time, or "" if there is none
+func OOMKilledPod(pods typedv1.PodInterface, podLabels map[string]string, since time.Time) string {
+	selector := labels.SelectorFromSet(podLabels).String()
+	list, err := pods.List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Warnf("Unable to list the pods %s: %v", selector, err)
+		return ""
+	}
+	// the termination time of containers is in seconds
+	since = since.Truncate(time.Second)
+	for _, pod := range list.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			for _, terminated := range []*v1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+				if terminated != nil && terminated.Reason == oomKilledReason && !terminated.FinishedAt.Time.Before(since) {
+					return pod.Name
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sFake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/functions"
+)
+
+func TestApplyFunctionSettings(t *testing.T) {
+	f := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{Name: "hello"},
+		Limits:     &functions.FunctionResources{Memory: "128Mi", CPU: "500m"},
+		Sandbox: &functions.FunctionSandbox{
+			ReadOnlyRootFS:   true,
+			DropCapabilities: []string{"ALL"},
+			SeccompProfile:   "unconfined",
+		},
+	}
+	template := v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Image: "image",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")},
+				},
+			}},
+		},
+	}
+
+	assert.NoError(t, ApplyFunctionSettings(&template, f))
+	c := template.Spec.Containers[0]
+	assert.Equal(t, "image", c.Image)
+	assert.Equal(t, "128Mi", c.Resources.Limits.Memory().String())
+	assert.Equal(t, "500m", c.Resources.Limits.Cpu().String())
+	// the request is lowered to the limit
+	assert.Equal(t, "128Mi", c.Resources.Requests.Memory().String())
+	assert.True(t, *c.SecurityContext.ReadOnlyRootFilesystem)
+	assert.Equal(t, []v1.Capability{"ALL"}, c.SecurityContext.Capabilities.Drop)
+	assert.Equal(t, []v1.VolumeMount{{Name: tmpVolume, MountPath: "/tmp"}}, c.VolumeMounts)
+	assert.Len(t, template.Spec.Volumes, 1)
+	assert.Equal(t, map[string]string{v1.SeccompPodAnnotationKey: "unconfined"}, template.Annotations)
+
+	// the settings are applied once to a deployment updated again
+	assert.NoError(t, ApplyFunctionSettings(&template, f))
+	assert.Len(t, template.Spec.Volumes, 1)
+	assert.Len(t, template.Spec.Containers[0].VolumeMounts, 1)
+
+	f.Limits.CPU = "a lot"
+	assert.Error(t, ApplyFunctionSettings(&v1.PodTemplateSpec{}, f))
+
+	// settings kubernetes cannot enforce on a pod are rejected
+	f.Limits = &functions.FunctionResources{Pids: 100}
+	assert.Error(t, ApplyFunctionSettings(&v1.PodTemplateSpec{}, f))
+	f.Limits = nil
+	f.Sandbox.SeccompProfile = `{"defaultAction": "SCMP_ACT_ERRNO"}`
+	assert.Error(t, ApplyFunctionSettings(&v1.PodTemplateSpec{}, f))
+	f.Sandbox.SeccompProfile = ""
+	f.Sandbox.NoNetwork = true
+	assert.NoError(t, ApplyFunctionSettings(&v1.PodTemplateSpec{}, f))
+}
+
+func TestNetworkPolicy(t *testing.T) {
+	policies := k8sFake.NewSimpleClientset().NetworkingV1().NetworkPolicies("fakeNS")
+	labels := map[string]string{"function": "hello"}
+	f := &functions.Function{BaseEntity: entitystore.BaseEntity{Name: "hello"}}
+
+	// functions with network have no policy
+	assert.NoError(t, CreateNetworkPolicy(policies, "hello", labels, f))
+	list, err := policies.List(metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, list.Items)
+
+	f.Sandbox = &functions.FunctionSandbox{NoNetwork: true}
+	assert.NoError(t, CreateNetworkPolicy(policies, "hello", labels, f))
+	policy, err := policies.Get("hello", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, labels, policy.Spec.PodSelector.MatchLabels)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}, policy.Spec.PolicyTypes)
+	assert.Empty(t, policy.Spec.Egress)
+	// the policy left by a function which failed to be created is replaced
+	assert.NoError(t, CreateNetworkPolicy(policies, "hello", labels, f))
+
+	assert.NoError(t, DeleteNetworkPolicy(policies, "hello"))
+	_, err = policies.Get("hello", metav1.GetOptions{})
+	assert.Error(t, err)
+	assert.NoError(t, DeleteNetworkPolicy(policies, "hello"))
+}
+
+func TestOOMKilledPod(t *testing.T) {
+	start := time.Now()
+	killed := func(name string, finished time.Time) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "fakeNS", Labels: map[string]string{"function": "hello"}},
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{{
+					State: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+					LastTerminationState: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{Reason: oomKilledReason, FinishedAt: metav1.NewTime(finished)},
+					},
+				}},
+			},
+		}
+	}
+	pods := k8sFake.NewSimpleClientset(killed("before", start.Add(-time.Minute))).CoreV1().Pods("fakeNS")
+	// a pod killed before the run does not fail it
+	assert.Equal(t, "", OOMKilledPod(pods, map[string]string{"function": "hello"}, start))
+
+	pods = k8sFake.NewSimpleClientset(killed("during", start.Add(time.Second))).CoreV1().Pods("fakeNS")
+	assert.Equal(t, "during", OOMKilledPod(pods, map[string]string{"function": "hello"}, start))
+	assert.Equal(t, "", OOMKilledPod(pods, map[string]string{"function": "other"}, start))
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/vmware/dispatch/pkg/functions"
+	"github.com/vmware/dispatch/pkg/functions/k8s"
 	"github.com/vmware/dispatch/pkg/trace"
 	"github.com/vmware/dispatch/pkg/utils"
 	"k8s.io/api/core/v1"
@@ -27,7 +28,9 @@ import (
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	typedExtensionsv1beta1 "k8s.io/client-go/kubernetes/typed/extensions/v1beta1"
+	typedNetworkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -35,6 +38,9 @@ import (
 const (
 	jsonContentType = "application/json"
 
+	// functionLabel labels the pods of a function with the name of its Kubeless function, as Kubeless does
+	functionLabel = "function"
+
 	defaultCreateTimeout = 60 // seconds
 )
 
@@ -47,9 +53,11 @@ type Config struct {
 }
 
 type kubelessDriver struct {
-	deployments typedExtensionsv1beta1.DeploymentInterface
-	functions   kubelessv1beta1.FunctionInterface
-	fnNs        string
+	deployments     typedExtensionsv1beta1.DeploymentInterface
+	functions       kubelessv1beta1.FunctionInterface
+	pods            typedv1.PodInterface
+	networkPolicies typedNetworkingv1.NetworkPolicyInterface
+	fnNs            string
 
 	createTimeout   int
 	imagePullSecret string
@@ -75,6 +83,26 @@ func (err *systemError) StackTrace() errors.StackTrace {
 	return nil
 }
 
+type functionError struct {
+	Err error `json:"err"`
+}
+
+func (err *functionError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *functionError) AsFunctionErrorObject() interface{} {
+	return err
+}
+
+func (err *functionError) StackTrace() errors.StackTrace {
+	if e, ok := err.Err.(functions.StackTracer); ok {
+		return e.StackTrace()
+	}
+
+	return nil
+}
+
 // New creates a new Kubeless driver
 func New(config *Config) (functions.FaaSDriver, error) {
 	k8sConf, err := kubeClientConfig(config.K8sConfig)
@@ -90,10 +118,12 @@ func New(config *Config) (functions.FaaSDriver, error) {
 	}
 
 	d := &kubelessDriver{
-		deployments:   k8sClient.ExtensionsV1beta1().Deployments(fnNs),
-		functions:     kubelessCli.KubelessV1beta1().Functions(fnNs),
-		fnNs:          fnNs,
-		createTimeout: defaultCreateTimeout,
+		deployments:     k8sClient.ExtensionsV1beta1().Deployments(fnNs),
+		functions:       kubelessCli.KubelessV1beta1().Functions(fnNs),
+		pods:            k8sClient.CoreV1().Pods(fnNs),
+		networkPolicies: k8sClient.NetworkingV1().NetworkPolicies(fnNs),
+		fnNs:            fnNs,
+		createTimeout:   defaultCreateTimeout,
 	}
 	if config.CreateTimeout != nil {
 		d.createTimeout = *config.CreateTimeout
@@ -116,34 +146,59 @@ func getID(id string) string {
 	return fmt.Sprintf("kbls-%s", id)
 }
 
+// podLabels returns the labels of the pods of a function
+func podLabels(faasID string) map[string]string {
+	return map[string]string{functionLabel: getID(faasID)}
+}
+
 func (d *kubelessDriver) Create(ctx context.Context, f *functions.Function) error {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
 
+	template := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: podLabels(f.FaasID),
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Image: f.FunctionImageURL},
+			},
+		},
+	}
+	if err := k8s.ApplyFunctionSettings(&template, f); err != nil {
+		return err
+	}
+	// the network policy selects the pods before they start
+	if err := k8s.CreateNetworkPolicy(d.networkPolicies, getID(f.FaasID), podLabels(f.FaasID), f); err != nil {
+		return err
+	}
 	kf := v1beta1.Function{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: getID(f.FaasID),
+			Name:   getID(f.FaasID),
+			Labels: podLabels(f.FaasID),
 		},
 		Spec: v1beta1.FunctionSpec{
 			Deployment: extensionsv1beta1.Deployment{
 				Spec: extensionsv1beta1.DeploymentSpec{
-					Template: v1.PodTemplateSpec{
-						Spec: v1.PodSpec{
-							Containers: []v1.Container{
-								{Image: f.FunctionImageURL},
-							},
-						},
-					},
+					Template: template,
 				},
 			},
 		},
 	}
 	_, err := d.functions.Create(&kf)
 	if err != nil {
+		if err := k8s.DeleteNetworkPolicy(d.networkPolicies, getID(f.FaasID)); err != nil {
+			log.Warnf("Error deleting the network policy of function %s: %v", f.Name, err)
+		}
 		return err
 	}
 
 	// make sure the function has started
+	return d.waitAvailable(f)
+}
+
+// waitAvailable waits for a replica of the function deployment to be available
+func (d *kubelessDriver) waitAvailable(f *functions.Function) error {
 	return utils.Backoff(time.Duration(d.createTimeout)*time.Second, func() error {
 		deployment, err := d.deployments.Get(getID(f.FaasID), metav1.GetOptions{})
 		if err != nil {
@@ -158,6 +213,29 @@ func (d *kubelessDriver) Create(ctx context.Context, f *functions.Function) erro
 	})
 }
 
+// Scale sets the number of replicas of the function deployment, and waits for a replica to be available unless
+// replicas is 0
+func (d *kubelessDriver) Scale(ctx context.Context, f *functions.Function, replicas int) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	deployment, err := d.deployments.Get(getID(f.FaasID), metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to read function deployment: '%s'", f.Name)
+	}
+	n := int32(replicas)
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != n {
+		deployment.Spec.Replicas = &n
+		if _, err := d.deployments.Update(deployment); err != nil {
+			return errors.Wrapf(err, "failed to scale function deployment: '%s'", f.Name)
+		}
+	}
+	if replicas == 0 {
+		return nil
+	}
+	return d.waitAvailable(f)
+}
+
 func (d *kubelessDriver) Delete(ctx context.Context, f *functions.Function) error {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
@@ -165,17 +243,17 @@ func (d *kubelessDriver) Delete(ctx context.Context, f *functions.Function) erro
 	if err != nil && !k8sErrors.IsNotFound(err) {
 		return err
 	}
-	return nil
+	return k8s.DeleteNetworkPolicy(d.networkPolicies, getID(f.FaasID))
 }
 
-func (d *kubelessDriver) doHTTPReq(faasID string, body []byte) ([]byte, error) {
+func (d *kubelessDriver) doHTTPReq(ctx context.Context, faasID string, body []byte) ([]byte, error) {
 	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s.%s.svc.cluster.local:8080", getID(faasID), d.fnNs), bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create request %v", err)
 	}
 	req.Header.Add("Content-Type", jsonContentType)
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -191,18 +269,23 @@ func (d *kubelessDriver) doHTTPReq(faasID string, body []byte) ([]byte, error) {
 }
 
 func (d *kubelessDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
-	return func(ctx functions.Context, in interface{}) (interface{}, error) {
-		bytesIn, _ := json.Marshal(functions.Message{Context: ctx, Payload: in})
-		res, err := d.doHTTPReq(e.FaasID, bytesIn)
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		bytesIn, _ := json.Marshal(functions.Message{Context: fctx, Payload: in})
+		start := time.Now()
+		res, err := d.doHTTPReq(ctx, e.FaasID, bytesIn)
 		if err != nil {
+			// the pod serving the run may have been killed for exceeding its memory limit
+			if pod := k8s.OOMKilledPod(d.pods, podLabels(e.FaasID), start); pod != "" {
+				return nil, &functionError{errors.Errorf("function pod %s was killed: out of memory", pod)}
+			}
 			return nil, err
 		}
 		var out functions.Message
 		if err := json.Unmarshal(res, &out); err != nil {
 			return nil, &systemError{errors.Errorf("cannot JSON-parse result from OpenFaaS: %s %s", err, string(res))}
 		}
-		ctx.AddLogs(out.Context.Logs())
-		ctx.SetError(out.Context.GetError())
+		fctx.StreamLogs(ctx, out.Context.Logs())
+		fctx.SetError(out.Context.GetError())
 		return out.Payload, nil
 	}
 }
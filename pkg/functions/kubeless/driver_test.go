@@ -71,7 +71,7 @@ func TestOfDriver_GetRunnable(t *testing.T) {
 
 	f := d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef"})
 	ctx := functions.Context{}
-	r, err := f(ctx, map[string]interface{}{"name": "Me", "place": "Here"})
+	r, err := f(context.Background(), ctx, map[string]interface{}{"name": "Me", "place": "Here"})
 
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{"myField": "Hello, Me from Here"}, r)
@@ -108,3 +108,54 @@ func TestOfDriver_Delete(t *testing.T) {
 	err := d.Delete(context.Background(), &f)
 	assert.NoError(t, err)
 }
+
+func TestDriverCreateSandboxed(t *testing.T) {
+	f := functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name: "hello",
+			ID:   "deadbeef",
+		},
+		FaasID:           "cafe",
+		FunctionImageURL: "image",
+		Limits:           &functions.FunctionResources{Memory: "128Mi"},
+		Sandbox:          &functions.FunctionSandbox{ReadOnlyRootFS: true, NoNetwork: true},
+	}
+	deploymentObj := &extensionsv1beta1.Deployment{
+		ObjectMeta: k8sMetaV1.ObjectMeta{
+			Namespace: "fakeNS",
+			Name:      getID(f.FaasID),
+		},
+		Status: extensionsv1beta1.DeploymentStatus{
+			AvailableReplicas: 1,
+		},
+	}
+	clientSet := k8sFake.NewSimpleClientset(deploymentObj)
+	kubeCli := kubelessFake.NewSimpleClientset()
+	d := kubelessDriver{
+		createTimeout:   defaultCreateTimeout,
+		deployments:     clientSet.ExtensionsV1beta1().Deployments("fakeNS"),
+		functions:       kubeCli.KubelessV1beta1().Functions("fakeNS"),
+		networkPolicies: clientSet.NetworkingV1().NetworkPolicies("fakeNS"),
+	}
+
+	assert.NoError(t, d.Create(context.Background(), &f))
+	kf, err := d.functions.Get(getID(f.FaasID), k8sMetaV1.GetOptions{})
+	assert.NoError(t, err)
+	template := kf.Spec.Deployment.Spec.Template
+	assert.Equal(t, podLabels(f.FaasID), template.Labels)
+	c := template.Spec.Containers[0]
+	assert.Equal(t, "image", c.Image)
+	assert.Equal(t, "128Mi", c.Resources.Limits.Memory().String())
+	assert.True(t, *c.SecurityContext.ReadOnlyRootFilesystem)
+	policy, err := d.networkPolicies.Get(getID(f.FaasID), k8sMetaV1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, podLabels(f.FaasID), policy.Spec.PodSelector.MatchLabels)
+
+	assert.NoError(t, d.Delete(context.Background(), &f))
+	_, err = d.networkPolicies.Get(getID(f.FaasID), k8sMetaV1.GetOptions{})
+	assert.Error(t, err)
+
+	// settings kubernetes cannot enforce are rejected
+	f.Limits = &functions.FunctionResources{Pids: 100}
+	assert.Error(t, d.Create(context.Background(), &f))
+}
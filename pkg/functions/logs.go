@@ -0,0 +1,65 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// FramesContentType is the content type of function responses made of a sequence of JSON messages (frames), which
+// lets functions send their logs while they run. The logs of every frame are added to the run, the last frame holds
+// the output and the error of the function. A function response made of a single message is a single frame.
+const FramesContentType = "application/x-ndjson"
+
+// LogSink receives the lines logged by a function while it runs, stream is either stdout or stderr
+type LogSink func(stream, line string)
+
+type logSinkKey struct{}
+
+// WithLogSink returns a copy of ctx in which the lines logged by functions are sent to sink as they are written
+func WithLogSink(ctx context.Context, sink LogSink) context.Context {
+	return context.WithValue(ctx, logSinkKey{}, sink)
+}
+
+// LogSinkFrom returns the log sink of ctx, or nil if the logs of functions run with ctx are not streamed
+func LogSinkFrom(ctx context.Context) LogSink {
+	sink, _ := ctx.Value(logSinkKey{}).(LogSink)
+	return sink
+}
+
+// StreamLog sends a line logged by a function to the log sink of ctx, if any
+func StreamLog(ctx context.Context, stream, line string) {
+	if sink := LogSinkFrom(ctx); sink != nil {
+		sink(stream, line)
+	}
+}
+
+// ReadFrames reads the response of a function, made of one or more frames. The logs of each frame are recorded in
+// fctx and sent to the log sink of ctx as they are received. The last frame is returned.
+func ReadFrames(ctx context.Context, fctx Context, r io.Reader) (*Message, error) {
+	decoder := json.NewDecoder(r)
+	var last *Message
+	for {
+		frame := new(Message)
+		err := decoder.Decode(frame)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot JSON-parse frame of function response")
+		}
+		fctx.StreamLogs(ctx, frame.Context.Logs())
+		last = frame
+	}
+	if last == nil {
+		return nil, errors.New("empty function response")
+	}
+	return last, nil
+}
@@ -59,8 +59,8 @@ const xStderrHeader = "X-Stderr"
 
 // GetRunnable returns a functions.Runnable
 func (d *noopDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
-	return func(ctx functions.Context, in interface{}) (interface{}, error) {
-		return ctxAndIn{Context: ctx, Input: in}, nil
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		return ctxAndIn{Context: fctx, Input: in}, nil
 	}
 }
 
@@ -20,13 +20,17 @@ import (
 	"github.com/openfaas/faas/gateway/requests"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/typed/apps/v1beta1"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedNetworkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/vmware/dispatch/pkg/functions"
+	"github.com/vmware/dispatch/pkg/functions/k8s"
 	"github.com/vmware/dispatch/pkg/trace"
 	"github.com/vmware/dispatch/pkg/utils"
 )
@@ -34,6 +38,9 @@ import (
 const (
 	jsonContentType = "application/json"
 
+	// functionLabel labels the pods of a function with the name of its OpenFaaS function, as OpenFaaS does
+	functionLabel = "faas_function"
+
 	defaultCreateTimeout = 60 // seconds
 )
 
@@ -53,7 +60,9 @@ type ofDriver struct {
 
 	httpClient *http.Client
 
-	deployments v1beta1.DeploymentInterface
+	deployments     v1beta1.DeploymentInterface
+	pods            typedv1.PodInterface
+	networkPolicies typedNetworkingv1.NetworkPolicyInterface
 
 	createTimeout   int
 	imagePullSecret string
@@ -82,6 +91,26 @@ func (err *systemError) StackTrace() errors.StackTrace {
 	return nil
 }
 
+type functionError struct {
+	Err error `json:"err"`
+}
+
+func (err *functionError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *functionError) AsFunctionErrorObject() interface{} {
+	return err
+}
+
+func (err *functionError) StackTrace() errors.StackTrace {
+	if e, ok := err.Err.(functions.StackTracer); ok {
+		return e.StackTrace()
+	}
+
+	return nil
+}
+
 // New creates a new OpenFaaS driver
 func New(config *Config) (functions.FaaSDriver, error) {
 	k8sConf, err := kubeClientConfig(config.K8sConfig)
@@ -116,6 +145,8 @@ func New(config *Config) (functions.FaaSDriver, error) {
 		httpClient: http.DefaultClient,
 		// Use AppsV1beta1 until we remove support for Kubernetes 1.7
 		deployments:         k8sClient.AppsV1beta1().Deployments(fnNs),
+		pods:                k8sClient.CoreV1().Pods(fnNs),
+		networkPolicies:     k8sClient.NetworkingV1().NetworkPolicies(fnNs),
 		funcDefaultLimits:   funcDefaultLimits,
 		funcDefaultRequests: funcDefaultRequests,
 		createTimeout:       defaultCreateTimeout,
@@ -150,11 +181,49 @@ func (d *ofDriver) Create(ctx context.Context, f *functions.Function) error {
 		Limits:      d.funcDefaultLimits,
 		Requests:    d.funcDefaultRequests,
 	}
+	if f.Limits != nil && (f.Limits.CPU != "" || f.Limits.Memory != "") {
+		req.Limits = &requests.FunctionResources{
+			CPU:    f.Limits.CPU,
+			Memory: f.Limits.Memory,
+		}
+		if req.Limits.CPU == "" && d.funcDefaultLimits != nil {
+			req.Limits.CPU = d.funcDefaultLimits.CPU
+		}
+		if req.Limits.Memory == "" && d.funcDefaultLimits != nil {
+			req.Limits.Memory = d.funcDefaultLimits.Memory
+		}
+	}
+	// the gateway does not take the sandbox of the function, which is applied to its deployment once created. Settings
+	// Kubernetes cannot enforce are rejected before.
+	if err := k8s.ApplyFunctionSettings(&corev1.PodTemplateSpec{}, f); err != nil {
+		return err
+	}
+	// the network policy selects the pods before they start
+	if err := k8s.CreateNetworkPolicy(d.networkPolicies, getID(f.FaasID), podLabels(f.FaasID), f); err != nil {
+		return err
+	}
 	if d.imagePullSecret != "" {
 		req.Secrets = []string{d.imagePullSecret}
 	}
 
-	reqBytes, _ := json.Marshal(&req)
+	if err := d.deploy(f, &req); err != nil {
+		if err := k8s.DeleteNetworkPolicy(d.networkPolicies, getID(f.FaasID)); err != nil {
+			log.Warnf("Error deleting the network policy of function %s: %v", f.Name, err)
+		}
+		return err
+	}
+
+	if err := d.applySettings(f); err != nil {
+		return err
+	}
+
+	// make sure the function has started
+	return d.waitAvailable(f)
+}
+
+// deploy deploys a function with the gateway
+func (d *ofDriver) deploy(f *functions.Function, req *requests.CreateFunctionRequest) error {
+	reqBytes, _ := json.Marshal(req)
 	res, err := d.httpClient.Post(d.gateway+"/system/functions", jsonContentType, bytes.NewReader(reqBytes))
 	if err != nil {
 		return errors.Wrapf(err, "Error deploying function '%s'", f.ID)
@@ -164,7 +233,7 @@ func (d *ofDriver) Create(ctx context.Context, f *functions.Function) error {
 	log.Debugf("openfaas.Create.%s: status code: %v", f.ID, res.StatusCode)
 	switch res.StatusCode {
 	case 200, 201, 202:
-		// OK
+		return nil
 
 	default:
 		bytesOut, err := ioutil.ReadAll(res.Body)
@@ -173,15 +242,40 @@ func (d *ofDriver) Create(ctx context.Context, f *functions.Function) error {
 		}
 		return errors.Wrapf(err, "Error performing POST request, status: %v", res.StatusCode)
 	}
+}
 
-	// make sure the function has started
+// applySettings applies the limits and sandbox of a function to the pod template of its deployment
+func (d *ofDriver) applySettings(f *functions.Function) error {
+	if f.Limits == nil && f.Sandbox == nil {
+		return nil
+	}
+	return utils.Backoff(time.Duration(d.createTimeout)*time.Second, func() error {
+		deployment, err := d.deployments.Get(getID(f.FaasID), v1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to read function deployment: '%s'", getID(f.FaasID))
+		}
+		if err := k8s.ApplyFunctionSettings(&deployment.Spec.Template, f); err != nil {
+			return err
+		}
+		// the update fails if the deployment was updated meanwhile, e.g. by OpenFaaS
+		if _, err := d.deployments.Update(deployment); err != nil {
+			return errors.Wrapf(err, "failed to update function deployment: '%s'", getID(f.FaasID))
+		}
+		return nil
+	})
+}
+
+// waitAvailable waits for a replica of the function deployment to be available, and for the replicas of its former
+// pod template to be replaced
+func (d *ofDriver) waitAvailable(f *functions.Function) error {
 	return utils.Backoff(time.Duration(d.createTimeout)*time.Second, func() error {
 		deployment, err := d.deployments.Get(getID(f.FaasID), v1.GetOptions{})
 		if err != nil {
 			return errors.Wrapf(err, "failed to read function deployment status: '%s'", getID(f.FaasID))
 		}
 
-		if deployment.Status.AvailableReplicas > 0 {
+		status := deployment.Status
+		if status.ObservedGeneration >= deployment.Generation && status.UpdatedReplicas == status.Replicas && status.AvailableReplicas > 0 {
 			return nil
 		}
 
@@ -189,6 +283,29 @@ func (d *ofDriver) Create(ctx context.Context, f *functions.Function) error {
 	})
 }
 
+// Scale sets the number of replicas of the function deployment, and waits for a replica to be available unless
+// replicas is 0
+func (d *ofDriver) Scale(ctx context.Context, f *functions.Function, replicas int) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	deployment, err := d.deployments.Get(getID(f.FaasID), v1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to read function deployment: '%s'", getID(f.FaasID))
+	}
+	n := int32(replicas)
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != n {
+		deployment.Spec.Replicas = &n
+		if _, err := d.deployments.Update(deployment); err != nil {
+			return errors.Wrapf(err, "failed to scale function deployment: '%s'", getID(f.FaasID))
+		}
+	}
+	if replicas == 0 {
+		return nil
+	}
+	return d.waitAvailable(f)
+}
+
 func (d *ofDriver) Delete(ctx context.Context, f *functions.Function) error {
 	span, ctx := trace.Trace(ctx, "")
 	defer span.Finish()
@@ -206,7 +323,7 @@ func (d *ofDriver) Delete(ctx context.Context, f *functions.Function) error {
 	log.Debugf("openfaas.Delete.%s: status code: %v", f.ID, res.StatusCode)
 	switch res.StatusCode {
 	case 200, 201, 202, 404, 500:
-		return nil
+		return k8s.DeleteNetworkPolicy(d.networkPolicies, getID(f.FaasID))
 	default:
 		bytesOut, err := ioutil.ReadAll(res.Body)
 		if err == nil {
@@ -219,11 +336,20 @@ func (d *ofDriver) Delete(ctx context.Context, f *functions.Function) error {
 const xStderrHeader = "X-Stderr"
 
 func (d *ofDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
-	return func(ctx functions.Context, in interface{}) (interface{}, error) {
-		bytesIn, _ := json.Marshal(functions.Message{Context: ctx, Payload: in})
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		bytesIn, _ := json.Marshal(functions.Message{Context: fctx, Payload: in})
 		postURL := d.gateway + "/function/" + getID(e.FaasID)
-		res, err := d.httpClient.Post(postURL, jsonContentType, bytes.NewReader(bytesIn))
+		req, err := http.NewRequest("POST", postURL, bytes.NewReader(bytesIn))
+		if err != nil {
+			return nil, &systemError{errors.Wrapf(err, "invalid OpenFaaS URL %s", postURL)}
+		}
+		req.Header.Set("Content-Type", jsonContentType)
+		start := time.Now()
+		res, err := d.httpClient.Do(req.WithContext(ctx))
 		if err != nil {
+			if err := d.podFailure(e.FaasID, start); err != nil {
+				return nil, err
+			}
 			log.Errorf("Error when sending POST request to %s: %+v", postURL, err)
 			return nil, &systemError{errors.Wrapf(err, "request to OpenFaaS on %s failed", d.gateway)}
 		}
@@ -240,11 +366,14 @@ func (d *ofDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnabl
 			if err := json.Unmarshal(resBytes, &out); err != nil {
 				return nil, &systemError{errors.Errorf("cannot JSON-parse result from OpenFaaS: %s %s", err, string(resBytes))}
 			}
-			ctx.AddLogs(out.Context.Logs())
-			ctx.SetError(out.Context.GetError())
+			fctx.StreamLogs(ctx, out.Context.Logs())
+			fctx.SetError(out.Context.GetError())
 			return out.Payload, nil
 
 		default:
+			if err := d.podFailure(e.FaasID, start); err != nil {
+				return nil, err
+			}
 			bytesOut, err := ioutil.ReadAll(res.Body)
 			if err == nil {
 				return nil, &systemError{errors.Errorf("Server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))}
@@ -254,10 +383,24 @@ func (d *ofDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnabl
 	}
 }
 
+// podFailure returns the error of a function which failed to serve a request since start, if its pod was killed for
+// exceeding its memory limit
+func (d *ofDriver) podFailure(faasID string, start time.Time) error {
+	if pod := k8s.OOMKilledPod(d.pods, podLabels(faasID), start); pod != "" {
+		return &functionError{errors.Errorf("function pod %s was killed: out of memory", pod)}
+	}
+	return nil
+}
+
 func getID(id string) string {
 	return fmt.Sprintf("of-%s", id)
 }
 
+// podLabels returns the labels of the pods of a function
+func podLabels(faasID string) map[string]string {
+	return map[string]string{functionLabel: getID(faasID)}
+}
+
 func logsReader(res *http.Response) io.Reader {
 	bs := base64Decode(res.Header.Get(xStderrHeader))
 	return bytes.NewReader(bs)
@@ -11,10 +11,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	k8sMetaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sFake "k8s.io/client-go/kubernetes/fake"
 
@@ -74,14 +76,67 @@ func TestOfDriverCreate(t *testing.T) {
 	fakeDeployments := fakeAppsV1beta1.Deployments("fakeNS")
 
 	d := ofDriver{
-		gateway:       testHttpserver.URL,
-		httpClient:    testHttpserver.Client(),
-		createTimeout: defaultCreateTimeout,
-		deployments:   fakeDeployments,
+		gateway:         testHttpserver.URL,
+		httpClient:      testHttpserver.Client(),
+		createTimeout:   defaultCreateTimeout,
+		deployments:     fakeDeployments,
+		networkPolicies: clientSet.NetworkingV1().NetworkPolicies("fakeNS"),
 	}
 
 	err := d.Create(context.Background(), &f)
 	assert.NoError(t, err)
+
+	// the sandbox is applied to the deployment, and the network policy denies network access to its pods
+	f.Sandbox = &functions.FunctionSandbox{ReadOnlyRootFS: true, NoNetwork: true}
+	deploymentObj.Spec.Template.Spec.Containers = []corev1.Container{{Image: f.FunctionImageURL}}
+	_, err = fakeDeployments.Update(deploymentObj)
+	assert.NoError(t, err)
+	err = d.Create(context.Background(), &f)
+	assert.NoError(t, err)
+	deployment, err := fakeDeployments.Get(getID(f.FaasID), k8sMetaV1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, *deployment.Spec.Template.Spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem)
+	policy, err := d.networkPolicies.Get(getID(f.FaasID), k8sMetaV1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, podLabels(f.FaasID), policy.Spec.PodSelector.MatchLabels)
+
+	// settings kubernetes cannot enforce are rejected
+	f.Sandbox = nil
+	f.Limits = &functions.FunctionResources{Pids: 100}
+	err = d.Create(context.Background(), &f)
+	assert.Error(t, err)
+}
+
+func TestOfDriverGetRunnableOOMKilled(t *testing.T) {
+	start := time.Now()
+	testHttpserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer testHttpserver.Close()
+	pod := &corev1.Pod{
+		ObjectMeta: k8sMetaV1.ObjectMeta{Name: "hello-pod", Namespace: "fakeNS", Labels: podLabels("cafe")},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", FinishedAt: k8sMetaV1.NewTime(start)},
+				},
+			}},
+		},
+	}
+	d := ofDriver{
+		gateway:    testHttpserver.URL,
+		httpClient: testHttpserver.Client(),
+		pods:       k8sFake.NewSimpleClientset(pod).CoreV1().Pods("fakeNS"),
+	}
+
+	run := d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef", FaasID: "cafe"})
+	_, err := run(context.Background(), functions.Context{}, nil)
+	assert.IsType(t, &functionError{}, err)
+
+	// a function whose pod was not killed failed for another reason
+	run = d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef", FaasID: "other"})
+	_, err = run(context.Background(), functions.Context{}, nil)
+	assert.IsType(t, &systemError{}, err)
 }
 
 func TestOfDriver_GetRunnable(t *testing.T) {
@@ -91,7 +146,7 @@ func TestOfDriver_GetRunnable(t *testing.T) {
 
 	f := d.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef"})
 	ctx := functions.Context{}
-	r, err := f(ctx, map[string]interface{}{"name": "Me", "place": "Here"})
+	r, err := f(context.Background(), ctx, map[string]interface{}{"name": "Me", "place": "Here"})
 
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{"myField": "Hello, Me from Here"}, r)
@@ -129,3 +184,38 @@ func TestOfDriver_Delete(t *testing.T) {
 	err := d.Delete(context.Background(), &f)
 	assert.NoError(t, err)
 }
+
+func TestOfDriverScale(t *testing.T) {
+	f := functions.Function{
+		BaseEntity: entitystore.BaseEntity{
+			Name: "hello",
+			ID:   "deadbeef",
+		},
+	}
+
+	deploymentObj := &v1beta1.Deployment{
+		ObjectMeta: k8sMetaV1.ObjectMeta{
+			Namespace: "fakeNS",
+			Name:      getID(f.FaasID),
+		},
+		Status: v1beta1.DeploymentStatus{
+			AvailableReplicas: 1,
+		},
+	}
+	fakeDeployments := k8sFake.NewSimpleClientset(deploymentObj).AppsV1beta1().Deployments("fakeNS")
+
+	d := ofDriver{
+		createTimeout: defaultCreateTimeout,
+		deployments:   fakeDeployments,
+	}
+
+	assert.NoError(t, d.Scale(context.Background(), &f, 0))
+	deployment, err := fakeDeployments.Get(getID(f.FaasID), k8sMetaV1.GetOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, *deployment.Spec.Replicas)
+
+	assert.NoError(t, d.Scale(context.Background(), &f, 2))
+	deployment, err = fakeDeployments.Get(getID(f.FaasID), k8sMetaV1.GetOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, *deployment.Spec.Replicas)
+}
@@ -86,11 +86,25 @@ type ctxAndIn struct {
 }
 
 func (d *wskDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
-	return func(ctx functions.Context, in interface{}) (interface{}, error) {
-		result, _, err := d.client.Actions.Invoke(e.FunctionID, ctxAndIn{Context: ctx, Input: in}, true, true)
-		if err != nil {
-			return nil, &systemError{errors.Wrapf(err, "openwhisk: error invoking function: '%s', runID: '%s'", e.FunctionID, e.RunID)} // TODO err should be JSON-serializable and usable (e.g. invalid arg vs runtime error)
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		// the openwhisk client cannot be cancelled, stop waiting for its response once ctx is done
+		type response struct {
+			result map[string]interface{}
+			err    error
+		}
+		resChan := make(chan response, 1)
+		go func() {
+			result, _, err := d.client.Actions.Invoke(e.FunctionID, ctxAndIn{Context: fctx, Input: in}, true, true)
+			resChan <- response{result, err}
+		}()
+		select {
+		case res := <-resChan:
+			if res.err != nil {
+				return nil, &systemError{errors.Wrapf(res.err, "openwhisk: error invoking function: '%s', runID: '%s'", e.FunctionID, e.RunID)} // TODO err should be JSON-serializable and usable (e.g. invalid arg vs runtime error)
+			}
+			return res.result, nil
+		case <-ctx.Done():
+			return nil, &systemError{errors.Wrapf(ctx.Err(), "openwhisk: error invoking function: '%s', runID: '%s'", e.FunctionID, e.RunID)}
 		}
-		return result, nil
 	}
 }
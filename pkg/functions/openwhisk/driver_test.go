@@ -31,7 +31,7 @@ func init() {
 func TestWskDriver_GetRunnable(t *testing.T) {
 	dev.EnsureLocal(t)
 	f := driver.GetRunnable(&functions.FunctionExecution{FunctionID: "deadbeef"})
-	r, err := f(functions.Context{}, map[string]interface{}{})
+	r, err := f(context.Background(), functions.Context{}, map[string]interface{}{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{"myField": "Hello, Noone from Nowhere"}, r)
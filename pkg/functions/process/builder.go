@@ -0,0 +1,148 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/functions"
+	"github.com/vmware/dispatch/pkg/trace"
+	"github.com/vmware/dispatch/pkg/utils"
+)
+
+// ImageBuilder lays out functions on the local filesystem instead of building docker images: the "image" of a
+// function is a directory holding its source code and the function template of its image, which is the directory
+// the image URL points to.
+type ImageBuilder struct {
+	// FunctionsDir is the directory the function directories are created in.
+	FunctionsDir string
+}
+
+// NewImageBuilder is the constructor for the ImageBuilder
+func NewImageBuilder(functionsDir string) *ImageBuilder {
+	return &ImageBuilder{FunctionsDir: functionsDir}
+}
+
+// BuildImage creates the directory of a function, and returns its path
+func (ib *ImageBuilder) BuildImage(ctx context.Context, f *functions.Function, code []byte) (string, error) {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if err := os.MkdirAll(ib.FunctionsDir, 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to create functions dir '%s'", ib.FunctionsDir)
+	}
+	// every build gets its own directory, the previous one may still be used by a running process
+	dir, err := ioutil.TempDir(ib.FunctionsDir, "func-"+f.FaasID+"-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create function dir")
+	}
+	log.Debugf("Building function '%s' in %s", f.Name, dir)
+
+	if err := writeSourceDir(dir, code); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	if err := copyDir(dir, f.ImageURL); err != nil {
+		os.RemoveAll(dir)
+		return "", errors.Wrapf(err, "failed to copy function template '%s'", f.ImageURL)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Entrypoint)); err != nil {
+		os.RemoveAll(dir)
+		return "", errors.Wrapf(err, "function template '%s' has no %s executable", f.ImageURL, Entrypoint)
+	}
+	return dir, nil
+}
+
+// RemoveImage removes the directory of a function
+func (ib *ImageBuilder) RemoveImage(ctx context.Context, f *functions.Function) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if f.FunctionImageURL == "" || filepath.Dir(f.FunctionImageURL) != filepath.Clean(ib.FunctionsDir) {
+		return errors.Errorf("directory '%s' of function %s is not in the functions dir", f.FunctionImageURL, f.Name)
+	}
+	if err := os.RemoveAll(f.FunctionImageURL); err != nil {
+		return errors.Wrapf(err, "failed to delete directory of function %s", f.Name)
+	}
+	return nil
+}
+
+func writeSourceDir(destDir string, code []byte) error {
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return errors.Wrapf(err, "failed to read gzip stream, writing source dir to '%s'", destDir)
+	}
+	err = utils.Untar(destDir, "/", gr)
+	return errors.Wrapf(err, "failed to untar, writing source dir to '%s'", destDir)
+}
+
+// copyDir copies the content of src into dst, keeping the file modes
+func copyDir(dst, src string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, strings.TrimPrefix(path, src))
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// TemplateImages serves the function templates of a directory as images: the image named name is ready if Dir has a
+// name sub-directory, which is used as the function template of the image.
+type TemplateImages struct {
+	Dir string
+}
+
+// GetImage returns the image of a function template
+func (t *TemplateImages) GetImage(ctx context.Context, organizationID string, imageName string) (*v1.Image, error) {
+	if imageName == "" || filepath.Base(imageName) != imageName {
+		return nil, errors.Errorf("invalid function template name '%s'", imageName)
+	}
+	dir, err := filepath.Abs(filepath.Join(t.Dir, imageName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid function template dir for '%s'", imageName)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "function template '%s' not found", imageName)
+	}
+	if !info.IsDir() {
+		return nil, errors.Errorf("function template '%s' is not a directory", dir)
+	}
+	return &v1.Image{
+		Name:      swag.String(imageName),
+		DockerURL: dir,
+		Status:    v1.StatusREADY,
+	}, nil
+}
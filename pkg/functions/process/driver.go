@@ -0,0 +1,355 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/functions"
+	"github.com/vmware/dispatch/pkg/trace"
+)
+
+const (
+	jsonContentType     = "application/json"
+	healthcheckEndpoint = "/healthz"
+	healthcheckInterval = 100 * time.Millisecond
+	defaultHost         = "127.0.0.1"
+	defaultStartTimeout = 60 * time.Second
+	stopTimeout         = 5 * time.Second
+
+	// Entrypoint is the executable of a function directory which starts the function server. It is run in the
+	// function directory, with the PORT the server must listen on and the HANDLER of the function in its environment.
+	// It should exec the server, which is killed when the function is deleted.
+	Entrypoint = "run"
+)
+
+// inheritedEnv are the variables of the environment of Dispatch passed to function processes, which get no other
+var inheritedEnv = []string{"PATH", "HOME", "TMPDIR"}
+
+// Driver implements a FaaSDriver running each function as a child process of Dispatch, from the directory laid out
+// by the ImageBuilder of this package. It requires neither Docker nor Kubernetes, and is meant for development and
+// CI: functions are neither isolated nor limited, and their processes are not restarted after Dispatch restarts.
+// Functions with limits or a sandbox are rejected. See examples/process-templates for the function templates.
+type Driver struct {
+	// Host is the address the function servers listen on.
+	Host string
+	// StartTimeout is how long a function server may take to become healthy.
+	StartTimeout time.Duration
+
+	mu        sync.Mutex
+	processes map[string]*process
+}
+
+// process is a running function server
+type process struct {
+	f   *functions.Function
+	cmd *exec.Cmd
+	url string
+
+	recycle sync.Once
+
+	// exited is closed once the process exited, with err
+	exited chan struct{}
+	err    error
+}
+
+// New creates a new process driver
+func New() *Driver {
+	return &Driver{
+		Host:         defaultHost,
+		StartTimeout: defaultStartTimeout,
+		processes:    make(map[string]*process),
+	}
+}
+
+// Create starts the process of a function, replacing the process of its previous revision
+func (d *Driver) Create(ctx context.Context, f *functions.Function) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if l := f.Limits; l != nil && (l.CPU != "" || l.Memory != "" || l.Pids > 0) {
+		return errors.Errorf("invalid limits for function %s: the process driver does not limit functions", f.Name)
+	}
+	if s := f.Sandbox; s != nil && (s.ReadOnlyRootFS || len(s.DropCapabilities) > 0 || s.SeccompProfile != "" || s.NoNetwork) {
+		return errors.Errorf("invalid sandbox for function %s: the process driver does not support sandboxing", f.Name)
+	}
+
+	p, err := d.start(ctx, f)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	previous := d.processes[f.ID]
+	d.processes[f.ID] = p
+	d.mu.Unlock()
+	if previous != nil {
+		previous.stop()
+	}
+	return nil
+}
+
+// Delete stops the process of a function
+func (d *Driver) Delete(ctx context.Context, f *functions.Function) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	d.mu.Lock()
+	p := d.processes[f.ID]
+	delete(d.processes, f.ID)
+	d.mu.Unlock()
+	if p != nil {
+		p.stop()
+	}
+	return nil
+}
+
+// Scale starts the process of a function if replicas is positive, and stops it otherwise. A function has a single
+// process, which serves all its runs.
+func (d *Driver) Scale(ctx context.Context, f *functions.Function, replicas int) error {
+	span, ctx := trace.Trace(ctx, "")
+	defer span.Finish()
+
+	if replicas == 0 {
+		return d.Delete(ctx, f)
+	}
+	d.mu.Lock()
+	p := d.processes[f.ID]
+	d.mu.Unlock()
+	if p != nil && !p.hasExited() {
+		return nil
+	}
+	return d.Create(ctx, f)
+}
+
+// start runs the function server of a function and waits for it to be healthy
+func (d *Driver) start(ctx context.Context, f *functions.Function) (*process, error) {
+	dir := f.FunctionImageURL
+	port, err := freePort(d.Host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "no port available for function %s", f.Name)
+	}
+
+	cmd := exec.Command(filepath.Join(dir, Entrypoint))
+	cmd.Dir = dir
+	cmd.Env = []string{"HANDLER=" + f.Handler, "PORT=" + strconv.Itoa(port)}
+	for _, name := range inheritedEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			cmd.Env = append(cmd.Env, name+"="+value)
+		}
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error starting process of function %s", f.Name)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error starting process of function %s", f.Name)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "error starting process of function %s in %s", f.Name, dir)
+	}
+
+	p := &process{
+		f:      f,
+		cmd:    cmd,
+		url:    fmt.Sprintf("http://%s", net.JoinHostPort(d.Host, strconv.Itoa(port))),
+		exited: make(chan struct{}),
+	}
+	var output sync.WaitGroup
+	output.Add(2)
+	go readOutput(&output, f.Name, v1.LogLineStreamStdout, stdout)
+	go readOutput(&output, f.Name, v1.LogLineStreamStderr, stderr)
+	go func() {
+		// the pipes must be read until the end before waiting for the process
+		output.Wait()
+		p.err = cmd.Wait()
+		log.Debugf("Process of function %s exited: %v", f.Name, p.err)
+		close(p.exited)
+	}()
+
+	if err := d.waitHealthy(ctx, f, p); err != nil {
+		p.stop()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (d *Driver) waitHealthy(ctx context.Context, f *functions.Function, p *process) error {
+	deadline := time.NewTimer(d.StartTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(healthcheckInterval)
+	defer ticker.Stop()
+	for {
+		res, err := http.Get(p.url + healthcheckEndpoint)
+		if err == nil {
+			res.Body.Close()
+			if res.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = errors.Errorf("incorrect status code %d", res.StatusCode)
+		}
+		select {
+		case <-p.exited:
+			return errors.Errorf("process of function %s exited before it was healthy: %v", f.Name, p.err)
+		case <-deadline.C:
+			return errors.Wrapf(err, "error when checking health of function %s", f.Name)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetRunnable creates runnable representation of the function
+func (d *Driver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
+		bytesIn, _ := json.Marshal(functions.Message{Context: fctx, Payload: in})
+
+		d.mu.Lock()
+		p := d.processes[e.FunctionID]
+		d.mu.Unlock()
+		if p == nil {
+			return nil, &systemError{errors.Errorf("missing process for function %s", e.FunctionID)}
+		}
+		if p.hasExited() {
+			return nil, &systemError{errors.Errorf("process of function %s exited: %v", e.FunctionID, p.err)}
+		}
+		defer func() {
+			// the process may still be running the function if it timed out, e.g. if it hangs, it is replaced by a new one.
+			// A cancelled run, e.g. by its user, says nothing about the health of the process, which keeps serving.
+			if ctx.Err() == context.DeadlineExceeded {
+				p.recycle.Do(func() { go d.recycle(p, ctx.Err()) })
+			}
+		}()
+		postURL := p.url + "/"
+		req, err := http.NewRequest("POST", postURL, bytes.NewReader(bytesIn))
+		if err != nil {
+			return nil, &systemError{errors.Wrapf(err, "invalid function URL %s", postURL)}
+		}
+		req.Header.Set("Content-Type", jsonContentType)
+		req.Header.Set("Accept", functions.FramesContentType+", "+jsonContentType)
+		res, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, &systemError{errors.Wrapf(err, "request to function process on %s failed", postURL)}
+		}
+		defer res.Body.Close()
+
+		log.Debugf("process.run.%s: status code: %v", e.FunctionID, res.StatusCode)
+		if res.StatusCode != http.StatusOK {
+			bytesOut, err := ioutil.ReadAll(res.Body)
+			if err == nil {
+				return nil, &systemError{errors.Errorf("Server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))}
+			}
+			return nil, &systemError{errors.Wrapf(err, "Error performing request, status: %v", res.StatusCode)}
+		}
+		out, err := functions.ReadFrames(ctx, fctx, res.Body)
+		if err != nil {
+			return nil, &systemError{errors.Wrapf(err, "cannot read result from function process on URL: %s", postURL)}
+		}
+		fctx.SetError(out.Context.GetError())
+		return out.Payload, nil
+	}
+}
+
+// recycle stops the process of a function and starts a new one, unless the function was deleted or updated meanwhile.
+// The runs served by the process fail until the new process is healthy.
+func (d *Driver) recycle(p *process, reason error) {
+	log.Warnf("Restarting process %d of function %s: %v", p.cmd.Process.Pid, p.f.Name, reason)
+	p.stop()
+	restarted, err := d.start(context.Background(), p.f)
+	if err != nil {
+		log.Errorf("Error restarting process of function %s: %+v", p.f.Name, err)
+		return
+	}
+	d.mu.Lock()
+	current := d.processes[p.f.ID] == p
+	if current {
+		d.processes[p.f.ID] = restarted
+	}
+	d.mu.Unlock()
+	if !current {
+		restarted.stop()
+	}
+}
+
+func (p *process) hasExited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *process) stop() {
+	if err := p.cmd.Process.Kill(); err != nil && !p.hasExited() {
+		log.Warnf("Error killing function process %d: %v", p.cmd.Process.Pid, err)
+	}
+	select {
+	case <-p.exited:
+	case <-time.After(stopTimeout):
+		// the output of the process is still open, e.g. held by a child of the process
+		log.Warnf("Function process %d did not exit after it was killed", p.cmd.Process.Pid)
+	}
+}
+
+// readOutput logs the output of the process. It is not attributed to runs, which send their logs in the frames of
+// their response.
+func readOutput(wg *sync.WaitGroup, name, stream string, r io.Reader) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Debugf("function %s %s: %s", name, stream, scanner.Text())
+	}
+}
+
+// freePort returns a port host can be listened on
+func freePort(host string) (int, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+type systemError struct {
+	Err error `json:"err"`
+}
+
+func (err *systemError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *systemError) AsSystemErrorObject() interface{} {
+	return err
+}
+
+func (err *systemError) StackTrace() errors.StackTrace {
+	if e, ok := err.Err.(functions.StackTracer); ok {
+		return e.StackTrace()
+	}
+
+	return nil
+}
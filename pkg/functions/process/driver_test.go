@@ -0,0 +1,194 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/dispatch/pkg/api/v1"
+	"github.com/vmware/dispatch/pkg/entity-store"
+	"github.com/vmware/dispatch/pkg/functions"
+)
+
+const (
+	helperEnv = "DISPATCH_TEST_FUNCTION_SERVER"
+	// leakedEnv is set in the environment of the tests, it must not be passed to function processes
+	leakedEnv = "DISPATCH_TEST_LEAKED"
+)
+
+// TestFunctionServer is not a test: it is the function server run by the template of the tests
+func TestFunctionServer(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+	source, _ := ioutil.ReadFile("greeting.txt")
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var in functions.Message
+		json.NewDecoder(r.Body).Decode(&in)
+		fmt.Println("running", os.Getenv("HANDLER"))
+		if in.Payload == "hang" {
+			select {}
+		}
+		json.NewEncoder(w).Encode(functions.Message{
+			Context: in.Context,
+			Payload: map[string]interface{}{"greeting": string(source), "payload": in.Payload, "leaked": os.Getenv(leakedEnv)},
+		})
+	})
+	http.ListenAndServe("127.0.0.1:"+os.Getenv("PORT"), nil)
+	os.Exit(0)
+}
+
+// templateDir creates a function template running TestFunctionServer
+func templateDir(t *testing.T, root string) string {
+	dir := filepath.Join(root, "templates", "test")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	script := fmt.Sprintf("#!/bin/sh\n%s=1 exec %s -test.run=TestFunctionServer\n", helperEnv, os.Args[0])
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, Entrypoint), []byte(script), 0755))
+	return dir
+}
+
+func sourceCode(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestDriver(t *testing.T) {
+	root, err := ioutil.TempDir("", "process-driver-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	templateDir(t, root)
+
+	images := &TemplateImages{Dir: filepath.Join(root, "templates")}
+	img, err := images.GetImage(context.Background(), "testOrg", "test")
+	require.NoError(t, err)
+	assert.Equal(t, v1.StatusREADY, img.Status)
+	_, err = images.GetImage(context.Background(), "testOrg", "missing")
+	assert.Error(t, err)
+	_, err = images.GetImage(context.Background(), "testOrg", "../templates")
+	assert.Error(t, err)
+
+	f := &functions.Function{
+		BaseEntity: entitystore.BaseEntity{ID: "fn-id", Name: "hello"},
+		FaasID:     "faas-id",
+		Handler:    "hello.handle",
+		ImageURL:   img.DockerURL,
+	}
+	builder := NewImageBuilder(filepath.Join(root, "functions"))
+	f.FunctionImageURL, err = builder.BuildImage(context.Background(), f, sourceCode(t, map[string]string{"greeting.txt": "hello"}))
+	require.NoError(t, err)
+
+	os.Setenv(leakedEnv, "leaked")
+	defer os.Unsetenv(leakedEnv)
+
+	d := New()
+	require.NoError(t, d.Create(context.Background(), f))
+	defer d.Delete(context.Background(), f)
+
+	ctx := context.Background()
+	run := d.GetRunnable(&functions.FunctionExecution{FunctionID: f.ID})
+	out, err := run(ctx, functions.Context{}, map[string]interface{}{"name": "Jon"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"greeting": "hello", "payload": map[string]interface{}{"name": "Jon"}, "leaked": ""}, out)
+
+	// a cancelled run does not restart the process
+	d.mu.Lock()
+	hung := d.processes[f.ID]
+	d.mu.Unlock()
+	cancelCtx, cancelRun := context.WithCancel(ctx)
+	time.AfterFunc(100*time.Millisecond, cancelRun)
+	_, err = run(cancelCtx, functions.Context{}, "hang")
+	assert.Error(t, err)
+	time.Sleep(100 * time.Millisecond)
+	d.mu.Lock()
+	assert.True(t, d.processes[f.ID] == hung)
+	d.mu.Unlock()
+	assert.False(t, hung.hasExited())
+
+	// a process which hangs is restarted once the run timed out
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	_, err = run(timeoutCtx, functions.Context{}, "hang")
+	cancel()
+	assert.Error(t, err)
+	select {
+	case <-hung.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hung process not stopped")
+	}
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if _, err = run(ctx, functions.Context{}, nil); err == nil {
+			break
+		}
+	}
+	assert.NoError(t, err)
+
+	require.NoError(t, d.Scale(context.Background(), f, 0))
+	_, err = run(ctx, functions.Context{}, nil)
+	assert.Error(t, err)
+
+	require.NoError(t, d.Scale(context.Background(), f, 1))
+	_, err = run(ctx, functions.Context{}, nil)
+	assert.NoError(t, err)
+
+	require.NoError(t, d.Delete(context.Background(), f))
+	require.NoError(t, builder.RemoveImage(context.Background(), f))
+	_, err = os.Stat(f.FunctionImageURL)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDriverCreateUnsupported(t *testing.T) {
+	d := New()
+	err := d.Create(context.Background(), &functions.Function{
+		BaseEntity: entitystore.BaseEntity{ID: "fn-id", Name: "limited"},
+		Limits:     &functions.FunctionResources{Memory: "64Mi"},
+	})
+	assert.Error(t, err)
+	err = d.Create(context.Background(), &functions.Function{
+		BaseEntity: entitystore.BaseEntity{ID: "fn-id", Name: "sandboxed"},
+		Sandbox:    &functions.FunctionSandbox{NoNetwork: true},
+	})
+	assert.Error(t, err)
+	assert.Empty(t, d.processes)
+}
+
+func TestDriverCreateUnhealthy(t *testing.T) {
+	root, err := ioutil.TempDir("", "process-driver-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, Entrypoint), []byte("#!/bin/sh\nexit 1\n"), 0755))
+
+	f := &functions.Function{
+		BaseEntity:       entitystore.BaseEntity{ID: "fn-id", Name: "broken"},
+		FunctionImageURL: root,
+	}
+	err = New().Create(context.Background(), f)
+	assert.Error(t, err)
+}
@@ -102,24 +102,40 @@ func (d *riffDriver) Delete(ctx context.Context, f *functions.Function) error {
 }
 
 func (d *riffDriver) GetRunnable(e *functions.FunctionExecution) functions.Runnable {
-	return func(ctx functions.Context, in interface{}) (interface{}, error) {
+	return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
 
-		bytesIn, _ := json.Marshal(functions.Message{Context: ctx, Payload: in})
+		bytesIn, _ := json.Marshal(functions.Message{Context: fctx, Payload: in})
 		topic := fnID(e.FaasID)
 
 		log.Debugf("Posting to topic '%s': '%s'", topic, string(bytesIn))
 
-		resBytes, err := d.requester.Request(topic, e.RunID, bytesIn)
-		if err != nil {
-			return nil, &systemError{errors.Wrapf(err, "riff: error invoking function: '%s', runID: '%s'", e.FunctionID, e.RunID)}
+		// the requester cannot be cancelled, stop waiting for its response once ctx is done
+		type response struct {
+			payload []byte
+			err     error
+		}
+		resChan := make(chan response, 1)
+		go func() {
+			resBytes, err := d.requester.Request(topic, e.RunID, bytesIn)
+			resChan <- response{resBytes, err}
+		}()
+		var resBytes []byte
+		select {
+		case res := <-resChan:
+			if res.err != nil {
+				return nil, &systemError{errors.Wrapf(res.err, "riff: error invoking function: '%s', runID: '%s'", e.FunctionID, e.RunID)}
+			}
+			resBytes = res.payload
+		case <-ctx.Done():
+			return nil, &systemError{errors.Wrapf(ctx.Err(), "riff: error invoking function: '%s', runID: '%s'", e.FunctionID, e.RunID)}
 		}
 
 		var out functions.Message
 		if err := json.Unmarshal(resBytes, &out); err != nil {
 			return nil, &systemError{errors.Errorf("cannot JSON-parse result from riff: %s %s", err, string(resBytes))}
 		}
-		ctx.AddLogs(out.Context.Logs())
-		ctx.SetError(out.Context.GetError())
+		fctx.StreamLogs(ctx, out.Context.Logs())
+		fctx.SetError(out.Context.GetError())
 		return out.Payload, nil
 	}
 }
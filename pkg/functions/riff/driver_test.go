@@ -45,7 +45,7 @@ func TestDriver_GetRunnable(t *testing.T) {
 
 	f := d.GetRunnable(&functions.FunctionExecution{FunctionID: funID})
 	ctx := functions.Context{}
-	r, err := f(ctx, map[string]interface{}{"name": "Noone", "place": "Braavos"})
+	r, err := f(context.Background(), ctx, map[string]interface{}{"name": "Noone", "place": "Braavos"})
 
 	require.NoError(t, err)
 	assert.Equal(t, map[string]interface{}{"greeting": "Hello, Noone from Braavos"}, r)
@@ -6,6 +6,10 @@
 package runner
 
 import (
+	"context"
+
+	"github.com/pkg/errors"
+
 	"github.com/vmware/dispatch/pkg/functions"
 )
 
@@ -26,7 +30,9 @@ func New(config *Config) functions.Runner {
 	return &impl{*config}
 }
 
-func (r *impl) Run(fn *functions.FunctionExecution, in interface{}) (interface{}, error) {
+// Run runs a function until it returns or ctx is done. Functions stopped by the deadline of ctx fail with a
+// functions.TimeoutError.
+func (r *impl) Run(ctx context.Context, fn *functions.FunctionExecution, in interface{}) (interface{}, error) {
 	f := r.Faas.GetRunnable(fn)
 
 	m := Compose(
@@ -35,7 +41,31 @@ func (r *impl) Run(fn *functions.FunctionExecution, in interface{}) (interface{}
 		r.ServiceInjector.GetMiddleware(fn.OrganizationID, fn.Services, fn.Cookie),
 	)
 
-	return m(f)(fn.Context, in)
+	out, err := m(f)(ctx, fn.Context, in)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, &timeoutError{errors.Wrapf(err, "function %s timed out", fn.FunctionID)}
+	}
+	return out, err
+}
+
+type timeoutError struct {
+	Err error `json:"err"`
+}
+
+func (err *timeoutError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *timeoutError) AsTimeoutErrorObject() interface{} {
+	return err
+}
+
+func (err *timeoutError) StackTrace() errors.StackTrace {
+	if e, ok := err.Err.(functions.StackTracer); ok {
+		return e.StackTrace()
+	}
+
+	return nil
 }
 
 // Compose applies middleware so that:
@@ -5,6 +5,7 @@
 package runner
 
 import (
+	"context"
 	"testing"
 
 	"errors"
@@ -62,7 +63,7 @@ func TestRun(t *testing.T) {
 	}
 	args := map[string]interface{}{test: test}
 
-	result, err := testRunner.Run(fn, args)
+	result, err := testRunner.Run(context.Background(), fn, args)
 	faas.AssertExpectations(t)
 	v.AssertExpectations(t)
 	assert.Nil(t, err)
@@ -74,7 +75,7 @@ func TestRun(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
-func runnable0(ctx functions.Context, in interface{}) (interface{}, error) {
+func runnable0(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
 	args := in.(map[string]interface{})
 	if args == nil {
 		return nil, errors.New("nil args")
@@ -89,13 +90,13 @@ func runnable0(ctx functions.Context, in interface{}) (interface{}, error) {
 
 func mw0(n string) functions.Middleware {
 	return func(f functions.Runnable) functions.Runnable {
-		return func(ctx functions.Context, in interface{}) (interface{}, error) {
+		return func(ctx context.Context, fctx functions.Context, in interface{}) (interface{}, error) {
 			args := in.(map[string]interface{})
 
 			traceIn, _ := args[traceInStr].([]string)
 			args[traceInStr] = append(traceIn, n)
 
-			out, err := f(ctx, in)
+			out, err := f(ctx, fctx, in)
 			if err != nil {
 				return nil, err
 			}
@@ -118,7 +119,7 @@ func TestCompose(t *testing.T) {
 		traceOutStr: []string{f0, m2, m1},
 	}
 
-	result, err := Compose(mw0(m1), mw0(m2))(runnable0)(functions.Context{}, a0)
+	result, err := Compose(mw0(m1), mw0(m2))(runnable0)(context.Background(), functions.Context{}, a0)
 	assert.Nil(t, err)
 	assert.Equal(t, expected, result)
 }
@@ -7,6 +7,7 @@ package functions
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -22,8 +23,9 @@ type Message struct {
 	Payload interface{} `json:"payload"`
 }
 
-// Runnable is a runnable representation of a function
-type Runnable func(ctx Context, in interface{}) (interface{}, error)
+// Runnable is a runnable representation of a function. The function must be stopped once ctx is done, e.g. when
+// its timeout is reached.
+type Runnable func(ctx context.Context, fctx Context, in interface{}) (interface{}, error)
 
 // Middleware allows injecting extra steps for each function execution
 type Middleware func(f Runnable) Runnable
@@ -70,10 +72,51 @@ type FaaSDriver interface {
 	GetRunnable(e *FunctionExecution) Runnable
 }
 
-// FunctionResources Memory and CPU
+// Scaler is implemented by FaaS drivers which can stop the instances of idle functions. A function without running
+// instances must be scaled up before its runnable is called.
+type Scaler interface {
+	// Scale sets the number of instances of a function kept running when it is idle, starting missing instances and
+	// stopping idle ones. replicas may be 0.
+	Scale(ctx context.Context, f *Function, replicas int) error
+}
+
+// FunctionResources Memory, CPU and number of processes. Memory and CPU are Kubernetes quantities (e.g. 128Mi, 500m).
 type FunctionResources struct {
 	Memory string `json:"memory"`
 	CPU    string `json:"cpu"`
+	Pids   int64  `json:"pids,omitempty"`
+}
+
+// FunctionSandbox restricts what a function container is allowed to do
+type FunctionSandbox struct {
+	// ReadOnlyRootFS mounts the root filesystem of the container read-only
+	ReadOnlyRootFS bool `json:"readOnlyRootFS,omitempty"`
+	// DropCapabilities are the Linux capabilities removed from the container, ALL removes all of them
+	DropCapabilities []string `json:"dropCapabilities,omitempty"`
+	// SeccompProfile is a JSON seccomp profile, or "unconfined"
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+	// NoNetwork denies network access to the function, which is only reachable by Dispatch
+	NoNetwork bool `json:"noNetwork,omitempty"`
+}
+
+// RetryPolicy defines how failed asynchronous runs are retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Backoff is the delay before the second attempt, doubled after each attempt
+	Backoff time.Duration `json:"backoff,omitempty"`
+	// MaxBackoff caps the delay between attempts, if set
+	MaxBackoff time.Duration `json:"maxBackoff,omitempty"`
+	// RetryOn lists the retried error types, all but InputError are retried if empty
+	RetryOn []string `json:"retryOn,omitempty"`
+}
+
+// RunRetention defines how long the finished runs of a function are kept
+type RunRetention struct {
+	// MaxAge is the age after which finished runs are deleted, not limited if 0
+	MaxAge time.Duration `json:"maxAge,omitempty"`
+	// MaxCount is the maximum number of finished runs kept, not limited if 0
+	MaxCount int `json:"maxCount,omitempty"`
 }
 
 //go:generate mockery -name ImageBuilder -case underscore -dir . -note "CLOSE THIS FILE AS QUICKLY AS POSSIBLE"
@@ -90,7 +133,7 @@ type ImageBuilder interface {
 
 // Runner knows how to execute a function
 type Runner interface {
-	Run(fn *FunctionExecution, in interface{}) (interface{}, error)
+	Run(ctx context.Context, fn *FunctionExecution, in interface{}) (interface{}, error)
 }
 
 // Validator validates function input/output
@@ -122,6 +165,11 @@ type FunctionError interface {
 	AsFunctionErrorObject() interface{}
 }
 
+// TimeoutError represents a function which did not complete before its timeout
+type TimeoutError interface {
+	AsTimeoutErrorObject() interface{}
+}
+
 // SystemError represents error in the Dispatch infrastructure
 type SystemError interface {
 	AsSystemErrorObject() interface{}
@@ -5,6 +5,7 @@
 package validator
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -45,7 +46,7 @@ func TestImpl_GetMiddleware(t *testing.T) {
 			},
 		},
 	}
-	identity := func(ctx functions.Context, input interface{}) (interface{}, error) {
+	identity := func(ctx context.Context, fctx functions.Context, input interface{}) (interface{}, error) {
 		return input, nil
 	}
 
@@ -106,7 +107,7 @@ func TestImpl_GetMiddleware(t *testing.T) {
 	for _, testCase := range testCases {
 		log.Debugf("testcase: %s", testCase.name)
 
-		output, err := v.GetMiddleware(testCase.schemas)(identity)(functions.Context{}, testCase.input)
+		output, err := v.GetMiddleware(testCase.schemas)(identity)(context.Background(), functions.Context{}, testCase.input)
 
 		if !testCase.expectedInputErr && !testCase.expectedFuncErr {
 			require.NoError(t, err)
@@ -11,15 +11,18 @@ import (
 	"github.com/casbin/casbin"
 
 	"github.com/vmware/dispatch/pkg/controller"
+	"github.com/vmware/dispatch/pkg/coordination"
 	"github.com/vmware/dispatch/pkg/entity-store"
 )
 
 // NewIdentityController creates a new controller to manage the reconciliation of policy entities
-func NewIdentityController(store entitystore.EntityStore, enforcer *casbin.SyncedEnforcer, resync time.Duration, zookeeper string) controller.Controller {
+func NewIdentityController(store entitystore.EntityStore, enforcer *casbin.SyncedEnforcer, resync time.Duration, coord coordination.Config) controller.Controller {
 	c := controller.NewController(controller.Options{
-		ResyncPeriod:      resync,
-		Workers:           5, // TODO: make this configurable
-		ZookeeperLocation: zookeeper,
+		ServiceName:  "identity",
+		ResyncPeriod: resync,
+		Workers:      5, // TODO: make this configurable
+		Coordination: coord,
+		Store:        store,
 	})
 
 	c.AddEntityHandler(&policyEntityHandler{store: store, enforcer: enforcer})
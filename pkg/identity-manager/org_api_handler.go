@@ -11,6 +11,7 @@ import (
 	middleware "github.com/go-openapi/runtime/middleware"
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/vmware/dispatch/pkg/api/v1"
@@ -51,7 +52,16 @@ func (h *Handlers) getOrganizations(params organizationOperations.GetOrganizatio
 	opts := entitystore.Options{
 		Filter: entitystore.FilterExists(),
 	}
-	err := h.store.ListGlobal(ctx, opts, &organizations)
+	opts, err := utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return organizationOperations.NewGetOrganizationsDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	err = h.store.ListGlobal(ctx, opts, &organizations)
 	if err != nil {
 		log.Errorf("store error when listing organizations: %+v", err)
 		return organizationOperations.NewGetOrganizationsDefault(500).WithPayload(
@@ -64,7 +74,7 @@ func (h *Handlers) getOrganizations(params organizationOperations.GetOrganizatio
 	for _, organization := range organizations {
 		organizationModels = append(organizationModels, organizationEntityToModel(organization))
 	}
-	return organizationOperations.NewGetOrganizationsOK().WithPayload(organizationModels)
+	return organizationOperations.NewGetOrganizationsOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(organizations))).WithPayload(organizationModels)
 }
 
 func (h *Handlers) getOrganization(params organizationOperations.GetOrganizationParams, principal interface{}) middleware.Responder {
@@ -138,8 +148,31 @@ func (h *Handlers) deleteOrganization(params organizationOperations.DeleteOrgani
 			})
 	}
 
-	e.Status = entitystore.StatusDELETING
-	if err := h.store.Delete(ctx, name, name, &e); err != nil {
+	// the policies and service accounts of the organization are removed with it, in a transaction
+	var policies []*Policy
+	var serviceAccounts []*ServiceAccount
+	err := h.store.List(ctx, name, entitystore.Options{Filter: entitystore.FilterExists()}, &policies)
+	if err == nil {
+		err = h.store.List(ctx, name, entitystore.Options{}, &serviceAccounts)
+	}
+	if err == nil {
+		e.Status = entitystore.StatusDELETING
+		err = h.store.Transaction(ctx, func(tx entitystore.EntityStore) error {
+			for _, sa := range serviceAccounts {
+				if err := tx.Delete(ctx, sa.OrganizationID, sa.Name, sa); err != nil {
+					return errors.Wrapf(err, "error deleting service account %s", sa.Name)
+				}
+			}
+			for _, policy := range policies {
+				policy.Status = entitystore.StatusDELETING
+				if _, err := tx.Update(ctx, policy.Revision, policy); err != nil {
+					return errors.Wrapf(err, "error deleting policy %s", policy.Name)
+				}
+			}
+			return tx.Delete(ctx, name, name, &e)
+		})
+	}
+	if err != nil {
 		log.Errorf("store error when deleting a organization %s: %+v", e.Name, err)
 		return organizationOperations.NewDeleteOrganizationDefault(500).WithPayload(&v1.Error{
 			Code:    http.StatusInternalServerError,
@@ -147,6 +180,9 @@ func (h *Handlers) deleteOrganization(params organizationOperations.DeleteOrgani
 		})
 	}
 
+	for _, policy := range policies {
+		h.watcher.OnAction(ctx, policy)
+	}
 	return organizationOperations.NewDeleteOrganizationOK().WithPayload(organizationEntityToModel(&e))
 }
 
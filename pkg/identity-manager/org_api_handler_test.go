@@ -118,6 +118,40 @@ func TestDeleteOrganizationHandler(t *testing.T) {
 	helpers.HandlerRequest(t, responder, &respBody, http.StatusNotFound)
 }
 
+func TestDeleteOrganizationHandlerRemovesOrganizationEntities(t *testing.T) {
+	api := operations.NewIdentityManagerAPI(nil)
+	es := helpers.MakeEntityStore(t)
+	ctx := context.Background()
+	for _, e := range []entitystore.Entity{
+		&Organization{BaseEntity: entitystore.BaseEntity{Name: "test-org", OrganizationID: "test-org"}},
+		&Policy{BaseEntity: entitystore.BaseEntity{Name: "test-policy", OrganizationID: "test-org"}},
+		&ServiceAccount{BaseEntity: entitystore.BaseEntity{Name: "test-sa", OrganizationID: "test-org"}},
+		&Policy{BaseEntity: entitystore.BaseEntity{Name: "other-policy", OrganizationID: "other-org"}},
+	} {
+		_, err := es.Add(ctx, e)
+		assert.NoError(t, err)
+	}
+	handlers := NewHandlers(nil, es, SetupEnforcer(es))
+	helpers.MakeAPI(t, handlers.ConfigureHandlers, api)
+
+	params := organizationOperations.DeleteOrganizationParams{
+		HTTPRequest:      httptest.NewRequest("DELETE", "/v1/iam/organization/test-org", nil),
+		OrganizationName: "test-org",
+	}
+	responder := api.OrganizationDeleteOrganizationHandler.Handle(params, "testCookie")
+	var respBody v1.Organization
+	helpers.HandlerRequest(t, responder, &respBody, http.StatusOK)
+
+	found, err := es.Find(ctx, "test-org", "test-sa", entitystore.Options{}, &ServiceAccount{})
+	assert.NoError(t, err)
+	assert.False(t, found)
+	var policy Policy
+	assert.NoError(t, es.Get(ctx, "test-org", "test-policy", entitystore.Options{}, &policy))
+	assert.Equal(t, entitystore.StatusDELETING, policy.Status)
+	assert.NoError(t, es.Get(ctx, "other-org", "other-policy", entitystore.Options{}, &policy))
+	assert.NotEqual(t, entitystore.StatusDELETING, policy.Status)
+}
+
 func TestDeleteOrganizationHandlerNotFound(t *testing.T) {
 
 	r := httptest.NewRequest("DELETE", "/v1/iam/organization/test-organization-unknown", nil)
@@ -69,7 +69,16 @@ func (h *Handlers) getPolicies(params policyOperations.GetPoliciesParams, princi
 	opts := entitystore.Options{
 		Filter: entitystore.FilterExists(),
 	}
-	err := h.store.List(ctx, params.XDispatchOrg, opts, &policies)
+	opts, err := utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return policyOperations.NewGetPoliciesDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	err = h.store.List(ctx, params.XDispatchOrg, opts, &policies)
 	if err != nil {
 		log.Errorf("store error when listing policies: %+v", err)
 		return policyOperations.NewGetPoliciesDefault(500).WithPayload(
@@ -82,7 +91,7 @@ func (h *Handlers) getPolicies(params policyOperations.GetPoliciesParams, princi
 	for _, policy := range policies {
 		policyModels = append(policyModels, policyEntityToModel(policy))
 	}
-	return policyOperations.NewGetPoliciesOK().WithPayload(policyModels)
+	return policyOperations.NewGetPoliciesOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(policies))).WithPayload(policyModels)
 }
 
 func (h *Handlers) getPolicy(params policyOperations.GetPolicyParams, principal interface{}) middleware.Responder {
@@ -63,7 +63,16 @@ func (h *Handlers) getServiceAccounts(params serviceAccountOperations.GetService
 	opts := entitystore.Options{
 		Filter: entitystore.FilterExists(),
 	}
-	err := h.store.List(ctx, params.XDispatchOrg, opts, &serviceAccounts)
+	opts, err := utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return serviceAccountOperations.NewGetServiceAccountsDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	err = h.store.List(ctx, params.XDispatchOrg, opts, &serviceAccounts)
 	if err != nil {
 		log.Errorf("store error when listing service accounts: %+v", err)
 		return serviceAccountOperations.NewGetServiceAccountsDefault(500).WithPayload(
@@ -76,7 +85,7 @@ func (h *Handlers) getServiceAccounts(params serviceAccountOperations.GetService
 	for _, serviceAccount := range serviceAccounts {
 		serviceAccountModels = append(serviceAccountModels, serviceAccountEntityToModel(serviceAccount))
 	}
-	return serviceAccountOperations.NewGetServiceAccountsOK().WithPayload(serviceAccountModels)
+	return serviceAccountOperations.NewGetServiceAccountsOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(serviceAccounts))).WithPayload(serviceAccountModels)
 }
 
 func (h *Handlers) getServiceAccount(params serviceAccountOperations.GetServiceAccountParams, principal interface{}) middleware.Responder {
@@ -14,14 +14,15 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/vmware/dispatch/pkg/controller"
+	"github.com/vmware/dispatch/pkg/coordination"
 	entitystore "github.com/vmware/dispatch/pkg/entity-store"
 	"github.com/vmware/dispatch/pkg/trace"
 )
 
 // ControllerConfig defines the image manager controller configuration
 type ControllerConfig struct {
-	ResyncPeriod      time.Duration
-	ZookeeperLocation string
+	ResyncPeriod time.Duration
+	Coordination coordination.Config
 }
 
 type baseImageEntityHandler struct {
@@ -181,10 +182,11 @@ func (h *imageEntityHandler) Error(ctx context.Context, obj entitystore.Entity)
 // NewController creates a new image manager controller
 func NewController(config *ControllerConfig, store entitystore.EntityStore, baseImageBuilder *BaseImageBuilder, imageBuilder *ImageBuilder) controller.Controller {
 	c := controller.NewController(controller.Options{
-		ResyncPeriod:      config.ResyncPeriod,
-		Workers:           10, // want more functions concurrently? add more workers // TODO configure workers
-		ServiceName:       "images",
-		ZookeeperLocation: config.ZookeeperLocation,
+		ResyncPeriod: config.ResyncPeriod,
+		Workers:      10, // want more functions concurrently? add more workers // TODO configure workers
+		ServiceName:  "images",
+		Coordination: config.Coordination,
+		Store:        store,
 	})
 
 	c.AddEntityHandler(&baseImageEntityHandler{Store: store, Builder: baseImageBuilder})
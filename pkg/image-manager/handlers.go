@@ -254,6 +254,15 @@ func (h *Handlers) getBaseImages(params baseimage.GetBaseImagesParams, principal
 	opts := entitystore.Options{
 		Filter: entitystore.FilterExists(),
 	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return baseimage.NewGetBaseImagesDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
 	err = h.Store.List(ctx, params.XDispatchOrg, opts, &images)
 	if err != nil {
 		log.Errorf("store error when listing base images: %+v", err)
@@ -267,7 +276,7 @@ func (h *Handlers) getBaseImages(params baseimage.GetBaseImagesParams, principal
 	for _, i := range images {
 		imageModels = append(imageModels, baseImageEntityToModel(i))
 	}
-	return baseimage.NewGetBaseImagesOK().WithPayload(imageModels)
+	return baseimage.NewGetBaseImagesOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(images))).WithPayload(imageModels)
 }
 
 func (h *Handlers) updateBaseImageByName(params baseimage.UpdateBaseImageByNameParams, principal interface{}) middleware.Responder {
@@ -397,7 +406,7 @@ func (h *Handlers) getImageByName(params image.GetImageByNameParams, principal i
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return image.NewGetImageByNameBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -430,7 +439,16 @@ func (h *Handlers) getImages(params image.GetImagesParams, principal interface{}
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
+		return image.NewGetImagesBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
 		return image.NewGetImagesBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -452,7 +470,7 @@ func (h *Handlers) getImages(params image.GetImagesParams, principal interface{}
 		imageModels = append(imageModels, imageEntityToModel(i))
 	}
 
-	return image.NewGetImagesOK().WithPayload(imageModels)
+	return image.NewGetImagesOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(images))).WithPayload(imageModels)
 }
 
 func (h *Handlers) updateImageByName(params image.UpdateImageByNameParams, principal interface{}) middleware.Responder {
@@ -522,7 +540,7 @@ func (h *Handlers) deleteImageByName(params image.DeleteImageByNameParams, princ
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return image.NewDeleteImageByNameBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
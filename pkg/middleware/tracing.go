@@ -33,6 +33,13 @@ func (w *statusCodeTracker) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 }
 
+// Flush sends the buffered data to the client, if the wrapped ResponseWriter supports it
+func (w *statusCodeTracker) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 type mwOptions struct {
 	opNameFunc func(r *http.Request) string
 }
@@ -46,6 +46,20 @@ func (_m *EntityStore) Delete(ctx context.Context, organizationID string, id str
 	return r0
 }
 
+// Export provides a mock function with given fields: ctx, opts, fn
+func (_m *EntityStore) Export(ctx context.Context, opts entitystore.ExportOptions, fn func(entitystore.Record) error) error {
+	ret := _m.Called(ctx, opts, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entitystore.ExportOptions, func(entitystore.Record) error) error); ok {
+		r0 = rf(ctx, opts, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Find provides a mock function with given fields: ctx, organizationID, key, opts, entity
 func (_m *EntityStore) Find(ctx context.Context, organizationID string, key string, opts entitystore.Options, entity entitystore.Entity) (bool, error) {
 	ret := _m.Called(ctx, organizationID, key, opts, entity)
@@ -81,6 +95,20 @@ func (_m *EntityStore) Get(ctx context.Context, organizationID string, key strin
 	return r0
 }
 
+// Import provides a mock function with given fields: ctx, record
+func (_m *EntityStore) Import(ctx context.Context, record entitystore.Record) error {
+	ret := _m.Called(ctx, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entitystore.Record) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // List provides a mock function with given fields: ctx, organizationID, opts, entities
 func (_m *EntityStore) List(ctx context.Context, organizationID string, opts entitystore.Options, entities interface{}) error {
 	ret := _m.Called(ctx, organizationID, opts, entities)
@@ -123,6 +151,20 @@ func (_m *EntityStore) SoftDelete(ctx context.Context, entity entitystore.Entity
 	return r0
 }
 
+// Transaction provides a mock function with given fields: ctx, fn
+func (_m *EntityStore) Transaction(ctx context.Context, fn func(entitystore.EntityStore) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(entitystore.EntityStore) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: ctx, lastRevision, entity
 func (_m *EntityStore) Update(ctx context.Context, lastRevision uint64, entity entitystore.Entity) (int64, error) {
 	ret := _m.Called(ctx, lastRevision, entity)
@@ -148,3 +190,26 @@ func (_m *EntityStore) Update(ctx context.Context, lastRevision uint64, entity e
 func (_m *EntityStore) UpdateWithError(ctx context.Context, e entitystore.Entity, err error) {
 	_m.Called(ctx, e, err)
 }
+
+// Watch provides a mock function with given fields: ctx, dataType, organizationID, sinceRevision
+func (_m *EntityStore) Watch(ctx context.Context, dataType entitystore.DataType, organizationID string, sinceRevision uint64) (<-chan entitystore.Event, error) {
+	ret := _m.Called(ctx, dataType, organizationID, sinceRevision)
+
+	var r0 <-chan entitystore.Event
+	if rf, ok := ret.Get(0).(func(context.Context, entitystore.DataType, string, uint64) <-chan entitystore.Event); ok {
+		r0 = rf(ctx, dataType, organizationID, sinceRevision)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan entitystore.Event)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entitystore.DataType, string, uint64) error); ok {
+		r1 = rf(ctx, dataType, organizationID, sinceRevision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
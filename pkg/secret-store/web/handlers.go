@@ -93,7 +93,7 @@ func (h *Handlers) getSecrets(params secret.GetSecretsParams, principal interfac
 
 	filter, err := utils.ParseTags(nil, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return secret.NewGetSecretsBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -101,9 +101,17 @@ func (h *Handlers) getSecrets(params secret.GetSecretsParams, principal interfac
 			})
 	}
 
-	vmwSecrets, err := h.secretsService.GetSecrets(ctx, params.XDispatchOrg, entitystore.Options{
-		Filter: filter,
-	})
+	opts, err := utils.ParsePage(entitystore.Options{Filter: filter}, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return secret.NewGetSecretsBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+
+	vmwSecrets, err := h.secretsService.GetSecrets(ctx, params.XDispatchOrg, opts)
 	if err != nil {
 		log.Errorf("error when listing secrets from k8s APIs: %+v", err)
 		return secret.NewGetSecretsDefault(http.StatusInternalServerError).WithPayload(&v1.Error{
@@ -112,7 +120,7 @@ func (h *Handlers) getSecrets(params secret.GetSecretsParams, principal interfac
 		})
 	}
 
-	return secret.NewGetSecretsOK().WithPayload(vmwSecrets)
+	return secret.NewGetSecretsOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(vmwSecrets))).WithPayload(vmwSecrets)
 }
 
 func (h *Handlers) getSecret(params secret.GetSecretParams, principal interface{}) middleware.Responder {
@@ -121,7 +129,7 @@ func (h *Handlers) getSecret(params secret.GetSecretParams, principal interface{
 
 	filter, err := utils.ParseTags(nil, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return secret.NewGetSecretBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -153,7 +161,7 @@ func (h *Handlers) updateSecret(params secret.UpdateSecretParams, principal inte
 
 	filter, err := utils.ParseTags(nil, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return secret.NewUpdateSecretBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -188,7 +196,7 @@ func (h *Handlers) deleteSecret(params secret.DeleteSecretParams, principal inte
 
 	filter, err := utils.ParseTags(nil, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
 		return secret.NewDeleteSecretBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
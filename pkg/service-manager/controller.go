@@ -15,6 +15,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/vmware/dispatch/pkg/controller"
+	"github.com/vmware/dispatch/pkg/coordination"
 	entitystore "github.com/vmware/dispatch/pkg/entity-store"
 	"github.com/vmware/dispatch/pkg/service-manager/clients"
 	"github.com/vmware/dispatch/pkg/service-manager/entities"
@@ -25,8 +26,8 @@ var serviceClassOrganizationID = "___global___"
 
 // ControllerConfig defines the image manager controller configuration
 type ControllerConfig struct {
-	ResyncPeriod      time.Duration
-	ZookeeperLocation string
+	ResyncPeriod time.Duration
+	Coordination coordination.Config
 }
 
 type serviceClassEntityHandler struct {
@@ -464,9 +465,11 @@ func (h *serviceBindingEntityHandler) Error(ctx context.Context, obj entitystore
 // NewController creates a new service manager controller
 func NewController(config *ControllerConfig, store entitystore.EntityStore, brokerClient clients.BrokerClient) controller.Controller {
 	c := controller.NewController(controller.Options{
-		ResyncPeriod:      config.ResyncPeriod,
-		Workers:           10, // want more functions concurrently? add more workers // TODO configure workers
-		ZookeeperLocation: config.ZookeeperLocation,
+		ServiceName:  "services",
+		ResyncPeriod: config.ResyncPeriod,
+		Workers:      10, // want more functions concurrently? add more workers // TODO configure workers
+		Coordination: config.Coordination,
+		Store:        store,
 	})
 
 	c.AddEntityHandler(&serviceClassEntityHandler{Store: store, BrokerClient: brokerClient})
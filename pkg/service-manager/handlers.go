@@ -99,6 +99,24 @@ func (h *Handlers) getServiceClasses(params serviceclass.GetServiceClassesParams
 	opts := entitystore.Options{
 		Filter: entitystore.FilterExists(),
 	}
+	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
+	if err != nil {
+		log.Debugf("%v", err)
+		return serviceclass.NewGetServiceClassesDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
+		return serviceclass.NewGetServiceClassesDefault(http.StatusBadRequest).WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
 	err = h.Store.List(ctx, serviceClassOrganizationID, opts, &classes)
 	if err != nil {
 		log.Errorf("store error when listing service classes: %+v", err)
@@ -112,7 +130,7 @@ func (h *Handlers) getServiceClasses(params serviceclass.GetServiceClassesParams
 	for _, class := range classes {
 		classModels = append(classModels, entities.ServiceClassEntityToModel(class))
 	}
-	return serviceclass.NewGetServiceClassesOK().WithPayload(classModels)
+	return serviceclass.NewGetServiceClassesOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(classes))).WithPayload(classModels)
 }
 
 func (h *Handlers) addServiceInstance(params serviceinstance.AddServiceInstanceParams, principal interface{}) middleware.Responder {
@@ -240,7 +258,16 @@ func (h *Handlers) getServiceInstances(params serviceinstance.GetServiceInstance
 	}
 	opts.Filter, err = utils.ParseTags(opts.Filter, params.Tags)
 	if err != nil {
-		log.Errorf(err.Error())
+		log.Debugf("%v", err)
+		return serviceinstance.NewGetServiceInstancesBadRequest().WithPayload(
+			&v1.Error{
+				Code:    http.StatusBadRequest,
+				Message: swag.String(err.Error()),
+			})
+	}
+	opts, err = utils.ParsePage(opts, params.Limit, params.OrderBy, params.Page)
+	if err != nil {
+		log.Debugf("%v", err)
 		return serviceinstance.NewGetServiceInstancesBadRequest().WithPayload(
 			&v1.Error{
 				Code:    http.StatusBadRequest,
@@ -257,8 +284,9 @@ func (h *Handlers) getServiceInstances(params serviceinstance.GetServiceInstance
 				Message: swag.String("internal server error while listing service instances"),
 			})
 	}
+	// bindings are matched by name, list them all rather than the same page
 	var bindings []*entities.ServiceBinding
-	err = h.Store.List(ctx, params.XDispatchOrg, opts, &bindings)
+	err = h.Store.List(ctx, params.XDispatchOrg, entitystore.Options{Filter: opts.Filter}, &bindings)
 	if err != nil {
 		log.Errorf("store error when listing service bindings: %+v", err)
 		return serviceinstance.NewGetServiceInstancesDefault(http.StatusInternalServerError).WithPayload(
@@ -276,7 +304,7 @@ func (h *Handlers) getServiceInstances(params serviceinstance.GetServiceInstance
 		binding := bindingsMap[service.Name]
 		serviceModels = append(serviceModels, entities.ServiceInstanceEntityToModel(service, binding))
 	}
-	return serviceinstance.NewGetServiceInstancesOK().WithPayload(serviceModels)
+	return serviceinstance.NewGetServiceInstancesOK().WithXDispatchNextPage(entitystore.NextCursor(opts, len(services))).WithPayload(serviceModels)
 }
 
 func (h *Handlers) deleteServiceInstanceByName(params serviceinstance.DeleteServiceInstanceByNameParams, principal interface{}) middleware.Responder {
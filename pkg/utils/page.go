@@ -0,0 +1,32 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package utils
+
+// NO TESTS
+
+import (
+	"github.com/pkg/errors"
+
+	es "github.com/vmware/dispatch/pkg/entity-store"
+)
+
+// ParsePage sets the limit, order and page token passed from dispatch client to the list options,
+// the page token being the one returned by a previous list request
+func ParsePage(opts es.Options, limit *int64, orderBy *string, page *string) (es.Options, error) {
+	if limit != nil {
+		opts.Limit = int(*limit)
+	}
+	if orderBy != nil {
+		opts.OrderBy = *orderBy
+	}
+	if page != nil {
+		opts.Cursor = *page
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, errors.Wrap(err, "error parsing page")
+	}
+	return opts, nil
+}
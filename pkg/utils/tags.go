@@ -5,34 +5,103 @@
 
 package utils
 
-// NO TESTS
-
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	es "github.com/vmware/dispatch/pkg/entity-store"
 )
 
-// ParseTags parses tags pass from dispatch client,
-// a tag is key-value pair string, separate by an equal symbol
-// the only valid format of a tag is "<key>=<value>"
+// tagOperators are the operators of a tag filter, the two characters ones first
+var tagOperators = []struct {
+	operator string
+	verb     es.Verb
+}{
+	{"!=", es.FilterVerbNotEqual},
+	{">=", es.FilterVerbGreaterOrEqual},
+	{"<=", es.FilterVerbLessOrEqual},
+	{"^=", es.FilterVerbPrefix},
+	{"*=", es.FilterVerbContains},
+	{"=", es.FilterVerbEqual},
+	{">", es.FilterVerbGreaterThan},
+	{"<", es.FilterVerbLessThan},
+}
+
+// ParseTags parses tags pass from dispatch client, an entity must match all of them.
+// A tag is "<key>=<value>" or "<key>!=<value>" to test (in)equality, "<key>=<v1>|<v2>" to match any of the values,
+// "<key>^=<prefix>" and "<key>*=<text>" to test that the value starts with, or contains, a string,
+// "<key>>n", "<key>>=n", "<key><n" and "<key><=n" to compare the value to a number,
+// and "<key>" or "!<key>" to test that the tag is set, or not.
 func ParseTags(filter es.Filter, tags []string) (es.Filter, error) {
 	if filter == nil {
 		filter = es.FilterEverything()
 	}
 	for _, tag := range tags {
-		values := strings.Split(tag, "=")
-		if len(values) != 2 {
-			return nil, fmt.Errorf("error parsing tag '%s': invalid format", tag)
-		}
-		key, value := values[0], values[1]
-		switch strings.ToLower(key) {
-		case "application", "app":
-			filter.Add(es.FilterStatByApplication(value))
-		default:
-			return nil, fmt.Errorf("error parsing tag '%s': key '%s' not exist", tag, key)
+		stat, err := parseTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing tag '%s': %s", tag, err)
 		}
+		filter.Add(stat)
 	}
 	return filter, nil
 }
+
+func parseTag(tag string) (es.FilterStat, error) {
+	if strings.HasPrefix(tag, "!") && !strings.HasPrefix(tag, "!=") {
+		key, err := tagKey(tag[1:])
+		if err != nil {
+			return es.FilterStat{}, err
+		}
+		return es.FilterStatNot(es.FilterStat{Scope: es.FilterScopeTag, Subject: key, Verb: es.FilterVerbExists}), nil
+	}
+
+	i := strings.IndexAny(tag, "!=<>^*")
+	if i < 0 {
+		key, err := tagKey(tag)
+		if err != nil {
+			return es.FilterStat{}, err
+		}
+		return es.FilterStat{Scope: es.FilterScopeTag, Subject: key, Verb: es.FilterVerbExists}, nil
+	}
+	key, err := tagKey(tag[:i])
+	if err != nil {
+		return es.FilterStat{}, err
+	}
+	for _, op := range tagOperators {
+		if !strings.HasPrefix(tag[i:], op.operator) {
+			continue
+		}
+		value := tag[i+len(op.operator):]
+		stat := es.FilterStat{Scope: es.FilterScopeTag, Subject: key, Verb: op.verb, Object: value}
+		switch op.verb {
+		case es.FilterVerbEqual, es.FilterVerbNotEqual:
+			if !strings.Contains(value, "|") {
+				return stat, nil
+			}
+			stat.Verb, stat.Object = es.FilterVerbIn, strings.Split(value, "|")
+			if op.verb == es.FilterVerbNotEqual {
+				return es.FilterStatNot(stat), nil
+			}
+		case es.FilterVerbGreaterThan, es.FilterVerbGreaterOrEqual, es.FilterVerbLessThan, es.FilterVerbLessOrEqual:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return es.FilterStat{}, fmt.Errorf("'%s' is not a number", value)
+			}
+			stat.Object = n
+		}
+		return stat, nil
+	}
+	return es.FilterStat{}, fmt.Errorf("invalid format")
+}
+
+// tagKey validates a tag key, "app" being short for the application tag
+func tagKey(key string) (string, error) {
+	switch strings.ToLower(key) {
+	case "":
+		return "", fmt.Errorf("missing key")
+	case "application", "app":
+		return "Application", nil
+	}
+	return key, nil
+}
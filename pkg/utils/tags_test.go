@@ -0,0 +1,50 @@
+///////////////////////////////////////////////////////////////////////
+// Copyright (c) 2018 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+///////////////////////////////////////////////////////////////////////
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	es "github.com/vmware/dispatch/pkg/entity-store"
+)
+
+func TestParseTags(t *testing.T) {
+	tag := func(key string, verb es.Verb, object interface{}) es.FilterStat {
+		return es.FilterStat{Scope: es.FilterScopeTag, Subject: key, Verb: verb, Object: object}
+	}
+
+	cases := []struct {
+		given    string
+		expected es.FilterStat
+	}{
+		{"app=foo", es.FilterStatByApplication("foo")},
+		{"env=prod", tag("env", es.FilterVerbEqual, "prod")},
+		{"env!=prod", tag("env", es.FilterVerbNotEqual, "prod")},
+		{"tier=gold|silver", tag("tier", es.FilterVerbIn, []string{"gold", "silver"})},
+		{"tier!=gold|silver", es.FilterStatNot(tag("tier", es.FilterVerbIn, []string{"gold", "silver"}))},
+		{"name^=team-a-", tag("name", es.FilterVerbPrefix, "team-a-")},
+		{"name*=a=b", tag("name", es.FilterVerbContains, "a=b")},
+		{"replicas>3", tag("replicas", es.FilterVerbGreaterThan, 3.0)},
+		{"replicas>=3", tag("replicas", es.FilterVerbGreaterOrEqual, 3.0)},
+		{"replicas<0.5", tag("replicas", es.FilterVerbLessThan, 0.5)},
+		{"replicas<=-1", tag("replicas", es.FilterVerbLessOrEqual, -1.0)},
+		{"env", tag("env", es.FilterVerbExists, nil)},
+		{"!env", es.FilterStatNot(tag("env", es.FilterVerbExists, nil))},
+	}
+	for _, c := range cases {
+		filter, err := ParseTags(nil, []string{c.given})
+		if assert.NoError(t, err, c.given) {
+			assert.Equal(t, []es.FilterStat{c.expected}, filter.FilterStats(), c.given)
+		}
+	}
+
+	for _, given := range []string{"", "=foo", "!", "!=foo", "replicas>many"} {
+		_, err := ParseTags(nil, []string{given})
+		assert.Error(t, err, given)
+	}
+}
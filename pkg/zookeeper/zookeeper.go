@@ -7,6 +7,8 @@ package zookeeper
 
 import (
 	"fmt"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,22 +24,7 @@ const (
 	NodeCreated = zk.EventNodeCreated
 )
 
-// Driver is an interface that abstracts zookeeper calls
-// This is a simple interface that allows for the creation/deletion of nodes
-// Also we can watch for the creation of a node
-type Driver interface {
-	Connect(url string) error
-	GetConnection() *zk.Conn
-	CreateNode(path string, data []byte) error
-	DeleteNode(path string) error
-	WatchForNode(path string) (<-chan zk.Event, error)
-	LockEntity(name string) (string, bool)
-	ReleaseEntity(lock string)
-	GetData(path string) ([]byte, error)
-	Close()
-}
-
-// Zdriver is a simple implementation of the Driver interface
+// Zdriver is the zookeeper implementation of the coordination driver
 // This allows us to create and delete nodes, and create watches
 type Zdriver struct {
 	client *zk.Conn
@@ -50,14 +37,25 @@ type Zdriver struct {
 // Source: http://zookeeper.apache.org/doc/r3.1.2/recipes.html
 // The only change here is that we don't want to spin waiting for the lock
 func (d *Zdriver) LockEntity(entity string) (string, bool) {
+	if err := d.CreateNode("/entities", []byte{}); err != nil {
+		log.Warnf("Unable to create lock node for %v: %v", entity, err)
+		return "", false
+	}
+	if err := d.CreateNode(fmt.Sprintf("/entities/%v", entity), []byte{}); err != nil {
+		log.Warnf("Unable to create lock node for %v: %v", entity, err)
+		return "", false
+	}
 	createOn := fmt.Sprintf("/entities/%v/lock-", entity)
 	path, err := d.client.CreateProtectedEphemeralSequential(createOn, []byte("lock"), d.acl)
 	if err != nil {
 		log.Warnf("Unable to create lock node for %v: %v", entity, err)
+		return "", false
 	}
 	children, _, err := d.client.Children(fmt.Sprintf("/entities/%v", entity))
 	if err != nil {
 		log.Warnf("Unable to get children of entity %v: %v", entity, err)
+		d.DeleteNode(path)
+		return "", false
 	}
 	sfx := strings.Split(path, "lock-")[1]
 	for _, child := range children {
@@ -70,11 +68,49 @@ func (d *Zdriver) LockEntity(entity string) (string, bool) {
 	return path, true
 }
 
+// Join registers the driver as a member of a group, for as long as its zookeeper session lives
+// Returns the ID of the member, members are ordered by the time they joined
+// This is the first half of the leader election recipe, the leader being the oldest member
+func (d *Zdriver) Join(group string) (string, error) {
+	if err := d.CreateNode("/members", []byte{}); err != nil {
+		return "", err
+	}
+	groupPath := fmt.Sprintf("/members/%v", group)
+	if err := d.CreateNode(groupPath, []byte{}); err != nil {
+		return "", err
+	}
+	member, err := d.client.Create(groupPath+"/member-", []byte{}, zk.FlagEphemeral|zk.FlagSequence, d.acl)
+	if err != nil {
+		return "", errors.Errorf("Unable to join group %v: %v", group, err)
+	}
+	log.Infof("Joined group %v as %v", group, path.Base(member))
+	return path.Base(member), nil
+}
+
+// Members returns the IDs of the live members of a group, oldest first, and a channel closed on the next
+// membership change
+func (d *Zdriver) Members(group string) ([]string, <-chan struct{}, error) {
+	members, _, watch, err := d.client.ChildrenW(fmt.Sprintf("/members/%v", group))
+	if err != nil {
+		return nil, nil, errors.Errorf("Unable to get members of group %v: %v", group, err)
+	}
+	// sequence numbers are zero-padded, so they sort lexically
+	sort.Strings(members)
+	changed := make(chan struct{})
+	go func() {
+		<-watch
+		close(changed)
+	}()
+	return members, changed, nil
+}
+
 // ReleaseEntity releases the entity by deleting the znode that represents the lock
 func (d *Zdriver) ReleaseEntity(path string) {
 	err := d.client.Delete(path, -1)
 	if err != nil {
-		log.Fatalf("Unable to delete lock %v: %v", path, err)
+		// the lock is ephemeral, it goes away with the session anyway
+		log.Errorf("Unable to delete lock %v: %v", path, err)
+		return
 	}
 	log.Infof("Released lock %v", path)
 }